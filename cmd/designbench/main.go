@@ -2,20 +2,53 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc/credentials"
 
+	"github.com/tahatesser/designbench/pkg/agent"
+	"github.com/tahatesser/designbench/pkg/agent/agentpb"
 	"github.com/tahatesser/designbench/pkg/android"
+	"github.com/tahatesser/designbench/pkg/appium"
+	"github.com/tahatesser/designbench/pkg/calibration"
+	"github.com/tahatesser/designbench/pkg/ci"
+	"github.com/tahatesser/designbench/pkg/config"
+	"github.com/tahatesser/designbench/pkg/events"
+	"github.com/tahatesser/designbench/pkg/export"
+	"github.com/tahatesser/designbench/pkg/harness"
+	"github.com/tahatesser/designbench/pkg/history"
 	"github.com/tahatesser/designbench/pkg/ios"
+	"github.com/tahatesser/designbench/pkg/notify"
 	"github.com/tahatesser/designbench/pkg/preflight"
+	"github.com/tahatesser/designbench/pkg/redact"
 	"github.com/tahatesser/designbench/pkg/report"
+	"github.com/tahatesser/designbench/pkg/reviewdog"
+	"github.com/tahatesser/designbench/pkg/schedule"
+	"github.com/tahatesser/designbench/pkg/server"
+	"github.com/tahatesser/designbench/pkg/stats"
+	"github.com/tahatesser/designbench/pkg/watchdog"
 )
 
 var (
@@ -23,10 +56,66 @@ var (
 	viewFlag      string
 	outputPath    string
 	timeoutFlag   string
+
+	iterationsFlag    int
+	targetCIWidthFlag string
+	percentilesFlag   string
+	scenarioFlag      string
+	verboseFlag       bool
+	logFileFlag       string
+	configPath        string
+	profileFlag       string
+	idleCPUFlag       string
+	idleTimeoutFlag   string
+	settleFlag        string
+	launchTimeoutFlag string
+	allowDebugFlag    bool
+	exportFlags       []string
+	gitlabMRFlag      bool
+	formatFlags       []string
+	noSaveFlag        bool
+	appendFlag        bool
+	noColorFlag       bool
+
+	// spreadStatistics are the statistics parsed from --percentiles.
+	spreadStatistics []string
+	// regressionThresholds are the config's per-family thresholds, which take precedence over
+	// --regression-threshold for the metrics they cover.
+	regressionThresholds report.Thresholds
+	// derivedMetrics are the config's derived metrics, computed into every report.
+	derivedMetrics []report.DerivedMetric
 )
 
+// projectConfig holds the values loaded from designbench.yaml, if any.
+var projectConfig config.Config
+
 const defaultReportsDir = "designbench-reports"
 
+// defaultSourceMap maps components to their source files for --reviewdog.
+const defaultSourceMap = "designbench-sources.yaml"
+
+// reportFormats are the values accepted by --format; each is also the default report file extension.
+var reportFormats = []string{"json", "html", "pdf", "csv"}
+
+// Benchmark scenarios selectable with --scenario.
+const (
+	scenarioLaunch      = "launch"
+	scenarioSplitView   = "split-view"
+	scenarioThemeSwitch = "theme-switch"
+	scenarioMonkey      = "monkey"
+	scenarioTapLatency  = "tap-latency"
+	scenarioAnimation   = "animation"
+)
+
+// multitaskingLayouts are forwarded to the iOS harness as DESIGNBENCH_MULTITASKING; the harness hosts
+// the component at the matching width: full screen, 1/2 and 1/3 Split View, and Slide Over.
+var multitaskingLayouts = []string{"full", "split-half", "split-third", "slide-over"}
+
+const (
+	minAdaptiveIterations        = 3
+	defaultAdaptiveMaxIterations = 20
+)
+
 func main() {
 	root := newRootCmd()
 	if err := root.Execute(); err != nil {
@@ -39,47 +128,271 @@ func newRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "designbench",
 		Short: "designbench benchmarks UI render performance across Android and iOS.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyEnvFlags(cmd.Flags()); err != nil {
+				return err
+			}
+			if cmd.Name() == "init" {
+				return nil
+			}
+			if err := loadProjectConfig(cmd); err != nil {
+				return err
+			}
+			statistics, err := report.ParseStatistics(percentilesFlag)
+			if err != nil {
+				return fmt.Errorf("invalid --percentiles: %w", err)
+			}
+			spreadStatistics = statistics
+			formatFlags = splitList(strings.Join(formatFlags, ","))
+			if len(formatFlags) == 0 {
+				return errors.New("--format needs at least one format")
+			}
+			for _, format := range formatFlags {
+				if !slices.Contains(reportFormats, format) {
+					return fmt.Errorf("unsupported --format %q (expected %s)", format, strings.Join(reportFormats, ", "))
+				}
+			}
+			if reportToStdout() && !slices.Equal(formatFlags, []string{"json"}) {
+				return errors.New("--no-save prints the JSON report only; drop --format")
+			}
+			if appendFlag {
+				if strings.TrimSpace(outputPath) == "" || reportToStdout() {
+					return errors.New("--append needs a file to append to, e.g. -o results.jsonl")
+				}
+				if !slices.Equal(formatFlags, []string{"json"}) {
+					return errors.New("--append writes JSON lines only; drop --format")
+				}
+			}
+			if _, err := expandOutputTemplate(outputPath, runDir{}, ""); err != nil {
+				return err
+			}
+			if slices.Contains(formatFlags, "pdf") {
+				if _, err := report.FindChrome(); err != nil {
+					return err
+				}
+			}
+			for _, uri := range exportFlags {
+				if err := export.Validate(uri); err != nil {
+					return err
+				}
+			}
+			if gitlabMRFlag {
+				if _, err := notify.GitLabMRFromEnv(); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
 	}
 
+	cmd.PersistentFlags().StringVar(&configPath, "config", config.FileName, "Project config file providing defaults for flags that are not set.")
+	cmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Apply this named profile from the config file's profiles section (e.g. local, ci, nightly).")
 	cmd.PersistentFlags().StringVar(&componentFlag, "component", "", "Component name label for the benchmark run.")
 	cmd.PersistentFlags().StringVar(&viewFlag, "view", "", "UI view identifier forwarded to benchmark harnesses on each platform.")
-	cmd.PersistentFlags().StringVarP(&outputPath, "output", "o", "", "Write JSON report to this exact path (defaults to ./designbench-reports/<component>-<platform>.json).")
+	cmd.PersistentFlags().StringVarP(&outputPath, "output", "o", "", "Write the report to this path; {component}, {platform}, {timestamp}, {runId} and {format} are filled in, and relative paths are under ./designbench-reports/ (defaults to report.<format> in a new ./designbench-reports/<component>-<platform>-<timestamp>/ run directory).")
+	cmd.PersistentFlags().StringSliceVar(&formatFlags, "format", []string{"json"}, "Report formats, comma-separated: json, html (standalone page with frame histogram), pdf (the html page printed with headless Chrome; set DESIGNBENCH_CHROME to pick the browser) or csv (one row per metric).")
+	cmd.PersistentFlags().BoolVar(&noSaveFlag, "no-save", false, "Print the JSON report to stdout instead of saving it and create no designbench-reports directory (same as -o -); the summary goes to stderr.")
+	cmd.PersistentFlags().BoolVar(&appendFlag, "append", false, "Append the report as one JSON line to --output (e.g. -o results.jsonl) instead of overwriting it.")
 	cmd.PersistentFlags().StringVar(&timeoutFlag, "timeout", "60s", "Overall command timeout (e.g. 45s, 2m).")
+	cmd.PersistentFlags().IntVar(&iterationsFlag, "iterations", 1, "Number of launches to measure (upper bound when --target-ci-width is set).")
+	cmd.PersistentFlags().StringVar(&scenarioFlag, "scenario", scenarioLaunch, "Benchmark scenario: launch, split-view (iPadOS multitasking widths), theme-switch (Android dark/light re-render), monkey (Android stress run, see --events), tap-latency (Android input-to-frame latency, see --taps) or animation (Android harness animation smoothness, see --animation).")
+	cmd.PersistentFlags().StringVar(&targetCIWidthFlag, "target-ci-width", "", "Keep iterating until the 95% confidence interval of the primary metric is within this width of the mean (e.g. 5%).")
+	cmd.PersistentFlags().StringVar(&percentilesFlag, "percentiles", strings.Join(report.DefaultStatistics, ","), "Statistics of each metric across iterations shown in the summary and stored as spread in the report, comma-separated: min, max, mean or percentiles such as p50,p90,p99.")
+	cmd.PersistentFlags().StringVar(&idleCPUFlag, "idle-cpu", "", "Before each iteration, wait until device CPU load (host load for simulators) is at or below this value (e.g. 20%).")
+	cmd.PersistentFlags().StringVar(&idleTimeoutFlag, "idle-timeout", "30s", "Maximum time to wait for --idle-cpu before measuring anyway.")
+	cmd.PersistentFlags().StringVar(&settleFlag, "settle", "0s", "Wait this long after launch before collecting memory/CPU snapshots (e.g. 2s).")
+	cmd.PersistentFlags().StringVar(&launchTimeoutFlag, "launch-timeout", "20s", "Kill a launch (am start -W or simctl launch) that hangs longer than this, force-stop the app and continue with the next iteration; 0 disables.")
+	cmd.PersistentFlags().BoolVar(&allowDebugFlag, "allow-debug", false, "Benchmark debuggable (Android) or Debug-configuration (iOS) builds instead of refusing; the report is tagged buildType: debug.")
+	cmd.PersistentFlags().StringSliceVar(&exportFlags, "export", nil, "Also send the results to this sink after saving the report (repeatable), e.g. bigquery://project.dataset.table.")
+	cmd.PersistentFlags().BoolVar(&gitlabMRFlag, "gitlab-mr", false, "Post the results table as a note on the current GitLab merge request, updating the note from earlier pipelines (needs a merge request pipeline and DESIGNBENCH_GITLAB_TOKEN or GITLAB_TOKEN).")
+	cmd.PersistentFlags().BoolVar(&noColorFlag, "no-color", false, "Disable colors in the terminal summary (also set by NO_COLOR).")
+	cmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false, "Print progress events (phases, device commands, collected metrics) to stderr.")
+	cmd.PersistentFlags().StringVar(&logFileFlag, "log-file", "", "Append structured progress events as NDJSON to this file (e.g. run.ndjson).")
 
-	cmd.AddCommand(newAndroidCmd(), newIOSCmd(), newPreflightCmd())
+	cmd.AddCommand(newAndroidCmd(), newIOSCmd(), newPreflightCmd(), newInitCmd(), newGenerateCmd(), newServeCmd(), newAgentCmd(), newRemoteCmd(), newDaemonCmd(), newCalibrateCmd(), newCompareCmd(), newSummarizeCmd(), newComponentsCmd(), newSuiteCmd(), newCICmd(), newSimulatorCmd(), newAVDCmd(), newReproCmd())
 
 	return cmd
 }
 
 type androidOptions struct {
-	packageName string
-	activity    string
-	deviceID    string
-	adbPath     string
+	packageName  string
+	activity     string
+	deviceID     string
+	displayID    string
+	adbPath      string
+	trace        bool
+	traceConfig  string
+	methodTrace  bool
+	logcat       bool
+	interactions string
+	navigate     string
+	postures     string
+	monkeyEvents int
+	taps         int
+	animation    string
+	animationDur string
+	strictMode   bool
+	// install builds and installs the app with Gradle before benchmarking, under installTimeout
+	// rather than --timeout.
+	install        bool
+	variant        string
+	flavor         string
+	gradleArgs     string
+	installTimeout string
+	// apk is a prebuilt APK installed with adb instead of building with Gradle.
+	apk string
+	// managedDevice names a Gradle Managed Device to boot for the run and shut down afterwards.
+	managedDevice string
+	// noViewCheck skips asking the harness for its components before running --view.
+	noViewCheck bool
+	// osMatrix lists the API levels to benchmark on in turn, e.g. "33,34".
+	osMatrix string
+	// reboot restarts the device once the app is installed, before the first iteration.
+	reboot bool
+	// flow is a YAML file of activities and deep links opened in turn after launch.
+	flow string
 }
 
 type iosOptions struct {
-	bundleID  string
-	deviceID  string
-	xcrunPath string
+	bundleID      string
+	deviceID      string
+	xcrunPath     string
+	install       bool
+	scheme        string
+	configuration string
+	// terminateRunning relaunches an app that is already running, so that every launch starts cold.
+	terminateRunning bool
+	cpuProfile       bool
+	// app is a prebuilt simulator .app installed instead of building with Xcode.
+	app string
+	// installTimeout bounds --install, --app and --reboot, separately from --timeout.
+	installTimeout string
+	// noViewCheck skips asking the harness for its components before running --view.
+	noViewCheck bool
+	// osMatrix lists the iOS versions to benchmark on in turn, e.g. "iOS 16.4,iOS 17.5".
+	osMatrix string
+	// reboot restarts the simulator once the app is installed, before the first iteration.
+	reboot bool
 }
 
 func newAndroidCmd() *cobra.Command {
 	var opts androidOptions
 	opts.adbPath = "adb"
+	traceFlag := ""
 	cmd := &cobra.Command{
 		Use:   "android",
 		Short: "Run Android render benchmark.",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.trace, opts.traceConfig = parseTraceFlag(traceFlag)
+			var animation string
+			var animationDuration time.Duration
+			switch scenarioFlag {
+			case scenarioLaunch, scenarioThemeSwitch:
+			case scenarioMonkey:
+				if opts.monkeyEvents < 1 {
+					return fmt.Errorf("invalid --events %d (must be at least 1)", opts.monkeyEvents)
+				}
+			case scenarioTapLatency:
+				if opts.taps < 1 {
+					return fmt.Errorf("invalid --taps %d (must be at least 1)", opts.taps)
+				}
+			case scenarioAnimation:
+				if opts.animation == "" {
+					return errors.New("--scenario animation requires --animation")
+				}
+				dur, err := time.ParseDuration(strings.TrimSpace(opts.animationDur))
+				if err != nil || dur <= 0 {
+					return fmt.Errorf("invalid --animation-duration %q (expected a duration such as 800ms)", opts.animationDur)
+				}
+				animation, animationDuration = opts.animation, dur
+			default:
+				return unsupportedScenario("android", scenarioLaunch, scenarioThemeSwitch, scenarioMonkey, scenarioTapLatency, scenarioAnimation)
+			}
+			if opts.displayID != "" {
+				if id, err := strconv.Atoi(opts.displayID); err != nil || id < 0 {
+					return fmt.Errorf("invalid --display-id %q (expected a display id such as 2; list them with adb shell dumpsys display)", opts.displayID)
+				}
+				if opts.interactions != "" || opts.navigate != "" || opts.flow != "" {
+					return errors.New("--interactions, --navigate and --flow drive the default display; they cannot be combined with --display-id")
+				}
+			}
+			if opts.apk != "" {
+				if opts.install {
+					return errors.New("--apk installs a prebuilt APK; it cannot be combined with --install")
+				}
+				if _, err := os.Stat(opts.apk); err != nil {
+					return fmt.Errorf("--apk: %w", err)
+				}
+			}
+			apiLevels, err := resolveAPILevels(opts.osMatrix)
+			if err != nil {
+				return err
+			}
+			if len(apiLevels) > 0 && (opts.postures != "" || opts.managedDevice != "") {
+				return errors.New("--os-matrix picks the device for each API level; it cannot be combined with --postures or --managed-device")
+			}
+			if len(apiLevels) > 0 && opts.reboot {
+				return errors.New("--reboot cannot be combined with --os-matrix")
+			}
+			if reportToStdout() && (opts.trace || opts.methodTrace || opts.logcat) {
+				return errors.New("--trace, --method-trace and --logcat save artifacts next to the report; they cannot be used with --no-save")
+			}
 			if err := ensureAndroidDefaults(&opts); err != nil {
 				return err
 			}
+			var script *android.InteractionScript
+			if opts.interactions != "" {
+				loaded, err := android.LoadInteractionScript(opts.interactions)
+				if err != nil {
+					return fmt.Errorf("load interactions: %w", err)
+				}
+				script = loaded
+			}
+			var navigation *android.Navigation
+			if opts.navigate != "" {
+				loaded, err := android.LoadNavigation(opts.navigate)
+				if err != nil {
+					return fmt.Errorf("load navigation: %w", err)
+				}
+				navigation = loaded
+			}
+			var flow *android.Flow
+			if opts.flow != "" {
+				loaded, err := android.LoadFlow(opts.flow)
+				if err != nil {
+					return fmt.Errorf("load flow: %w", err)
+				}
+				flow = loaded
+			}
+			var traceConfig string
+			if opts.traceConfig != "" {
+				loaded, err := android.LoadTraceConfig(opts.traceConfig)
+				if err != nil {
+					return fmt.Errorf("load trace config: %w", err)
+				}
+				traceConfig = loaded
+			}
 			component := resolveComponent(opts.activity)
-			ctx, cancel, err := commandContext(cmd)
+			plan, err := resolveIterationPlan()
 			if err != nil {
 				return err
 			}
-			defer cancel()
+			idle, err := resolveIdleWait()
+			if err != nil {
+				return err
+			}
+			settle, err := resolveSettle()
+			if err != nil {
+				return err
+			}
+			launchTimeout, err := resolveLaunchTimeout()
+			if err != nil {
+				return err
+			}
+			installTimeout, err := time.ParseDuration(strings.TrimSpace(opts.installTimeout))
+			if err != nil || installTimeout <= 0 {
+				return fmt.Errorf("invalid --install-timeout %q (expected a duration such as 15m)", opts.installTimeout)
+			}
 
 			benchmarkComponent := viewFlag
 
@@ -88,305 +401,3349 @@ func newAndroidCmd() *cobra.Command {
 				Package:            opts.packageName,
 				Activity:           opts.activity,
 				DeviceID:           opts.deviceID,
+				DisplayID:          opts.displayID,
 				ADBPath:            opts.adbPath,
 				LaunchArgs:         nil,
 				BenchmarkComponent: benchmarkComponent,
+				Variant:            androidVariant(opts),
+				ColdStart:          plan.max > 1,
+				ThemeSwitch:        scenarioFlag == scenarioThemeSwitch,
+				MonkeyEvents:       monkeyEvents(opts),
+				InputLatencyTaps:   inputLatencyTaps(opts),
+				Animation:          animation,
+				AnimationDuration:  animationDuration,
+				IdleCPUThreshold:   idle.threshold,
+				IdleTimeout:        idle.timeout,
+				Settle:             settle,
+				LaunchTimeout:      launchTimeout,
+				StrictMode:         opts.strictMode,
+				Navigation:         navigation,
+				Flow:               flow,
+				AllowDebugBuild:    allowDebugFlag,
 			}
-			metrics, err := android.Run(ctx, cfg)
+			cfg.TraceConfig = traceConfig
+			run, err := newRunDir(component, "android")
 			if err != nil {
 				return err
 			}
-
-			result := report.Result{
-				Component:  component,
-				Android:    metrics,
-				CLICommand: currentCLICommand(cmd),
-			}
-			fmt.Print(report.FormatSummary(result))
-			if path, err := resolveOutputFile(component, "android"); err != nil {
+			defer run.remove()
+			onEvent, closeLog, err := eventHandler(cmd, run.id)
+			if err != nil {
 				return err
-			} else if path != "" {
-				if err := report.SaveJSON(path, result); err != nil {
+			}
+			defer closeLog()
+			cfg.OnEvent = onEvent
+			if opts.managedDevice != "" {
+				device, err := startManagedDevice(cmd, opts, installTimeout, onEvent)
+				if err != nil {
 					return err
 				}
+				defer stopManagedDevice(device)
+				opts.deviceID = device.Serial
+				cfg.DeviceID = device.Serial
 			}
-			return nil
-		},
-	}
-	return cmd
-}
-
-func newIOSCmd() *cobra.Command {
-	var opts iosOptions
-	opts.xcrunPath = "xcrun"
-	cmd := &cobra.Command{
-		Use:   "ios",
-		Short: "Run iOS render benchmark.",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := ensureIOSDefaults(&opts); err != nil {
+			var install *report.InstallRecord
+			switch {
+			case opts.install:
+				install, err = installAndroidApp(cmd, opts, installTimeout, onEvent)
+			case opts.apk != "" && len(apiLevels) == 0:
+				install, err = installAndroidAPK(cmd, opts, installTimeout, onEvent)
+			}
+			if err != nil {
 				return err
 			}
-			component := resolveComponent(opts.bundleID)
+			if opts.reboot {
+				if err := rebootAndroidDevice(cmd, opts, installTimeout, onEvent); err != nil {
+					return err
+				}
+			}
+			// The benchmark's timeout starts after the install, which has a deadline of its own.
 			ctx, cancel, err := commandContext(cmd)
 			if err != nil {
 				return err
 			}
 			defer cancel()
-
-			benchmarkComponent := viewFlag
-
-			cfg := ios.Config{
-				Component:          component,
-				BundleID:           opts.bundleID,
-				DeviceID:           opts.deviceID,
-				LaunchArgs:         nil,
-				XCRunPath:          opts.xcrunPath,
-				BenchmarkComponent: benchmarkComponent,
+			// A --navigate run reaches the component through the app itself, not the harness registry.
+			// An --os-matrix run checks each device once the app is on it.
+			checkAndroidView := func(deviceID string) error {
+				if opts.noViewCheck || navigation != nil {
+					return nil
+				}
+				return checkView(events.WithHandler(ctx, "android", onEvent), "android", func(ctx context.Context) ([]string, bool, error) {
+					return android.ListComponents(ctx, opts.adbPath, deviceID, opts.packageName, opts.activity)
+				})
+			}
+			if len(apiLevels) == 0 {
+				if err := checkAndroidView(opts.deviceID); err != nil {
+					return err
+				}
 			}
-			metrics, err := ios.Run(ctx, cfg)
+			session, err := startAppium(ctx, map[string]any{
+				"platformName":          "Android",
+				"appium:automationName": "UiAutomator2",
+				"appium:appPackage":     opts.packageName,
+				"appium:udid":           opts.deviceID,
+			})
 			if err != nil {
 				return err
 			}
-
+			if session != nil {
+				defer closeAppium(session)
+				cfg.Interact = session.Interact
+			}
+			if script != nil {
+				appiumInteract := cfg.Interact
+				cfg.Interact = func(ctx context.Context) error {
+					if appiumInteract != nil {
+						if err := appiumInteract(ctx); err != nil {
+							return err
+						}
+					}
+					return android.RunInteractionScript(ctx, opts.adbPath, opts.deviceID, script)
+				}
+			}
 			result := report.Result{
 				Component:  component,
-				IOS:        metrics,
+				RunID:      run.id,
 				CLICommand: currentCLICommand(cmd),
+				Install:    install,
 			}
-			fmt.Print(report.FormatSummary(result))
-			if path, err := resolveOutputFile(component, "ios"); err != nil {
+			result.Environment = recordedEnv()
+			postures, err := resolvePostures(opts.postures)
+			if err != nil {
 				return err
-			} else if path != "" {
-				if err := report.SaveJSON(path, result); err != nil {
+			}
+			switch {
+			case len(apiLevels) > 0:
+				for _, level := range apiLevels {
+					if err := measureAndroidOS(ctx, cmd, level, plan, cfg, &opts, run, installTimeout, checkAndroidView, &result); err != nil {
+						return fmt.Errorf("API %d: %w", level, err)
+					}
+				}
+			case len(postures) == 0:
+				var runs []*report.AndroidMetrics
+				result.Android, runs, err = measureAndroid(ctx, plan, cfg, opts, run, "android")
+				if err != nil {
 					return err
 				}
+				result.Iterations = appendAndroidIterations(result.Iterations, runs, "")
+			default:
+				// Every posture change restarts the activity, so each measurement must start cold.
+				cfg.ColdStart = true
+				var emulatedPosture bool
+				defer func() { resetPosture(opts, emulatedPosture) }()
+				for _, posture := range postures {
+					emulated, err := android.SetPosture(ctx, opts.adbPath, opts.deviceID, posture)
+					emulatedPosture = emulatedPosture || emulated
+					if err != nil {
+						return err
+					}
+					metrics, runs, err := measureAndroid(ctx, plan, cfg, opts, run, "android-"+posture)
+					if err != nil {
+						return fmt.Errorf("posture %s: %w", posture, err)
+					}
+					result.Postures = append(result.Postures, report.PostureMetrics{Posture: posture, Android: metrics})
+					result.Iterations = appendAndroidIterations(result.Iterations, runs, posture)
+				}
 			}
-			return nil
+
+			deriveMetrics(cmd, &result)
+			printSummary(cmd, result)
+			if err := saveReport(run, result); err != nil {
+				return err
+			}
+			return exportResult(result)
 		},
 	}
+	cmd.Flags().BoolVar(&opts.install, "install", false, "Build the app with ./gradlew and install it on the device before benchmarking, streaming Gradle's output.")
+	cmd.Flags().StringVar(&opts.variant, "variant", "", "Build type installed by --install and recorded in the report (default release).")
+	cmd.Flags().StringVar(&opts.flavor, "flavor", "", "Product flavor installed by --install and recorded in the report, e.g. benchmark for installBenchmarkRelease.")
+	cmd.Flags().StringVar(&opts.gradleArgs, "gradle-args", "", "Extra arguments passed to Gradle by --install and the --managed-device setup task, e.g. \"--offline -Pvariant=benchmark\".")
+	cmd.Flags().StringVar(&opts.apk, "apk", "", "Install this prebuilt APK with adb install -r before benchmarking, for machines without the Gradle project.")
+	cmd.Flags().StringVar(&opts.managedDevice, "managed-device", "", "Boot this Gradle Managed Device (declared under testOptions.managedDevices) as a headless emulator for the run and shut it down afterwards.")
+	cmd.Flags().StringVar(&opts.installTimeout, "install-timeout", "15m", "Timeout for --managed-device, --install, --apk or --reboot, each separate from --timeout, which starts once the app is installed.")
+	cmd.Flags().StringVar(&opts.displayID, "display-id", "", "Launch on this logical display (am start --display), e.g. a secondary, desktop-mode or virtual display; its resolution, refresh rate and type are recorded.")
+	cmd.Flags().StringVar(&traceFlag, "trace", "", "Record a Perfetto trace with Compose composition tracing alongside the report; --trace=<file> records with this Perfetto text config (.pbtxt) instead, with {package} replaced by the app's package.")
+	cmd.Flags().Lookup("trace").NoOptDefVal = "true"
+	cmd.Flags().BoolVar(&opts.methodTrace, "method-trace", false, "Record a sampled method trace of each cold launch (am start --start-profiler) until the first frame and save the .trace file alongside the report; the app must be debuggable or profileable by the shell.")
+	cmd.Flags().BoolVar(&opts.logcat, "logcat", false, "Save the app's logcat output for each iteration alongside the report.")
+	cmd.Flags().BoolVar(&opts.strictMode, "strict-mode", false, "Have the harness enable StrictMode and count the violations it logs (disk or network access on the main thread, leaks) per policy.")
+	cmd.Flags().StringVar(&opts.navigate, "navigate", "", "YAML file with a deepLink and uiautomator steps (e.g. taps by contentDesc) that reach the component after launch, for apps without a harness activity; its duration is reported as navigationMs.")
+	cmd.Flags().BoolVar(&opts.noViewCheck, "no-view-check", false, "Skip launching the harness to confirm that --view is one of its registered components before benchmarking.")
+	cmd.Flags().StringVar(&opts.flow, "flow", "", "YAML file of steps, each an activity or deep link, opened one after another after launch to benchmark a navigation flow; each transition's am start -W time is reported under flow and their sum as flowTimeMs.")
+	cmd.Flags().StringVar(&opts.interactions, "interactions", "", "YAML script of uiautomator steps (tap, swipe, waitFor) to run after each launch, inside the measurement window.")
+	cmd.Flags().IntVar(&opts.monkeyEvents, "events", 5000, "Number of pseudo-random events injected by --scenario monkey.")
+	cmd.Flags().IntVar(&opts.taps, "taps", 20, "Number of taps measured by --scenario tap-latency.")
+	cmd.Flags().StringVar(&opts.animation, "animation", "", "Harness animation played and measured by --scenario animation.")
+	cmd.Flags().StringVar(&opts.animationDur, "animation-duration", "1s", "How long the --animation runs; frames are counted over this window.")
+	cmd.Flags().StringVar(&opts.postures, "postures", "", "Benchmark a foldable in each posture: comma-separated folded, half-opened, unfolded, or all.")
+	cmd.Flags().StringVar(&opts.osMatrix, "os-matrix", "", "Benchmark on each of these API levels in turn, e.g. \"33,34\": on a connected device at that level, else on an emulator booted headless from the first AVD targeting it and shut down afterwards. The app is copied from the --device unless --apk is given, which is installed on each.")
+	cmd.Flags().BoolVar(&opts.reboot, "reboot", false, "Reboot the device once the app is installed and wait for it to finish booting before the first iteration, so that no state from earlier runs remains; it has --install-timeout to finish.")
 	return cmd
 }
 
-func ensureAndroidDefaults(opts *androidOptions) error {
+// rebootAndroidDevice reboots the device under the install timeout, since a cold boot can take minutes.
+func rebootAndroidDevice(cmd *cobra.Command, opts androidOptions, timeout time.Duration, onEvent events.Handler) error {
+	ctx, cancel := installContext(cmd, timeout)
+	defer cancel()
+	return android.Reboot(ctx, opts.adbPath, opts.deviceID, onEvent)
+}
+
+// installAndroidApp runs the install task of the detected Gradle module. Gradle's output goes to
+// stderr so that a long build is visible and stdout stays free for the JSON report.
+func installAndroidApp(cmd *cobra.Command, opts androidOptions, timeout time.Duration, onEvent events.Handler) (*report.InstallRecord, error) {
 	root, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("resolve project root: %w", err)
+		return nil, fmt.Errorf("resolve project root: %w", err)
 	}
-	absRoot, err := filepath.Abs(root)
-	if err == nil {
-		root = absRoot
+	proj, err := preflight.DetectAndroidProject(root)
+	if err != nil {
+		return nil, fmt.Errorf("detect Android project: %w", err)
 	}
-	proj, detectErr := preflight.DetectAndroidProject(root)
-	missingPackage := strings.TrimSpace(opts.packageName) == ""
-	missingActivity := strings.TrimSpace(opts.activity) == ""
-	if !missingPackage && !missingActivity {
-		return nil
+	ctx, cancel := installContext(cmd, timeout)
+	defer cancel()
+	return android.Install(ctx, android.InstallConfig{
+		ProjectDir: root,
+		Task:       gradleTaskPrefix(proj.ModuleDir) + "install" + capitalize(androidVariant(opts)),
+		GradleArgs: strings.Fields(opts.gradleArgs),
+		DeviceID:   opts.deviceID,
+		Output:     cmd.ErrOrStderr(),
+		OnEvent:    onEvent,
+	})
+}
+
+// startManagedDevice sets up and boots the --managed-device declared in the detected Gradle module.
+func startManagedDevice(cmd *cobra.Command, opts androidOptions, timeout time.Duration, onEvent events.Handler) (*android.ManagedDevice, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("resolve project root: %w", err)
 	}
-	if detectErr == nil && proj != nil {
-		if missingPackage && proj.Package != "" {
-			opts.packageName = proj.Package
+	proj, err := preflight.DetectAndroidProject(root)
+	if err != nil {
+		return nil, fmt.Errorf("detect Android project: %w", err)
+	}
+	profile, err := preflight.FindManagedDevice(root, proj.ModuleDir, opts.managedDevice)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := installContext(cmd, timeout)
+	defer cancel()
+	return android.StartManagedDevice(ctx, android.ManagedDeviceConfig{
+		ProjectDir: root,
+		SetupTask:  gradleTaskPrefix(proj.ModuleDir) + profile.Name + "Setup",
+		GradleArgs: strings.Fields(opts.gradleArgs),
+		Device:     profile.Device,
+		APILevel:   profile.APILevel,
+		ADBPath:    opts.adbPath,
+		Output:     cmd.ErrOrStderr(),
+		OnEvent:    onEvent,
+	})
+}
+
+// measureAndroidOS measures one API level of --os-matrix on a device at that level, copying the app
+// to it first. opts.deviceID points at the device while it is measured, for the interaction script.
+func measureAndroidOS(ctx context.Context, cmd *cobra.Command, level int, plan iterationPlan, cfg android.Config, opts *androidOptions, run runDir, installTimeout time.Duration, checkView func(deviceID string) error, result *report.Result) error {
+	setupCtx, cancel := installContext(cmd, installTimeout)
+	defer cancel()
+	target, err := android.StartOSTarget(setupCtx, opts.adbPath, opts.deviceID, level, cfg.OnEvent)
+	if err != nil {
+		return err
+	}
+	defer stopOSTarget(target)
+	reference := opts.deviceID
+	defer func() { opts.deviceID = reference }()
+	opts.deviceID, cfg.DeviceID = target.Serial, target.Serial
+	if opts.apk != "" {
+		install, err := installAndroidAPK(cmd, *opts, installTimeout, cfg.OnEvent)
+		if err != nil {
+			return err
 		}
-		if missingActivity && proj.Activity != "" {
-			opts.activity = proj.Activity
+		if result.Install == nil {
+			result.Install = install
+		}
+	} else if target.Serial != reference {
+		if _, err := android.CopyApp(setupCtx, opts.adbPath, reference, target.Serial, opts.packageName, cfg.OnEvent); err != nil {
+			return err
 		}
 	}
-	if strings.TrimSpace(opts.packageName) != "" && strings.TrimSpace(opts.activity) != "" {
-		return nil
+	if err := checkView(target.Serial); err != nil {
+		return err
 	}
-	if detectErr != nil {
-		return fmt.Errorf("unable to auto-detect Android defaults: %w (set --package/--activity manually)", detectErr)
+	metrics, runs, err := measureAndroid(ctx, plan, cfg, *opts, run, fmt.Sprintf("android-api%d", level))
+	if err != nil {
+		return err
 	}
-	missing := make([]string, 0, 2)
-	if strings.TrimSpace(opts.packageName) == "" {
-		missing = append(missing, "--package")
+	result.OSVersions = append(result.OSVersions, report.OSMetrics{OS: target.Label(), Android: metrics})
+	result.Iterations = labelOS(result.Iterations, appendAndroidIterations(nil, runs, ""), target.Label())
+	return nil
+}
+
+// stopOSTarget runs after the command context may have expired, so it uses its own deadline.
+func stopOSTarget(target interface{ Stop(context.Context) }) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	target.Stop(ctx)
+}
+
+// labelOS appends an --os-matrix configuration's iteration records to records, marked with its OS.
+func labelOS(records, added []report.IterationRecord, version string) []report.IterationRecord {
+	for _, record := range added {
+		record.OS = version
+		records = append(records, record)
 	}
-	if strings.TrimSpace(opts.activity) == "" {
-		missing = append(missing, "--activity")
+	return records
+}
+
+// stopManagedDevice runs after the command context may have expired, so it uses its own deadline.
+func stopManagedDevice(device *android.ManagedDevice) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	device.Stop(ctx)
+}
+
+// installAndroidAPK installs the --apk artifact with adb.
+func installAndroidAPK(cmd *cobra.Command, opts androidOptions, timeout time.Duration, onEvent events.Handler) (*report.InstallRecord, error) {
+	ctx, cancel := installContext(cmd, timeout)
+	defer cancel()
+	return android.InstallAPK(ctx, opts.adbPath, opts.deviceID, opts.apk, onEvent)
+}
+
+// installContext bounds an install by --install-timeout instead of the benchmark's --timeout.
+func installContext(cmd *cobra.Command, timeout time.Duration) (context.Context, context.CancelFunc) {
+	parent := cmd.Context()
+	if parent == nil {
+		parent = context.Background()
 	}
-	return fmt.Errorf("missing Android %s (run from project root or provide flags)", strings.Join(missing, " and "))
+	return context.WithTimeout(parent, timeout)
 }
 
-func ensureIOSDefaults(opts *iosOptions) error {
-	if strings.TrimSpace(opts.bundleID) != "" {
-		return nil
+// androidVariant combines --flavor and --variant into the Gradle variant name, e.g. benchmarkRelease.
+// Without either flag it is release when --install builds the app and unknown otherwise.
+func androidVariant(opts androidOptions) string {
+	buildType := strings.TrimSpace(opts.variant)
+	flavor := strings.TrimSpace(opts.flavor)
+	if buildType == "" && flavor == "" && !opts.install {
+		return ""
 	}
-	root, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("resolve project root: %w", err)
+	if buildType == "" {
+		buildType = "release"
 	}
-	proj, detectErr := preflight.DetectIOSProject(root)
-	if detectErr != nil {
-		return fmt.Errorf("unable to auto-detect iOS bundle id: %w (set --bundle manually)", detectErr)
+	if flavor == "" {
+		return buildType
 	}
-	if proj == nil || strings.TrimSpace(proj.BundleID) == "" {
-		return fmt.Errorf("Info.plist detected but bundle id empty (set --bundle manually)")
+	return flavor + capitalize(buildType)
+}
+
+// capitalize upper-cases the first letter, as Gradle does when it names variant tasks.
+func capitalize(s string) string {
+	if s == "" {
+		return s
 	}
-	opts.bundleID = proj.BundleID
-	return nil
+	return strings.ToUpper(s[:1]) + s[1:]
 }
 
-func resolveComponent(fallback string) string {
-	if componentFlag != "" {
-		return componentFlag
+// parseTraceFlag reads --trace, which is a boolean on its own (also from the environment or the
+// config file) and a Perfetto config path as --trace=<file>.
+func parseTraceFlag(value string) (enabled bool, configPath string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false, ""
 	}
-	if viewFlag != "" {
-		return viewFlag
+	if on, err := strconv.ParseBool(value); err == nil {
+		return on, ""
 	}
-	if fallback != "" {
-		return fallback
+	return true, value
+}
+
+// measureAndroid runs the iteration plan for one Android configuration and returns the aggregate
+// together with each iteration's metrics. The artifact label keeps traces from different
+// configurations (e.g. postures) apart within the run directory.
+func measureAndroid(ctx context.Context, plan iterationPlan, cfg android.Config, opts androidOptions, run runDir, artifactLabel string) (*report.AndroidMetrics, []*report.AndroidMetrics, error) {
+	runs := make([]*report.AndroidMetrics, 0, plan.max)
+	ciWidth, err := runIterations(ctx, plan, func(iteration int) (float64, error) {
+		if opts.trace {
+			path, err := resolveArtifactFile(run, artifactLabel, iteration, plan.max, ".perfetto-trace")
+			if err != nil {
+				return 0, err
+			}
+			cfg.TracePath = path
+		}
+		if opts.methodTrace {
+			path, err := resolveArtifactFile(run, artifactLabel, iteration, plan.max, ".trace")
+			if err != nil {
+				return 0, err
+			}
+			cfg.MethodTracePath = path
+		}
+		if opts.logcat {
+			path, err := resolveArtifactFile(run, artifactLabel, iteration, plan.max, ".logcat.txt")
+			if err != nil {
+				return 0, err
+			}
+			cfg.LogcatPath = path
+		}
+		run, err := android.Run(ctx, cfg)
+		if err != nil {
+			if watchdog.IsHung(err) {
+				runs = append(runs, nil)
+			}
+			return 0, err
+		}
+		runs = append(runs, run)
+		if run.ThemeSwitch != nil {
+			return run.ThemeSwitch.MeanMs(), nil
+		}
+		if run.InputLatency != nil {
+			return run.InputLatency.P50Ms, nil
+		}
+		if run.Animation != nil {
+			return float64(run.Animation.RenderedFrames), nil
+		}
+		if len(run.Flow) > 0 {
+			return run.FlowTimeMs(), nil
+		}
+		return run.TotalTimeMs, nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	return "component"
+	measured := measuredRuns(runs)
+	metrics := report.AggregateAndroid(measured)
+	metrics.HungIterations = len(runs) - len(measured)
+	metrics.CIWidthPct = ciWidth * 100
+	metrics.Spread = report.Spread(metrics.IterationValues, spreadStatistics)
+	return metrics, runs, nil
 }
 
-func commandContext(cmd *cobra.Command) (context.Context, context.CancelFunc, error) {
-	timeout := strings.TrimSpace(timeoutFlag)
-	parent := cmd.Context()
-	if parent == nil {
-		parent = context.Background()
+// appendAndroidIterations records each run of a multi-iteration plan in the report. Nil runs hung.
+func appendAndroidIterations(records []report.IterationRecord, runs []*report.AndroidMetrics, posture string) []report.IterationRecord {
+	if len(runs) < 2 {
+		return records
 	}
-	if timeout == "" {
-		ctx, cancel := context.WithCancel(parent)
-		return ctx, cancel, nil
+	for i, run := range runs {
+		records = append(records, report.IterationRecord{Iteration: i + 1, Posture: posture, Android: run, Hung: run == nil})
 	}
-	dur, err := time.ParseDuration(timeout)
-	if err != nil {
-		return nil, nil, fmt.Errorf("invalid timeout %q: %w", timeout, err)
+	return records
+}
+
+// measuredRuns leaves out the nil entries of iterations whose launch hung.
+func measuredRuns[T any](runs []*T) []*T {
+	measured := make([]*T, 0, len(runs))
+	for _, run := range runs {
+		if run != nil {
+			measured = append(measured, run)
+		}
 	}
-	if dur <= 0 {
-		ctx, cancel := context.WithCancel(parent)
-		return ctx, cancel, nil
+	return measured
+}
+
+// resolveAPILevels parses the Android --os-matrix, e.g. "33,34" or "API 33,API 34".
+func resolveAPILevels(value string) ([]int, error) {
+	var levels []int
+	for _, entry := range splitList(value) {
+		level, err := android.ParseAPILevel(entry)
+		if err != nil {
+			return nil, fmt.Errorf("--os-matrix: %w", err)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+func resolvePostures(value string) ([]string, error) {
+	postures := splitList(value)
+	if len(postures) == 1 && postures[0] == "all" {
+		return []string{android.PostureFolded, android.PostureHalfOpened, android.PostureUnfolded}, nil
+	}
+	for _, posture := range postures {
+		switch posture {
+		case android.PostureFolded, android.PostureHalfOpened, android.PostureUnfolded:
+		default:
+			return nil, fmt.Errorf("invalid --postures value %q (expected folded, half-opened, unfolded or all)", posture)
+		}
+	}
+	return postures, nil
+}
+
+// monkeyEvents returns the event count for --scenario monkey and 0 for every other scenario.
+func monkeyEvents(opts androidOptions) int {
+	if scenarioFlag != scenarioMonkey {
+		return 0
+	}
+	return opts.monkeyEvents
+}
+
+// inputLatencyTaps returns the tap count for --scenario tap-latency and 0 for every other scenario.
+func inputLatencyTaps(opts androidOptions) int {
+	if scenarioFlag != scenarioTapLatency {
+		return 0
+	}
+	return opts.taps
+}
+
+// resetPosture runs after the command context may have expired, so it uses its own deadline.
+func resetPosture(opts androidOptions, emulated bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = android.ResetPosture(ctx, opts.adbPath, opts.deviceID, emulated)
+}
+
+// startAppium opens the Appium session configured in designbench.yaml, if any. The platform
+// defaults (platformName, automation driver, app and device) are filled in unless the config sets them.
+func startAppium(ctx context.Context, defaults map[string]any) (*appium.Session, error) {
+	if projectConfig.Appium == nil {
+		return nil, nil
+	}
+	opts := *projectConfig.Appium
+	caps := make(map[string]any, len(defaults)+len(opts.Capabilities))
+	for key, value := range defaults {
+		if value != "" {
+			caps[key] = value
+		}
+	}
+	for key, value := range opts.Capabilities {
+		caps[key] = value
+	}
+	opts.Capabilities = caps
+	return appium.NewSession(ctx, opts)
+}
+
+// closeAppium runs after the command context may have expired, so it uses its own deadline.
+func closeAppium(session *appium.Session) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = session.Close(ctx)
+}
+
+// deriveMetrics computes the config's derived metrics into result, warning about those that could
+// not be computed.
+func deriveMetrics(cmd *cobra.Command, result *report.Result) {
+	for _, warning := range report.ApplyDerived(result, derivedMetrics) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", warning)
+	}
+}
+
+// printSummary prints the terminal summary, to stderr when stdout carries the JSON report. Metrics
+// are checked against the config's budgets.
+func printSummary(cmd *cobra.Command, result report.Result) {
+	out := cmd.OutOrStdout()
+	if reportToStdout() {
+		out = cmd.ErrOrStderr()
+	}
+	fmt.Fprint(out, report.FormatBudgetSummary(result, projectConfig.Budgets, useColor(out)))
+}
+
+// useColor reports whether out should get ANSI colors: on terminals and in CI logs, which render
+// them, unless --no-color or NO_COLOR (https://no-color.org) is set.
+func useColor(out io.Writer) bool {
+	if noColorFlag || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if os.Getenv("CI") != "" {
+		return true
+	}
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// reportToStdout reports whether the JSON report goes to stdout instead of a file (--no-save or -o -).
+func reportToStdout() bool {
+	return noSaveFlag || strings.TrimSpace(outputPath) == "-"
+}
+
+// saveReport writes result in each --format format. Rendering a PDF starts a browser after the
+// command context may have expired, so it uses its own deadline.
+func saveReport(run runDir, result report.Result) error {
+	if reportToStdout() {
+		return report.WriteJSON(os.Stdout, result)
+	}
+	for _, format := range formatFlags {
+		path, err := resolveOutputFile(run, format)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case "html":
+			err = report.SaveHTML(path, result)
+		case "pdf":
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			err = report.SavePDF(ctx, path, result)
+			cancel()
+		case "csv":
+			err = export.SaveCSV(path, result)
+		default:
+			if appendFlag {
+				err = report.AppendJSONL(path, result)
+			} else {
+				err = report.SaveJSON(path, result)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportResult sends the saved result to every --export sink and, with --gitlab-mr, to the merge
+// request. It runs after the command context may have expired, so it uses its own deadline.
+func exportResult(result report.Result) error {
+	if len(exportFlags) == 0 && !gitlabMRFlag {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, uri := range exportFlags {
+		if err := export.Export(ctx, uri, result); err != nil {
+			return fmt.Errorf("export to %s: %w", uri, err)
+		}
+	}
+	if gitlabMRFlag {
+		mr, err := notify.GitLabMRFromEnv()
+		if err != nil {
+			return err
+		}
+		// Each component and platform keeps its own note, so parallel jobs do not overwrite each other.
+		key := result.Component + "/" + resultPlatform(result)
+		if err := notify.GitLabNote(ctx, mr, key, report.FormatMarkdown(result, projectConfig.Budgets)); err != nil {
+			return fmt.Errorf("post merge request note: %w", err)
+		}
+	}
+	return nil
+}
+
+// resultPlatform names the platform result was measured on.
+func resultPlatform(result report.Result) string {
+	if result.Android != nil || len(result.Postures) > 0 {
+		return "android"
+	}
+	if len(result.OSVersions) > 0 && result.OSVersions[0].Android != nil {
+		return "android"
+	}
+	return "ios"
+}
+
+// splitList parses comma-separated flag values, trimming whitespace and dropping empty entries.
+func splitList(value string) []string {
+	parts := strings.Split(value, ",")
+	items := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if item := strings.ToLower(strings.TrimSpace(part)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// resolveIOSVersions parses the iOS --os-matrix, e.g. "iOS 16.4,iOS 17.5"; a bare "17.5" means iOS.
+func resolveIOSVersions(value string) []string {
+	var versions []string
+	for _, part := range strings.Split(value, ",") {
+		version := strings.TrimSpace(part)
+		if version == "" {
+			continue
+		}
+		if version[0] >= '0' && version[0] <= '9' {
+			version = "iOS " + version
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+func newIOSCmd() *cobra.Command {
+	var opts iosOptions
+	opts.xcrunPath = "xcrun"
+	cmd := &cobra.Command{
+		Use:   "ios",
+		Short: "Run iOS render benchmark.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if reportToStdout() && opts.cpuProfile {
+				return errors.New("--cpu-profile saves an artifact next to the report; it cannot be used with --no-save")
+			}
+			if opts.app != "" {
+				if opts.install {
+					return errors.New("--app installs a prebuilt app; it cannot be combined with --install")
+				}
+				if _, err := os.Stat(opts.app); err != nil {
+					return fmt.Errorf("--app: %w", err)
+				}
+			}
+			osVersions := resolveIOSVersions(opts.osMatrix)
+			if len(osVersions) > 0 && scenarioFlag == scenarioSplitView {
+				return errors.New("--os-matrix cannot be combined with --scenario split-view")
+			}
+			if len(osVersions) > 0 && opts.reboot {
+				return errors.New("--reboot cannot be combined with --os-matrix")
+			}
+			installTimeout, err := time.ParseDuration(strings.TrimSpace(opts.installTimeout))
+			if err != nil || installTimeout <= 0 {
+				return fmt.Errorf("invalid --install-timeout %q (expected a duration such as 15m)", opts.installTimeout)
+			}
+			if err := ensureIOSDefaults(&opts); err != nil {
+				return err
+			}
+			component := resolveComponent(opts.bundleID)
+			plan, err := resolveIterationPlan()
+			if err != nil {
+				return err
+			}
+			idle, err := resolveIdleWait()
+			if err != nil {
+				return err
+			}
+			settle, err := resolveSettle()
+			if err != nil {
+				return err
+			}
+			launchTimeout, err := resolveLaunchTimeout()
+			if err != nil {
+				return err
+			}
+
+			benchmarkComponent := viewFlag
+
+			cfg := ios.Config{
+				Component:          component,
+				BundleID:           opts.bundleID,
+				DeviceID:           opts.deviceID,
+				LaunchArgs:         nil,
+				XCRunPath:          opts.xcrunPath,
+				BenchmarkComponent: benchmarkComponent,
+				TerminateRunning:   opts.terminateRunning || plan.max > 1,
+				IdleCPUThreshold:   idle.threshold,
+				IdleTimeout:        idle.timeout,
+				Settle:             settle,
+				LaunchTimeout:      launchTimeout,
+				AllowDebugBuild:    allowDebugFlag,
+			}
+			run, err := newRunDir(component, "ios")
+			if err != nil {
+				return err
+			}
+			defer run.remove()
+			onEvent, closeLog, err := eventHandler(cmd, run.id)
+			if err != nil {
+				return err
+			}
+			defer closeLog()
+			cfg.OnEvent = onEvent
+			if len(osVersions) > 0 {
+				// The matrix boots more simulators, so pin the reference before "the booted one" changes.
+				setupCtx, cancelSetup := installContext(cmd, installTimeout)
+				reference, err := ios.ResolveDevice(events.WithHandler(setupCtx, "ios", onEvent), opts.xcrunPath, opts.deviceID)
+				cancelSetup()
+				if err != nil {
+					return err
+				}
+				opts.deviceID, cfg.DeviceID = reference.ID, reference.ID
+			}
+			var install *report.InstallRecord
+			switch {
+			case opts.install:
+				install, err = installIOSApp(cmd, opts, installTimeout, onEvent)
+			case opts.app != "" && len(osVersions) == 0:
+				install, err = installIOSArtifact(cmd, opts, opts.deviceID, installTimeout, onEvent)
+			}
+			if err != nil {
+				return err
+			}
+			if opts.reboot {
+				rebootCtx, cancelReboot := installContext(cmd, installTimeout)
+				udid, err := ios.Reboot(rebootCtx, opts.xcrunPath, opts.deviceID, onEvent)
+				cancelReboot()
+				if err != nil {
+					return err
+				}
+				// Pin the simulator, which the booted one no longer has to be once it restarted.
+				opts.deviceID, cfg.DeviceID = udid, udid
+			}
+			// The benchmark's timeout starts after the install, which has a deadline of its own.
+			ctx, cancel, err := commandContext(cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+			// An --os-matrix run checks each simulator once the app is on it.
+			checkIOSView := func(deviceID string) error {
+				if opts.noViewCheck {
+					return nil
+				}
+				return checkView(events.WithHandler(ctx, "ios", onEvent), "ios", func(ctx context.Context) ([]string, bool, error) {
+					return ios.ListComponents(ctx, opts.xcrunPath, deviceID, opts.bundleID)
+				})
+			}
+			if len(osVersions) == 0 {
+				if err := checkIOSView(opts.deviceID); err != nil {
+					return err
+				}
+			}
+			session, err := startAppium(ctx, map[string]any{
+				"platformName":          "iOS",
+				"appium:automationName": "XCUITest",
+				"appium:bundleId":       opts.bundleID,
+				"appium:udid":           opts.deviceID,
+			})
+			if err != nil {
+				return err
+			}
+			if session != nil {
+				defer closeAppium(session)
+				cfg.Interact = session.Interact
+			}
+			result := report.Result{
+				Component:  component,
+				RunID:      run.id,
+				CLICommand: currentCLICommand(cmd),
+				Install:    install,
+			}
+			result.Environment = recordedEnv()
+			switch scenarioFlag {
+			case scenarioLaunch:
+				if len(osVersions) > 0 {
+					for _, version := range osVersions {
+						if err := measureIOSOS(ctx, cmd, version, plan, cfg, opts, run, installTimeout, checkIOSView, &result); err != nil {
+							return fmt.Errorf("%s: %w", version, err)
+						}
+					}
+					break
+				}
+				var runs []*report.IOSMetrics
+				result.IOS, runs, err = measureIOS(ctx, plan, cfg, opts, run, "ios")
+				if err != nil {
+					return err
+				}
+				result.Iterations = appendIOSIterations(result.Iterations, runs, "")
+			case scenarioSplitView:
+				if err := ensureIPad(ctx, cfg); err != nil {
+					return err
+				}
+				// Each layout needs a fresh process so the harness picks up the new size on launch.
+				cfg.TerminateRunning = true
+				for _, layout := range multitaskingLayouts {
+					cfg.Env = map[string]string{"DESIGNBENCH_MULTITASKING": layout}
+					metrics, runs, err := measureIOS(ctx, plan, cfg, opts, run, "ios-"+layout)
+					if err != nil {
+						return fmt.Errorf("layout %s: %w", layout, err)
+					}
+					result.Layouts = append(result.Layouts, report.LayoutMetrics{Layout: layout, IOS: metrics})
+					result.Iterations = appendIOSIterations(result.Iterations, runs, layout)
+				}
+			default:
+				return unsupportedScenario("ios", scenarioLaunch, scenarioSplitView)
+			}
+
+			deriveMetrics(cmd, &result)
+			printSummary(cmd, result)
+			if err := saveReport(run, result); err != nil {
+				return err
+			}
+			return exportResult(result)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.install, "install", false, "Build the app with xcodebuild and install it on the simulator before benchmarking.")
+	cmd.Flags().StringVar(&opts.scheme, "scheme", "", "Xcode scheme built by --install (auto-detected with xcodebuild -list when empty).")
+	cmd.Flags().StringVar(&opts.configuration, "configuration", "", "Build configuration used by --install (default Release).")
+	cmd.Flags().StringVar(&opts.app, "app", "", "Install this prebuilt simulator .app with simctl install before benchmarking, without the Xcode project.")
+	cmd.Flags().StringVar(&opts.installTimeout, "install-timeout", "15m", "Timeout for --install, --app or --reboot, each separate from --timeout, which starts once the app is installed.")
+	cmd.Flags().BoolVar(&opts.terminateRunning, "terminate-running", false, "Terminate the app if it is already running (including a process iOS prewarmed) so the launch starts cold; implied by --iterations above 1.")
+	cmd.Flags().BoolVar(&opts.noViewCheck, "no-view-check", false, "Skip launching the harness to confirm that --view is one of its registered components before benchmarking.")
+	cmd.Flags().StringVar(&opts.osMatrix, "os-matrix", "", "Benchmark on simulators of the --device's type running each of these iOS versions in turn, e.g. \"iOS 16.4,iOS 17.5\", creating and booting them as needed and shutting down or deleting them afterwards. The app is copied from the --device unless --app is given, which is installed on each.")
+	cmd.Flags().BoolVar(&opts.reboot, "reboot", false, "Shut down and boot the simulator once the app is installed and wait for it to finish booting before the first iteration, so that no state from earlier runs remains.")
+	cmd.Flags().BoolVar(&opts.cpuProfile, "cpu-profile", false, "Record an xctrace Time Profiler session from just before each launch until the first frame and save the .trace bundle alongside the report.")
+	return cmd
+}
+
+// installIOSArtifact installs --app on deviceID within the install timeout.
+func installIOSArtifact(cmd *cobra.Command, opts iosOptions, deviceID string, timeout time.Duration, onEvent events.Handler) (*report.InstallRecord, error) {
+	ctx, cancel := installContext(cmd, timeout)
+	defer cancel()
+	return ios.InstallArtifact(ctx, opts.xcrunPath, deviceID, opts.app, onEvent)
+}
+
+// installIOSApp resolves the Xcode workspace or project and scheme, then builds and installs the app
+// within the install timeout.
+func installIOSApp(cmd *cobra.Command, opts iosOptions, timeout time.Duration, onEvent events.Handler) (*report.InstallRecord, error) {
+	ctx, cancel := installContext(cmd, timeout)
+	defer cancel()
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("resolve project root: %w", err)
+	}
+	proj, err := preflight.DetectIOSProject(root)
+	if err != nil {
+		return nil, fmt.Errorf("detect iOS project: %w", err)
+	}
+	if proj.Container == "" {
+		return nil, errors.New("no .xcworkspace or .xcodeproj found to build for --install")
+	}
+	scheme := opts.scheme
+	if scheme == "" {
+		schemes, err := preflight.ListXcodeSchemes(ctx, proj.Container)
+		if err != nil {
+			return nil, err
+		}
+		scheme = preflight.PickScheme(schemes.Schemes, proj.Container)
+		if scheme == "" {
+			return nil, fmt.Errorf("no app scheme found in %s (set --scheme)", filepath.Base(proj.Container))
+		}
+	}
+	return ios.Install(ctx, ios.InstallConfig{
+		XCRunPath:     opts.xcrunPath,
+		DeviceID:      opts.deviceID,
+		Container:     proj.Container,
+		Scheme:        scheme,
+		Configuration: opts.configuration,
+		OnEvent:       onEvent,
+	})
+}
+
+// measureIOSOS measures one iOS version of --os-matrix on a simulator running it, copying the app
+// to it first.
+func measureIOSOS(ctx context.Context, cmd *cobra.Command, version string, plan iterationPlan, cfg ios.Config, opts iosOptions, run runDir, installTimeout time.Duration, checkView func(deviceID string) error, result *report.Result) error {
+	target, err := ios.StartOSTarget(ctx, opts.xcrunPath, opts.deviceID, version, cfg.OnEvent)
+	if err != nil {
+		return err
+	}
+	defer stopOSTarget(target)
+	reference := opts.deviceID
+	opts.deviceID, cfg.DeviceID = target.UDID, target.UDID
+	if opts.app != "" {
+		install, err := installIOSArtifact(cmd, opts, target.UDID, installTimeout, cfg.OnEvent)
+		if err != nil {
+			return err
+		}
+		if result.Install == nil {
+			result.Install = install
+		}
+	} else if target.UDID != reference {
+		if _, err := ios.CopyApp(ctx, opts.xcrunPath, reference, target.UDID, opts.bundleID, cfg.OnEvent); err != nil {
+			return err
+		}
+	}
+	if err := checkView(target.UDID); err != nil {
+		return err
+	}
+	label := strings.ToLower(strings.ReplaceAll(target.OS, " ", ""))
+	metrics, runs, err := measureIOS(ctx, plan, cfg, opts, run, "ios-"+label)
+	if err != nil {
+		return err
+	}
+	result.OSVersions = append(result.OSVersions, report.OSMetrics{OS: target.OS, IOS: metrics})
+	result.Iterations = labelOS(result.Iterations, appendIOSIterations(nil, runs, ""), target.OS)
+	return nil
+}
+
+// measureIOS runs the iteration plan for one iOS configuration and returns the aggregate together
+// with each iteration's metrics. The artifact label keeps profiles from different configurations
+// (e.g. multitasking layouts) apart within the run directory.
+func measureIOS(ctx context.Context, plan iterationPlan, cfg ios.Config, opts iosOptions, run runDir, artifactLabel string) (*report.IOSMetrics, []*report.IOSMetrics, error) {
+	runs := make([]*report.IOSMetrics, 0, plan.max)
+	ciWidth, err := runIterations(ctx, plan, func(iteration int) (float64, error) {
+		if opts.cpuProfile {
+			path, err := resolveArtifactFile(run, artifactLabel, iteration, plan.max, ".trace")
+			if err != nil {
+				return 0, err
+			}
+			cfg.CPUProfilePath = path
+		}
+		run, err := ios.Run(ctx, cfg)
+		if err != nil {
+			if watchdog.IsHung(err) {
+				runs = append(runs, nil)
+			}
+			return 0, err
+		}
+		runs = append(runs, run)
+		return run.RenderTimeMs, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	measured := measuredRuns(runs)
+	metrics := report.AggregateIOS(measured)
+	metrics.HungIterations = len(runs) - len(measured)
+	metrics.CIWidthPct = ciWidth * 100
+	metrics.Spread = report.Spread(metrics.IterationValues, spreadStatistics)
+	return metrics, runs, nil
+}
+
+// appendIOSIterations records each run of a multi-iteration plan in the report. Nil runs hung.
+func appendIOSIterations(records []report.IterationRecord, runs []*report.IOSMetrics, layout string) []report.IterationRecord {
+	if len(runs) < 2 {
+		return records
+	}
+	for i, run := range runs {
+		records = append(records, report.IterationRecord{Iteration: i + 1, Layout: layout, IOS: run, Hung: run == nil})
+	}
+	return records
+}
+
+// ensureIPad rejects the split-view scenario on devices without iPad multitasking.
+func ensureIPad(ctx context.Context, cfg ios.Config) error {
+	meta, err := ios.ResolveDevice(ctx, cfg.XCRunPath, cfg.DeviceID)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(meta.Model, "iPad") && !strings.Contains(meta.DeviceType, "iPad") {
+		return fmt.Errorf("scenario %s requires an iPad simulator or device (target is %q)", scenarioSplitView, meta.Model)
+	}
+	return nil
+}
+
+func unsupportedScenario(platform string, supported ...string) error {
+	return fmt.Errorf("scenario %q is not supported on %s (expected %s)", scenarioFlag, platform, strings.Join(supported, ", "))
+}
+
+func ensureAndroidDefaults(opts *androidOptions) error {
+	if opts.packageName == "" {
+		opts.packageName = projectConfig.Android.Package
+	}
+	if opts.activity == "" {
+		opts.activity = projectConfig.Android.Activity
+	}
+	if opts.deviceID == "" {
+		opts.deviceID = projectConfig.Android.Device
+	}
+	if projectConfig.Android.ADBPath != "" {
+		opts.adbPath = projectConfig.Android.ADBPath
+	}
+	if opts.variant == "" {
+		opts.variant = projectConfig.Android.Variant
+	}
+	if opts.flavor == "" {
+		opts.flavor = projectConfig.Android.Flavor
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("resolve project root: %w", err)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err == nil {
+		root = absRoot
+	}
+	proj, detectErr := preflight.DetectAndroidProject(root)
+	missingPackage := strings.TrimSpace(opts.packageName) == ""
+	missingActivity := strings.TrimSpace(opts.activity) == ""
+	if !missingPackage && !missingActivity {
+		return nil
+	}
+	if detectErr == nil && proj != nil {
+		if missingPackage && proj.Package != "" {
+			opts.packageName = proj.Package
+		}
+		if missingActivity && proj.Activity != "" {
+			opts.activity = proj.Activity
+		}
+	}
+	if strings.TrimSpace(opts.packageName) != "" && strings.TrimSpace(opts.activity) != "" {
+		return nil
+	}
+	if detectErr != nil {
+		return fmt.Errorf("unable to auto-detect Android defaults: %w (set --package/--activity manually)", detectErr)
+	}
+	missing := make([]string, 0, 2)
+	if strings.TrimSpace(opts.packageName) == "" {
+		missing = append(missing, "--package")
+	}
+	if strings.TrimSpace(opts.activity) == "" {
+		missing = append(missing, "--activity")
+	}
+	return fmt.Errorf("missing Android %s (run from project root or provide flags)", strings.Join(missing, " and "))
+}
+
+func ensureIOSDefaults(opts *iosOptions) error {
+	if opts.bundleID == "" {
+		opts.bundleID = projectConfig.IOS.BundleID
+	}
+	if opts.deviceID == "" {
+		opts.deviceID = projectConfig.IOS.Device
+	}
+	if projectConfig.IOS.XCRunPath != "" {
+		opts.xcrunPath = projectConfig.IOS.XCRunPath
+	}
+	if opts.scheme == "" {
+		opts.scheme = projectConfig.IOS.Scheme
+	}
+	if opts.configuration == "" {
+		opts.configuration = projectConfig.IOS.Configuration
+	}
+	if strings.TrimSpace(opts.bundleID) != "" {
+		return nil
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("resolve project root: %w", err)
+	}
+	proj, detectErr := preflight.DetectIOSProject(root)
+	if detectErr != nil {
+		return fmt.Errorf("unable to auto-detect iOS bundle id: %w (set --bundle manually)", detectErr)
+	}
+	if proj == nil || strings.TrimSpace(proj.BundleID) == "" {
+		return fmt.Errorf("Info.plist detected but bundle id empty (set --bundle manually)")
+	}
+	opts.bundleID = proj.BundleID
+	return nil
+}
+
+func resolveComponent(fallback string) string {
+	if componentFlag != "" {
+		return componentFlag
+	}
+	if viewFlag != "" {
+		return viewFlag
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return "component"
+}
+
+// iterationPlan bounds how many times a benchmark is launched.
+type iterationPlan struct {
+	max    int
+	target float64 // relative CI half-width; 0 runs exactly max iterations
+}
+
+func resolveIterationPlan() (iterationPlan, error) {
+	plan := iterationPlan{max: iterationsFlag}
+	if plan.max < 1 {
+		return plan, fmt.Errorf("invalid --iterations %d (must be at least 1)", iterationsFlag)
+	}
+	raw := strings.TrimSpace(targetCIWidthFlag)
+	if raw == "" {
+		return plan, nil
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(raw, "%")), 64)
+	if err != nil || value <= 0 {
+		return plan, fmt.Errorf("invalid --target-ci-width %q (expected a percentage such as 5%%)", raw)
+	}
+	plan.target = value / 100
+	if plan.max == 1 {
+		plan.max = defaultAdaptiveMaxIterations
+	}
+	if plan.max < minAdaptiveIterations {
+		plan.max = minAdaptiveIterations
+	}
+	return plan, nil
+}
+
+// idleWait is the pre-iteration CPU stabilisation requested with --idle-cpu.
+type idleWait struct {
+	threshold float64 // percent; 0 disables the wait
+	timeout   time.Duration
+}
+
+func resolveIdleWait() (idleWait, error) {
+	var wait idleWait
+	raw := strings.TrimSpace(idleCPUFlag)
+	if raw == "" {
+		return wait, nil
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(raw, "%")), 64)
+	if err != nil || value <= 0 || value > 100 {
+		return wait, fmt.Errorf("invalid --idle-cpu %q (expected a percentage such as 20%%)", raw)
+	}
+	wait.threshold = value
+	if timeout := strings.TrimSpace(idleTimeoutFlag); timeout != "" {
+		dur, err := time.ParseDuration(timeout)
+		if err != nil {
+			return wait, fmt.Errorf("invalid --idle-timeout %q: %w", timeout, err)
+		}
+		wait.timeout = dur
+	}
+	return wait, nil
+}
+
+func resolveSettle() (time.Duration, error) {
+	raw := strings.TrimSpace(settleFlag)
+	if raw == "" {
+		return 0, nil
+	}
+	dur, err := time.ParseDuration(raw)
+	if err != nil || dur < 0 {
+		return 0, fmt.Errorf("invalid --settle %q (expected a duration such as 2s)", raw)
+	}
+	return dur, nil
+}
+
+func resolveLaunchTimeout() (time.Duration, error) {
+	raw := strings.TrimSpace(launchTimeoutFlag)
+	if raw == "" {
+		return 0, nil
+	}
+	dur, err := time.ParseDuration(raw)
+	if err != nil || dur < 0 {
+		return 0, fmt.Errorf("invalid --launch-timeout %q (expected a duration such as 20s, or 0 to disable)", raw)
+	}
+	return dur, nil
+}
+
+// runIterations calls run until the plan's iteration cap is reached or, in adaptive mode, the
+// primary metric's 95% confidence interval narrows to the target. It returns the final relative CI half-width.
+// An iteration whose launch hung is skipped; the plan fails only when every iteration hung.
+func runIterations(ctx context.Context, plan iterationPlan, run func(iteration int) (float64, error)) (float64, error) {
+	samples := make([]float64, 0, plan.max)
+	var hung error
+	for i := 0; i < plan.max; i++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		value, err := run(i + 1)
+		if watchdog.IsHung(err) {
+			hung = fmt.Errorf("iteration %d: %w", i+1, err)
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("iteration %d: %w", i+1, err)
+		}
+		samples = append(samples, value)
+		if plan.target > 0 && len(samples) >= minAdaptiveIterations && stats.RelativeCIHalfWidth(samples) <= plan.target {
+			break
+		}
+	}
+	if len(samples) == 0 && hung != nil {
+		return 0, hung
+	}
+	if len(samples) < 2 {
+		return 0, nil
+	}
+	// A primary metric that is 0 in every sample has no relative width, and JSON cannot hold +Inf.
+	width := stats.RelativeCIHalfWidth(samples)
+	if math.IsInf(width, 0) || math.IsNaN(width) {
+		return 0, nil
+	}
+	return width, nil
+}
+
+func commandContext(cmd *cobra.Command) (context.Context, context.CancelFunc, error) {
+	timeout := strings.TrimSpace(timeoutFlag)
+	parent := cmd.Context()
+	if parent == nil {
+		parent = context.Background()
+	}
+	if timeout == "" {
+		ctx, cancel := context.WithCancel(parent)
+		return ctx, cancel, nil
+	}
+	dur, err := time.ParseDuration(timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timeout %q: %w", timeout, err)
+	}
+	if dur <= 0 {
+		ctx, cancel := context.WithCancel(parent)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithTimeout(parent, dur)
+	return ctx, cancel, nil
+}
+
+// runDir is the per-invocation directory under designbench-reports that holds the JSON report
+// and every artifact the run produces, e.g. designbench-reports/button-android-20240501-093000.
+type runDir struct {
+	id   string
+	path string
+	// component, platform and started fill in --output templates.
+	component string
+	platform  string
+	started   time.Time
+	// ephemeral directories live in the system temp dir and are deleted with their contents.
+	ephemeral bool
+}
+
+// remove drops the run directory again if the run produced nothing to put in it, or entirely when
+// it is ephemeral.
+func (run runDir) remove() {
+	if run.ephemeral {
+		os.RemoveAll(run.path)
+		return
+	}
+	os.Remove(run.path)
+}
+
+func newRunDir(component, platform string) (runDir, error) {
+	started := time.Now()
+	base := fmt.Sprintf("%s-%s", strings.TrimSuffix(defaultReportFileName(component, platform), ".json"), started.Format(runTimestampLayout))
+	if reportToStdout() {
+		path, err := os.MkdirTemp("", base+"-")
+		if err != nil {
+			return runDir{}, fmt.Errorf("create run dir: %w", err)
+		}
+		return runDir{id: base, path: path, component: component, platform: platform, started: started, ephemeral: true}, nil
+	}
+	if err := os.MkdirAll(defaultReportsDir, 0o755); err != nil {
+		return runDir{}, fmt.Errorf("create reports dir: %w", err)
+	}
+	id := base
+	for attempt := 2; ; attempt++ {
+		path := filepath.Join(defaultReportsDir, id)
+		err := os.Mkdir(path, 0o755)
+		if err == nil {
+			return runDir{id: id, path: path, component: component, platform: platform, started: started}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return runDir{}, fmt.Errorf("create run dir: %w", err)
+		}
+		// Another run started within the same second.
+		id = fmt.Sprintf("%s-%d", base, attempt)
+	}
+}
+
+// runTimestampLayout formats the start time in run directory names and {timestamp}.
+const runTimestampLayout = "20060102-150405"
+
+var outputPlaceholderRe = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// expandOutputTemplate replaces the {component}, {platform}, {timestamp}, {runId} and {format}
+// placeholders of an --output path with the run's values.
+func expandOutputTemplate(template string, run runDir, format string) (string, error) {
+	var unknown []string
+	path := outputPlaceholderRe.ReplaceAllStringFunc(template, func(match string) string {
+		switch name := match[1 : len(match)-1]; name {
+		case "component":
+			return sanitizeToken(run.component, "component")
+		case "platform":
+			return sanitizeToken(run.platform, "run")
+		case "timestamp":
+			return run.started.Format(runTimestampLayout)
+		case "runId":
+			return run.id
+		case "format":
+			return format
+		default:
+			unknown = append(unknown, match)
+			return match
+		}
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown placeholder %s in --output %q (expected {component}, {platform}, {timestamp}, {runId} or {format})", strings.Join(unknown, ", "), template)
+	}
+	return path, nil
+}
+
+// resolveOutputFile returns the path of the report in format: report.<format> inside the run
+// directory, or --output with its placeholders expanded. When several formats are written and
+// --output has no {format}, each one replaces the extension.
+func resolveOutputFile(run runDir, format string) (string, error) {
+	path := strings.TrimSpace(outputPath)
+	if path == "" {
+		return filepath.Join(run.path, "report."+format), nil
+	}
+	if len(formatFlags) > 1 && !strings.Contains(path, "{format}") {
+		path = strings.TrimSuffix(path, filepath.Ext(path)) + "." + format
+	}
+	path, err := expandOutputTemplate(path, run, format)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(path) {
+		if err := os.MkdirAll(defaultReportsDir, 0o755); err != nil {
+			return "", fmt.Errorf("create reports dir: %w", err)
+		}
+		path = filepath.Join(defaultReportsDir, path)
+	} else {
+		dir := filepath.Dir(path)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", fmt.Errorf("create output dir: %w", err)
+		}
+	}
+	return path, nil
+}
+
+// resolveArtifactFile places an artifact in the run directory, e.g. <run>/android.perfetto-trace,
+// adding the iteration number for repeated runs.
+func resolveArtifactFile(run runDir, label string, iteration, iterations int, ext string) (string, error) {
+	name := sanitizeToken(label, "artifact")
+	if iterations > 1 {
+		name = fmt.Sprintf("%s-%d", name, iteration)
+	}
+	return filepath.Join(run.path, name+ext), nil
+}
+
+// recordedEnv returns the environment variables allowed into reports: redact.DefaultEnv and the
+// config's recordEnv.
+func recordedEnv() map[string]string {
+	return redact.Env(append(append([]string(nil), redact.DefaultEnv...), projectConfig.RecordEnv...))
+}
+
+// currentCLICommand returns the command line of this run with the values of sensitive flags scrubbed,
+// quoted so that designbench repro can run it again.
+func currentCLICommand(cmd *cobra.Command) string {
+	if len(os.Args) == 0 {
+		return ""
+	}
+	rootName := "designbench"
+	if cmd != nil && cmd.Root() != nil && cmd.Root().Use != "" {
+		rootName = cmd.Root().Use
+	}
+	var b strings.Builder
+	b.WriteString(rootName)
+	for _, arg := range redact.Args(os.Args[1:]) {
+		b.WriteByte(' ')
+		b.WriteString(quoteShellArg(arg))
+	}
+	return b.String()
+}
+
+// quoteShellArg single-quotes arg when a POSIX shell would otherwise split or expand it.
+func quoteShellArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;~#!") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func defaultReportFileName(component string, platform string) string {
+	componentToken := sanitizeToken(component, "component")
+	platformToken := sanitizeToken(platform, "run")
+	if platformToken != "" {
+		return fmt.Sprintf("%s-%s.json", componentToken, platformToken)
+	}
+	return componentToken + ".json"
+}
+
+func sanitizeToken(value string, fallback string) string {
+	v := strings.TrimSpace(strings.ToLower(value))
+	if v == "" {
+		return fallback
+	}
+
+	var b strings.Builder
+	lastDash := false
+	for i := 0; i < len(v); i++ {
+		ch := v[i]
+		if (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') {
+			b.WriteByte(ch)
+			lastDash = false
+			continue
+		}
+		if !lastDash {
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+
+	token := strings.Trim(b.String(), "-")
+	if token == "" {
+		return fallback
+	}
+	return token
+}
+
+func newPreflightCmd() *cobra.Command {
+	rootDir := "."
+	adbPath := "adb"
+	xcrunPath := "xcrun"
+	apkPath := ""
+
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Run a readiness checklist for Android and iOS benchmarking.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rootDir == "" {
+				rootDir = "."
+			}
+			absRoot, err := filepath.Abs(rootDir)
+			if err != nil {
+				return fmt.Errorf("resolve project root: %w", err)
+			}
+
+			ctx, cancel, err := commandContext(cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			out := cmd.OutOrStdout()
+
+			androidProj, androidProjErr := preflight.DetectAndroidProject(absRoot)
+			androidDevice, androidDeviceErr := preflight.DetectAndroidDevice(ctx, adbPath)
+			iosProj, iosProjErr := preflight.DetectIOSProject(absRoot)
+			iosDevice, iosDeviceErr := preflight.DetectIOSDevice(ctx, xcrunPath)
+
+			items := []checklistItem{
+				checkBinaryItem("adb available", adbPath),
+				checkBinaryItem("xcodebuild available", "xcodebuild"),
+				checkBinaryItem("xcrun available", xcrunPath),
+				checkAndroidProjectItem(androidProj, androidProjErr),
+				checkAndroidDeviceItem(androidDevice, androidDeviceErr),
+				checkIOSProjectItem(ctx, iosProj, iosProjErr),
+				checkIOSDeviceItem(iosDevice, iosDeviceErr),
+			}
+			if kmpProj, err := preflight.DetectKMPProject(absRoot); kmpProj != nil || err != nil {
+				items = append(items, checkKMPProjectItem(kmpProj, err))
+			}
+			androidDeviceID, iosRuntime := "", ""
+			if androidDevice != nil {
+				androidDeviceID = androidDevice.ID
+			}
+			if iosDevice != nil {
+				iosRuntime = iosDevice.Runtime
+			}
+			androidToolchain, androidToolchainErr := preflight.ReadAndroidToolchain(ctx, adbPath, androidDeviceID)
+			xcodeToolchain, xcodeToolchainErr := preflight.ReadXcodeToolchain(ctx, iosRuntime)
+			items = append(items,
+				checkAndroidToolchainItem(androidToolchain, androidToolchainErr),
+				checkXcodeToolchainItem(xcodeToolchain, xcodeToolchainErr))
+			if androidProj != nil {
+				items = append(items, checkGradleItem(preflight.InspectGradle(ctx, absRoot, androidProj.ModuleDir)))
+			}
+			items = append(items, checkIOSRuntimeItem(ctx, xcrunPath, absRoot))
+			items = append(items, checkHostResourcesItem(ctx, absRoot))
+			if androidDevice != nil {
+				items = append(items, checkAndroidResourcesItem(ctx, adbPath, androidDevice.ID))
+				settings, err := preflight.ReadAndroidSettings(ctx, adbPath, androidDevice.ID)
+				items = append(items, checkAndroidSettingsItem(settings, err))
+			}
+			if apkPath != "" || androidDevice != nil {
+				packageName := projectConfig.Android.Package
+				if packageName == "" && androidProj != nil {
+					packageName = androidProj.Package
+				}
+				build, err := preflight.InspectAndroidBuild(ctx, adbPath, androidDeviceID, packageName, apkPath)
+				items = append(items, checkAndroidBuildItem(build, err))
+			}
+
+			fmt.Fprintf(out, "Preflight checklist (root: %s)\n\n", absRoot)
+			printChecklist(out, items)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&apkPath, "apk", "", "Check this APK's build type and signature instead of the one installed on the device.")
+
+	return cmd
+}
+
+func newInitCmd() *cobra.Command {
+	force := false
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a starter designbench.yaml for the project in the current directory.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("resolve project root: %w", err)
+			}
+			path := configPath
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(root, path)
+			}
+			if _, err := os.Stat(path); err == nil && !force {
+				return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+			}
+
+			out := cmd.OutOrStdout()
+			starter := config.Config{
+				Component:  "Component",
+				Iterations: 5,
+				Timeout:    "2m",
+			}
+			androidProj, androidErr := preflight.DetectAndroidProject(root)
+			if androidProj != nil {
+				starter.Android.Package = androidProj.Package
+				starter.Android.Activity = androidProj.Activity
+			}
+			iosProj, iosErr := preflight.DetectIOSProject(root)
+			if iosErr == nil && iosProj != nil {
+				starter.IOS.BundleID = iosProj.BundleID
+			}
+			// In a Kotlin Multiplatform project both apps are benchmarked, so also pin the scheme that
+			// `designbench ios --install` builds.
+			kmpProj, _ := preflight.DetectKMPProject(root)
+			if kmpProj != nil && kmpProj.IOS != nil && kmpProj.IOS.Container != "" {
+				if schemes, err := preflight.ListXcodeSchemes(cmd.Context(), kmpProj.IOS.Container); err == nil {
+					starter.IOS.Scheme = preflight.PickScheme(schemes.Schemes, kmpProj.IOS.Container)
+				}
+			}
+			data, err := config.Starter(starter)
+			if err != nil {
+				return fmt.Errorf("render config: %w", err)
+			}
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				return fmt.Errorf("write config: %w", err)
+			}
+
+			fmt.Fprintf(out, "Wrote %s\n\n", path)
+			items := []checklistItem{
+				checkAndroidProjectItem(androidProj, androidErr),
+				checkIOSProjectItem(cmd.Context(), iosProj, iosErr),
+			}
+			if kmpProj != nil {
+				items = append(items, checkKMPProjectItem(kmpProj, nil))
+			}
+			printChecklist(out, items)
+			fmt.Fprintln(out, "\nNext steps:")
+			step := 1
+			if androidProj != nil {
+				fmt.Fprintf(out, "  %d. Make %s read the designbench_component extra and render that component.\n", step, displayOrPlaceholder(androidProj.Activity, "the benchmark activity"))
+				step++
+				fmt.Fprintf(out, "  %d. Install the app: designbench android --install (or ./gradlew %sinstallRelease; a release-like build gives representative timings).\n", step, gradleTaskPrefix(androidProj.ModuleDir))
+				step++
+			}
+			if iosProj != nil {
+				fmt.Fprintf(out, "  %d. Make the iOS app read DESIGNBENCH_COMPONENT from its environment and render that view.\n", step)
+				step++
+				fmt.Fprintf(out, "  %d. Build the app for the simulator and install it: designbench ios --install (or xcrun simctl install booted <App>.app).\n", step)
+				step++
+			}
+			fmt.Fprintf(out, "  %d. Set component in %s, then run designbench preflight and designbench android / designbench ios.\n", step, filepath.Base(path))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing config file.")
+	return cmd
+}
+
+func newServeCmd() *cobra.Command {
+	api := false
+	addr := "127.0.0.1:8080"
+	token := ""
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an HTTP API that queues benchmark runs on this machine's devices.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !api {
+				return errors.New("nothing to serve; pass --api")
+			}
+			if token == "" {
+				token = os.Getenv("DESIGNBENCH_API_TOKEN")
+			}
+			if token == "" && !isLoopbackAddr(addr) {
+				return fmt.Errorf("--addr %s accepts remote clients; set --token or DESIGNBENCH_API_TOKEN", addr)
+			}
+			srv, err := newRunServer(cmd, "api", token)
+			if err != nil {
+				return err
+			}
+
+			// The server runs until interrupted, so --timeout only applies to the runs it starts.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			httpServer := &http.Server{Addr: addr, Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+			go srv.Run(ctx)
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = httpServer.Shutdown(shutdownCtx)
+			}()
+			fmt.Fprintf(cmd.OutOrStdout(), "Serving the designbench API on http://%s/api\n", addr)
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&api, "api", false, "Serve the REST API for triggering runs and fetching their status and reports.")
+	cmd.Flags().StringVar(&addr, "addr", addr, "Address to listen on; use 0.0.0.0:8080 to accept remote clients.")
+	cmd.Flags().StringVar(&token, "token", "", "Require this bearer token on every request (default $DESIGNBENCH_API_TOKEN).")
+	return cmd
+}
+
+func newAgentCmd() *cobra.Command {
+	addr := "127.0.0.1:50051"
+	token := ""
+	tlsCert := ""
+	tlsKey := ""
+
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Accept benchmark runs from a controller over gRPC and execute them on this machine's devices.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if token == "" {
+				token = os.Getenv("DESIGNBENCH_AGENT_TOKEN")
+			}
+			if token == "" && !isLoopbackAddr(addr) {
+				return fmt.Errorf("--addr %s accepts remote controllers; set --token or DESIGNBENCH_AGENT_TOKEN", addr)
+			}
+			if (tlsCert == "") != (tlsKey == "") {
+				return errors.New("--tls-cert and --tls-key must be set together")
+			}
+			var creds credentials.TransportCredentials
+			if tlsCert != "" {
+				loaded, err := credentials.NewServerTLSFromFile(tlsCert, tlsKey)
+				if err != nil {
+					return fmt.Errorf("load TLS certificate: %w", err)
+				}
+				creds = loaded
+			} else if !isLoopbackAddr(addr) {
+				fmt.Fprintln(cmd.ErrOrStderr(), "warning: without --tls-cert the token and reports travel unencrypted; use a VPN or SSH tunnel")
+			}
+			srv, err := newRunServer(cmd, "agent", "")
+			if err != nil {
+				return err
+			}
+			lis, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			grpcServer := agent.NewServer(srv, token, creds)
+
+			// The agent runs until interrupted, so --timeout only applies to the runs it starts.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go srv.Run(ctx)
+			go func() {
+				<-ctx.Done()
+				grpcServer.Stop()
+			}()
+			fmt.Fprintf(cmd.OutOrStdout(), "Serving the designbench agent on %s\n", lis.Addr())
+			return grpcServer.Serve(lis)
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", addr, "Address to listen on; use 0.0.0.0:50051 to accept remote controllers.")
+	cmd.Flags().StringVar(&token, "token", "", "Require this bearer token on every call (default $DESIGNBENCH_AGENT_TOKEN); required unless --addr is a loopback address.")
+	cmd.Flags().StringVar(&tlsCert, "tls-cert", "", "Serve over TLS with this PEM certificate (with --tls-key).")
+	cmd.Flags().StringVar(&tlsKey, "tls-key", "", "PEM private key for --tls-cert.")
+	return cmd
+}
+
+// remoteForwardedFlags are the global flags that `designbench remote` passes on to the agent's run
+// when they are set on its command line.
+var remoteForwardedFlags = []string{"view", "timeout", "iterations", "scenario", "target-ci-width", "percentiles", "idle-cpu", "idle-timeout", "settle", "launch-timeout", "allow-debug"}
+
+func newRemoteCmd() *cobra.Command {
+	agentAddr := ""
+	token := ""
+	useTLS := false
+	tlsCA := ""
+
+	cmd := &cobra.Command{
+		Use:   "remote <android|ios> [-- platform flags]",
+		Short: "Run a benchmark on a designbench agent and save its report locally.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if agentAddr == "" {
+				return errors.New("--agent is required")
+			}
+			if token == "" {
+				token = os.Getenv("DESIGNBENCH_AGENT_TOKEN")
+			}
+			platform := args[0]
+			runArgs := make([]string, 0, len(args))
+			for _, name := range remoteForwardedFlags {
+				if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+					runArgs = append(runArgs, fmt.Sprintf("--%s=%s", name, flag.Value))
+				}
+			}
+			runArgs = append(runArgs, args[1:]...)
+
+			// Runs may wait in the agent's queue, so the local command has no deadline; --timeout
+			// is forwarded to the run instead.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			var creds credentials.TransportCredentials
+			switch {
+			case tlsCA != "":
+				loaded, err := credentials.NewClientTLSFromFile(tlsCA, "")
+				if err != nil {
+					return fmt.Errorf("load --tls-ca: %w", err)
+				}
+				creds = loaded
+			case useTLS:
+				creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+			}
+			client, closeConn, err := agent.Dial(agentAddr, token, creds)
+			if err != nil {
+				return fmt.Errorf("connect to agent: %w", err)
+			}
+			defer closeConn()
+			queued, err := client.RunBenchmark(ctx, &agentpb.RunBenchmarkRequest{
+				Platform:  platform,
+				Component: componentFlag,
+				Args:      runArgs,
+			})
+			if err != nil {
+				return fmt.Errorf("start remote run: %w", err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Queued run %s on %s\n", queued.GetId(), agentAddr)
+
+			run, err := newRunDir(resolveComponent(""), platform)
+			if err != nil {
+				return err
+			}
+			defer run.remove()
+			onEvent, closeLog, err := eventHandler(cmd, run.id)
+			if err != nil {
+				return err
+			}
+			defer closeLog()
+			stream, err := client.StreamProgress(ctx, &agentpb.StreamProgressRequest{RunId: queued.GetId()})
+			if err != nil {
+				return fmt.Errorf("stream progress: %w", err)
+			}
+			for {
+				event, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					return fmt.Errorf("stream progress: %w", err)
+				}
+				if onEvent != nil {
+					onEvent(agent.EventFromProto(event))
+				}
+			}
+
+			resp, err := client.GetReport(ctx, &agentpb.GetReportRequest{RunId: queued.GetId()})
+			if err != nil {
+				return fmt.Errorf("fetch report: %w", err)
+			}
+			if resp.GetRun().GetStatus() != agentpb.RunStatus_RUN_STATUS_SUCCEEDED {
+				return fmt.Errorf("remote run %s failed: %s", queued.GetId(), resp.GetRun().GetError())
+			}
+			var result report.Result
+			if err := json.Unmarshal(resp.GetReport(), &result); err != nil {
+				return fmt.Errorf("parse remote report: %w", err)
+			}
+			deriveMetrics(cmd, &result)
+			printSummary(cmd, result)
+			if err := saveReport(run, result); err != nil {
+				return err
+			}
+			return exportResult(result)
+		},
+	}
+	cmd.Flags().StringVar(&agentAddr, "agent", "", "Address of the designbench agent, e.g. mac-mini-3:50051.")
+	cmd.Flags().StringVar(&token, "token", "", "Bearer token expected by the agent (default $DESIGNBENCH_AGENT_TOKEN).")
+	cmd.Flags().BoolVar(&useTLS, "tls", false, "Connect over TLS, verifying the agent's certificate against the system roots.")
+	cmd.Flags().StringVar(&tlsCA, "tls-ca", "", "Connect over TLS, verifying the agent's certificate against this PEM CA file (implies --tls).")
+	return cmd
+}
+
+// Regression detection in `designbench daemon`: a result is compared with the median of up to
+// historyWindow earlier runs of the same suite entry once there are at least minHistoryRuns.
+const (
+	historyWindow  = 10
+	minHistoryRuns = 3
+)
+
+func newDaemonCmd() *cobra.Command {
+	scheduleSpec := ""
+	var suiteNames []string
+	webhook := ""
+	webhookFormat := ""
+	thresholdFlag := "10%"
+	runNow := false
+	shuffle := false
+	var seed int64
+
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run the suites from designbench.yaml on a cron schedule, record their history and report regressions.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cron, err := schedule.Parse(scheduleSpec)
+			if err != nil {
+				return fmt.Errorf("invalid --schedule: %w", err)
+			}
+			threshold, err := resolveRegressionThreshold(cmd, thresholdFlag)
+			if err != nil {
+				return err
+			}
+			suiteNames, err = resolveSuites(suiteNames)
+			if err != nil {
+				return err
+			}
+			if webhook == "" {
+				webhook = os.Getenv("DESIGNBENCH_WEBHOOK_URL")
+			}
+			switch webhookFormat {
+			case "":
+				webhookFormat = notify.DetectFormat(webhook)
+			case notify.FormatSlack, notify.FormatTeams:
+			default:
+				return fmt.Errorf("invalid --webhook-format %q (expected %s or %s)", webhookFormat, notify.FormatSlack, notify.FormatTeams)
+			}
+			var email *notify.EmailConfig
+			if cfg := projectConfig.Email; cfg != nil {
+				email = &notify.EmailConfig{
+					Server:   cfg.Server,
+					From:     cfg.From,
+					To:       cfg.To,
+					Username: cfg.Username,
+					Password: os.Getenv("DESIGNBENCH_SMTP_PASSWORD"),
+				}
+				if email.Server == "" || email.From == "" || len(email.To) == 0 {
+					return fmt.Errorf("%s: email needs server, from and to", configPath)
+				}
+			}
+			srv, err := newRunServer(cmd, "daemon", "")
+			if err != nil {
+				return err
+			}
+
+			// The daemon runs until interrupted, so --timeout only applies to the runs it starts.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go srv.Run(ctx)
+			d := &daemon{
+				out:       cmd.OutOrStdout(),
+				runs:      srv,
+				suites:    suiteNames,
+				history:   filepath.Join(defaultReportsDir, history.FileName),
+				threshold: threshold,
+				webhook:   webhook,
+				format:    webhookFormat,
+				email:     email,
+				color:     useColor(cmd.OutOrStdout()),
+				shuffle:   shuffle || seed != 0,
+				seed:      seed,
+			}
+			if runNow {
+				d.runSuites(ctx)
+			}
+			for {
+				next := cron.Next(time.Now())
+				if next.IsZero() {
+					return fmt.Errorf("--schedule %q never fires", scheduleSpec)
+				}
+				fmt.Fprintf(d.out, "Next run at %s\n", next.Format(time.RFC3339))
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(time.Until(next)):
+				}
+				d.runSuites(ctx)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&scheduleSpec, "schedule", "", "Cron expression in local time, e.g. \"0 2 * * *\" for 02:00 every night.")
+	cmd.Flags().StringSliceVar(&suiteNames, "suite", nil, "Suite from designbench.yaml to run (repeatable; default all).")
+	cmd.Flags().StringVar(&webhook, "webhook", "", "Post regressions and failed runs to this Slack-compatible or Microsoft Teams webhook (default $DESIGNBENCH_WEBHOOK_URL).")
+	cmd.Flags().StringVar(&webhookFormat, "webhook-format", "", "Webhook payload: slack (a text field, also accepted by most chat tools) or teams (an Adaptive Card); detected from the webhook's host when empty.")
+	cmd.Flags().StringVar(&thresholdFlag, "regression-threshold", thresholdFlag, "Flag a run whose primary metric exceeds its history median by more than this.")
+	cmd.Flags().BoolVar(&runNow, "run-now", false, "Also run the suites once at startup.")
+	cmd.Flags().BoolVar(&shuffle, "shuffle", false, "Run the suite entries in a random order each time, so no component always runs on a warmer device.")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "Shuffle with this seed to reproduce a recorded order (implies --shuffle).")
+	_ = cmd.MarkFlagRequired("schedule")
+	return cmd
+}
+
+// resolveRegressionThreshold returns the --regression-threshold fraction, taking the config's value
+// when the flag is not set.
+func resolveRegressionThreshold(cmd *cobra.Command, value string) (float64, error) {
+	if projectConfig.RegressionThreshold != "" && !cmd.Flags().Changed("regression-threshold") {
+		value = projectConfig.RegressionThreshold
+	}
+	threshold, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+	if err != nil || threshold <= 0 {
+		return 0, fmt.Errorf("invalid --regression-threshold %q (expected a percentage such as 10%%)", value)
+	}
+	return threshold / 100, nil
+}
+
+// resolveSuites checks that every named suite is configured, and names them all when none are given.
+func resolveSuites(names []string) ([]string, error) {
+	if len(names) == 0 {
+		for name := range projectConfig.Suites {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no suites configured; add a suites section to %s", configPath)
+	}
+	for _, name := range names {
+		if _, ok := projectConfig.Suites[name]; !ok {
+			return nil, fmt.Errorf("suite %q not found in %s", name, configPath)
+		}
+	}
+	return names, nil
+}
+
+func newSuiteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "suite",
+		Short: "Run the suites from designbench.yaml on demand.",
+	}
+	cmd.AddCommand(newSuiteRunCmd())
+	return cmd
+}
+
+func newSuiteRunCmd() *cobra.Command {
+	var tags []string
+	match := ""
+	thresholdFlag := "10%"
+	shuffle := false
+	var seed int64
+	reviewdogPath := ""
+	sourceMapPath := defaultSourceMap
+	maxDurationFlag := ""
+
+	cmd := &cobra.Command{
+		Use:   "run [suite...]",
+		Short: "Run the suites (default all) once, or only the entries selected by --tags and --match, and fail on any failed or regressed run.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			threshold, err := resolveRegressionThreshold(cmd, thresholdFlag)
+			if err != nil {
+				return err
+			}
+			if _, err := path.Match(match, ""); err != nil {
+				return fmt.Errorf("invalid --match %q: %w", match, err)
+			}
+			suiteNames, err := resolveSuites(args)
+			if err != nil {
+				return err
+			}
+			sources, err := loadSourceMap(reviewdogPath, sourceMapPath)
+			if err != nil {
+				return err
+			}
+			var budget *suiteBudget
+			if raw := strings.TrimSpace(maxDurationFlag); raw != "" {
+				maxDuration, err := time.ParseDuration(raw)
+				if err != nil || maxDuration <= 0 {
+					return fmt.Errorf("invalid --max-duration %q (expected a duration such as 30m)", maxDurationFlag)
+				}
+				now := time.Now()
+				budget = &suiteBudget{started: now, deadline: now.Add(maxDuration)}
+			}
+			srv, err := newRunServer(cmd, "suite", "")
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go srv.Run(ctx)
+			d := &daemon{
+				out:       cmd.OutOrStdout(),
+				runs:      srv,
+				suites:    suiteNames,
+				history:   filepath.Join(defaultReportsDir, history.FileName),
+				threshold: threshold,
+				color:     useColor(cmd.OutOrStdout()),
+				shuffle:   shuffle || seed != 0,
+				seed:      seed,
+				tags:      splitList(strings.Join(tags, ",")),
+				match:     match,
+				review:    reviewdogPath != "",
+				budget:    budget,
+			}
+			ran, problems := d.runSuites(ctx)
+			if budget != nil && ctx.Err() == nil {
+				fmt.Fprintf(cmd.OutOrStdout(), "Finished in %s of the %s --max-duration", time.Since(budget.started).Round(time.Second), maxDurationFlag)
+				if budget.cuts > 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), ", cutting the iterations of %d of %d runs", budget.cuts, ran)
+				}
+				fmt.Fprintln(cmd.OutOrStdout())
+			}
+			if reviewdogPath != "" && ctx.Err() == nil {
+				if err := writeReviewdog(cmd, reviewdogPath, d.findings, sources); err != nil {
+					return err
+				}
+			}
+			switch {
+			case ctx.Err() != nil:
+				return ctx.Err()
+			case ran == 0:
+				return errors.New("no suite entries match --tags and --match")
+			case problems > 0:
+				return fmt.Errorf("%d of %d runs failed or regressed", problems, ran)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&tags, "tags", nil, "Run only the entries with at least one of these tags, comma-separated (e.g. buttons,cards).")
+	cmd.Flags().StringVar(&match, "match", "", "Run only the entries whose component matches this glob, e.g. 'Button*'.")
+	cmd.Flags().StringVar(&thresholdFlag, "regression-threshold", thresholdFlag, "Flag a run whose primary metric exceeds its history median by more than this.")
+	cmd.Flags().BoolVar(&shuffle, "shuffle", false, "Run the selected entries in a random order.")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "Shuffle with this seed to reproduce a recorded order (implies --shuffle).")
+	cmd.Flags().StringVar(&reviewdogPath, "reviewdog", "", "Write failed and regressed runs to this file as reviewdog diagnostics (rdjsonl) placed in each component's source from --source-map.")
+	cmd.Flags().StringVar(&sourceMapPath, "source-map", sourceMapPath, "YAML file mapping component names to their source file (path or path:line), optionally per platform, for --reviewdog.")
+	cmd.Flags().StringVar(&maxDurationFlag, "max-duration", "", "Time budget for the whole run, e.g. 30m to stay within a CI job's timeout: once the remaining entries would not fit at the pace so far, their iterations are cut (and recorded in the history).")
+	return cmd
+}
+
+// daemon executes scheduled suites through the shared run queue.
+type daemon struct {
+	out       io.Writer
+	runs      *server.Server
+	suites    []string
+	history   string
+	threshold float64
+	webhook   string
+	format    string
+	color     bool
+	// email, when set, receives every cycle's outcomes and reports.
+	email *notify.EmailConfig
+	// shuffle randomises the entry order of each cycle; seed fixes it, 0 picks a new one per cycle.
+	shuffle bool
+	seed    int64
+	// tags and match select the entries to run: those with any of tags and a component matching
+	// the match glob. Empty values select every entry.
+	tags  []string
+	match string
+	// review collects a finding for every failed or regressed run in findings.
+	review   bool
+	findings []reviewdog.Finding
+	// budget, when set, cuts the iterations of the remaining entries to finish by --max-duration.
+	budget *suiteBudget
+}
+
+// suiteBudget spreads the time left before --max-duration over the remaining suite entries. Once
+// the pace of the finished entries says the rest will not fit, it cuts their iterations.
+type suiteBudget struct {
+	started  time.Time
+	deadline time.Time
+	// iterations counts the iterations requested from the finished entries, cuts counts the
+	// entries that ran fewer than planned.
+	iterations int
+	cuts       int
+}
+
+// iterationCut is the iteration count a suite entry was cut to from its planned count; zero when
+// it runs as configured.
+type iterationCut struct {
+	planned    int
+	iterations int
+}
+
+// cut returns the iterations the first of the remaining entries may run so that all of them finish
+// by the deadline, at the seconds per iteration the finished entries took. The first entry always
+// runs as planned, to measure the pace.
+func (b *suiteBudget) cut(remaining []suiteEntry) iterationCut {
+	planned, floor := plannedIterations(remaining[0].run.Args)
+	c := iterationCut{planned: planned}
+	if b.iterations == 0 {
+		return c
+	}
+	total := 0
+	for _, entry := range remaining {
+		n, _ := plannedIterations(entry.run.Args)
+		total += n
+	}
+	perIteration := time.Since(b.started).Seconds() / float64(b.iterations)
+	need, left := perIteration*float64(total), time.Until(b.deadline).Seconds()
+	if need <= left {
+		return c
+	}
+	if n := min(planned, max(floor, int(float64(planned)*max(left, 0)/need))); n < planned {
+		c.iterations = n
+	}
+	return c
+}
+
+// finished accounts for an entry that ran with cut.
+func (b *suiteBudget) finished(cut iterationCut) {
+	if cut.iterations > 0 {
+		b.iterations += cut.iterations
+		b.cuts++
+	} else {
+		b.iterations += cut.planned
+	}
+}
+
+// plannedIterations returns the iterations a suite entry runs as configured, by its own
+// --iterations or the configured count, and the fewest it can be cut to: one, or the minimum of an
+// adaptive --target-ci-width plan.
+func plannedIterations(args []string) (planned, floor int) {
+	planned, floor = iterationsFlag, 1
+	adaptive := strings.TrimSpace(targetCIWidthFlag) != ""
+	for i, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok && i+1 < len(args) {
+			value = args[i+1]
+		}
+		switch name {
+		case "--iterations":
+			if n, err := strconv.Atoi(value); err == nil {
+				planned = n
+			}
+		case "--target-ci-width":
+			adaptive = strings.TrimSpace(value) != ""
+		}
+	}
+	if adaptive {
+		if planned == 1 {
+			planned = defaultAdaptiveMaxIterations
+		}
+		floor = minAdaptiveIterations
+	}
+	return max(planned, floor), floor
+}
+
+// suiteEntry is one run of a named suite.
+type suiteEntry struct {
+	suite string
+	run   config.SuiteRun
+}
+
+// runSuites runs every selected suite entry, in order or shuffled, appends each result to the
+// history store and posts one message listing regressions and failures. With email configured, it
+// also mails every outcome with the HTML reports attached. It returns how many entries ran and how
+// many of them failed or regressed.
+func (d *daemon) runSuites(ctx context.Context) (ran, problemCount int) {
+	var entries []suiteEntry
+	for _, suite := range d.suites {
+		for _, run := range projectConfig.Suites[suite] {
+			if d.selects(run) {
+				entries = append(entries, suiteEntry{suite: suite, run: run})
+			}
+		}
+	}
+	var seed int64
+	if d.shuffle {
+		seed = d.seed
+		if seed == 0 {
+			seed = rand.Int64N(math.MaxInt64) + 1
+		}
+		rng := rand.New(rand.NewPCG(uint64(seed), 0))
+		rng.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
+		fmt.Fprintf(d.out, "Shuffled %d runs with seed %d (reproduce with --seed %d)\n", len(entries), seed, seed)
+	}
+	var outcomes, problems []string
+	var attachments []notify.Attachment
+	for i, entry := range entries {
+		if ctx.Err() != nil {
+			return ran, len(problems)
+		}
+		ran++
+		component := displayOrPlaceholder(entry.run.Component, projectConfig.Component)
+		label := fmt.Sprintf("[%s] %s %s", entry.suite, entry.run.Platform, component)
+		var cut iterationCut
+		if d.budget != nil {
+			cut = d.budget.cut(entries[i:])
+		}
+		line, problem, result := d.runEntry(ctx, entry.suite, entry.run, seed, cut)
+		if d.budget != nil {
+			d.budget.finished(cut)
+		}
+		if cut.iterations > 0 {
+			line += fmt.Sprintf(" [iterations cut from %d to %d for --max-duration]", cut.planned, cut.iterations)
+		}
+		if d.color {
+			fmt.Fprintf(d.out, "%s: %s\n", label, report.Paint(line, problem))
+		} else {
+			fmt.Fprintf(d.out, "%s: %s\n", label, line)
+		}
+		outcomes = append(outcomes, fmt.Sprintf("%s: %s", label, line))
+		if problem {
+			problems = append(problems, fmt.Sprintf("%s: %s", label, line))
+		}
+		if d.email != nil && result != nil {
+			if page, err := report.HTML(*result, ""); err == nil {
+				attachments = append(attachments, notify.Attachment{
+					Name:        sanitizeToken(strings.Join([]string{entry.suite, entry.run.Platform, component}, "-"), "report") + ".html",
+					ContentType: "text/html; charset=utf-8",
+					Data:        page,
+				})
+			}
+		}
+	}
+	if d.email != nil && ctx.Err() == nil {
+		subject := fmt.Sprintf("designbench daemon: %d runs passed", ran)
+		if len(problems) > 0 {
+			subject = fmt.Sprintf("designbench daemon: %d of %d runs failed or regressed", len(problems), ran)
+		}
+		text := strings.Join(outcomes, "\n") + "\n"
+		if seed != 0 {
+			text += fmt.Sprintf("(shuffled with seed %d)\n", seed)
+		}
+		if err := notify.Email(ctx, *d.email, subject, text, attachments); err != nil {
+			fmt.Fprintf(d.out, "email: %v\n", err)
+		}
+	}
+	if len(problems) == 0 || d.webhook == "" {
+		return ran, len(problems)
+	}
+	text := "designbench daemon:\n" + strings.Join(problems, "\n")
+	if seed != 0 {
+		text += fmt.Sprintf("\n(shuffled with seed %d)", seed)
+	}
+	if err := notify.Send(ctx, d.format, d.webhook, text); err != nil {
+		fmt.Fprintf(d.out, "notify: %v\n", err)
+	}
+	return ran, len(problems)
+}
+
+// selects reports whether entry passes the tag and component filters.
+func (d *daemon) selects(entry config.SuiteRun) bool {
+	if len(d.tags) > 0 && !slices.ContainsFunc(entry.Tags, func(tag string) bool { return slices.Contains(d.tags, tag) }) {
+		return false
+	}
+	if d.match != "" {
+		matched, _ := path.Match(d.match, displayOrPlaceholder(entry.Component, projectConfig.Component))
+		return matched
+	}
+	return true
+}
+
+// runEntry returns a one-line outcome, whether it is worth a notification, and the run's report
+// when it produced one.
+func (d *daemon) runEntry(ctx context.Context, suite string, entry config.SuiteRun, seed int64, cut iterationCut) (string, bool, *report.Result) {
+	rec := history.Record{
+		Time:      time.Now(),
+		Key:       strings.Join(slices.DeleteFunc(append([]string{suite, entry.Platform, entry.Component}, entry.Args...), func(part string) bool { return part == "" }), " "),
+		Suite:     suite,
+		Platform:  entry.Platform,
+		Component: entry.Component,
+		Seed:      seed,
+	}
+	if cut.iterations > 0 {
+		// The key stays that of the entry as configured, so that the run is compared with its history.
+		rec.Iterations, rec.PlannedIterations = cut.iterations, cut.planned
+		entry.Args = append(slices.Clip(entry.Args), fmt.Sprintf("--iterations=%d", cut.iterations))
+	}
+	result, err := d.execute(ctx, entry)
+	if ctx.Err() != nil {
+		// Shutting down; an interrupted run says nothing about the app.
+		return "interrupted", false, nil
+	}
+	if err != nil {
+		rec.Error = err.Error()
+		d.addFinding(reviewdog.Finding{
+			Component: entry.Component,
+			Platform:  entry.Platform,
+			Message:   fmt.Sprintf("designbench: %s %s run in suite %s failed: %s", entry.Platform, displayOrPlaceholder(entry.Component, projectConfig.Component), suite, rec.Error),
+			Severity:  reviewdog.SeverityError,
+		})
+		if err := history.Append(d.history, rec); err != nil {
+			return fmt.Sprintf("failed: %s (history: %v)", rec.Error, err), true, nil
+		}
+		return "failed: " + rec.Error, true, nil
+	}
+	rec.RunID = result.RunID
+	metric, value, ok := report.PrimaryMetric(*result)
+	if !ok {
+		return "report has no metrics", true, result
+	}
+	rec.Metric, rec.Value = metric, value
+	if device := report.PrimaryDevice(*result); device != nil {
+		rec.Device = device.Model
+	}
+
+	records, err := history.Load(d.history)
+	if err != nil {
+		return err.Error(), true, result
+	}
+	baseline, n := history.Baseline(records, rec.Key, metric, historyWindow)
+	if err := history.Append(d.history, rec); err != nil {
+		return err.Error(), true, result
+	}
+	line := fmt.Sprintf("%s=%.1f", metric, value)
+	if n < minHistoryRuns || baseline <= 0 {
+		return line + fmt.Sprintf(" (collecting history, %d/%d runs)", n, minHistoryRuns), false, result
+	}
+	change := (value - baseline) / baseline
+	line += fmt.Sprintf(" (baseline %.1f over %d runs, %+.1f%%)", baseline, n, change*100)
+	threshold, ok := regressionThresholds.For(metric)
+	if !ok {
+		threshold = report.Threshold{Value: d.threshold * 100, Unit: "%"}
+	}
+	if threshold.Exceeded(baseline, value) {
+		d.addFinding(reviewdog.Finding{
+			Component: result.Component,
+			Platform:  entry.Platform,
+			Metric:    metric,
+			Message: fmt.Sprintf("designbench: %s %s regressed in suite %s: %s=%.1f is %.1f%% above its baseline of %.1f over %d runs (threshold %s)",
+				entry.Platform, result.Component, suite, metric, value, change*100, baseline, n, threshold),
+			Severity: reviewdog.SeverityWarning,
+		})
+		return line + " REGRESSION", true, result
+	}
+	return line, false, result
+}
+
+func (d *daemon) addFinding(f reviewdog.Finding) {
+	if !d.review {
+		return
+	}
+	if f.Component == "" {
+		f.Component = projectConfig.Component
+	}
+	d.findings = append(d.findings, f)
+}
+
+// loadSourceMap reads the --source-map file for --reviewdog, so that a missing map fails before
+// anything runs.
+func loadSourceMap(reviewdogPath, sourceMapPath string) (reviewdog.SourceMap, error) {
+	if reviewdogPath == "" {
+		return nil, nil
+	}
+	sources, err := reviewdog.LoadSourceMap(sourceMapPath)
+	if err != nil {
+		return nil, fmt.Errorf("--reviewdog needs a source map: %w", err)
+	}
+	return sources, nil
+}
+
+// writeReviewdog writes findings as rdjsonl to path, warning about components that the source map
+// does not place.
+func writeReviewdog(cmd *cobra.Command, path string, findings []reviewdog.Finding, sources reviewdog.SourceMap) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create reviewdog output: %w", err)
+	}
+	unmapped, err := reviewdog.Write(f, findings, sources)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("write reviewdog output: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write reviewdog output: %w", err)
+	}
+	slices.Sort(unmapped)
+	for _, component := range slices.Compact(unmapped) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: no source mapped for %s; its findings are left out of the reviewdog output\n", component)
+	}
+	return nil
+}
+
+// execute queues the entry and waits for its report.
+func (d *daemon) execute(ctx context.Context, entry config.SuiteRun) (*report.Result, error) {
+	queued, err := d.runs.SubmitTrusted(server.RunRequest{Platform: entry.Platform, Component: entry.Component, Args: entry.Args})
+	if err != nil {
+		return nil, err
+	}
+	if err := d.runs.Follow(ctx, queued.ID, func(events.Event) error { return nil }); err != nil {
+		return nil, err
+	}
+	run, _ := d.runs.Lookup(queued.ID)
+	if run.Status != server.StatusSucceeded {
+		return nil, errors.New(run.Error)
+	}
+	data, err := os.ReadFile(d.runs.RunFile(run.ID, "report.json"))
+	if err != nil {
+		return nil, fmt.Errorf("read report: %w", err)
+	}
+	var result report.Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse report: %w", err)
+	}
+	return &result, nil
+}
+
+func newCalibrateCmd() *cobra.Command {
+	rounds := 5
+	deviceID := ""
+
+	cmd := &cobra.Command{
+		Use:   "calibrate <android|ios>",
+		Short: "Score the connected device with a fixed CPU workload so compare --normalize can scale results between devices.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rounds < 1 {
+				return fmt.Errorf("invalid --rounds %d (must be at least 1)", rounds)
+			}
+			ctx, cancel, err := commandContext(cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			var meta *report.DeviceMetadata
+			var score float64
+			switch platform := args[0]; platform {
+			case "android":
+				adbPath := displayOrPlaceholder(projectConfig.Android.ADBPath, "adb")
+				meta, score, err = android.Calibrate(ctx, adbPath, displayOrPlaceholder(deviceID, projectConfig.Android.Device), rounds)
+			case "ios":
+				xcrunPath := displayOrPlaceholder(projectConfig.IOS.XCRunPath, "xcrun")
+				meta, score, err = ios.Calibrate(ctx, xcrunPath, displayOrPlaceholder(deviceID, projectConfig.IOS.Device), rounds)
+			default:
+				return fmt.Errorf("unsupported platform %q (expected android or ios)", platform)
+			}
+			if err != nil {
+				return err
+			}
+			if meta.Model == "" {
+				return errors.New("device reported no model; calibration is stored per model")
+			}
+
+			path := filepath.Join(defaultReportsDir, calibration.FileName)
+			store, err := calibration.Load(path)
+			if err != nil {
+				return err
+			}
+			store[calibration.Key(args[0], meta.Model)] = calibration.Entry{
+				Platform: args[0],
+				Model:    meta.Model,
+				ScoreMs:  score,
+				Rounds:   rounds,
+				Time:     time.Now(),
+			}
+			if err := store.Save(path); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Calibrated %s %s: %.1fms over %d rounds (saved to %s)\n", args[0], meta.Model, score, rounds, path)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&rounds, "rounds", rounds, "Number of times the workload runs; the median is stored.")
+	cmd.Flags().StringVar(&deviceID, "device", "", "adb serial or simulator UDID (defaults to the configured or only connected device).")
+	return cmd
+}
+
+func newComponentsCmd() *cobra.Command {
+	deviceID := ""
+
+	cmd := &cobra.Command{
+		Use:   "components <android|ios>",
+		Short: "List the components registered in the installed benchmark harness, the names --view accepts.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel, err := commandContext(cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			var names []string
+			var ok bool
+			switch platform := args[0]; platform {
+			case "android":
+				opts := androidOptions{adbPath: "adb", deviceID: deviceID}
+				if err := ensureAndroidDefaults(&opts); err != nil {
+					return err
+				}
+				names, ok, err = android.ListComponents(ctx, opts.adbPath, opts.deviceID, opts.packageName, opts.activity)
+			case "ios":
+				opts := iosOptions{xcrunPath: "xcrun", deviceID: deviceID}
+				if err := ensureIOSDefaults(&opts); err != nil {
+					return err
+				}
+				names, ok, err = ios.ListComponents(ctx, opts.xcrunPath, opts.deviceID, opts.bundleID)
+			default:
+				return fmt.Errorf("unsupported platform %q (expected android or ios)", platform)
+			}
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("the app did not list its components; regenerate the harness with designbench generate %s-harness", args[0])
+			}
+			for _, name := range names {
+				fmt.Fprintln(cmd.OutOrStdout(), name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&deviceID, "device", "", "adb serial or simulator UDID (defaults to the configured or only connected device).")
+	return cmd
+}
+
+// checkView confirms that --view names a component registered in the harness before the benchmark
+// launches it. A harness that predates the components handshake is benchmarked unchecked.
+func checkView(ctx context.Context, platform string, list func(context.Context) ([]string, bool, error)) error {
+	if viewFlag == "" {
+		return nil
+	}
+	events.Phase(ctx, "components")
+	names, ok, err := list(ctx)
+	if err != nil {
+		return fmt.Errorf("list harness components: %w", err)
+	}
+	if !ok {
+		events.Warn(ctx, fmt.Sprintf("the harness did not list its components, so --view %s is not checked; regenerate it with designbench generate %s-harness", viewFlag, platform))
+		return nil
+	}
+	if slices.Contains(names, viewFlag) {
+		return nil
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("--view %s: the harness has no registered components", viewFlag)
+	}
+	return fmt.Errorf("--view %s is not registered in the harness (registered: %s)", viewFlag, strings.Join(names, ", "))
+}
+
+func newCICmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ci",
+		Short: "Helpers for CI jobs.",
+	}
+	cmd.AddCommand(newCICollectCmd())
+	return cmd
+}
+
+func newCICollectCmd() *cobra.Command {
+	providerName := ""
+	dest := ""
+
+	cmd := &cobra.Command{
+		Use:   "collect [run-dir...]",
+		Short: "Copy run directories (default every run under ./designbench-reports) to the CI provider's artifacts directory and write metadata files for it.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider := ci.Detect()
+			if providerName != "" {
+				var err error
+				if provider, err = ci.Lookup(providerName); err != nil {
+					return err
+				}
+			}
+			if dest != "" {
+				provider.ArtifactsDir = dest
+			}
+			dirs := args
+			if len(dirs) == 0 {
+				var err error
+				if dirs, err = findRunDirs(defaultReportsDir); err != nil {
+					return err
+				}
+				if len(dirs) == 0 {
+					return fmt.Errorf("no runs to collect in %s; run a benchmark first or name run directories", defaultReportsDir)
+				}
+			}
+			if err := os.MkdirAll(provider.ArtifactsDir, 0o755); err != nil {
+				return fmt.Errorf("create artifacts dir: %w", err)
+			}
+			artifactsDir, err := filepath.Abs(provider.ArtifactsDir)
+			if err != nil {
+				return err
+			}
+
+			meta := ci.Metadata{Provider: provider, Collected: time.Now().UTC()}
+			var summary strings.Builder
+			for _, dir := range dirs {
+				info, err := os.Stat(dir)
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() {
+					return fmt.Errorf("%s is not a run directory", dir)
+				}
+				// Runs keep their place under designbench-reports, e.g. suite/<id>, so names stay unique.
+				rel, err := filepath.Rel(defaultReportsDir, dir)
+				if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+					rel = filepath.Base(filepath.Clean(dir))
+				}
+				if err := ci.CopyDir(dir, filepath.Join(artifactsDir, rel)); err != nil {
+					return fmt.Errorf("collect %s: %w", dir, err)
+				}
+				run := ci.Run{Path: filepath.ToSlash(rel)}
+				if result, err := loadReport(filepath.Join(dir, "report.json")); err == nil {
+					run.ID, run.Component, run.Platform = result.RunID, result.Component, resultPlatform(result)
+					run.Metric, run.Value, _ = report.PrimaryMetric(result)
+					summary.WriteString(report.FormatMarkdown(result, projectConfig.Budgets) + "\n")
+				}
+				meta.Runs = append(meta.Runs, run)
+			}
+
+			written := []string{filepath.Join(artifactsDir, ci.MetadataFile)}
+			if err := ci.WriteMetadata(artifactsDir, meta); err != nil {
+				return err
+			}
+			if provider.Name == ci.GitLab {
+				metrics := filepath.Join(artifactsDir, ci.MetricsFile)
+				if err := ci.WriteMetrics(metrics, meta.Runs); err != nil {
+					return err
+				}
+				written = append(written, metrics)
+			}
+			exported, err := provider.ExportEnv("DESIGNBENCH_ARTIFACTS_DIR", artifactsDir, filepath.Join(artifactsDir, ci.DotenvFile))
+			if err != nil {
+				return err
+			}
+			if exported != "" {
+				written = append(written, exported)
+			}
+			if summary.Len() > 0 {
+				summarized, err := provider.Summarize(summary.String())
+				if err != nil {
+					return err
+				}
+				if summarized != "" {
+					written = append(written, summarized)
+				}
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Collected %d run(s) into %s for %s\n", len(meta.Runs), artifactsDir, provider.Name)
+			for _, path := range written {
+				fmt.Fprintf(cmd.OutOrStdout(), "  wrote %s\n", path)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&providerName, "provider", "", fmt.Sprintf("CI provider to collect for instead of detecting it from the environment: %s.", strings.Join(ci.Providers, ", ")))
+	cmd.Flags().StringVar(&dest, "dest", "", "Collect into this directory instead of the provider's artifacts directory.")
+	return cmd
+}
+
+// findRunDirs returns the run directories under root: those holding a report file, including the
+// runs of suites and the daemon in their subdirectories.
+func findRunDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == root {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !entry.IsDir() || path == root {
+			return nil
+		}
+		reports, _ := filepath.Glob(filepath.Join(path, "report.*"))
+		if len(reports) > 0 {
+			dirs = append(dirs, path)
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find runs: %w", err)
+	}
+	return dirs, nil
+}
+
+func newSimulatorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "simulator",
+		Short: "Provision iOS simulators for benchmarking.",
+	}
+	cmd.AddCommand(newSimulatorCreateCmd())
+	return cmd
+}
+
+func newSimulatorCreateCmd() *cobra.Command {
+	deviceType := ""
+	runtime := ""
+	name := ""
+	jsonOut := false
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a dedicated DesignBench simulator of a device type and runtime, or reuse the one created earlier, and print its UDID.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(deviceType) == "" || strings.TrimSpace(runtime) == "" {
+				return errors.New("--device-type and --runtime are required, e.g. --device-type \"iPhone 15\" --runtime \"iOS 17.5\"")
+			}
+			versions := resolveIOSVersions(runtime)
+			if len(versions) != 1 {
+				return fmt.Errorf("invalid --runtime %q (expected one version, e.g. iOS 17.5)", runtime)
+			}
+			ctx, cancel, err := commandContext(cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+			onEvent, closeLog, err := eventHandler(cmd, "")
+			if err != nil {
+				return err
+			}
+			defer closeLog()
+			xcrunPath := displayOrPlaceholder(projectConfig.IOS.XCRunPath, "xcrun")
+			sim, err := ios.CreateSimulator(ctx, xcrunPath, strings.TrimSpace(deviceType), versions[0], strings.TrimSpace(name), onEvent)
+			if err != nil {
+				return err
+			}
+			if jsonOut {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(sim)
+			}
+			// The UDID alone goes to stdout, so that scripts can capture it for --device.
+			verb := "Reusing"
+			if sim.Created {
+				verb = "Created"
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s simulator %q\n", verb, sim.Name)
+			fmt.Fprintln(cmd.OutOrStdout(), sim.UDID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&deviceType, "device-type", "", "Device type by name or identifier, e.g. \"iPhone 15\" (xcrun simctl list devicetypes).")
+	cmd.Flags().StringVar(&runtime, "runtime", "", "Installed runtime to create the simulator on, e.g. \"iOS 17.5\" or 17.5.")
+	cmd.Flags().StringVar(&name, "name", "", "Simulator name (default \"<device type> (<runtime>, DesignBench)\"); a simulator of that name, type and runtime is reused.")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Print the simulator's UDID, name, device type, runtime and whether it was created as JSON.")
+	return cmd
+}
+
+func newAVDCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "avd",
+		Short: "Provision the Android emulator images declared under avds in designbench.yaml.",
+	}
+	cmd.AddCommand(newAVDCreateCmd(), newAVDListCmd())
+	return cmd
+}
+
+// resolveAVDs checks that every named AVD is declared, and names them all when none are given.
+func resolveAVDs(names []string) ([]string, error) {
+	if len(names) == 0 {
+		for name := range projectConfig.AVDs {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no AVDs declared; add an avds section to %s", configPath)
+	}
+	for _, name := range names {
+		if _, ok := projectConfig.AVDs[name]; !ok {
+			return nil, fmt.Errorf("AVD %q not found in %s", name, configPath)
+		}
+	}
+	return names, nil
+}
+
+func avdConfig(name string) android.AVDConfig {
+	avd := projectConfig.AVDs[name]
+	return android.AVDConfig{Name: name, APILevel: avd.APILevel, Image: avd.Image, Device: avd.Device, ABI: avd.ABI}
+}
+
+func newAVDCreateCmd() *cobra.Command {
+	force := false
+	installTimeout := "30m"
+	jsonOut := false
+
+	cmd := &cobra.Command{
+		Use:   "create [name...]",
+		Short: "Install the system images of the declared AVDs (default all) with sdkmanager and create the AVDs with avdmanager, keeping those that already exist.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := resolveAVDs(args)
+			if err != nil {
+				return err
+			}
+			timeout, err := time.ParseDuration(strings.TrimSpace(installTimeout))
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("invalid --install-timeout %q (expected e.g. 30m)", installTimeout)
+			}
+			onEvent, closeLog, err := eventHandler(cmd, "")
+			if err != nil {
+				return err
+			}
+			defer closeLog()
+			ctx, cancel := installContext(cmd, timeout)
+			defer cancel()
+			var avds []*android.AVD
+			for _, name := range names {
+				cfg := avdConfig(name)
+				// The tools' output goes to stderr so that stdout stays free for --json.
+				cfg.Output, cfg.OnEvent = cmd.ErrOrStderr(), onEvent
+				avd, err := android.CreateAVD(ctx, cfg, force)
+				if err != nil {
+					return err
+				}
+				avds = append(avds, avd)
+				if !jsonOut {
+					verb := "Kept"
+					if avd.Created {
+						verb = "Created"
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "%s AVD %s (%s)\n", verb, avd.Name, avd.Package)
+				}
+			}
+			if jsonOut {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(avds)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "Recreate AVDs that already exist, e.g. after changing their declaration.")
+	cmd.Flags().StringVar(&installTimeout, "install-timeout", installTimeout, "Timeout for downloading the system images and creating the AVDs, separate from --timeout.")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Print the AVDs' names, system image packages, hardware profiles and whether they were created as JSON.")
+	return cmd
+}
+
+func newAVDListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the declared AVDs, their system images and whether they exist on this machine.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := resolveAVDs(nil)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, name := range names {
+				cfg := avdConfig(name)
+				state := "missing"
+				if existing, ok := android.AVDSystemImage(name); ok {
+					state = "created"
+					if existing != "" && existing != cfg.SystemImagePackage() {
+						state = "outdated, created from " + existing
+					}
+				}
+				device := cfg.Device
+				if device == "" {
+					device = "default device"
+				}
+				fmt.Fprintf(out, "%s: %s on %s (%s)\n", name, cfg.SystemImagePackage(), device, state)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newReproCmd() *cobra.Command {
+	scriptPath := ""
+
+	cmd := &cobra.Command{
+		Use:   "repro <report.json>",
+		Short: "Print the device, build and command needed to reproduce a recorded run, as a shell script.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := loadReport(args[0])
+			if err != nil {
+				return err
+			}
+			script := report.ReproScript(result)
+			if scriptPath == "" {
+				fmt.Fprint(cmd.OutOrStdout(), script)
+				return nil
+			}
+			if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
+				return fmt.Errorf("write script: %w", err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %s\n", scriptPath)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&scriptPath, "script", "", "Write the script to this executable file instead of printing it.")
+	return cmd
+}
+
+func newSummarizeCmd() *cobra.Command {
+	historyPath := filepath.Join(defaultReportsDir, history.FileName)
+	top := 5
+	window := historyWindow
+	jsonOut := false
+
+	cmd := &cobra.Command{
+		Use:   "summarize",
+		Short: "Summarize the suite and daemon run history, ranking the benchmarks whose numbers are least stable.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if window < minHistoryRuns {
+				return fmt.Errorf("invalid --window %d (must be at least %d)", window, minHistoryRuns)
+			}
+			records, err := history.Load(historyPath)
+			if err != nil {
+				return err
+			}
+			scores := history.ScoreFlakiness(records, window, minHistoryRuns)
+			if top > 0 && len(scores) > top {
+				scores = scores[:top]
+			}
+			out := cmd.OutOrStdout()
+			if jsonOut {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(scores)
+			}
+			if len(records) == 0 {
+				fmt.Fprintf(out, "No runs recorded in %s yet; designbench suite run and designbench daemon record them.\n", historyPath)
+				return nil
+			}
+			keys := map[string]bool{}
+			failed := 0
+			for _, rec := range records {
+				keys[rec.Key] = true
+				if rec.Error != "" {
+					failed++
+				}
+			}
+			fmt.Fprintf(out, "%s: %d runs (%d failed) of %d benchmarks\n", historyPath, len(records), failed, len(keys))
+			if len(scores) == 0 {
+				fmt.Fprintf(out, "No benchmark has %d successful runs to score yet.\n", minHistoryRuns)
+				return nil
+			}
+			fmt.Fprintf(out, "\nLeast stable over the last %d successful runs (score = variation + %% of runs failed):\n", window)
+			fmt.Fprintf(out, "  %6s  %9s  %6s  %s\n", "score", "variation", "failed", "benchmark")
+			for _, f := range scores {
+				name := f.Key
+				if f.Device != "" {
+					name += " [" + f.Device + "]"
+				}
+				fmt.Fprintf(out, "  %6.1f  %8.1f%%  %6s  %s (%s median %.1f)\n", f.Score, f.VariationPct, fmt.Sprintf("%d/%d", f.Failures, f.Runs), name, f.Metric, f.Median)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&historyPath, "history", historyPath, "History store to summarize.")
+	cmd.Flags().IntVar(&top, "top", top, "Show this many of the least stable benchmarks; 0 shows all.")
+	cmd.Flags().IntVar(&window, "window", window, "Score each benchmark on its last this many successful runs.")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Print the scores as JSON instead.")
+	return cmd
+}
+
+func newCompareCmd() *cobra.Command {
+	normalize := false
+	htmlPath := ""
+	reviewdogPath := ""
+	sourceMapPath := defaultSourceMap
+
+	cmd := &cobra.Command{
+		Use:   "compare <baseline.json> <candidate.json>",
+		Short: "Show how each headline metric changed between two reports.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if path := strings.TrimSpace(outputPath); path != "" && path != "-" && !strings.EqualFold(filepath.Ext(path), ".md") {
+				return fmt.Errorf("compare -o writes Markdown; use a .md path or - for stdout, and --html for a page, not %q", path)
+			}
+			baseline, err := loadReport(args[0])
+			if err != nil {
+				return err
+			}
+			candidate, err := loadReport(args[1])
+			if err != nil {
+				return err
+			}
+			sources, err := loadSourceMap(reviewdogPath, sourceMapPath)
+			if err != nil {
+				return err
+			}
+			comparisons := report.Compare(baseline, candidate)
+			if len(comparisons) == 0 {
+				return errors.New("the reports have no measurement in common")
+			}
+			if normalize {
+				store, err := calibration.Load(filepath.Join(defaultReportsDir, calibration.FileName))
+				if err != nil {
+					return err
+				}
+				for i := range comparisons {
+					c := &comparisons[i]
+					if !c.DevicesDiffer() {
+						continue
+					}
+					if c.BaselineDevice.IsVirtual() || c.CandidateDevice.IsVirtual() {
+						return fmt.Errorf("%s: cannot normalize emulator or simulator results; their speed depends on the host", c.Name)
+					}
+					factor, err := store.Factor(c.Platform(), c.BaselineDevice.Model, c.CandidateDevice.Model)
+					if err != nil {
+						return fmt.Errorf("%s: %w", c.Name, err)
+					}
+					c.Normalize(factor)
+				}
+			}
+			out := cmd.OutOrStdout()
+			markdownPath := strings.TrimSpace(outputPath)
+			if markdownPath == "-" {
+				// The Markdown takes stdout, so everything else moves to stderr.
+				fmt.Fprint(out, report.FormatComparisonMarkdown(baseline, candidate, comparisons, regressionThresholds))
+				out = cmd.ErrOrStderr()
+			}
+			fmt.Fprint(out, report.FormatComparison(comparisons))
+			if markdownPath != "" && markdownPath != "-" {
+				if err := report.SaveComparisonMarkdown(markdownPath, baseline, candidate, comparisons, regressionThresholds); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "Comparison saved to %s\n", markdownPath)
+			}
+			if htmlPath != "" {
+				if err := report.SaveComparisonHTML(htmlPath, baseline, candidate, comparisons); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "Comparison saved to %s\n", htmlPath)
+			}
+			if reviewdogPath != "" {
+				if err := writeReviewdog(cmd, reviewdogPath, comparisonFindings(candidate.Component, comparisons), sources); err != nil {
+					return err
+				}
+			}
+			if regressions := regressionThresholds.Regressions(comparisons); len(regressions) > 0 {
+				for _, regression := range regressions {
+					fmt.Fprintf(out, "REGRESSION: %s\n", regression)
+				}
+				total := 0
+				for _, c := range comparisons {
+					total += len(c.Metrics)
+				}
+				return fmt.Errorf("%d of %d metrics exceed their thresholds", len(regressions), total)
+			}
+			return nil
+		},
 	}
-	ctx, cancel := context.WithTimeout(parent, dur)
-	return ctx, cancel, nil
+	cmd.Flags().BoolVar(&normalize, "normalize", false, "When the devices differ, scale the candidate's durations by the devices' calibration scores (see calibrate); results are estimates.")
+	cmd.Flags().StringVar(&htmlPath, "html", "", "Also write a standalone HTML page to this path with box plots of each metric's baseline and candidate iterations and the significance of each change, e.g. for a pull request.")
+	cmd.Flags().StringVar(&reviewdogPath, "reviewdog", "", "Write significant regressions to this file as reviewdog diagnostics (rdjsonl) placed in the component's source from --source-map.")
+	cmd.Flags().StringVar(&sourceMapPath, "source-map", sourceMapPath, "YAML file mapping component names to their source file (path or path:line), optionally per platform, for --reviewdog.")
+	return cmd
 }
 
-func resolveOutputFile(component string, platform string) (string, error) {
-	path := strings.TrimSpace(outputPath)
-	if path == "" {
-		if err := os.MkdirAll(defaultReportsDir, 0o755); err != nil {
-			return "", fmt.Errorf("create reports dir: %w", err)
-		}
-		filename := defaultReportFileName(component, platform)
-		return filepath.Join(defaultReportsDir, filename), nil
-	}
-
-	if !filepath.IsAbs(path) {
-		if err := os.MkdirAll(defaultReportsDir, 0o755); err != nil {
-			return "", fmt.Errorf("create reports dir: %w", err)
-		}
-		path = filepath.Join(defaultReportsDir, path)
-	} else {
-		dir := filepath.Dir(path)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
-			return "", fmt.Errorf("create output dir: %w", err)
+// comparisonFindings returns a finding for every metric that exceeds its family's threshold and,
+// for metrics without one, every metric that got significantly worse. Those that cannot be tested,
+// with a single run on either side, are left out.
+func comparisonFindings(component string, comparisons []report.Comparison) []reviewdog.Finding {
+	var findings []reviewdog.Finding
+	for _, c := range comparisons {
+		for _, m := range c.Metrics {
+			if threshold, ok := regressionThresholds.For(m.Metric); ok {
+				if threshold.Exceeded(m.Baseline, m.Candidate) {
+					findings = append(findings, reviewdog.Finding{
+						Component: component,
+						Platform:  c.Platform(),
+						Metric:    m.Metric,
+						Message: fmt.Sprintf("designbench: %s %s %s rose %.1f%% from %.1f %s to %.1f %s (threshold %s)",
+							component, c.Name, m.Metric, m.ChangePct(), m.Baseline, m.Unit, m.Candidate, m.Unit, threshold),
+						Severity: reviewdog.SeverityWarning,
+					})
+				}
+				continue
+			}
+			p, ok := m.Significance()
+			if !ok || p >= report.SignificanceLevel || m.Candidate <= m.Baseline {
+				continue
+			}
+			findings = append(findings, reviewdog.Finding{
+				Component: component,
+				Platform:  c.Platform(),
+				Metric:    m.Metric,
+				Message: fmt.Sprintf("designbench: %s %s %s rose %.1f%% from %.1f %s to %.1f %s (Mann-Whitney p=%.3f)",
+					component, c.Name, m.Metric, m.ChangePct(), m.Baseline, m.Unit, m.Candidate, m.Unit, p),
+				Severity: reviewdog.SeverityWarning,
+			})
 		}
 	}
-	return path, nil
+	return findings
 }
 
-func currentCLICommand(cmd *cobra.Command) string {
-	if len(os.Args) == 0 {
-		return ""
-	}
-	rootName := "designbench"
-	if cmd != nil && cmd.Root() != nil && cmd.Root().Use != "" {
-		rootName = cmd.Root().Use
+func loadReport(path string) (report.Result, error) {
+	var result report.Result
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return result, fmt.Errorf("read report: %w", err)
 	}
-	var b strings.Builder
-	b.WriteString(rootName)
-	for _, arg := range os.Args[1:] {
-		b.WriteByte(' ')
-		b.WriteString(arg)
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("parse %s: %w", path, err)
 	}
-	return b.String()
+	return result, nil
 }
 
-func defaultReportFileName(component string, platform string) string {
-	componentToken := sanitizeToken(component, "component")
-	platformToken := sanitizeToken(platform, "run")
-	if platformToken != "" {
-		return fmt.Sprintf("%s-%s.json", componentToken, platformToken)
+// newRunServer returns the run queue shared by `serve` and `agent`, keeping each run's files under
+// designbench-reports/<dir>. Runs start this binary again and get an explicit --config and --profile
+// forwarded, so every run uses the same project defaults.
+// isLoopbackAddr reports whether addr only listens on this machine, e.g. 127.0.0.1:8080 or
+// localhost:8080. An empty host listens on every interface.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
 	}
-	return componentToken + ".json"
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
-func sanitizeToken(value string, fallback string) string {
-	v := strings.TrimSpace(strings.ToLower(value))
-	if v == "" {
-		return fallback
+func newRunServer(cmd *cobra.Command, dir, token string) (*server.Server, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locate designbench binary: %w", err)
 	}
-
-	var b strings.Builder
-	lastDash := false
-	for i := 0; i < len(v); i++ {
-		ch := v[i]
-		if (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') {
-			b.WriteByte(ch)
-			lastDash = false
-			continue
-		}
-		if !lastDash {
-			b.WriteByte('-')
-			lastDash = true
+	var baseArgs []string
+	if cmd.Flags().Changed("config") {
+		path, err := filepath.Abs(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("resolve config path: %w", err)
 		}
+		baseArgs = []string{"--config", path}
+	}
+	if cmd.Flags().Changed("profile") {
+		baseArgs = append(baseArgs, "--profile", profileFlag)
 	}
+	return server.New(server.Config{
+		Executable: exe,
+		Dir:        filepath.Join(defaultReportsDir, dir),
+		BaseArgs:   baseArgs,
+		Token:      token,
+	}), nil
+}
 
-	token := strings.Trim(b.String(), "-")
-	if token == "" {
-		return fallback
+func newGenerateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate benchmark harness code for the app under test.",
 	}
-	return token
+	cmd.AddCommand(newGenerateAndroidHarnessCmd(), newGenerateIOSHarnessCmd())
+	return cmd
 }
 
-func newPreflightCmd() *cobra.Command {
-	rootDir := "."
-	adbPath := "adb"
-	xcrunPath := "xcrun"
+func newGenerateAndroidHarnessCmd() *cobra.Command {
+	var packageName, className, dir string
 
 	cmd := &cobra.Command{
-		Use:   "preflight",
-		Short: "Run a readiness checklist for Android and iOS benchmarking.",
+		Use:   "android-harness",
+		Short: "Emit a Compose activity that renders the component named by the designbench_component extra.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if rootDir == "" {
-				rootDir = "."
+			if packageName == "" {
+				packageName = projectConfig.Android.Package
 			}
-			absRoot, err := filepath.Abs(rootDir)
-			if err != nil {
-				return fmt.Errorf("resolve project root: %w", err)
+			if packageName == "" {
+				if root, err := os.Getwd(); err == nil {
+					if proj, err := preflight.DetectAndroidProject(root); proj != nil && err == nil {
+						packageName = proj.Package
+					}
+				}
 			}
-
-			ctx, cancel, err := commandContext(cmd)
+			if packageName == "" {
+				return errors.New("unable to determine the Kotlin package (set --package)")
+			}
+			source, err := harness.AndroidHarness(harness.AndroidOptions{Package: packageName, Activity: className})
 			if err != nil {
 				return err
 			}
-			defer cancel()
-
 			out := cmd.OutOrStdout()
+			if dir == "" {
+				_, err := out.Write(source)
+				return err
+			}
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("create harness directory: %w", err)
+			}
+			path := filepath.Join(dir, className+".kt")
+			if err := os.WriteFile(path, source, 0o644); err != nil {
+				return fmt.Errorf("write harness: %w", err)
+			}
+			fmt.Fprintf(out, "Wrote %s\n\nNext steps:\n", path)
+			fmt.Fprintf(out, "  1. Register the activity in AndroidManifest.xml: <activity android:name=\"%s.%s\" android:exported=\"true\" />\n", packageName, className)
+			fmt.Fprintf(out, "  2. Add your composables to DesignBenchRegistry.\n")
+			fmt.Fprintf(out, "  3. Point designbench at it: set android.activity to %s.%s in %s.\n", packageName, className, config.FileName)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&packageName, "package", "", "Kotlin package for the generated activity (defaults to the detected app package).")
+	cmd.Flags().StringVar(&className, "class", harness.DefaultAndroidActivity, "Class name of the generated activity.")
+	cmd.Flags().StringVar(&dir, "dir", "", "Write <class>.kt into this source directory instead of printing it.")
+	return cmd
+}
 
-			androidProj, androidProjErr := preflight.DetectAndroidProject(absRoot)
-			androidDevice, androidDeviceErr := preflight.DetectAndroidDevice(ctx, adbPath)
-			iosProj, iosProjErr := preflight.DetectIOSProject(absRoot)
-			iosDevice, iosDeviceErr := preflight.DetectIOSDevice(ctx, xcrunPath)
+func newGenerateIOSHarnessCmd() *cobra.Command {
+	var dir string
 
-			items := []checklistItem{
-				checkBinaryItem("adb available", adbPath),
-				checkBinaryItem("xcodebuild available", "xcodebuild"),
-				checkBinaryItem("xcrun available", xcrunPath),
-				checkAndroidProjectItem(androidProj, androidProjErr),
-				checkAndroidDeviceItem(androidDevice, androidDeviceErr),
-				checkIOSProjectItem(iosProj, iosProjErr),
-				checkIOSDeviceItem(iosDevice, iosDeviceErr),
+	cmd := &cobra.Command{
+		Use:   "ios-harness",
+		Short: "Emit a SwiftUI view that renders the component named by DESIGNBENCH_COMPONENT.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, err := harness.IOSHarness()
+			if err != nil {
+				return err
 			}
-
-			fmt.Fprintf(out, "Preflight checklist (root: %s)\n\n", absRoot)
-			printChecklist(out, items)
+			out := cmd.OutOrStdout()
+			if dir == "" {
+				_, err := out.Write(source)
+				return err
+			}
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("create harness directory: %w", err)
+			}
+			path := filepath.Join(dir, harness.IOSHarnessFile)
+			if err := os.WriteFile(path, source, 0o644); err != nil {
+				return fmt.Errorf("write harness: %w", err)
+			}
+			fmt.Fprintf(out, "Wrote %s\n\nNext steps:\n", path)
+			fmt.Fprintf(out, "  1. Add the file to your app target in Xcode.\n")
+			fmt.Fprintf(out, "  2. Add your views to DesignBenchRegistry.\n")
+			fmt.Fprintf(out, "  3. Show DesignBenchRootView() from your App body when DesignBenchHarness.isActive.\n")
 			return nil
 		},
 	}
-
+	cmd.Flags().StringVar(&dir, "dir", "", "Write "+harness.IOSHarnessFile+" into this source directory instead of printing it.")
 	return cmd
 }
 
+// gradleTaskPrefix turns a module directory such as androidApp into the ":androidApp:" task prefix.
+func gradleTaskPrefix(moduleDir string) string {
+	if moduleDir == "" {
+		return ""
+	}
+	return ":" + strings.ReplaceAll(filepath.ToSlash(moduleDir), "/", ":") + ":"
+}
+
+func displayOrPlaceholder(value, placeholder string) string {
+	if strings.TrimSpace(value) == "" {
+		return placeholder
+	}
+	return value
+}
+
+// loadProjectConfig reads --config (designbench.yaml by default) and applies it to every global
+// flag the user did not set explicitly. A missing default config file is not an error.
+// applyEnvFlags sets every flag that was not given on the command line from its DESIGNBENCH_<FLAG>
+// environment variable. The flags are not marked as changed, so config file values still override
+// them.
+func applyEnvFlags(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "help" {
+			return
+		}
+		name := envFlagName(flag.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if setErr := flag.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("invalid %s %q: %w", name, value, setErr)
+		}
+	})
+	return err
+}
+
+// envFlagName returns the environment variable read for a flag, e.g. DESIGNBENCH_TARGET_CI_WIDTH
+// for --target-ci-width.
+func envFlagName(flag string) string {
+	return config.EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+func loadProjectConfig(cmd *cobra.Command) error {
+	projectConfig = config.FromEnv()
+	cfg, err := config.LoadOver(configPath, projectConfig)
+	if err != nil {
+		_, configFromEnv := os.LookupEnv(envFlagName("config"))
+		if errors.Is(err, fs.ErrNotExist) && !cmd.Flags().Changed("config") && !configFromEnv && profileFlag == "" {
+			return nil
+		}
+		return fmt.Errorf("load config: %w", err)
+	}
+	if profileFlag != "" {
+		if err := cfg.ApplyProfile(profileFlag); err != nil {
+			return fmt.Errorf("%s: %w", configPath, err)
+		}
+	}
+	for metric := range cfg.Budgets {
+		if !slices.Contains(report.BudgetMetrics, metric) {
+			return fmt.Errorf("%s: unknown budget metric %q (expected one of %s)", configPath, metric, strings.Join(report.BudgetMetrics, ", "))
+		}
+	}
+	thresholds, err := report.ParseThresholds(cfg.Thresholds)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configPath, err)
+	}
+	regressionThresholds = thresholds
+	derived, err := report.ParseDerivedMetrics(cfg.DerivedMetrics)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configPath, err)
+	}
+	derivedMetrics = derived
+	projectConfig = *cfg
+	flags := cmd.Flags()
+	if cfg.Component != "" && !flags.Changed("component") {
+		componentFlag = cfg.Component
+	}
+	if cfg.Iterations > 0 && !flags.Changed("iterations") {
+		iterationsFlag = cfg.Iterations
+	}
+	if cfg.Timeout != "" && !flags.Changed("timeout") {
+		timeoutFlag = cfg.Timeout
+	}
+	if cfg.LaunchTimeout != "" && !flags.Changed("launch-timeout") {
+		launchTimeoutFlag = cfg.LaunchTimeout
+	}
+	if cfg.TargetCIWidth != "" && !flags.Changed("target-ci-width") {
+		targetCIWidthFlag = cfg.TargetCIWidth
+	}
+	if cfg.Percentiles != "" && !flags.Changed("percentiles") {
+		percentilesFlag = cfg.Percentiles
+	}
+	return nil
+}
+
 type checklistStatus int
 
 const (
@@ -472,23 +3829,287 @@ func checkAndroidDeviceItem(device *preflight.AndroidDevice, err error) checklis
 	return newChecklistItem("Android device detected", statusPass, desc)
 }
 
-func checkIOSProjectItem(proj *preflight.IOSProject, err error) checklistItem {
+// checkAndroidBuildItem warns unless the APK is a non-debuggable, R8-minified build signed with a
+// release key, since anything else benchmarks code the user never ships.
+func checkAndroidBuildItem(build *preflight.AndroidBuild, err error) checklistItem {
+	const name = "Android release build"
+	if err != nil {
+		return newChecklistItem(name, statusWarn, err.Error())
+	}
+	source := "target APK"
+	if build.Installed {
+		source = "installed APK"
+	}
+	notes := []string{source + ": " + build.APKPath}
+	status := statusPass
+	if build.Debuggable {
+		status = statusWarn
+		notes = append(notes, "APK is debuggable; benchmark the release variant")
+	}
+	if build.DebugSigned {
+		status = statusWarn
+		notes = append(notes, "signed with the Android debug key")
+	} else if build.Signer != "" {
+		notes = append(notes, "Signer: "+build.Signer)
+	}
+	if !build.Minified {
+		status = statusWarn
+		notes = append(notes, "not minified by R8 in release mode (enable isMinifyEnabled)")
+	}
+	for _, warning := range build.Warnings {
+		status = statusWarn
+		notes = append(notes, warning)
+	}
+	return newChecklistItem(name, status, notes...)
+}
+
+func checkGradleItem(setup *preflight.GradleSetup) checklistItem {
+	const name = "Gradle and JDK"
+	notes := []string{
+		fmt.Sprintf("Gradle wrapper: %s", displayOrPlaceholder(setup.GradleVersion, "version unknown")),
+		fmt.Sprintf("JDK: %s (JAVA_HOME=%s)", displayOrPlaceholder(setup.JDKVersion, "unknown"), displayOrPlaceholder(setup.JavaHome, "unset")),
+		fmt.Sprintf("Android Gradle Plugin: %s, needs JDK %d+", displayOrPlaceholder(setup.AGPVersion, "version unknown"), setup.RequiredJDK),
+	}
+	switch {
+	case len(setup.Failures) > 0:
+		return newChecklistItem(name, statusFail, append(append(notes, setup.Failures...), setup.Warnings...)...)
+	case len(setup.Warnings) > 0:
+		return newChecklistItem(name, statusWarn, append(notes, setup.Warnings...)...)
+	}
+	return newChecklistItem(name, statusPass, notes...)
+}
+
+// checkIOSRuntimeItem looks for an installed simulator runtime that can run the project's deployment
+// target and at least one simulator created for it.
+func checkIOSRuntimeItem(ctx context.Context, xcrunPath, root string) checklistItem {
+	const name = "iOS simulator runtime"
+	runtimes, err := preflight.ListIOSRuntimes(ctx, xcrunPath)
+	if err != nil {
+		return newChecklistItem(name, statusFail, err.Error())
+	}
+	target, targetErr := preflight.DetectIOSDeploymentTarget(root)
+	notes := make([]string, 0, 3)
+	if targetErr == nil {
+		notes = append(notes, fmt.Sprintf("deployment target: iOS %s", target))
+	} else {
+		notes = append(notes, fmt.Sprintf("deployment target unknown (%v); accepting any runtime", targetErr))
+	}
+	var compatible []preflight.IOSRuntime
+	for _, rt := range runtimes {
+		if rt.Available && (targetErr != nil || preflight.RuntimeSupports(rt.Version, target)) {
+			compatible = append(compatible, rt)
+		}
+	}
+	if len(compatible) == 0 {
+		return newChecklistItem(name, statusFail, append(notes,
+			"no compatible iOS simulator runtime installed",
+			"install one with `xcodebuild -downloadPlatform iOS` or Xcode > Settings > Components")...)
+	}
+	for _, rt := range compatible {
+		if rt.Devices > 0 {
+			return newChecklistItem(name, statusPass, append(notes, fmt.Sprintf("%s with %d simulator(s)", rt.Name, rt.Devices))...)
+		}
+	}
+	rt := compatible[len(compatible)-1]
+	return newChecklistItem(name, statusWarn, append(notes,
+		fmt.Sprintf("%s is installed but has no simulators", rt.Name),
+		fmt.Sprintf("create one with `xcrun simctl create <name> <device type from simctl list devicetypes> %s`", rt.Identifier))...)
+}
+
+// checkHostResourcesItem checks free disk space for reports, traces and builds (plus simulator
+// disks, which live on the host) and memory available to emulators and simulators.
+func checkHostResourcesItem(ctx context.Context, root string) checklistItem {
+	const name = "Host resources"
+	status := statusPass
+	notes := make([]string, 0, 3)
+	disks := []struct{ label, path string }{{"disk free", root}}
+	if dir := preflight.SimulatorDataDir(); dir != "" {
+		disks = append(disks, struct{ label, path string }{"simulator disk free", dir})
+	}
+	for _, disk := range disks {
+		free, err := preflight.HostDiskFree(disk.path)
+		switch {
+		case err != nil:
+			status = statusWarn
+			notes = append(notes, fmt.Sprintf("%s unknown: %v", disk.label, err))
+		case free < preflight.MinHostDiskBytes:
+			status = statusWarn
+			notes = append(notes, fmt.Sprintf("%s: %s (below %s; installs and traces may fail)", disk.label, formatGB(free), formatGB(preflight.MinHostDiskBytes)))
+		default:
+			notes = append(notes, fmt.Sprintf("%s: %s", disk.label, formatGB(free)))
+		}
+	}
+	memory, err := preflight.HostMemoryAvailable(ctx)
+	switch {
+	case err != nil:
+		status = statusWarn
+		notes = append(notes, fmt.Sprintf("available memory unknown: %v", err))
+	case memory < preflight.MinHostMemoryBytes:
+		status = statusWarn
+		notes = append(notes, fmt.Sprintf("available memory: %s (below %s; emulators and simulators will be throttled)", formatGB(memory), formatGB(preflight.MinHostMemoryBytes)))
+	default:
+		notes = append(notes, fmt.Sprintf("available memory: %s", formatGB(memory)))
+	}
+	return newChecklistItem(name, status, notes...)
+}
+
+// checkAndroidResourcesItem checks the device's /data partition (APK installs, traces) and memory,
+// since a device under memory pressure kills and cold-starts the app under test.
+func checkAndroidResourcesItem(ctx context.Context, adbPath, deviceID string) checklistItem {
+	const name = "Android device resources"
+	status := statusPass
+	notes := make([]string, 0, 2)
+	storage, err := preflight.AndroidStorageFree(ctx, adbPath, deviceID)
+	switch {
+	case err != nil:
+		status = statusWarn
+		notes = append(notes, fmt.Sprintf("storage unknown: %v", err))
+	case storage < preflight.MinDeviceStorageBytes:
+		status = statusWarn
+		notes = append(notes, fmt.Sprintf("storage free: %s (below %s; installs may fail)", formatGB(storage), formatGB(preflight.MinDeviceStorageBytes)))
+	default:
+		notes = append(notes, fmt.Sprintf("storage free: %s", formatGB(storage)))
+	}
+	memory, err := preflight.AndroidMemoryAvailable(ctx, adbPath, deviceID)
+	switch {
+	case err != nil:
+		status = statusWarn
+		notes = append(notes, fmt.Sprintf("available memory unknown: %v", err))
+	case memory < preflight.MinDeviceMemoryBytes:
+		status = statusWarn
+		notes = append(notes, fmt.Sprintf("available memory: %s (below %s; the app may be killed between runs)", formatGB(memory), formatGB(preflight.MinDeviceMemoryBytes)))
+	default:
+		notes = append(notes, fmt.Sprintf("available memory: %s", formatGB(memory)))
+	}
+	return newChecklistItem(name, status, notes...)
+}
+
+func formatGB(bytes uint64) string {
+	return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+}
+
+func checkAndroidToolchainItem(toolchain *preflight.AndroidToolchain, err error) checklistItem {
+	const name = "Android toolchain"
+	if err != nil {
+		return newChecklistItem(name, statusFail, err.Error())
+	}
+	notes := []string{
+		fmt.Sprintf("adb: %s", displayOrPlaceholder(toolchain.ADBVersion, "unknown")),
+		fmt.Sprintf("platform-tools: %s", displayOrPlaceholder(toolchain.PlatformToolsVersion, "unknown")),
+	}
+	if toolchain.DeviceSDK > 0 {
+		notes = append(notes, fmt.Sprintf("device API level: %d", toolchain.DeviceSDK))
+	}
+	if len(toolchain.Warnings) > 0 {
+		return newChecklistItem(name, statusWarn, append(notes, toolchain.Warnings...)...)
+	}
+	return newChecklistItem(name, statusPass, notes...)
+}
+
+func checkXcodeToolchainItem(toolchain *preflight.XcodeToolchain, err error) checklistItem {
+	const name = "Xcode toolchain"
+	if err != nil {
+		return newChecklistItem(name, statusFail, err.Error())
+	}
+	notes := []string{
+		fmt.Sprintf("Xcode: %s (%s)", displayOrPlaceholder(toolchain.Version, "unknown"), displayOrPlaceholder(toolchain.Build, "unknown build")),
+		fmt.Sprintf("developer directory: %s", displayOrPlaceholder(toolchain.DeveloperDir, "unknown")),
+	}
+	if len(toolchain.Warnings) > 0 {
+		return newChecklistItem(name, statusWarn, append(notes, toolchain.Warnings...)...)
+	}
+	return newChecklistItem(name, statusPass, notes...)
+}
+
+// checkAndroidSettingsItem flags device settings that distort measurements: non-default animation
+// scales change frame and animation timings, "Don't keep activities" turns every launch cold,
+// a sleeping screen stops rendering, and battery saver throttles the CPU.
+func checkAndroidSettingsItem(settings *preflight.AndroidSettings, err error) checklistItem {
+	const name = "Android developer options"
+	if err != nil {
+		return newChecklistItem(name, statusWarn, err.Error())
+	}
+	status := statusPass
+	notes := make([]string, 0, 4)
+	for _, key := range preflight.AnimationScaleKeys {
+		if scale := settings.AnimationScales[key]; scale != 1 {
+			status = statusWarn
+			notes = append(notes, fmt.Sprintf("%s is %gx; reset it to 1x in developer options", key, scale))
+		}
+	}
+	if settings.DontKeepActivities {
+		status = statusWarn
+		notes = append(notes, "\"Don't keep activities\" is on; every launch will be cold")
+	}
+	if !settings.StayAwake {
+		status = statusWarn
+		notes = append(notes, "stay awake is off; the screen may turn off mid-run (adb shell svc power stayon usb)")
+	}
+	if settings.BatterySaver {
+		status = statusWarn
+		notes = append(notes, "battery saver is on and throttles the CPU")
+	}
+	if status == statusPass {
+		notes = append(notes, "animation scales 1x, stay awake on, battery saver off")
+	}
+	return newChecklistItem(name, status, notes...)
+}
+
+func checkIOSProjectItem(ctx context.Context, proj *preflight.IOSProject, err error) checklistItem {
 	if err != nil {
 		return newChecklistItem("iOS project", statusFail, err.Error())
 	}
 	if proj == nil {
 		return newChecklistItem("iOS project", statusWarn, "Info.plist not found")
 	}
-	notes := make([]string, 0, 2)
+	notes := make([]string, 0, 4)
 	if proj.BundleID != "" {
 		notes = append(notes, fmt.Sprintf("Bundle ID: %s", proj.BundleID))
 	}
 	if proj.InfoPlistPath != "" {
 		notes = append(notes, fmt.Sprintf("Info.plist: %s", proj.InfoPlistPath))
 	}
+	if proj.Container == "" {
+		return newChecklistItem("iOS project", statusPass, notes...)
+	}
+	notes = append(notes, fmt.Sprintf("Xcode: %s", proj.Container))
+	schemes, err := preflight.ListXcodeSchemes(ctx, proj.Container)
+	if err != nil {
+		return newChecklistItem("iOS project", statusWarn, append(notes, fmt.Sprintf("schemes unavailable: %v", err))...)
+	}
+	notes = append(notes, fmt.Sprintf("Schemes: %s", strings.Join(schemes.Schemes, ", ")))
+	scheme := projectConfig.IOS.Scheme
+	if scheme == "" {
+		scheme = preflight.PickScheme(schemes.Schemes, proj.Container)
+	}
+	if scheme == "" {
+		return newChecklistItem("iOS project", statusWarn, append(notes, "no app scheme found; set ios.scheme or pass --scheme")...)
+	}
+	notes = append(notes, fmt.Sprintf("--install builds scheme: %s", scheme))
 	return newChecklistItem("iOS project", statusPass, notes...)
 }
 
+// checkKMPProjectItem reports both apps of a Kotlin Multiplatform project together, since a change
+// to the shared module affects the numbers on both platforms.
+func checkKMPProjectItem(proj *preflight.KMPProject, err error) checklistItem {
+	if err != nil {
+		return newChecklistItem("Kotlin Multiplatform project", statusFail, err.Error())
+	}
+	notes := []string{fmt.Sprintf("Shared modules: %s", strings.Join(proj.SharedModules, ", "))}
+	if proj.Android != nil {
+		notes = append(notes, fmt.Sprintf("Android app: %s (%s)", displayOrPlaceholder(proj.Android.ModuleDir, "."), displayOrPlaceholder(proj.Android.Package, "package unknown")))
+	}
+	if proj.IOS != nil {
+		notes = append(notes, fmt.Sprintf("iOS app: %s (%s)", displayOrPlaceholder(proj.IOS.Container, proj.IOS.InfoPlistPath), displayOrPlaceholder(proj.IOS.BundleID, "bundle ID unknown")))
+	}
+	notes = append(notes, proj.Warnings...)
+	if len(proj.Warnings) > 0 {
+		return newChecklistItem("Kotlin Multiplatform project", statusWarn, notes...)
+	}
+	notes = append(notes, "Benchmark both targets with the same component: designbench android && designbench ios")
+	return newChecklistItem("Kotlin Multiplatform project", statusPass, notes...)
+}
+
 func checkIOSDeviceItem(device *preflight.IOSDevice, err error) checklistItem {
 	if err != nil {
 		return newChecklistItem("iOS device detected", statusFail, err.Error())
@@ -505,3 +4126,44 @@ func checkIOSDeviceItem(device *preflight.IOSDevice, err error) checklistItem {
 	}
 	return newChecklistItem("iOS device detected", statusPass, desc)
 }
+
+// eventHandler combines the event consumers requested on the command line: human-readable lines
+// on stderr for --verbose and one JSON object per line in the --log-file. The returned func closes
+// the log file.
+func eventHandler(cmd *cobra.Command, runID string) (events.Handler, func(), error) {
+	var handlers []events.Handler
+	closeLog := func() {}
+	if verboseFlag {
+		w := cmd.ErrOrStderr()
+		handlers = append(handlers, func(event events.Event) {
+			fmt.Fprintln(w, event.String())
+		})
+	}
+	if path := strings.TrimSpace(logFileFlag); path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, nil, fmt.Errorf("create log file directory: %w", err)
+		}
+		// Append so that a fleet of runs can share one log; the run ID tells them apart.
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open log file: %w", err)
+		}
+		closeLog = func() { _ = f.Close() }
+		enc := json.NewEncoder(f)
+		handlers = append(handlers, func(event events.Event) {
+			event.RunID = runID
+			_ = enc.Encode(event)
+		})
+	}
+	switch len(handlers) {
+	case 0:
+		return nil, closeLog, nil
+	case 1:
+		return handlers[0], closeLog, nil
+	}
+	return func(event events.Event) {
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}, closeLog, nil
+}
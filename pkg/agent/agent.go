@@ -0,0 +1,199 @@
+// Package agent serves the gRPC remote execution protocol defined in agentpb/agent.proto, so that
+// a controller machine can run benchmarks on the Macs and Linux boxes hosting the devices.
+package agent
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tahatesser/designbench/pkg/agent/agentpb"
+	"github.com/tahatesser/designbench/pkg/events"
+	"github.com/tahatesser/designbench/pkg/server"
+)
+
+// authorizationKey is the metadata key carrying `Bearer <token>`.
+const authorizationKey = "authorization"
+
+type service struct {
+	agentpb.UnimplementedAgentServer
+	runs *server.Server
+}
+
+// NewServer returns a gRPC server executing runs with runs, which must be running (see
+// server.Server.Run). Runs are submitted with server.Server.Submit, so they accept the same flags
+// as the REST API. When token is set, every call must carry it as a bearer token. creds, when not
+// nil, serves over TLS; otherwise connections are not encrypted.
+func NewServer(runs *server.Server, token string, creds credentials.TransportCredentials) *grpc.Server {
+	var opts []grpc.ServerOption
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+	if token != "" {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if err := authorize(ctx, token); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := authorize(stream.Context(), token); err != nil {
+					return err
+				}
+				return handler(srv, stream)
+			}))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	agentpb.RegisterAgentServer(grpcServer, &service{runs: runs})
+	return grpcServer
+}
+
+func authorize(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(authorizationKey) {
+		if subtle.ConstantTimeCompare([]byte(value), []byte("Bearer "+token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+}
+
+func (s *service) RunBenchmark(ctx context.Context, req *agentpb.RunBenchmarkRequest) (*agentpb.Run, error) {
+	run, err := s.runs.Submit(server.RunRequest{
+		Platform:  req.GetPlatform(),
+		Component: req.GetComponent(),
+		Args:      req.GetArgs(),
+	})
+	switch {
+	case errors.Is(err, server.ErrQueueFull):
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return runToProto(run), nil
+}
+
+func (s *service) StreamProgress(req *agentpb.StreamProgressRequest, stream grpc.ServerStreamingServer[agentpb.ProgressEvent]) error {
+	err := s.runs.Follow(stream.Context(), req.GetRunId(), func(event events.Event) error {
+		return stream.Send(eventToProto(event))
+	})
+	if errors.Is(err, server.ErrRunNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
+func (s *service) GetReport(ctx context.Context, req *agentpb.GetReportRequest) (*agentpb.GetReportResponse, error) {
+	run, ok := s.runs.Lookup(req.GetRunId())
+	if !ok {
+		return nil, status.Error(codes.NotFound, server.ErrRunNotFound.Error())
+	}
+	if !run.Finished() {
+		return nil, status.Errorf(codes.FailedPrecondition, "run is %s", run.Status)
+	}
+	resp := &agentpb.GetReportResponse{Run: runToProto(run)}
+	if run.Status == server.StatusSucceeded {
+		data, err := os.ReadFile(s.runs.RunFile(run.ID, "report.json"))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "read report: %v", err)
+		}
+		resp.Report = data
+	}
+	return resp, nil
+}
+
+// Dial connects to an agent over TLS with creds, or unencrypted when creds is nil; reach agents
+// without TLS over a VPN or SSH tunnel when the network is not trusted.
+func Dial(addr, token string, creds credentials.TransportCredentials) (agentpb.AgentClient, func() error, error) {
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if token != "" {
+		opts = append(opts,
+			grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				return invoker(withToken(ctx, token), method, req, reply, cc, opts...)
+			}),
+			grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return streamer(withToken(ctx, token), desc, cc, method, opts...)
+			}))
+	}
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return agentpb.NewAgentClient(conn), conn.Close, nil
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, authorizationKey, "Bearer "+token)
+}
+
+// EventFromProto converts a streamed progress event back into the event the agent's run emitted.
+func EventFromProto(event *agentpb.ProgressEvent) events.Event {
+	return events.Event{
+		Time:       event.GetTime().AsTime(),
+		Kind:       events.Kind(event.GetKind()),
+		RunID:      event.GetReportRunId(),
+		Platform:   event.GetPlatform(),
+		Phase:      event.GetPhase(),
+		Command:    event.GetCommand(),
+		DurationMs: event.GetDurationMs(),
+		Metric:     event.GetMetric(),
+		Value:      event.GetValue(),
+		Message:    event.GetMessage(),
+		Error:      event.GetError(),
+	}
+}
+
+func eventToProto(event events.Event) *agentpb.ProgressEvent {
+	return &agentpb.ProgressEvent{
+		Time:        timestamppb.New(event.Time),
+		Kind:        string(event.Kind),
+		ReportRunId: event.RunID,
+		Platform:    event.Platform,
+		Phase:       event.Phase,
+		Command:     event.Command,
+		DurationMs:  event.DurationMs,
+		Metric:      event.Metric,
+		Value:       event.Value,
+		Message:     event.Message,
+		Error:       event.Error,
+	}
+}
+
+var runStatuses = map[server.Status]agentpb.RunStatus{
+	server.StatusQueued:    agentpb.RunStatus_RUN_STATUS_QUEUED,
+	server.StatusRunning:   agentpb.RunStatus_RUN_STATUS_RUNNING,
+	server.StatusSucceeded: agentpb.RunStatus_RUN_STATUS_SUCCEEDED,
+	server.StatusFailed:    agentpb.RunStatus_RUN_STATUS_FAILED,
+}
+
+func runToProto(run server.Run) *agentpb.Run {
+	pb := &agentpb.Run{
+		Id:        run.ID,
+		Platform:  run.Platform,
+		Component: run.Component,
+		Args:      run.Args,
+		Status:    runStatuses[run.Status],
+		CreatedAt: timestamppb.New(run.CreatedAt),
+		Error:     run.Error,
+	}
+	if run.StartedAt != nil {
+		pb.StartedAt = timestamppb.New(*run.StartedAt)
+	}
+	if run.FinishedAt != nil {
+		pb.FinishedAt = timestamppb.New(*run.FinishedAt)
+	}
+	return pb
+}
@@ -0,0 +1,75 @@
+package android
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/harness"
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// animationTailDelay gives the last animation frames time to complete before framestats is read.
+const animationTailDelay = 500 * time.Millisecond
+
+// defaultRefreshRateHz is assumed when `dumpsys display` does not report the active refresh rate.
+const defaultRefreshRateHz = 60.0
+
+// measureAnimation runs right after launch, while the harness waits harness.AnimationDelay before
+// playing the animation named in its designbench_animation extra. Frames from the first animation
+// frame until duration has passed are compared with the number the display could have shown.
+func measureAnimation(ctx context.Context, adbPath, deviceID, packageName, name string, duration time.Duration) (*report.AnimationMetrics, error) {
+	if _, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "gfxinfo", packageName, "reset"); err != nil {
+		return nil, fmt.Errorf("reset gfxinfo: %w", err)
+	}
+	refreshRate := defaultRefreshRateHz
+	if display, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "display"); err == nil {
+		if hz := parseRefreshRate(display); hz > 0 {
+			refreshRate = hz
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(harness.AnimationDelay + duration + animationTailDelay):
+	}
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "gfxinfo", packageName, "framestats")
+	if err != nil {
+		return nil, fmt.Errorf("dumpsys gfxinfo framestats: %w", err)
+	}
+	frames, err := parseFramestatsTimeline(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames rendered for animation %q", name)
+	}
+	return animationMetrics(name, frames, duration, refreshRate), nil
+}
+
+// animationMetrics counts the frames starting within duration of the first one. The longest pause
+// is the largest gap between consecutive frame completions, i.e. the longest time the screen
+// showed the same animation state.
+func animationMetrics(name string, frames []frameTiming, duration time.Duration, refreshRate float64) *report.AnimationMetrics {
+	windowEnd := frames[0].start + float64(duration.Nanoseconds())
+	expected := int(math.Round(duration.Seconds() * refreshRate))
+	result := &report.AnimationMetrics{
+		Name:           name,
+		DurationMs:     float64(duration.Milliseconds()),
+		ExpectedFrames: expected,
+	}
+	var previousEnd float64
+	for i, frame := range frames {
+		if frame.start >= windowEnd {
+			break
+		}
+		result.RenderedFrames++
+		if i > 0 {
+			result.LongestPauseMs = max(result.LongestPauseMs, (frame.end-previousEnd)/1e6)
+		}
+		previousEnd = frame.end
+	}
+	result.DroppedFrames = max(expected-result.RenderedFrames, 0)
+	return result
+}
@@ -0,0 +1,150 @@
+package android
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// AVDConfig declares an emulator image for CreateAVD.
+type AVDConfig struct {
+	Name     string
+	APILevel int
+	// Image is the system image tag: default (AOSP), google_apis or google_apis_playstore. The
+	// aliases aosp and play are accepted. Empty means google_apis.
+	Image string
+	// Device is the hardware profile, e.g. pixel_6 (avdmanager list device). Empty leaves
+	// avdmanager's default.
+	Device string
+	// ABI defaults to the host's: arm64-v8a on Apple silicon and ARM Linux, x86_64 otherwise.
+	ABI string
+	// Output receives sdkmanager's and avdmanager's output as they run.
+	Output  io.Writer
+	OnEvent events.Handler
+}
+
+// AVD describes an AVD that CreateAVD created or found.
+type AVD struct {
+	Name string `json:"name"`
+	// Package is the system image's sdkmanager package, e.g. "system-images;android-34;google_apis;x86_64".
+	Package string `json:"package"`
+	Device  string `json:"device,omitempty"`
+	// Created is false when an AVD of that name already existed on the same system image.
+	Created bool `json:"created"`
+}
+
+// SystemImagePackage returns the sdkmanager package of cfg's system image.
+func (cfg AVDConfig) SystemImagePackage() string {
+	image := cfg.Image
+	switch strings.ToLower(image) {
+	case "":
+		image = "google_apis"
+	case "aosp":
+		image = "default"
+	case "play":
+		image = "google_apis_playstore"
+	}
+	abi := cfg.ABI
+	if abi == "" {
+		abi = "x86_64"
+		if runtime.GOARCH == "arm64" {
+			abi = "arm64-v8a"
+		}
+	}
+	return fmt.Sprintf("system-images;android-%d;%s;%s", cfg.APILevel, image, abi)
+}
+
+// CreateAVD installs cfg's system image with sdkmanager, accepting its licenses, and creates the AVD
+// with avdmanager in $ANDROID_AVD_HOME. An existing AVD of that name is kept when it uses the same
+// system image and an error otherwise, unless force recreates it.
+func CreateAVD(ctx context.Context, cfg AVDConfig, force bool) (*AVD, error) {
+	ctx = events.WithHandler(ctx, "android", cfg.OnEvent)
+	if cfg.Name == "" || cfg.APILevel < 1 {
+		return nil, fmt.Errorf("AVD %q needs a name and an apiLevel", cfg.Name)
+	}
+	avd := &AVD{Name: cfg.Name, Package: cfg.SystemImagePackage(), Device: cfg.Device}
+	if existing, ok := AVDSystemImage(cfg.Name); ok && !force {
+		if existing != "" && existing != avd.Package {
+			return nil, fmt.Errorf("AVD %s exists on %s, not %s; pass --force to recreate it", cfg.Name, existing, avd.Package)
+		}
+		return avd, nil
+	}
+	events.Phase(ctx, "avd-system-image")
+	// sdkmanager asks to accept each license of the package; the image is only usable once they are.
+	if err := runSDKTool(ctx, cfg.Output, strings.Repeat("y\n", 20), "sdkmanager", "--install", avd.Package); err != nil {
+		return nil, err
+	}
+	events.Phase(ctx, "avd-create")
+	args := []string{"create", "avd", "--name", cfg.Name, "--package", avd.Package}
+	if cfg.Device != "" {
+		args = append(args, "--device", cfg.Device)
+	}
+	if force {
+		args = append(args, "--force")
+	}
+	// avdmanager asks whether to create a custom hardware profile.
+	if err := runSDKTool(ctx, cfg.Output, "no\n", "avdmanager", args...); err != nil {
+		return nil, err
+	}
+	avd.Created = true
+	return avd, nil
+}
+
+// AVDSystemImage returns the sdkmanager package of the system image the AVD name in
+// $ANDROID_AVD_HOME was created from, read from image.sysdir.1 in its config.ini, e.g.
+// system-images/android-34/google_apis/x86_64/. It reports false when there is no such AVD, and an
+// empty package when its image is unknown.
+func AVDSystemImage(name string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(defaultAVDHome(), name+".avd", "config.ini"))
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(key) == "image.sysdir.1" {
+			value = strings.Trim(filepath.ToSlash(strings.TrimSpace(value)), "/")
+			return strings.ReplaceAll(value, "/", ";"), true
+		}
+	}
+	return "", true
+}
+
+// runSDKTool runs an SDK command-line tool with input on stdin and its output sent to output.
+func runSDKTool(ctx context.Context, output io.Writer, input, tool string, args ...string) error {
+	if output == nil {
+		output = io.Discard
+	}
+	path := sdkToolPath(tool)
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	started := time.Now()
+	err := cmd.Run()
+	events.Command(ctx, path, args, started, err)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", tool, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// sdkToolPath prefers the tool in the SDK's latest command-line tools, which are rarely on PATH.
+func sdkToolPath(tool string) string {
+	for _, env := range []string{"ANDROID_HOME", "ANDROID_SDK_ROOT"} {
+		if sdk := os.Getenv(env); sdk != "" {
+			path := filepath.Join(sdk, "cmdline-tools", "latest", "bin", tool)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return tool
+}
@@ -0,0 +1,194 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// binderTopCalls is how many binder calls a report lists, slowest in total first.
+const binderTopCalls = 5
+
+// binderCallStat holds the cumulative system_server statistics of one binder call made by a UID.
+// Latencies are only recorded for sampled calls, so recordedCalls can be lower than calls.
+type binderCallStat struct {
+	calls            int64
+	recordedCalls    int64
+	latencyMicros    float64
+	maxLatencyMicros float64
+}
+
+// binderStatsSettings is the collection state of binder_calls_stats before a run changed it.
+type binderStatsSettings struct {
+	enabled          bool
+	detailedTracking bool
+}
+
+// enableBinderStats turns on binder call collection with detailed tracking, which stock builds
+// often leave off or sampled, and resets the counters. It returns the settings that
+// restoreBinderStats puts back.
+func enableBinderStats(ctx context.Context, adbPath, deviceID string) (binderStatsSettings, error) {
+	previous := readBinderStatsSettings(ctx, adbPath, deviceID)
+	for _, arg := range []string{"--enable", "--enable-detailed-tracking", "--reset"} {
+		if _, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "binder_calls_stats", arg); err != nil {
+			restoreBinderStats(ctx, adbPath, deviceID, previous)
+			return binderStatsSettings{}, fmt.Errorf("dumpsys binder_calls_stats %s: %w", arg, err)
+		}
+	}
+	return previous, nil
+}
+
+// restoreBinderStats switches off what enableBinderStats switched on.
+func restoreBinderStats(ctx context.Context, adbPath, deviceID string, previous binderStatsSettings) {
+	if !previous.detailedTracking {
+		_, _ = runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "binder_calls_stats", "--disable-detailed-tracking")
+	}
+	if !previous.enabled {
+		_, _ = runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "binder_calls_stats", "--disable")
+	}
+}
+
+// readBinderStatsSettings reads the binder_calls_stats global setting, a list such as
+// `enabled=false,detailed_tracking=true`, in which collection defaults to on. Detailed tracking
+// defaults to the persist.sys.binder_calls_detailed_tracking property, which
+// --enable-detailed-tracking sets.
+func readBinderStatsSettings(ctx context.Context, adbPath, deviceID string) binderStatsSettings {
+	settings := binderStatsSettings{enabled: true}
+	if prop, err := runADB(ctx, adbPath, deviceID, "shell", "getprop", "persist.sys.binder_calls_detailed_tracking"); err == nil {
+		settings.detailedTracking, _ = strconv.ParseBool(strings.TrimSpace(prop))
+	}
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "settings", "get", "global", "binder_calls_stats")
+	if err != nil {
+		return settings
+	}
+	for _, pair := range strings.Split(strings.TrimSpace(out), ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		on, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "enabled":
+			settings.enabled = on
+		case "detailed_tracking":
+			settings.detailedTracking = on
+		}
+	}
+	return settings
+}
+
+// captureBinderStats reads the binder calls uid has made to system services so far, keyed by
+// call description (e.g. android.app.IActivityManager#getRunningAppProcesses).
+func captureBinderStats(ctx context.Context, adbPath, deviceID, uid string) (map[string]binderCallStat, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "binder_calls_stats")
+	if err != nil {
+		return nil, fmt.Errorf("dumpsys binder_calls_stats: %w", err)
+	}
+	return parseBinderCallsStats(out, uid)
+}
+
+// parseBinderCallsStats reads the "Raw data (uid, call_desc, ...):" table. Its columns differ
+// between releases, so they are located by the names in the header.
+func parseBinderCallsStats(output, uid string) (map[string]binderCallStat, error) {
+	var columns map[string]int
+	found := false
+	stats := make(map[string]binderCallStat)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if header, ok := strings.CutPrefix(line, "Raw data ("); ok {
+			columns = make(map[string]int)
+			found = true
+			for i, name := range strings.Split(strings.TrimSuffix(header, "):"), ",") {
+				columns[strings.TrimSpace(name)] = i
+			}
+			continue
+		}
+		if columns == nil {
+			continue
+		}
+		if line == "" || strings.HasSuffix(line, ":") {
+			// The table ends at the next section.
+			columns = nil
+			continue
+		}
+		fields := strings.Split(line, ",")
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		if field("uid") != uid {
+			continue
+		}
+		calls, err := strconv.ParseInt(field("call_count"), 10, 64)
+		if err != nil {
+			continue
+		}
+		stat := stats[field("call_desc")]
+		stat.calls += calls
+		if recorded, err := strconv.ParseInt(field("recorded_call_count"), 10, 64); err == nil {
+			stat.recordedCalls += recorded
+		}
+		if latency, err := strconv.ParseFloat(field("latency_time_micros"), 64); err == nil {
+			stat.latencyMicros += latency
+		}
+		if maxLatency, err := strconv.ParseFloat(field("max_latency_time_micros"), 64); err == nil {
+			stat.maxLatencyMicros = max(stat.maxLatencyMicros, maxLatency)
+		}
+		stats[field("call_desc")] = stat
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("no binder call data (binder_calls_stats may be disabled)")
+	}
+	return stats, nil
+}
+
+// binderDeltas summarizes the calls made between two captures. The total latency is extrapolated
+// from the sampled calls; the longest call is only known for calls that ran in the window.
+func binderDeltas(before, after map[string]binderCallStat) *report.BinderMetrics {
+	metrics := &report.BinderMetrics{}
+	for desc, stat := range after {
+		prev := before[desc]
+		calls := stat.calls - prev.calls
+		if calls <= 0 {
+			continue
+		}
+		latencyMs := 0.0
+		if recorded := stat.recordedCalls - prev.recordedCalls; recorded > 0 {
+			latencyMs = (stat.latencyMicros - prev.latencyMicros) / float64(recorded) * float64(calls) / 1000
+		}
+		metrics.Calls += calls
+		metrics.LatencyMs += latencyMs
+		if stat.maxLatencyMicros > prev.maxLatencyMicros {
+			metrics.MaxLatencyMs = max(metrics.MaxLatencyMs, stat.maxLatencyMicros/1000)
+		}
+		metrics.Top = append(metrics.Top, report.BinderCall{Name: desc, Calls: calls, LatencyMs: latencyMs})
+	}
+	if metrics.Calls == 0 {
+		return nil
+	}
+	sort.Slice(metrics.Top, func(i, j int) bool {
+		if metrics.Top[i].LatencyMs != metrics.Top[j].LatencyMs {
+			return metrics.Top[i].LatencyMs > metrics.Top[j].LatencyMs
+		}
+		return metrics.Top[i].Name < metrics.Top[j].Name
+	})
+	if len(metrics.Top) > binderTopCalls {
+		metrics.Top = metrics.Top[:binderTopCalls]
+	}
+	return metrics
+}
@@ -0,0 +1,45 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// detectBuildType reports whether the installed package is debuggable. Debuggable builds run with
+// JIT debugging hooks and without full ART optimisation, so their timings are not representative.
+func detectBuildType(ctx context.Context, adbPath, deviceID, packageName string) (string, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "package", packageName)
+	if err != nil {
+		return "", fmt.Errorf("dumpsys package: %w", err)
+	}
+	return parsePackageBuildType(out, packageName)
+}
+
+// parsePackageBuildType reads the `flags=[ ... ]` (or `pkgFlags=[ ... ]`) line of `dumpsys package`.
+func parsePackageBuildType(output, packageName string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		flags, ok := strings.CutPrefix(line, "flags=[")
+		if !ok {
+			flags, ok = strings.CutPrefix(line, "pkgFlags=[")
+		}
+		if !ok {
+			continue
+		}
+		for _, flag := range strings.Fields(strings.TrimSuffix(flags, "]")) {
+			if flag == "DEBUGGABLE" {
+				return report.BuildTypeDebug, nil
+			}
+		}
+		return report.BuildTypeRelease, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("package flags for %s not found", packageName)
+}
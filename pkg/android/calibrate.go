@@ -0,0 +1,45 @@
+package android
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/calibration"
+	"github.com/tahatesser/designbench/pkg/report"
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// Calibrate runs calibration.Script rounds times and returns the device's metadata and the median
+// duration in milliseconds, net of the cost of starting an empty shell over adb.
+func Calibrate(ctx context.Context, adbPath, deviceID string, rounds int) (*report.DeviceMetadata, float64, error) {
+	if adbPath == "" {
+		adbPath = "adb"
+	}
+	meta := fetchDeviceMetadata(ctx, adbPath, deviceID, "")
+	if meta == nil {
+		return nil, 0, fmt.Errorf("no device found (is it connected and authorized?)")
+	}
+	scores := make([]float64, 0, rounds)
+	for range rounds {
+		overhead, err := timeShell(ctx, adbPath, deviceID, ":")
+		if err != nil {
+			return nil, 0, err
+		}
+		elapsed, err := timeShell(ctx, adbPath, deviceID, calibration.Script)
+		if err != nil {
+			return nil, 0, err
+		}
+		scores = append(scores, max(elapsed-overhead, 0))
+	}
+	return meta, stats.Median(scores), nil
+}
+
+func timeShell(ctx context.Context, adbPath, deviceID, script string) (float64, error) {
+	started := time.Now()
+	// adb joins its arguments into one device command line, so the script is quoted for sh -c.
+	if _, err := runADB(ctx, adbPath, deviceID, "shell", "sh", "-c", "'"+script+"'"); err != nil {
+		return 0, fmt.Errorf("run calibration workload: %w", err)
+	}
+	return float64(time.Since(started).Microseconds()) / 1000, nil
+}
@@ -0,0 +1,34 @@
+package android
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// cleanupTimeout bounds the device commands issued to restore state once a run has been interrupted.
+const cleanupTimeout = 10 * time.Second
+
+// commandWaitDelay is how long a cancelled adb invocation may keep its output pipes open before they
+// are closed forcibly; adb can leave forked server or shell children holding them.
+const commandWaitDelay = 2 * time.Second
+
+// adbCommand builds an adb invocation that is killed when ctx is done without waiting indefinitely
+// for orphaned children to release its output.
+func adbCommand(ctx context.Context, adbPath string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, adbPath, args...)
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
+
+// cleanupContext detaches from ctx's cancellation (keeping its values, such as the event handler) so
+// device state can still be restored after a timeout.
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+}
+
+// forceStop kills the app so an interrupted run does not leave it in the foreground.
+func forceStop(ctx context.Context, adbPath, deviceID, packageName string) error {
+	_, err := runADB(ctx, adbPath, deviceID, "shell", "am", "force-stop", packageName)
+	return err
+}
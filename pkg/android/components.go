@@ -0,0 +1,73 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/harness"
+)
+
+// componentsTimeout bounds how long ListComponents waits for the harness to log its registry.
+const componentsTimeout = 10 * time.Second
+
+// ListComponents launches the harness with harness.ListComponentsExtra and returns the component
+// names it logs before exiting. ok is false when no list was logged in time, as with a harness that
+// predates the handshake. The app is force-stopped afterwards so the next launch starts cold.
+func ListComponents(ctx context.Context, adbPath, deviceID, packageName, activity string) (names []string, ok bool, err error) {
+	if adbPath == "" {
+		adbPath = "adb"
+	}
+	if activity == "" {
+		activity = harness.DefaultAndroidActivity
+	}
+	since, err := deviceLogcatTime(ctx, adbPath, deviceID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		stopCtx, cancel := cleanupContext(ctx)
+		defer cancel()
+		_ = forceStop(stopCtx, adbPath, deviceID, packageName)
+	}()
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "am", "start", "-W",
+		buildComponentArg(packageName, activity), "--ez", harness.ListComponentsExtra, "true")
+	if err != nil {
+		return nil, false, fmt.Errorf("launch %s: %w", packageName, err)
+	}
+	if strings.Contains(out, "Error:") {
+		return nil, false, fmt.Errorf("launch %s: %s", packageName, strings.TrimSpace(out))
+	}
+
+	deadline := time.Now().Add(componentsTimeout)
+	for {
+		out, err := runADB(ctx, adbPath, deviceID, "logcat", "-d", "-T", since, "-s", harness.MarkerTag+":I")
+		if err != nil {
+			return nil, false, fmt.Errorf("read logcat: %w", err)
+		}
+		if names, ok := parseComponentList(out); ok {
+			return names, true, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// parseComponentList returns the last components marker in logcat output.
+func parseComponentList(output string) (names []string, ok bool) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if list, found := harness.ParseComponentList(scanner.Text()); found {
+			names, ok = list, true
+		}
+	}
+	return names, ok
+}
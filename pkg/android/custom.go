@@ -0,0 +1,31 @@
+package android
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tahatesser/designbench/pkg/harness"
+)
+
+// harnessResultPath is where the harness's DesignBenchMetrics writes, relative to the app's data
+// directory, which `run-as` starts in.
+const harnessResultPath = "files/" + harness.ResultFile
+
+// clearCustomMetrics deletes the metrics file left by an earlier run. run-as only works for
+// debuggable apps, which are the only ones whose file can be read afterwards.
+func clearCustomMetrics(ctx context.Context, adbPath, deviceID, packageName string) error {
+	_, err := runADB(ctx, adbPath, deviceID, "shell", "run-as", packageName, "rm", "-f", harnessResultPath)
+	return err
+}
+
+// collectCustomMetrics reads the metrics the app reported through the harness's result file. An app
+// that reported none has no file, which yields nil.
+func collectCustomMetrics(ctx context.Context, adbPath, deviceID, packageName string) (map[string]float64, error) {
+	// adb shell re-splits its arguments on the device, so the script is quoted for sh -c.
+	script := fmt.Sprintf("'[ ! -f %[1]s ] || cat %[1]s'", harnessResultPath)
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "run-as", packageName, "sh", "-c", script)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", harnessResultPath, err)
+	}
+	return harness.ParseResultFile([]byte(out))
+}
@@ -0,0 +1,159 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+var (
+	displayModeIDRe    = regexp.MustCompile(`modeId (\d+)`)
+	renderFrameRateRe  = regexp.MustCompile(`renderFrameRate ([\d.]+)`)
+	supportedModeRe    = regexp.MustCompile(`\{id=(\d+), width=\d+, height=\d+, fps=([\d.]+)`)
+	displaySizeValueRe = regexp.MustCompile(`(\d+x\d+)`)
+	displayInfoNameRe  = regexp.MustCompile(`^"([^"]*)"`)
+	displayTypeRe      = regexp.MustCompile(`, type (\w+)`)
+	displayRotationRe  = regexp.MustCompile(`, rotation (\d)`)
+)
+
+// collectDisplayMetadata fills in resolution, density and refresh rate of the display the app is
+// launched on: the default display when displayID is empty, else the logical display with that id.
+func collectDisplayMetadata(ctx context.Context, adbPath, deviceID, displayID string, meta *report.DeviceMetadata) {
+	if size, err := runADB(ctx, adbPath, deviceID, wmArgs("size", displayID)...); err == nil {
+		meta.Resolution = parseWMOverride(size, func(value string) string {
+			return displaySizeValueRe.FindString(value)
+		})
+	}
+	if density, err := runADB(ctx, adbPath, deviceID, wmArgs("density", displayID)...); err == nil {
+		dpi := parseWMOverride(density, strings.TrimSpace)
+		if v, err := strconv.Atoi(dpi); err == nil {
+			meta.DensityDPI = v
+		}
+	}
+	display, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "display")
+	if err != nil {
+		return
+	}
+	if displayID == "" {
+		meta.RefreshRateHz = parseRefreshRate(display)
+		return
+	}
+	meta.DisplayID = displayID
+	meta.DisplayName, meta.DisplayType, meta.RefreshRateHz = parseLogicalDisplay(display, displayID)
+}
+
+// wmArgs builds a `wm size`/`wm density` command, which takes -d for other displays on Android 10+.
+func wmArgs(command, displayID string) []string {
+	args := []string{"shell", "wm", command}
+	if displayID != "" {
+		args = append(args, "-d", displayID)
+	}
+	return args
+}
+
+// parseLogicalDisplay reads the name, type (e.g. INTERNAL, EXTERNAL, VIRTUAL) and refresh rate of
+// a logical display from the first DisplayInfo in `dumpsys display` with its displayId, such as
+//
+//	mBaseDisplayInfo=DisplayInfo{"HDMI Screen", displayId 2, ..., type EXTERNAL, ..., renderFrameRate 60.0, ...}
+func parseLogicalDisplay(output, displayID string) (name, displayType string, refreshRateHz float64) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		_, info, ok := strings.Cut(line, "DisplayInfo{")
+		if !ok || !strings.Contains(info, ", displayId "+displayID+",") {
+			continue
+		}
+		if match := displayInfoNameRe.FindStringSubmatch(info); len(match) > 1 {
+			name = match[1]
+		}
+		if match := displayTypeRe.FindStringSubmatch(info); len(match) > 1 {
+			displayType = match[1]
+		}
+		if match := renderFrameRateRe.FindStringSubmatch(info); len(match) > 1 {
+			refreshRateHz, _ = strconv.ParseFloat(match[1], 64)
+		}
+		return name, displayType, refreshRateHz
+	}
+	return "", "", 0
+}
+
+// parseDisplayRotation reads the current rotation (0 to 3, in quarter turns) of a logical display,
+// the default one when displayID is empty, from its first DisplayInfo in `dumpsys display`.
+func parseDisplayRotation(output, displayID string) int {
+	if displayID == "" {
+		displayID = "0"
+	}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		_, info, ok := strings.Cut(scanner.Text(), "DisplayInfo{")
+		if !ok || !strings.Contains(info, ", displayId "+displayID+",") {
+			continue
+		}
+		if match := displayRotationRe.FindStringSubmatch(info); len(match) > 1 {
+			rotation, _ := strconv.Atoi(match[1])
+			return rotation
+		}
+		return 0
+	}
+	return 0
+}
+
+// parseWMOverride reads `wm size`/`wm density` output, preferring the "Override" line over the
+// "Physical" one because it reflects what apps actually render at.
+func parseWMOverride(output string, extract func(string) string) string {
+	var physical, override string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Physical size", "Physical density":
+			physical = extract(value)
+		case "Override size", "Override density":
+			override = extract(value)
+		}
+	}
+	if override != "" {
+		return override
+	}
+	return physical
+}
+
+// parseRefreshRate reads the first DisplayDeviceInfo line of `dumpsys display`. Android 12+ prints
+// `renderFrameRate`; older releases only list the active `modeId` and the `supportedModes` table.
+func parseRefreshRate(output string) float64 {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, "DisplayDeviceInfo{") {
+			continue
+		}
+		if match := renderFrameRateRe.FindStringSubmatch(line); len(match) > 1 {
+			if v, err := strconv.ParseFloat(match[1], 64); err == nil {
+				return v
+			}
+		}
+		modeID := displayModeIDRe.FindStringSubmatch(line)
+		if len(modeID) < 2 {
+			continue
+		}
+		for _, mode := range supportedModeRe.FindAllStringSubmatch(line, -1) {
+			if mode[1] != modeID[1] {
+				continue
+			}
+			if v, err := strconv.ParseFloat(mode[2], 64); err == nil {
+				return v
+			}
+		}
+	}
+	return 0
+}
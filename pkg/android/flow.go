@@ -0,0 +1,89 @@
+package android
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// Flow is a navigation flow: screens opened one after another once the entry activity has launched,
+// each by activity or deep link, e.g.
+//
+//	steps:
+//	  - name: catalog
+//	    activity: .CatalogActivity
+//	  - name: details
+//	    deepLink: myapp://catalog/buttons/primary
+type Flow struct {
+	Steps []FlowStep `yaml:"steps"`
+}
+
+// FlowStep opens one screen of a Flow. Name labels it in reports and defaults to the activity or
+// deep link.
+type FlowStep struct {
+	Name     string `yaml:"name,omitempty"`
+	Activity string `yaml:"activity,omitempty"`
+	DeepLink string `yaml:"deepLink,omitempty"`
+}
+
+// LoadFlow reads and validates a YAML navigation flow.
+func LoadFlow(path string) (*Flow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var flow Flow
+	if err := yaml.Unmarshal(data, &flow); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(flow.Steps) == 0 {
+		return nil, fmt.Errorf("%s: needs at least one step", path)
+	}
+	for i, step := range flow.Steps {
+		if (step.Activity == "") == (step.DeepLink == "") {
+			return nil, fmt.Errorf("%s: step %d: needs exactly one of activity or deepLink", path, i+1)
+		}
+		// The link is single-quoted for the device shell, which re-splits adb's arguments.
+		if strings.Contains(step.DeepLink, "'") {
+			return nil, fmt.Errorf("%s: step %d: deepLink cannot contain a single quote", path, i+1)
+		}
+		if step.Name == "" {
+			flow.Steps[i].Name = step.Activity + step.DeepLink
+		}
+	}
+	return &flow, nil
+}
+
+// runFlow opens each step of flow in packageName in turn and times each transition with
+// `am start -W`, which waits until the new screen has drawn its first frame.
+func runFlow(ctx context.Context, adbPath, deviceID, packageName string, flow *Flow) ([]report.FlowTransition, error) {
+	transitions := make([]report.FlowTransition, 0, len(flow.Steps))
+	for i, step := range flow.Steps {
+		args := []string{"shell", "am", "start", "-W"}
+		if step.Activity != "" {
+			args = append(args, "-n", buildComponentArg(packageName, step.Activity))
+		} else {
+			args = append(args, "-a", "android.intent.action.VIEW", "-d", "'"+step.DeepLink+"'", packageName)
+		}
+		out, err := runADB(ctx, adbPath, deviceID, args...)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i+1, step.Name, err)
+		}
+		// am reports an unresolved intent on stdout and still exits zero.
+		if strings.Contains(out, "Error:") {
+			return nil, fmt.Errorf("step %d (%s): %s", i+1, step.Name, strings.TrimSpace(out))
+		}
+		timing := parseLaunchOutput([]byte(out))
+		if timing.TotalTimeMs == 0 {
+			// An intent delivered to the screen already on top starts nothing and reports no time.
+			return nil, fmt.Errorf("step %d (%s): am start reported no launch time; is the screen already on top?", i+1, step.Name)
+		}
+		transitions = append(transitions, report.FlowTransition{Name: step.Name, TotalTimeMs: timing.TotalTimeMs, WaitTimeMs: timing.WaitTimeMs})
+	}
+	return transitions, nil
+}
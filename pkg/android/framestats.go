@@ -0,0 +1,163 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+const profileDataMarker = "---PROFILEDATA---"
+
+// collectFramestats returns every frame the app rendered since its process started, as reported by
+// `dumpsys gfxinfo <package> framestats`.
+func collectFramestats(ctx context.Context, adbPath, deviceID, packageName string) ([]frameTiming, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "gfxinfo", packageName, "framestats")
+	if err != nil {
+		return nil, fmt.Errorf("dumpsys gfxinfo framestats: %w", err)
+	}
+	frames, err := parseFramestatsTimeline(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("no frames reported by gfxinfo framestats")
+	}
+	return frames, nil
+}
+
+// frameTiming holds a frame's IntendedVsync and FrameCompleted timestamps in nanoseconds. input is
+// HandleInputStart, which is zero unless the frame processed input events. The remaining fields
+// mark the pipeline stages in between and are zero when gfxinfo does not report them.
+type frameTiming struct {
+	start float64
+	end   float64
+	input float64
+	// present is when the frame reached the display (DisplayPresentTime, Android 12+), or 0.
+	present float64
+
+	vsync           float64
+	traversalsStart float64
+	drawStart       float64
+	syncQueued      float64
+	syncStart       float64
+	issueDrawStart  float64
+}
+
+// frameDurations returns each frame's duration in milliseconds.
+func frameDurations(frames []frameTiming) []float64 {
+	durations := make([]float64, 0, len(frames))
+	for _, frame := range frames {
+		durations = append(durations, (frame.end-frame.start)/1e6)
+	}
+	return durations
+}
+
+// framePipeline splits the frames' time between the UI thread, which runs from Vsync until it
+// queues the frame for sync, and the RenderThread, which runs from SyncStart until the frame is
+// completed. Frames without stage timestamps are left out.
+func framePipeline(frames []frameTiming) *report.FramePipeline {
+	var ui, measureLayout, draw, render, sync, gpu []float64
+	for _, frame := range frames {
+		if frame.vsync == 0 || frame.syncQueued < frame.vsync || frame.syncStart == 0 || frame.end < frame.syncStart {
+			continue
+		}
+		ui = append(ui, (frame.syncQueued-frame.vsync)/1e6)
+		render = append(render, (frame.end-frame.syncStart)/1e6)
+		if frame.traversalsStart > 0 && frame.drawStart >= frame.traversalsStart {
+			measureLayout = append(measureLayout, (frame.drawStart-frame.traversalsStart)/1e6)
+		}
+		if frame.drawStart > 0 && frame.syncQueued >= frame.drawStart {
+			draw = append(draw, (frame.syncQueued-frame.drawStart)/1e6)
+		}
+		if frame.issueDrawStart >= frame.syncStart && frame.end >= frame.issueDrawStart {
+			sync = append(sync, (frame.issueDrawStart-frame.syncStart)/1e6)
+			gpu = append(gpu, (frame.end-frame.issueDrawStart)/1e6)
+		}
+	}
+	if len(ui) == 0 {
+		return nil
+	}
+	return &report.FramePipeline{
+		Frames:          len(ui),
+		UIThreadMs:      stats.Mean(ui),
+		MeasureLayoutMs: stats.Mean(measureLayout),
+		DrawMs:          stats.Mean(draw),
+		RenderThreadMs:  stats.Mean(render),
+		SyncMs:          stats.Mean(sync),
+		GPUMs:           stats.Mean(gpu),
+	}
+}
+
+// parseFramestatsTimeline reads the CSV sections between PROFILEDATA markers. Each frame lasts from
+// IntendedVsync to FrameCompleted; rows with non-zero Flags are invalid per the platform
+// documentation and skipped.
+func parseFramestatsTimeline(output string) ([]frameTiming, error) {
+	frames := make([]frameTiming, 0, 128)
+	inProfile := false
+	var header map[string]int
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == profileDataMarker {
+			inProfile = !inProfile
+			header = nil
+			continue
+		}
+		if !inProfile || line == "" {
+			continue
+		}
+		columns := strings.Split(strings.TrimSuffix(line, ","), ",")
+		if header == nil {
+			header = make(map[string]int, len(columns))
+			for i, name := range columns {
+				header[strings.TrimSpace(name)] = i
+			}
+			continue
+		}
+		flagsIdx, okFlags := header["Flags"]
+		startIdx, okStart := header["IntendedVsync"]
+		endIdx, okEnd := header["FrameCompleted"]
+		if !okFlags || !okStart || !okEnd || len(columns) <= max(flagsIdx, startIdx, endIdx) {
+			continue
+		}
+		if strings.TrimSpace(columns[flagsIdx]) != "0" {
+			continue
+		}
+		start, startErr := strconv.ParseFloat(strings.TrimSpace(columns[startIdx]), 64)
+		end, endErr := strconv.ParseFloat(strings.TrimSpace(columns[endIdx]), 64)
+		if startErr != nil || endErr != nil || end <= start {
+			continue
+		}
+		column := func(name string) float64 {
+			idx, ok := header[name]
+			if !ok || idx >= len(columns) {
+				return 0
+			}
+			value, _ := strconv.ParseFloat(strings.TrimSpace(columns[idx]), 64)
+			return value
+		}
+		frame := frameTiming{
+			start:           start,
+			end:             end,
+			input:           column("HandleInputStart"),
+			present:         column("DisplayPresentTime"),
+			vsync:           column("Vsync"),
+			traversalsStart: column("PerformTraversalsStart"),
+			drawStart:       column("DrawStart"),
+			syncQueued:      column("SyncQueued"),
+			syncStart:       column("SyncStart"),
+			issueDrawStart:  column("IssueDrawCommandsStart"),
+		}
+		frames = append(frames, frame)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
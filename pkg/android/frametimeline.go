@@ -0,0 +1,70 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// collectFrameTimelineJank reads SurfaceFlinger's FrameTimeline (Android 12+), the same source
+// Perfetto's frame timeline tracks are built from, and classifies the app's frames by jank cause.
+func collectFrameTimelineJank(ctx context.Context, adbPath, deviceID, pid string) (*report.JankBreakdown, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "SurfaceFlinger", "--frametimeline", "-a")
+	if err != nil {
+		return nil, fmt.Errorf("dumpsys SurfaceFlinger --frametimeline: %w", err)
+	}
+	return parseFrameTimeline(out, pid)
+}
+
+// parseFrameTimeline walks SurfaceFrame blocks, which start with `Layer - <name>` and carry
+// `Owner Pid : <pid>` and `Jank Type : <comma separated causes>` lines.
+func parseFrameTimeline(output, pid string) (*report.JankBreakdown, error) {
+	breakdown := &report.JankBreakdown{ByType: make(map[string]int)}
+	var owner, jankType string
+	inSurfaceFrame := false
+	flush := func() {
+		if inSurfaceFrame && owner == pid && jankType != "" {
+			breakdown.TotalFrames++
+			if jankType != "None" {
+				breakdown.JankyFrames++
+				for _, cause := range strings.Split(jankType, ",") {
+					breakdown.ByType[strings.TrimSpace(cause)]++
+				}
+			}
+		}
+		owner, jankType = "", ""
+	}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "Layer - "):
+			flush()
+			inSurfaceFrame = true
+		case strings.HasPrefix(line, "Display Frame"):
+			flush()
+			inSurfaceFrame = false
+		case inSurfaceFrame && strings.HasPrefix(line, "Owner Pid"):
+			_, value, _ := strings.Cut(line, ":")
+			owner = strings.TrimSpace(value)
+		case inSurfaceFrame && strings.HasPrefix(line, "Jank Type"):
+			_, value, _ := strings.Cut(line, ":")
+			jankType = strings.TrimSpace(value)
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if breakdown.TotalFrames == 0 {
+		return nil, errors.New("no frames for process in FrameTimeline (requires Android 12+)")
+	}
+	if len(breakdown.ByType) == 0 {
+		breakdown.ByType = nil
+	}
+	return breakdown, nil
+}
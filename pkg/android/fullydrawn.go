@@ -0,0 +1,85 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fullyDrawnTags select the system_server lines written when an activity calls reportFullyDrawn():
+// ActivityTaskManager on Android 10 and later, ActivityManager before.
+var fullyDrawnTags = []string{"ActivityTaskManager:I", "ActivityManager:I"}
+
+// collectFullyDrawn returns the launch-to-fully-drawn time that the system logged for packageName
+// since the given device time. Apps that never call reportFullyDrawn() have no such line.
+func collectFullyDrawn(ctx context.Context, adbPath, deviceID, since, packageName string) (float64, error) {
+	args := append([]string{"logcat", "-d", "-T", since, "-s"}, fullyDrawnTags...)
+	out, err := runADB(ctx, adbPath, deviceID, args...)
+	if err != nil {
+		return 0, fmt.Errorf("read logcat: %w", err)
+	}
+	return parseFullyDrawn(out, packageName)
+}
+
+// parseFullyDrawn finds a line such as
+//
+//	I ActivityTaskManager: Fully drawn com.example/.MainActivity: +1s234ms
+//
+// for packageName and returns its duration in milliseconds.
+func parseFullyDrawn(output, packageName string) (float64, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		_, rest, ok := strings.Cut(scanner.Text(), "Fully drawn ")
+		if !ok {
+			continue
+		}
+		component, value, ok := strings.Cut(rest, ": ")
+		if !ok || !strings.HasPrefix(component, packageName+"/") {
+			continue
+		}
+		// Android 13+ appends the launch state, e.g. "+1s234ms (cold)".
+		if fields := strings.Fields(value); len(fields) > 0 {
+			return parseLogDuration(fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("no fully drawn event (the app does not call reportFullyDrawn)")
+}
+
+// parseLogDuration converts a duration in the format of Android's TimeUtils.formatDuration, such
+// as "+1s234ms" or "+850ms", to milliseconds.
+func parseLogDuration(value string) (float64, error) {
+	rest := strings.TrimPrefix(value, "+")
+	if rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	units := map[string]float64{"d": 86400000, "h": 3600000, "m": 60000, "s": 1000, "ms": 1}
+	total := 0.0
+	for rest != "" {
+		digits := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if digits <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		n, err := strconv.Atoi(rest[:digits])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		rest = rest[digits:]
+		unit := rest
+		if end := strings.IndexFunc(rest, func(r rune) bool { return r >= '0' && r <= '9' }); end >= 0 {
+			unit = rest[:end]
+		}
+		scale, ok := units[unit]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		total += float64(n) * scale
+		rest = rest[len(unit):]
+	}
+	return total, nil
+}
@@ -0,0 +1,105 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// defaultIdleTimeout caps the idle wait when Config.IdleTimeout is unset.
+const defaultIdleTimeout = 30 * time.Second
+
+// idleSampleInterval is the window over which device-wide CPU utilisation is measured.
+const idleSampleInterval = time.Second
+
+// cpuTimes holds the aggregate jiffy counters from the first line of /proc/stat.
+type cpuTimes struct {
+	busy  uint64
+	total uint64
+}
+
+// waitForIdle polls device-wide CPU utilisation until it drops to thresholdPct or timeout elapses,
+// returning the last reading. Background syncs and indexing otherwise bleed into the measurement.
+func waitForIdle(ctx context.Context, adbPath, deviceID string, thresholdPct float64, timeout time.Duration) (float64, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		load, err := sampleDeviceCPU(ctx, adbPath, deviceID)
+		if err != nil {
+			return 0, err
+		}
+		events.Metric(ctx, "deviceCpuPercent", load)
+		if load <= thresholdPct {
+			return load, nil
+		}
+		if !time.Now().Before(deadline) {
+			return load, fmt.Errorf("device CPU still at %.1f%% after %s (threshold %.1f%%)", load, timeout, thresholdPct)
+		}
+	}
+}
+
+// sampleDeviceCPU reads /proc/stat twice, idleSampleInterval apart, and returns the busy share.
+func sampleDeviceCPU(ctx context.Context, adbPath, deviceID string) (float64, error) {
+	before, err := readCPUTimes(ctx, adbPath, deviceID)
+	if err != nil {
+		return 0, err
+	}
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-time.After(idleSampleInterval):
+	}
+	after, err := readCPUTimes(ctx, adbPath, deviceID)
+	if err != nil {
+		return 0, err
+	}
+	if after.total <= before.total || after.busy < before.busy {
+		return 0, errors.New("cpu counters did not advance")
+	}
+	return float64(after.busy-before.busy) / float64(after.total-before.total) * 100, nil
+}
+
+func readCPUTimes(ctx context.Context, adbPath, deviceID string) (cpuTimes, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "cat", "/proc/stat")
+	if err != nil {
+		return cpuTimes{}, fmt.Errorf("read /proc/stat: %w", err)
+	}
+	return parseCPUTimes(out)
+}
+
+// parseCPUTimes reads `cpu  user nice system idle iowait irq softirq steal ...`; idle and iowait
+// count as idle time.
+func parseCPUTimes(output string) (cpuTimes, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		var times cpuTimes
+		for i, field := range fields[1:] {
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return cpuTimes{}, fmt.Errorf("parse /proc/stat: %w", err)
+			}
+			// Guest time (columns 9 and 10) is already included in user and nice.
+			if i >= 8 {
+				break
+			}
+			times.total += v
+			if i != 3 && i != 4 {
+				times.busy += v
+			}
+		}
+		return times, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return cpuTimes{}, err
+	}
+	return cpuTimes{}, errors.New("aggregate cpu line not found in /proc/stat")
+}
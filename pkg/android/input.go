@@ -0,0 +1,116 @@
+package android
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/report"
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// tapSettleDelay is how long the app gets to handle a tap and draw the response before framestats is read.
+const tapSettleDelay = 500 * time.Millisecond
+
+// measureInputLatency taps the centre of the screen, where the harness renders the component, and
+// measures each tap from the vsync that dispatched it (IntendedVsync of the first frame with a
+// HandleInputStart) to that frame's DisplayPresentTime, when SurfaceFlinger's FrameTimeline put it
+// on the display. The time between the touch event and its vsync, at most one frame interval, and
+// the touch digitiser are not visible to gfxinfo and are not included. displayID targets a display
+// other than the default one.
+func measureInputLatency(ctx context.Context, adbPath, deviceID, displayID, packageName string, taps int) (*report.InputLatencyMetrics, error) {
+	size, err := runADB(ctx, adbPath, deviceID, wmArgs("size", displayID)...)
+	if err != nil {
+		return nil, fmt.Errorf("read screen size: %w", err)
+	}
+	// wm size is in the display's natural orientation; input coordinates follow its rotation.
+	rotation := 0
+	if display, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "display"); err == nil {
+		rotation = parseDisplayRotation(display, displayID)
+	}
+	x, y, err := screenCenter(parseWMOverride(size, func(value string) string {
+		return displaySizeValueRe.FindString(value)
+	}), rotation)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &report.InputLatencyMetrics{Taps: taps}
+	for range taps {
+		latency, presented, ok, err := measureTap(ctx, adbPath, deviceID, displayID, packageName, x, y)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			result.Missed++
+			continue
+		}
+		if !presented {
+			result.NotPresented++
+		}
+		result.SamplesMs = append(result.SamplesMs, latency)
+	}
+	if len(result.SamplesMs) == 0 {
+		return nil, errors.New("no frame handled any of the injected taps")
+	}
+	result.P50Ms = stats.Percentile(result.SamplesMs, 50)
+	result.P95Ms = stats.Percentile(result.SamplesMs, 95)
+	return result, nil
+}
+
+// measureTap returns false when no frame processed input after the tap, e.g. because the
+// component does not react to touches. presented is false when the device reported no present
+// time and the latency runs to the frame's completion instead.
+func measureTap(ctx context.Context, adbPath, deviceID, displayID, packageName, x, y string) (latencyMs float64, presented, ok bool, err error) {
+	if _, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "gfxinfo", packageName, "reset"); err != nil {
+		return 0, false, false, fmt.Errorf("reset gfxinfo: %w", err)
+	}
+	tap := []string{"shell", "input"}
+	if displayID != "" {
+		tap = append(tap, "-d", displayID)
+	}
+	if _, err := runADB(ctx, adbPath, deviceID, append(tap, "tap", x, y)...); err != nil {
+		return 0, false, false, fmt.Errorf("inject tap: %w", err)
+	}
+	select {
+	case <-ctx.Done():
+		return 0, false, false, ctx.Err()
+	case <-time.After(tapSettleDelay):
+	}
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "gfxinfo", packageName, "framestats")
+	if err != nil {
+		return 0, false, false, fmt.Errorf("dumpsys gfxinfo framestats: %w", err)
+	}
+	frames, err := parseFramestatsTimeline(out)
+	if err != nil {
+		return 0, false, false, err
+	}
+	for _, frame := range frames {
+		if frame.input <= 0 {
+			continue
+		}
+		if frame.present > frame.start {
+			return (frame.present - frame.start) / 1e6, true, true, nil
+		}
+		return (frame.end - frame.start) / 1e6, false, true, nil
+	}
+	return 0, false, false, nil
+}
+
+// screenCenter turns a WIDTHxHEIGHT size in the natural orientation into the coordinates of its
+// centre on a display turned by rotation quarter turns, which swaps the axes in landscape.
+func screenCenter(size string, rotation int) (string, string, error) {
+	width, height, ok := strings.Cut(size, "x")
+	w, wErr := strconv.Atoi(width)
+	h, hErr := strconv.Atoi(height)
+	if !ok || wErr != nil || hErr != nil {
+		return "", "", fmt.Errorf("unexpected screen size %q", size)
+	}
+	if rotation%2 == 1 {
+		w, h = h, w
+	}
+	return strconv.Itoa(w / 2), strconv.Itoa(h / 2), nil
+}
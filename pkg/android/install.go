@@ -0,0 +1,203 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// installHeartbeat is how long Gradle may stay quiet before Install reports that it is still running.
+const installHeartbeat = 30 * time.Second
+
+var (
+	// gradleTasksRe matches Gradle's closing summary, e.g. "45 actionable tasks: 3 executed, 42 up-to-date".
+	gradleTasksRe   = regexp.MustCompile(`^\d+ actionable tasks?: (.+)$`)
+	gradleOutcomeRe = regexp.MustCompile(`(\d+) (executed|from cache|up-to-date)`)
+)
+
+// InstallConfig describes how to build the app with the project's Gradle wrapper and install it.
+type InstallConfig struct {
+	// ProjectDir is the directory holding gradlew.
+	ProjectDir string
+	// Task is the install task, e.g. :app:installRelease.
+	Task string
+	// GradleArgs are passed to Gradle after the task, e.g. --offline or -Pvariant=benchmark.
+	GradleArgs []string
+	DeviceID   string
+	// Output receives Gradle's output as it runs, and a heartbeat line whenever Gradle is quiet.
+	Output  io.Writer
+	OnEvent events.Handler
+}
+
+// Install runs the Gradle install task. Gradle installs on every connected device unless
+// ANDROID_SERIAL names one, so DeviceID is passed that way. The build is bounded by ctx alone, so
+// callers give it a deadline of its own rather than the benchmark's. The returned record tells how
+// much of the build Gradle took from its caches.
+func Install(ctx context.Context, cfg InstallConfig) (*report.InstallRecord, error) {
+	if cfg.ProjectDir == "" || cfg.Task == "" {
+		return nil, errors.New("a Gradle project and an install task are required to install")
+	}
+	ctx = events.WithHandler(ctx, "android", cfg.OnEvent)
+	events.Phase(ctx, "build")
+	record := &report.InstallRecord{Tool: "gradle", Task: cfg.Task}
+	started := time.Now()
+	err := runGradle(ctx, cfg, func(line string) { parseGradleOutcome(line, record) })
+	if err != nil {
+		return nil, err
+	}
+	record.DurationMs = float64(time.Since(started)) / float64(time.Millisecond)
+	return record, nil
+}
+
+// runGradle runs cfg.Task through the project's wrapper, streaming its output and passing each line
+// to onLine, which may be nil.
+func runGradle(ctx context.Context, cfg InstallConfig, onLine func(string)) error {
+	output := cfg.Output
+	if output == nil {
+		output = io.Discard
+	}
+	gradlew := filepath.Join(cfg.ProjectDir, "gradlew")
+	args := append([]string{cfg.Task}, cfg.GradleArgs...)
+
+	cmd := exec.CommandContext(ctx, gradlew, args...)
+	cmd.Dir = cfg.ProjectDir
+	cmd.Env = os.Environ()
+	if cfg.DeviceID != "" {
+		cmd.Env = append(cmd.Env, "ANDROID_SERIAL="+cfg.DeviceID)
+	}
+	// Gradle's daemon can outlive a killed wrapper while holding the pipe open.
+	cmd.WaitDelay = 5 * time.Second
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	started := time.Now()
+	if err := cmd.Start(); err != nil {
+		events.Command(ctx, gradlew, args, started, err)
+		return fmt.Errorf("start %s: %w", gradlew, err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		writer.Close()
+		done <- err
+	}()
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		// Keep draining after an overlong line so that Gradle never blocks on a full pipe.
+		_, _ = io.Copy(io.Discard, reader)
+	}()
+
+	quiet := time.NewTimer(installHeartbeat)
+	defer quiet.Stop()
+	var tail []string
+	for lines != nil {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				lines = nil
+				continue
+			}
+			fmt.Fprintln(output, line)
+			if onLine != nil {
+				onLine(line)
+			}
+			tail = append(tail, line)
+			if len(tail) > 20 {
+				tail = tail[1:]
+			}
+			quiet.Reset(installHeartbeat)
+		case <-quiet.C:
+			fmt.Fprintf(output, "gradle %s still running (%s elapsed)\n", cfg.Task, time.Since(started).Round(time.Second))
+			quiet.Reset(installHeartbeat)
+		}
+	}
+	err := <-done
+	events.Command(ctx, gradlew, args, started, err)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("gradle %s did not finish before the install timeout; raise --install-timeout", cfg.Task)
+	}
+	if err != nil {
+		return fmt.Errorf("gradle %s: %w: %s", cfg.Task, err, strings.Join(tail, "\n"))
+	}
+	return nil
+}
+
+// parseGradleOutcome records what a line of Gradle output says about its caches: the configuration
+// cache being reused or stored, and the closing task summary. A build whose tasks all ran is clean,
+// one whose tasks were all up-to-date or from the build cache is cached. The install task itself
+// always executes, so it does not count as building.
+func parseGradleOutcome(line string, record *report.InstallRecord) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "Reusing configuration cache"):
+		record.ConfigurationCache = "reused"
+	case strings.HasPrefix(line, "Configuration cache entry stored"):
+		record.ConfigurationCache = "stored"
+	}
+	match := gradleTasksRe.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	record.TasksExecuted, record.TasksFromCache, record.TasksUpToDate = 0, 0, 0
+	for _, outcome := range gradleOutcomeRe.FindAllStringSubmatch(match[1], -1) {
+		count, _ := strconv.Atoi(outcome[1])
+		switch outcome[2] {
+		case "executed":
+			record.TasksExecuted = count
+		case "from cache":
+			record.TasksFromCache = count
+		case "up-to-date":
+			record.TasksUpToDate = count
+		}
+	}
+	switch {
+	case record.TasksExecuted <= 1:
+		record.Cache = report.BuildCacheCached
+	case record.TasksFromCache+record.TasksUpToDate == 0:
+		record.Cache = report.BuildCacheClean
+	default:
+		record.Cache = report.BuildCachePartial
+	}
+}
+
+// InstallAPK installs a prebuilt APK with `adb install -r`, for pipelines that build the app on
+// another machine. -r keeps the app's data, as a Gradle install does.
+func InstallAPK(ctx context.Context, adbPath, deviceID, apkPath string, onEvent events.Handler) (*report.InstallRecord, error) {
+	if adbPath == "" {
+		adbPath = "adb"
+	}
+	ctx = events.WithHandler(ctx, "android", onEvent)
+	events.Phase(ctx, "install")
+	started := time.Now()
+	out, err := runADB(ctx, adbPath, deviceID, "install", "-r", apkPath)
+	if err != nil {
+		return nil, fmt.Errorf("adb install %s: %w", apkPath, err)
+	}
+	// Older adb versions exit zero and report the failure, e.g. INSTALL_FAILED_UPDATE_INCOMPATIBLE.
+	if !strings.Contains(out, "Success") {
+		return nil, fmt.Errorf("adb install %s: %s", apkPath, strings.TrimSpace(out))
+	}
+	return &report.InstallRecord{
+		Tool:       "adb",
+		Task:       "install -r " + filepath.Base(apkPath),
+		DurationMs: float64(time.Since(started)) / float64(time.Millisecond),
+	}, nil
+}
@@ -0,0 +1,50 @@
+package android
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// logcatTimeFormat is the device `date` format accepted by `logcat -T` on every Android release.
+const logcatTimeFormat = "+%m-%d %H:%M:%S.000"
+
+// deviceLogcatTime reads the device clock so the capture window starts from the device's own time
+// rather than the host's, which may be skewed.
+func deviceLogcatTime(ctx context.Context, adbPath, deviceID string) (string, error) {
+	// adb shell re-splits its arguments on the device, so the format must be quoted.
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "date", "'"+logcatTimeFormat+"'")
+	if err != nil {
+		return "", fmt.Errorf("read device time: %w", err)
+	}
+	since := strings.TrimSpace(out)
+	if since == "" {
+		return "", fmt.Errorf("read device time: empty output")
+	}
+	return since, nil
+}
+
+// captureLogcat dumps log lines written since the given device time and writes them to hostPath.
+// Lines are limited to pid when it is known; otherwise the whole window is kept, which is what
+// explains a run where the app died before its pid could be read.
+func captureLogcat(ctx context.Context, adbPath, deviceID, since, pid, hostPath string) error {
+	args := []string{"logcat", "-d", "-v", "threadtime", "-T", since}
+	if pid != "" {
+		args = append(args, "--pid="+pid)
+	}
+	out, err := runADB(ctx, adbPath, deviceID, args...)
+	if err != nil {
+		return fmt.Errorf("capture logcat: %w", err)
+	}
+	if dir := filepath.Dir(hostPath); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create logcat directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(hostPath, []byte(out), 0o644); err != nil {
+		return fmt.Errorf("write logcat: %w", err)
+	}
+	return nil
+}
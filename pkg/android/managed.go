@@ -0,0 +1,187 @@
+package android
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// managedDevicePort is the console port of the emulator DesignBench boots for a managed device. It
+// gives the adb serial emulator-5580, clear of emulators started from Android Studio at 5554 up.
+const managedDevicePort = 5580
+
+// ManagedDeviceConfig describes a Gradle Managed Device to boot for a run.
+type ManagedDeviceConfig struct {
+	// ProjectDir is the directory holding gradlew.
+	ProjectDir string
+	// SetupTask downloads the system image and creates the device's AVD, e.g. :app:pixel6Api34Setup.
+	SetupTask  string
+	GradleArgs []string
+	// Device and APILevel come from the managed device's declaration and identify the AVD that
+	// SetupTask creates.
+	Device   string
+	APILevel int
+	ADBPath  string
+	// Output receives the setup task's output as it runs.
+	Output  io.Writer
+	OnEvent events.Handler
+}
+
+// ManagedDevice is a booted Gradle Managed Device emulator. Stop shuts it down.
+type ManagedDevice struct {
+	Serial  string
+	adbPath string
+	cmd     *exec.Cmd
+	exited  chan struct{}
+}
+
+// StartManagedDevice runs the managed device's Gradle setup task, boots its AVD headless with the
+// SDK emulator and waits until Android has finished booting. Gradle only keeps managed devices up
+// for its own test tasks, so DesignBench runs the emulator itself.
+func StartManagedDevice(ctx context.Context, cfg ManagedDeviceConfig) (*ManagedDevice, error) {
+	ctx = events.WithHandler(ctx, "android", cfg.OnEvent)
+	adb := cfg.ADBPath
+	if adb == "" {
+		adb = "adb"
+	}
+	events.Phase(ctx, "managed-device-setup")
+	setup := InstallConfig{ProjectDir: cfg.ProjectDir, Task: cfg.SetupTask, GradleArgs: cfg.GradleArgs, Output: cfg.Output}
+	if err := runGradle(ctx, setup, nil); err != nil {
+		return nil, err
+	}
+	avdHome := managedAVDHome()
+	avd, err := findManagedAVD(avdHome, cfg.Device, cfg.APILevel)
+	if err != nil {
+		return nil, err
+	}
+	events.Phase(ctx, "managed-device-boot")
+	return bootEmulator(ctx, adb, avdHome, avd)
+}
+
+// bootEmulator boots avd from avdHome headless and waits until Android has finished booting.
+func bootEmulator(ctx context.Context, adb, avdHome, avd string) (*ManagedDevice, error) {
+	serial := fmt.Sprintf("emulator-%d", managedDevicePort)
+	if out, err := runADB(ctx, adb, "", "devices"); err == nil && strings.Contains(out, serial) {
+		return nil, fmt.Errorf("%s is already running; stop it before DesignBench boots an emulator", serial)
+	}
+	// The emulator must outlive ctx, which only bounds the boot, so Stop ends it instead.
+	cmd := exec.Command(emulatorPath(), "-avd", avd, "-port", strconv.Itoa(managedDevicePort),
+		"-no-window", "-no-audio", "-no-boot-anim", "-no-snapshot-save", "-read-only")
+	cmd.Env = append(os.Environ(), "ANDROID_AVD_HOME="+avdHome)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start emulator: %w", err)
+	}
+	device := &ManagedDevice{Serial: serial, adbPath: adb, cmd: cmd, exited: make(chan struct{})}
+	go func() {
+		_ = cmd.Wait()
+		close(device.exited)
+	}()
+	if err := device.waitForBoot(ctx); err != nil {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		device.Stop(stopCtx)
+		return nil, err
+	}
+	return device, nil
+}
+
+// waitForBoot polls sys.boot_completed until Android reports that it has booted.
+func (d *ManagedDevice) waitForBoot(ctx context.Context) error {
+	return waitForBootCompleted(ctx, d.adbPath, d.Serial, d.exited)
+}
+
+// waitForBootCompleted polls sys.boot_completed on serial until Android reports that it has booted.
+// exited, when not nil, is closed if the emulator process ends first.
+func waitForBootCompleted(ctx context.Context, adb, serial string, exited <-chan struct{}) error {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		out, err := runADB(ctx, adb, serial, "shell", "getprop", "sys.boot_completed")
+		if err == nil && strings.TrimSpace(out) == "1" {
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("emulator for %s exited before it finished booting", serial)
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%s did not finish booting before the install timeout; raise --install-timeout", serial)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop asks the emulator to shut down and kills it if it is still running after 10 seconds.
+func (d *ManagedDevice) Stop(ctx context.Context) {
+	_, _ = runADB(ctx, d.adbPath, d.Serial, "emu", "kill")
+	select {
+	case <-d.exited:
+	case <-time.After(10 * time.Second):
+		_ = d.cmd.Process.Kill()
+		<-d.exited
+	}
+}
+
+// managedAVDHome is where Gradle creates managed device AVDs: avd/gradle-managed under the Android
+// user home.
+func managedAVDHome() string {
+	return filepath.Join(androidUserHome(), "avd", "gradle-managed")
+}
+
+// defaultAVDHome is where the SDK tools keep other AVDs: $ANDROID_AVD_HOME, or avd under the Android user
+// home.
+func defaultAVDHome() string {
+	if home := os.Getenv("ANDROID_AVD_HOME"); home != "" {
+		return home
+	}
+	return filepath.Join(androidUserHome(), "avd")
+}
+
+func androidUserHome() string {
+	home := os.Getenv("ANDROID_USER_HOME")
+	if home == "" {
+		if user, err := os.UserHomeDir(); err == nil {
+			home = filepath.Join(user, ".android")
+		}
+	}
+	return home
+}
+
+// findManagedAVD finds the AVD Gradle created for device at apiLevel. Gradle names them
+// dev<api>_<source>_<abi>_<device>, e.g. dev34_aosp_x86_64_Pixel_6.
+func findManagedAVD(avdHome, device string, apiLevel int) (string, error) {
+	inis, _ := filepath.Glob(filepath.Join(avdHome, "*.ini"))
+	prefix := fmt.Sprintf("dev%d_", apiLevel)
+	suffix := "_" + strings.ReplaceAll(device, " ", "_")
+	for _, ini := range inis {
+		name := strings.TrimSuffix(filepath.Base(ini), ".ini")
+		if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no managed device AVD for %s (API %d) in %s", device, apiLevel, avdHome)
+}
+
+// emulatorPath prefers the emulator in the Android SDK, which is rarely on PATH.
+func emulatorPath() string {
+	for _, env := range []string{"ANDROID_HOME", "ANDROID_SDK_ROOT"} {
+		if sdk := os.Getenv(env); sdk != "" {
+			path := filepath.Join(sdk, "emulator", "emulator")
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return "emulator"
+}
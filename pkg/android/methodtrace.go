@@ -0,0 +1,66 @@
+package android
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deviceMethodTraceDir is writable by the shell user, which opens the profiler output for am.
+const deviceMethodTraceDir = "/data/local/tmp"
+
+// methodTraceSamplingMicros samples the call stacks every millisecond instead of instrumenting every
+// method call, which would slow the launch down many times over.
+const methodTraceSamplingMicros = 1000
+
+// methodTraceFlushTimeout bounds how long the runtime gets to finish writing the trace file.
+const methodTraceFlushTimeout = 10 * time.Second
+
+func newMethodTraceDevicePath() string {
+	return fmt.Sprintf("%s/designbench-%d.trace", deviceMethodTraceDir, time.Now().UnixNano())
+}
+
+// methodTraceArgs are the `am start` options that start method tracing together with the process.
+func methodTraceArgs(devicePath string) []string {
+	return []string{"--start-profiler", devicePath, "--sampling", strconv.Itoa(methodTraceSamplingMicros)}
+}
+
+// stopMethodTrace stops the profiler started by `am start --start-profiler`, waits until the trace
+// file stops growing and pulls it to hostPath.
+func stopMethodTrace(ctx context.Context, adbPath, deviceID, packageName, devicePath, hostPath string) error {
+	if _, err := runADB(ctx, adbPath, deviceID, "shell", "am", "profile", "stop", packageName); err != nil {
+		return fmt.Errorf("stop method trace: %w", err)
+	}
+	deadline := time.Now().Add(methodTraceFlushTimeout)
+	lastSize := ""
+	for {
+		out, err := runADB(ctx, adbPath, deviceID, "shell", "stat", "-c", "%s", devicePath)
+		size := strings.TrimSpace(out)
+		if err == nil && size != "0" && size == lastSize {
+			break
+		}
+		lastSize = size
+		if time.Now().After(deadline) {
+			return fmt.Errorf("method trace %s was not written within %s (the app must be debuggable or profileable by the shell)", devicePath, methodTraceFlushTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	if dir := filepath.Dir(hostPath); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create method trace directory: %w", err)
+		}
+	}
+	if _, err := runADB(ctx, adbPath, deviceID, "pull", devicePath, hostPath); err != nil {
+		return fmt.Errorf("pull method trace: %w", err)
+	}
+	_, _ = runADB(ctx, adbPath, deviceID, "shell", "rm", "-f", devicePath)
+	return nil
+}
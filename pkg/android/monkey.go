@@ -0,0 +1,197 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+const (
+	// monkeySampleInterval is how often memory and CPU are sampled while monkey runs.
+	monkeySampleInterval = 2 * time.Second
+	// monkeyThrottle is the delay between injected events in milliseconds.
+	monkeyThrottle = "50"
+	// monkeySeed keeps the event sequence identical across iterations and runs.
+	monkeySeed = "1"
+)
+
+type monkeyOutput struct {
+	injected int
+	crashes  int
+	anrs     int
+}
+
+// runMonkey injects pseudo-random events into packageName and samples its memory and CPU until
+// monkey finishes. Crashes and ANRs are counted rather than aborting the run. Jank comes from the
+// frame counters of `dumpsys gfxinfo`, reset before monkey starts and read once it finishes.
+func runMonkey(ctx context.Context, adbPath, deviceID, packageName string, eventCount int) (*report.MonkeyMetrics, error) {
+	_, resetErr := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "gfxinfo", packageName, "reset")
+
+	samplerCtx, stopSampler := context.WithCancel(ctx)
+	samples := make(chan monkeyResourceSamples, 1)
+	go func() {
+		samples <- sampleMonkeyResources(samplerCtx, adbPath, deviceID, packageName)
+	}()
+
+	started := time.Now()
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "monkey",
+		"-p", packageName,
+		"-s", monkeySeed,
+		"--throttle", monkeyThrottle,
+		"--pct-syskeys", "0",
+		"--ignore-crashes", "--ignore-timeouts", "--ignore-security-exceptions",
+		"-v", strconv.Itoa(eventCount))
+	elapsed := time.Since(started)
+	stopSampler()
+	resources := <-samples
+	var gfx gfxInfoCounts
+	var gfxErr error
+	if resetErr == nil {
+		gfx, gfxErr = collectGfxInfoCounts(ctx, adbPath, deviceID, packageName)
+	}
+
+	parsed, parseErr := parseMonkeyOutput(out)
+	if err != nil && parseErr != nil {
+		return nil, fmt.Errorf("run monkey: %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	metrics := &report.MonkeyMetrics{
+		Events:         eventCount,
+		EventsInjected: parsed.injected,
+		Crashes:        parsed.crashes,
+		ANRs:           parsed.anrs,
+		DurationMs:     float64(elapsed) / float64(time.Millisecond),
+		Samples:        len(resources.memoryMB),
+	}
+	if resetErr == nil && gfxErr == nil {
+		metrics.TotalFrames = gfx.total
+		metrics.JankyFrames = gfx.janky
+	}
+	for _, mb := range resources.memoryMB {
+		metrics.PeakMemoryMB = max(metrics.PeakMemoryMB, mb)
+	}
+	if len(resources.cpuPercent) > 0 {
+		total := 0.0
+		for _, pct := range resources.cpuPercent {
+			total += pct
+		}
+		metrics.MeanCPUPercent = total / float64(len(resources.cpuPercent))
+	}
+	return metrics, nil
+}
+
+type monkeyResourceSamples struct {
+	memoryMB   []float64
+	cpuPercent []float64
+}
+
+// sampleMonkeyResources polls until ctx is cancelled. The PID is looked up on every sample because
+// the app restarts after a crash.
+func sampleMonkeyResources(ctx context.Context, adbPath, deviceID, packageName string) monkeyResourceSamples {
+	var samples monkeyResourceSamples
+	ticker := time.NewTicker(monkeySampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return samples
+		case <-ticker.C:
+		}
+		if mb, err := collectMemoryUsage(ctx, adbPath, deviceID, packageName); err == nil {
+			samples.memoryMB = append(samples.memoryMB, mb)
+		}
+		if pid, err := resolveAndroidPID(ctx, adbPath, deviceID, packageName); err == nil {
+			if pct, err := androidCPUPercent(ctx, adbPath, deviceID, pid, packageName); err == nil {
+				samples.cpuPercent = append(samples.cpuPercent, pct)
+			}
+		}
+	}
+}
+
+type gfxInfoCounts struct {
+	total int
+	janky int
+}
+
+// collectGfxInfoCounts reads the frame counters that `dumpsys gfxinfo` keeps since its last reset.
+// They belong to the running process, so a crash during monkey restarts the count.
+func collectGfxInfoCounts(ctx context.Context, adbPath, deviceID, packageName string) (gfxInfoCounts, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "gfxinfo", packageName)
+	if err != nil {
+		return gfxInfoCounts{}, fmt.Errorf("dumpsys gfxinfo: %w", err)
+	}
+	return parseGfxInfoCounts(out)
+}
+
+// parseGfxInfoCounts reads the `Total frames rendered: 120` and `Janky frames: 7 (5.83%)` lines of
+// `dumpsys gfxinfo <package>`.
+func parseGfxInfoCounts(output string) (gfxInfoCounts, error) {
+	var counts gfxInfoCounts
+	found := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "Total frames rendered":
+			counts.total = n
+			found = true
+		case "Janky frames":
+			counts.janky = n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return gfxInfoCounts{}, err
+	}
+	if !found {
+		return gfxInfoCounts{}, errors.New("gfxinfo did not report rendered frames")
+	}
+	return counts, nil
+}
+
+// parseMonkeyOutput reads the verbose monkey log, where failures appear as
+// `// CRASH: com.example.app (pid 1234)` and `// NOT RESPONDING: com.example.app (pid 1234)`.
+func parseMonkeyOutput(output string) (monkeyOutput, error) {
+	var parsed monkeyOutput
+	found := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "// CRASH:"):
+			parsed.crashes++
+		case strings.HasPrefix(line, "// NOT RESPONDING:"):
+			parsed.anrs++
+		case strings.HasPrefix(line, "Events injected:"):
+			if n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Events injected:"))); err == nil {
+				parsed.injected = n
+				found = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return monkeyOutput{}, err
+	}
+	if !found {
+		return monkeyOutput{}, errors.New("monkey did not report injected events")
+	}
+	return parsed, nil
+}
@@ -0,0 +1,74 @@
+package android
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Navigation drives an app without a harness activity from its launch screen to the component
+// under test: an optional deep link, then uiautomator steps, e.g.
+//
+//	deepLink: myapp://catalog/buttons
+//	steps:
+//	  - tap: {contentDesc: Primary button}
+//	  - waitFor: {resourceId: com.example.app:id/button_preview}
+type Navigation struct {
+	DeepLink string            `yaml:"deepLink,omitempty"`
+	Steps    []InteractionStep `yaml:"steps,omitempty"`
+}
+
+// LoadNavigation reads and validates a YAML navigation file.
+func LoadNavigation(path string) (*Navigation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var nav Navigation
+	if err := yaml.Unmarshal(data, &nav); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if nav.DeepLink == "" && len(nav.Steps) == 0 {
+		return nil, fmt.Errorf("%s: needs a deepLink or steps", path)
+	}
+	// The link is single-quoted for the device shell, which re-splits adb's arguments.
+	if strings.Contains(nav.DeepLink, "'") {
+		return nil, fmt.Errorf("%s: deepLink cannot contain a single quote", path)
+	}
+	for i, step := range nav.Steps {
+		if err := step.validate(); err != nil {
+			return nil, fmt.Errorf("%s: step %d: %w", path, i+1, err)
+		}
+	}
+	return &nav, nil
+}
+
+// navigate opens nav's deep link in packageName and runs its steps, returning how long it took to
+// reach the component.
+func navigate(ctx context.Context, adbPath, deviceID, packageName string, nav *Navigation) (float64, error) {
+	started := time.Now()
+	if nav.DeepLink != "" {
+		out, err := runADB(ctx, adbPath, deviceID, "shell", "am", "start", "-W",
+			"-a", "android.intent.action.VIEW", "-d", "'"+nav.DeepLink+"'", packageName)
+		if err != nil {
+			return 0, fmt.Errorf("open %s: %w", nav.DeepLink, err)
+		}
+		// am reports an unresolved intent on stdout and still exits zero.
+		if strings.Contains(out, "Error:") {
+			return 0, fmt.Errorf("open %s: %s", nav.DeepLink, strings.TrimSpace(out))
+		}
+	}
+	for i, step := range nav.Steps {
+		if err := runInteractionStep(ctx, adbPath, deviceID, step); err != nil {
+			return 0, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	return float64(time.Since(started)) / float64(time.Millisecond), nil
+}
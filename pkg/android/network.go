@@ -0,0 +1,136 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// networkCounters holds cumulative per-UID traffic counters.
+type networkCounters struct {
+	rxBytes int64
+	txBytes int64
+}
+
+func resolvePackageUID(ctx context.Context, adbPath, deviceID, packageName string) (string, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "pm", "list", "packages", "-U", packageName)
+	if err != nil {
+		return "", fmt.Errorf("pm list packages: %w", err)
+	}
+	return parsePackageUID(out, packageName)
+}
+
+func parsePackageUID(output, packageName string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "package:"+packageName {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if uid, ok := strings.CutPrefix(field, "uid:"); ok {
+				// Multi-user devices may report a comma-separated list; the primary user comes first.
+				uid, _, _ = strings.Cut(uid, ",")
+				return uid, nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("uid for %s not found", packageName)
+}
+
+// errNoTrafficHistory reports that the UID has not used the network yet, so its counters are zero.
+var errNoTrafficHistory = errors.New("uid not present in netstats output")
+
+// captureNetworkCounters reads traffic counters from xt_qtaguid when the kernel still exposes it
+// (Android 9 and below) and falls back to `dumpsys netstats` on newer releases.
+func captureNetworkCounters(ctx context.Context, adbPath, deviceID, uid string) (networkCounters, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "cat", "/proc/net/xt_qtaguid/stats")
+	if err == nil {
+		if counters, parseErr := parseQtaguidStats(out, uid); parseErr == nil {
+			return counters, nil
+		}
+	}
+	netstats, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "netstats", "detail")
+	if err != nil {
+		return networkCounters{}, fmt.Errorf("dumpsys netstats: %w", err)
+	}
+	return parseNetstatsUID(netstats, uid)
+}
+
+// parseQtaguidStats sums untagged rows for uid; columns are
+// `idx iface acct_tag_hex uid_tag_int cnt_set rx_bytes rx_packets tx_bytes ...`.
+func parseQtaguidStats(output, uid string) (networkCounters, error) {
+	var counters networkCounters
+	found := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[3] != uid || fields[2] != "0x0" {
+			continue
+		}
+		rx, rxErr := strconv.ParseInt(fields[5], 10, 64)
+		tx, txErr := strconv.ParseInt(fields[7], 10, 64)
+		if rxErr != nil || txErr != nil {
+			continue
+		}
+		counters.rxBytes += rx
+		counters.txBytes += tx
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return networkCounters{}, err
+	}
+	if !found {
+		return networkCounters{}, errors.New("uid not present in qtaguid stats")
+	}
+	return counters, nil
+}
+
+// parseNetstatsUID sums the `rb=`/`tb=` history buckets that follow each untagged `uid=<uid>` identity.
+func parseNetstatsUID(output, uid string) (networkCounters, error) {
+	var counters networkCounters
+	found := false
+	inUID := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "ident=") {
+			inUID = strings.Contains(line, " uid="+uid+" ") && strings.Contains(line, "tag=0x0")
+			continue
+		}
+		if !inUID || !strings.HasPrefix(line, "st=") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rb":
+				counters.rxBytes += n
+				found = true
+			case "tb":
+				counters.txBytes += n
+				found = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return networkCounters{}, err
+	}
+	if !found {
+		return networkCounters{}, errNoTrafficHistory
+	}
+	return counters, nil
+}
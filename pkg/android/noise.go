@@ -0,0 +1,57 @@
+package android
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// noiseProcesses is how many of the busiest processes a background-noise snapshot keeps.
+const noiseProcesses = 5
+
+// captureBackgroundNoise lists the processes using the most CPU on the device, so that an outlier
+// iteration can be traced to e.g. a Play Services sync running alongside it.
+func captureBackgroundNoise(ctx context.Context, adbPath, deviceID string) ([]report.ProcessCPU, error) {
+	// One more than kept, since top lists itself.
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "top", "-b", "-n", "1", "-m", strconv.Itoa(noiseProcesses+1), "-o", "PID,%CPU,NAME")
+	if err != nil {
+		return nil, fmt.Errorf("top: %w", err)
+	}
+	return parseTopProcesses(out, noiseProcesses), nil
+}
+
+// parseTopProcesses reads toybox top's batch output, whose column header marks the sort column,
+// e.g. `  PID [%CPU] NAME`, and returns the busiest limit processes other than top itself.
+func parseTopProcesses(output string, limit int) []report.ProcessCPU {
+	var processes []report.ProcessCPU
+	header := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(strings.NewReplacer("[", " ", "]", " ").Replace(line))
+		if !header {
+			header = len(fields) >= 3 && fields[0] == "PID" && fields[1] == "%CPU"
+			continue
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		cpu, err := strconv.ParseFloat(fields[1], 64)
+		name := strings.Join(fields[2:], " ")
+		if err != nil || cpu <= 0 || name == "top" {
+			continue
+		}
+		processes = append(processes, report.ProcessCPU{PID: pid, Name: name, CPUPercent: cpu})
+	}
+	sort.SliceStable(processes, func(i, j int) bool { return processes[i].CPUPercent > processes[j].CPUPercent })
+	if len(processes) > limit {
+		processes = processes[:limit]
+	}
+	return processes
+}
@@ -0,0 +1,201 @@
+package android
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// OSTarget is the device that runs one API level of an OS matrix: a connected device already at
+// that level, or an emulator booted from an AVD for it. Stop shuts the emulator down again.
+type OSTarget struct {
+	APILevel int
+	Serial   string
+	// emulator is the emulator booted for the target, nil when a connected device was used.
+	emulator *ManagedDevice
+}
+
+// Label names the target's OS in reports, e.g. "API 34".
+func (t *OSTarget) Label() string {
+	return fmt.Sprintf("API %d", t.APILevel)
+}
+
+// ParseAPILevel reads an OS matrix entry such as "34", "API 34" or "android-34".
+func ParseAPILevel(value string) (int, error) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	for _, prefix := range []string{"android-", "api-", "api"} {
+		if rest, ok := strings.CutPrefix(v, prefix); ok {
+			v = rest
+			break
+		}
+	}
+	level, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || level < 1 {
+		return 0, fmt.Errorf("invalid API level %q (expected e.g. 34 or API 34)", value)
+	}
+	return level, nil
+}
+
+// StartOSTarget returns a device running apiLevel: preferred if it does, else another connected
+// device that does, else an emulator booted headless from the first AVD, by name, for that level.
+func StartOSTarget(ctx context.Context, adbPath, preferred string, apiLevel int, onEvent events.Handler) (*OSTarget, error) {
+	ctx = events.WithHandler(ctx, "android", onEvent)
+	if adbPath == "" {
+		adbPath = "adb"
+	}
+	serials, err := connectedDevices(ctx, adbPath)
+	if err != nil {
+		return nil, err
+	}
+	for i, serial := range serials {
+		if serial == preferred {
+			serials[0], serials[i] = serials[i], serials[0]
+		}
+	}
+	for _, serial := range serials {
+		if level, err := deviceAPILevel(ctx, adbPath, serial); err == nil && level == apiLevel {
+			return &OSTarget{APILevel: apiLevel, Serial: serial}, nil
+		}
+	}
+	home := defaultAVDHome()
+	avd, err := findAVD(home, apiLevel)
+	if err != nil {
+		return nil, err
+	}
+	events.Phase(ctx, "os-target-boot")
+	device, err := bootEmulator(ctx, adbPath, home, avd)
+	if err != nil {
+		return nil, fmt.Errorf("boot %s for API %d: %w", avd, apiLevel, err)
+	}
+	return &OSTarget{APILevel: apiLevel, Serial: device.Serial, emulator: device}, nil
+}
+
+// Stop shuts down the emulator booted for the target; connected devices are left alone.
+func (t *OSTarget) Stop(ctx context.Context) {
+	if t.emulator != nil {
+		t.emulator.Stop(ctx)
+	}
+}
+
+// connectedDevices lists the serials of the devices `adb devices -l` reports as ready.
+func connectedDevices(ctx context.Context, adbPath string) ([]string, error) {
+	out, err := runADB(ctx, adbPath, "", "devices", "-l")
+	if err != nil {
+		return nil, fmt.Errorf("list devices: %w", err)
+	}
+	var serials []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == "device" {
+			serials = append(serials, fields[0])
+		}
+	}
+	return serials, nil
+}
+
+func deviceAPILevel(ctx context.Context, adbPath, serial string) (int, error) {
+	out, err := runADB(ctx, adbPath, serial, "shell", "getprop", "ro.build.version.sdk")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// findAVD returns the first AVD in home, by name, whose <name>.ini targets apiLevel, e.g.
+// target=android-34.
+func findAVD(home string, apiLevel int) (string, error) {
+	inis, _ := filepath.Glob(filepath.Join(home, "*.ini"))
+	want := fmt.Sprintf("android-%d", apiLevel)
+	for _, ini := range inis {
+		data, err := os.ReadFile(ini)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, ok := strings.Cut(line, "=")
+			if ok && strings.TrimSpace(key) == "target" && strings.TrimSpace(value) == want {
+				return strings.TrimSuffix(filepath.Base(ini), ".ini"), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no device running API %d is connected and no AVD for it is in %s; create one with avdmanager", apiLevel, home)
+}
+
+// CopyApp installs packageName on dst from the APKs, including splits, installed on src, unless dst
+// has it already. With no src it copies from the first other connected device that has the app.
+// It reports whether it installed the app.
+func CopyApp(ctx context.Context, adbPath, src, dst, packageName string, onEvent events.Handler) (bool, error) {
+	ctx = events.WithHandler(ctx, "android", onEvent)
+	if adbPath == "" {
+		adbPath = "adb"
+	}
+	if len(apkPaths(ctx, adbPath, dst, packageName)) > 0 {
+		return false, nil
+	}
+	sources := []string{src}
+	if src == "" {
+		serials, err := connectedDevices(ctx, adbPath)
+		if err != nil {
+			return false, err
+		}
+		sources = serials
+	}
+	var remotes []string
+	for _, serial := range sources {
+		if serial == dst {
+			continue
+		}
+		if remotes = apkPaths(ctx, adbPath, serial, packageName); len(remotes) > 0 {
+			src = serial
+			break
+		}
+	}
+	if len(remotes) == 0 {
+		return false, fmt.Errorf("%s is not installed on another device to copy to %s; install it there or pass --apk", packageName, dst)
+	}
+	dir, err := os.MkdirTemp("", "designbench-apks-")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(dir)
+	events.Phase(ctx, "os-target-install")
+	args := []string{"install-multiple", "-r"}
+	if len(remotes) == 1 {
+		args = []string{"install", "-r"}
+	}
+	for _, remote := range remotes {
+		local := filepath.Join(dir, filepath.Base(remote))
+		if _, err := runADB(ctx, adbPath, src, "pull", remote, local); err != nil {
+			return false, fmt.Errorf("pull %s: %w", remote, err)
+		}
+		args = append(args, local)
+	}
+	out, err := runADB(ctx, adbPath, dst, args...)
+	if err != nil {
+		return false, fmt.Errorf("install %s on %s: %w", packageName, dst, err)
+	}
+	if !strings.Contains(out, "Success") {
+		return false, fmt.Errorf("install %s on %s: %s", packageName, dst, strings.TrimSpace(out))
+	}
+	return true, nil
+}
+
+// apkPaths returns the paths of packageName's APKs on the device, none when it is not installed.
+func apkPaths(ctx context.Context, adbPath, serial, packageName string) []string {
+	out, err := runADB(ctx, adbPath, serial, "shell", "pm", "path", packageName)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, line := range strings.Split(out, "\n") {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(line), "package:"); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
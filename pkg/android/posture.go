@@ -0,0 +1,102 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Foldable postures accepted by SetPosture.
+const (
+	PostureFolded     = "folded"
+	PostureHalfOpened = "half-opened"
+	PostureUnfolded   = "unfolded"
+)
+
+// postureSettleDelay gives the system time to apply the configuration change before launching.
+const postureSettleDelay = time.Second
+
+var deviceStateRe = regexp.MustCompile(`identifier=(\d+), name='([^']+)'`)
+
+// postureStateNames lists device state names used by the platform and OEMs for each posture.
+var postureStateNames = map[string][]string{
+	PostureFolded:     {"CLOSED", "FOLDED"},
+	PostureHalfOpened: {"HALF_OPENED", "HALF_FOLDED", "TENT"},
+	PostureUnfolded:   {"OPENED", "UNFOLDED", "FLAT"},
+}
+
+// SetPosture switches a foldable (or emulated foldable) into the given posture using the device
+// state manager, falling back to the emulator console's fold/unfold commands. emulated reports
+// whether the fallback was used, which ResetPosture must know to undo it.
+func SetPosture(ctx context.Context, adbPath, deviceID, posture string) (emulated bool, err error) {
+	names, ok := postureStateNames[posture]
+	if !ok {
+		return false, fmt.Errorf("unknown posture %q (expected %s, %s or %s)", posture, PostureFolded, PostureHalfOpened, PostureUnfolded)
+	}
+	if adbPath == "" {
+		adbPath = "adb"
+	}
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "cmd", "device_state", "print-states")
+	if err == nil {
+		states := parseDeviceStates(out)
+		for _, name := range names {
+			if id, ok := states[name]; ok {
+				if _, err := runADB(ctx, adbPath, deviceID, "shell", "cmd", "device_state", "state", id); err != nil {
+					return false, fmt.Errorf("set device state %s: %w", name, err)
+				}
+				return false, waitForPosture(ctx)
+			}
+		}
+	}
+	switch posture {
+	case PostureFolded:
+		_, err = runADB(ctx, adbPath, deviceID, "emu", "fold")
+	case PostureUnfolded:
+		_, err = runADB(ctx, adbPath, deviceID, "emu", "unfold")
+	default:
+		return false, fmt.Errorf("device does not expose a %s posture", posture)
+	}
+	if err != nil {
+		return false, fmt.Errorf("device does not support posture %s: %w", posture, err)
+	}
+	return true, waitForPosture(ctx)
+}
+
+// ResetPosture returns the device state manager to the physical hinge state, or unfolds an
+// emulator whose posture SetPosture changed through the emulator console.
+func ResetPosture(ctx context.Context, adbPath, deviceID string, emulated bool) error {
+	if adbPath == "" {
+		adbPath = "adb"
+	}
+	if emulated {
+		_, err := runADB(ctx, adbPath, deviceID, "emu", "unfold")
+		return err
+	}
+	_, err := runADB(ctx, adbPath, deviceID, "shell", "cmd", "device_state", "state", "reset")
+	return err
+}
+
+func waitForPosture(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(postureSettleDelay):
+		return nil
+	}
+}
+
+// parseDeviceStates maps state names to identifiers from lines such as
+// `DeviceState{identifier=1, name='HALF_OPENED', app_accessible=true}`.
+func parseDeviceStates(output string) map[string]string {
+	states := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		for _, match := range deviceStateRe.FindAllStringSubmatch(scanner.Text(), -1) {
+			states[strings.ToUpper(match[2])] = match[1]
+		}
+	}
+	return states
+}
@@ -0,0 +1,109 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+const powerStatsService = "android.hardware.power.stats.IPowerStats/default"
+
+type powerChannel struct {
+	name      string
+	subsystem string
+}
+
+// powerSnapshot holds cumulative ODPM energy counters keyed by channel id.
+type powerSnapshot struct {
+	channels map[string]powerChannel
+	energyUJ map[string]float64
+}
+
+func capturePowerSnapshot(ctx context.Context, adbPath, deviceID string) (*powerSnapshot, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", powerStatsService)
+	if err != nil {
+		return nil, fmt.Errorf("dumpsys power stats: %w", err)
+	}
+	return parsePowerStats(out)
+}
+
+// parsePowerStats reads the PowerStats HAL dump, where channel descriptors look like
+// `ChannelId: 0, ChannelName: S2M_VDD_CPUCL2, ChannelSubsystem: CPU` and readings like
+// `ChannelId: 0, Timestamp(ms): 1234, Duration(ms): 1234, Energy(uWs): 5678`.
+func parsePowerStats(output string) (*powerSnapshot, error) {
+	snap := &powerSnapshot{
+		channels: make(map[string]powerChannel),
+		energyUJ: make(map[string]float64),
+	}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := parsePowerFields(scanner.Text())
+		id, ok := fields["ChannelId"]
+		if !ok {
+			continue
+		}
+		if name, ok := fields["ChannelName"]; ok {
+			snap.channels[id] = powerChannel{name: name, subsystem: fields["ChannelSubsystem"]}
+			continue
+		}
+		for key, value := range fields {
+			if !strings.HasPrefix(key, "Energy") {
+				continue
+			}
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				snap.energyUJ[id] = v
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(snap.energyUJ) == 0 {
+		return nil, errors.New("no power rail readings reported (device may not support ODPM)")
+	}
+	return snap, nil
+}
+
+func parsePowerFields(line string) map[string]string {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(line, ",") {
+		key, value, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return fields
+}
+
+// powerRailDeltas returns the energy consumed on each rail between two snapshots, ordered by rail name.
+func powerRailDeltas(before, after *powerSnapshot) []report.PowerRail {
+	rails := make([]report.PowerRail, 0, len(after.energyUJ))
+	for id, end := range after.energyUJ {
+		start, ok := before.energyUJ[id]
+		if !ok || end < start {
+			continue
+		}
+		channel := after.channels[id]
+		if channel.name == "" {
+			channel = before.channels[id]
+		}
+		name := channel.name
+		if name == "" {
+			name = "channel-" + id
+		}
+		rails = append(rails, report.PowerRail{
+			Name:      name,
+			Subsystem: channel.subsystem,
+			EnergyUJ:  end - start,
+		})
+	}
+	sort.Slice(rails, func(i, j int) bool { return rails[i].Name < rails[j].Name })
+	return rails
+}
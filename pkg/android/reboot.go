@@ -0,0 +1,30 @@
+package android
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// Reboot restarts the device and waits until Android has finished booting, so that a run starts
+// without caches, services or background work left over from earlier runs.
+func Reboot(ctx context.Context, adbPath, deviceID string, onEvent events.Handler) error {
+	ctx = events.WithHandler(ctx, "android", onEvent)
+	if adbPath == "" {
+		adbPath = "adb"
+	}
+	events.Phase(ctx, "reboot")
+	if _, err := runADB(ctx, adbPath, deviceID, "reboot"); err != nil {
+		return fmt.Errorf("adb reboot: %w", err)
+	}
+	// The device stays online for a moment after adb reboot returns, still reporting its last boot
+	// as completed, so wait for it to go away before waiting for it to come back.
+	if _, err := runADB(ctx, adbPath, deviceID, "wait-for-disconnect"); err != nil {
+		return fmt.Errorf("wait for the device to shut down: %w", err)
+	}
+	if _, err := runADB(ctx, adbPath, deviceID, "wait-for-device"); err != nil {
+		return fmt.Errorf("wait for the device to come back: %w", err)
+	}
+	return waitForBootCompleted(ctx, adbPath, deviceID, nil)
+}
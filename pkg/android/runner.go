@@ -6,12 +6,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/tahatesser/designbench/pkg/devices"
+	"github.com/tahatesser/designbench/pkg/events"
+	"github.com/tahatesser/designbench/pkg/harness"
+	"github.com/tahatesser/designbench/pkg/redact"
 	"github.com/tahatesser/designbench/pkg/report"
+	"github.com/tahatesser/designbench/pkg/watchdog"
 )
 
 // Config controls a single Android render benchmark invocation.
@@ -24,6 +28,66 @@ type Config struct {
 	LaunchArgs         []string
 	Timeout            time.Duration
 	BenchmarkComponent string
+	// Variant is the Gradle build variant of the installed app, e.g. benchmarkRelease, recorded in
+	// the report. Empty when unknown.
+	Variant string
+	// ColdStart force-stops the package before launching (`am start -S`) so repeated runs stay cold.
+	ColdStart bool
+	// DisplayID launches the activity on this logical display (`am start --display`), such as a
+	// secondary, desktop-mode or virtual display. Empty uses the default display.
+	DisplayID string
+	// ThemeSwitch measures re-rendering after toggling the system dark/light theme once launched.
+	ThemeSwitch bool
+	// TracePath, when set, records a Perfetto trace (including Compose composition tracing) around
+	// the launch and writes it to this host path. Tracing implies a cold start.
+	TracePath string
+	// TraceConfig is a Perfetto config in text format recorded instead of the built-in one, with
+	// {package} replaced by Package. Empty uses the built-in config.
+	TraceConfig string
+	// MethodTracePath, when set, launches with `am start --start-profiler`, stops the sampling
+	// profiler once the first frame is drawn and writes the .trace file to this host path. Method
+	// tracing implies a cold start, since the profiler starts with the process.
+	MethodTracePath string
+	// IdleCPUThreshold, when positive, waits before launching until device-wide CPU utilisation
+	// (percent) is at or below this value, for up to IdleTimeout (default 30s).
+	IdleCPUThreshold float64
+	IdleTimeout      time.Duration
+	// Settle is how long to wait after launch before snapshotting memory, CPU and frame stats.
+	Settle time.Duration
+	// LaunchTimeout bounds `am start -W`. A launch that takes longer is killed together with its
+	// children, the app is force-stopped, and Run returns a watchdog.HungError. Zero waits as long as
+	// the run's context allows.
+	LaunchTimeout time.Duration
+	// MonkeyEvents, when positive, injects this many pseudo-random events with `adb shell monkey`
+	// after launch, counting crashes and ANRs and sampling memory/CPU while it runs.
+	MonkeyEvents int
+	// Animation, when set, asks the harness to play the named animation after the first frame and
+	// measures its smoothness over AnimationDuration.
+	Animation         string
+	AnimationDuration time.Duration
+	// InputLatencyTaps, when positive, taps the component this many times once metrics are collected
+	// and records the latency of each tap's response frame.
+	InputLatencyTaps int
+	// StrictMode asks the harness to enable StrictMode and counts the violations it logs, by
+	// policy, from just before launch until metric collection finishes.
+	StrictMode bool
+	// LogcatPath, when set, saves the app's logcat output from just before launch until metric
+	// collection finishes to this host path.
+	LogcatPath string
+	// Navigation, when set, runs right after launch to reach the component in apps without a
+	// harness activity; its duration is reported as navigationMs.
+	Navigation *Navigation
+	// Flow, when set, opens its screens one after another after launch and Navigation, timing each
+	// transition.
+	Flow *Flow
+	// Interact, when set, runs right after launch to drive the app to the screen under test (for
+	// example through an Appium session) before metrics are collected.
+	Interact func(ctx context.Context) error
+	// AllowDebugBuild measures debuggable/Debug builds instead of refusing them; the report is
+	// tagged with buildType "debug" either way.
+	AllowDebugBuild bool
+	// OnEvent, when set, receives progress events (phases, commands, metrics) as the run proceeds.
+	OnEvent events.Handler
 }
 
 // Run executes a basic render benchmark using `adb shell am start -W` to capture launch timings.
@@ -35,6 +99,8 @@ func Run(ctx context.Context, cfg Config) (*report.AndroidMetrics, error) {
 		return nil, errors.New("android activity is required")
 	}
 
+	ctx = events.WithHandler(ctx, "android", cfg.OnEvent)
+
 	component := cfg.Component
 	if component == "" {
 		component = cfg.Activity
@@ -50,40 +116,338 @@ func Run(ctx context.Context, cfg Config) (*report.AndroidMetrics, error) {
 	if cfg.DeviceID != "" {
 		args = append(args, "-s", cfg.DeviceID)
 	}
-	args = append(args, "shell", "am", "start", "-W", componentArg)
+	args = append(args, "shell", "am", "start", "-W")
+	if cfg.ColdStart || cfg.TracePath != "" || cfg.MethodTracePath != "" {
+		args = append(args, "-S")
+	}
+	var methodTraceDevicePath string
+	if cfg.MethodTracePath != "" {
+		methodTraceDevicePath = newMethodTraceDevicePath()
+		args = append(args, methodTraceArgs(methodTraceDevicePath)...)
+	}
+	if cfg.DisplayID != "" {
+		args = append(args, "--display", cfg.DisplayID)
+	}
+	args = append(args, componentArg)
+	// adb shell re-splits its arguments on the device, so extra values are quoted for sh.
 	if cfg.BenchmarkComponent != "" {
-		args = append(args, "-e", "designbench_component", cfg.BenchmarkComponent)
+		args = append(args, "-e", "designbench_component", shellQuote(cfg.BenchmarkComponent))
+	}
+	if cfg.Animation != "" {
+		args = append(args, "-e", harness.AnimationExtra, shellQuote(cfg.Animation))
+	}
+	if cfg.StrictMode {
+		args = append(args, "--ez", harness.StrictModeExtra, "true")
 	}
 	args = append(args, cfg.LaunchArgs...)
 
-	cmd := exec.CommandContext(ctx, adb, args...)
+	// A timeout or cancellation must not leave the app running or a trace recording on the device.
+	var trace *traceSession
+	defer func() {
+		if trace == nil && methodTraceDevicePath == "" && ctx.Err() == nil {
+			return
+		}
+		cleanupCtx, cancel := cleanupContext(ctx)
+		defer cancel()
+		if trace != nil {
+			trace.abort(cleanupCtx)
+		}
+		if methodTraceDevicePath != "" {
+			_, _ = runADB(cleanupCtx, adb, cfg.DeviceID, "shell", "rm", "-f", methodTraceDevicePath)
+		}
+		if ctx.Err() != nil {
+			events.Warn(cleanupCtx, fmt.Sprintf("run interrupted (%v); force-stopping %s", ctx.Err(), cfg.Package))
+			_ = forceStop(cleanupCtx, adb, cfg.DeviceID, cfg.Package)
+		}
+	}()
+
+	events.Phase(ctx, "build-type")
+	buildType, err := detectBuildType(ctx, adb, cfg.DeviceID, cfg.Package)
+	if err != nil {
+		events.Warn(ctx, fmt.Sprintf("build type unknown: %v", err))
+	} else if buildType == report.BuildTypeDebug && !cfg.AllowDebugBuild {
+		return nil, fmt.Errorf("%s is a debuggable build, which produces misleading numbers; install a release build or pass --allow-debug", cfg.Package)
+	}
+	// Only debuggable apps allow run-as, which reads and clears the harness's result file.
+	customMetrics := buildType == report.BuildTypeDebug
+	if customMetrics {
+		_ = clearCustomMetrics(ctx, adb, cfg.DeviceID, cfg.Package)
+	}
+
+	if cfg.IdleCPUThreshold > 0 {
+		events.Phase(ctx, "idle")
+		timeout := cfg.IdleTimeout
+		if timeout <= 0 {
+			timeout = defaultIdleTimeout
+		}
+		if _, err := waitForIdle(ctx, adb, cfg.DeviceID, cfg.IdleCPUThreshold, timeout); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			events.Warn(ctx, fmt.Sprintf("continuing without idle device: %v", err))
+		}
+	}
+	// The snapshot only explains outliers, so the iteration runs without one if top fails.
+	noise, _ := captureBackgroundNoise(ctx, adb, cfg.DeviceID)
+
+	events.Phase(ctx, "baseline")
+	// A warm or hot launch reuses the running process, whose earlier scheduling must not count. If
+	// that process could not be sampled, the launch window is unknown and the metric is left out.
+	schedBefore, schedBeforeErr := sampleMainSchedstat(ctx, adb, cfg.DeviceID, cfg.Package)
+	powerBefore, powerErr := capturePowerSnapshot(ctx, adb, cfg.DeviceID)
+	uid, uidErr := resolvePackageUID(ctx, adb, cfg.DeviceID, cfg.Package)
+	var networkBefore networkCounters
+	networkErr := uidErr
+	if uidErr == nil {
+		networkBefore, networkErr = captureNetworkCounters(ctx, adb, cfg.DeviceID, uid)
+		// The UID may have no traffic history yet, in which case the baseline is zero.
+		if errors.Is(networkErr, errNoTrafficHistory) {
+			networkErr = nil
+		}
+	}
+	var binderBefore map[string]binderCallStat
+	binderErr := uidErr
+	if uidErr == nil {
+		if previous, err := enableBinderStats(ctx, adb, cfg.DeviceID); err == nil {
+			defer func() {
+				cleanupCtx, cancel := cleanupContext(ctx)
+				defer cancel()
+				restoreBinderStats(cleanupCtx, adb, cfg.DeviceID, previous)
+			}()
+		}
+		binderBefore, binderErr = captureBinderStats(ctx, adb, cfg.DeviceID, uid)
+	}
+
+	if cfg.TracePath != "" {
+		events.Phase(ctx, "trace-start")
+		_ = enableCompositionTracing(ctx, adb, cfg.DeviceID, cfg.Package)
+		session, err := startTrace(ctx, adb, cfg.DeviceID, cfg.Package, cfg.TraceConfig)
+		if err != nil {
+			return nil, err
+		}
+		trace = session
+	}
+
+	// The logcat window also holds the system's fully drawn event, so it is opened for every run.
+	logcatSince, sinceErr := deviceLogcatTime(ctx, adb, cfg.DeviceID)
+	if sinceErr != nil && (cfg.LogcatPath != "" || cfg.StrictMode) {
+		return nil, sinceErr
+	}
+
+	events.Phase(ctx, "launch")
+	launchCtx, cancelLaunch := watchdog.Start(ctx, cfg.LaunchTimeout)
+	cmd := adbCommand(launchCtx, adb, args...)
+	watchdog.KillTree(cmd)
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stdout
 
-	if err := cmd.Run(); err != nil {
+	launchStarted := time.Now()
+	err = cmd.Run()
+	cancelLaunch()
+	events.Command(ctx, adb, args, launchStarted, err)
+	if err != nil && watchdog.Fired(ctx, launchCtx) {
+		events.Warn(ctx, fmt.Sprintf("am start hung for more than %s; force-stopping %s", cfg.LaunchTimeout, cfg.Package))
+		_ = forceStop(ctx, adb, cfg.DeviceID, cfg.Package)
+		return nil, &watchdog.HungError{Command: "am start", Deadline: cfg.LaunchTimeout}
+	}
+	if err != nil {
 		return nil, fmt.Errorf("run adb: %w: %s", err, stdout.String())
 	}
 
 	metrics := parseLaunchOutput(stdout.Bytes())
+	events.Metric(ctx, "totalTimeMs", metrics.TotalTimeMs)
+	// Sampled as soon as the launch returns, so that the window is as close to the launch as adb allows.
+	if schedAfter, err := sampleMainSchedstat(ctx, adb, cfg.DeviceID, cfg.Package); err == nil && (schedBeforeErr == nil || schedAfter.pid != schedBefore.pid) {
+		metrics.StartupScheduling = startupScheduling(schedBefore, schedAfter, launchStarted)
+		events.Metric(ctx, "startupRunnableMs", metrics.StartupScheduling.RunnableMs)
+	}
+	if binderErr == nil {
+		if binderAfter, err := captureBinderStats(ctx, adb, cfg.DeviceID, uid); err == nil {
+			metrics.Binder = binderDeltas(binderBefore, binderAfter)
+		}
+	}
+	if methodTraceDevicePath != "" {
+		events.Phase(ctx, "method-trace")
+		if err := stopMethodTrace(ctx, adb, cfg.DeviceID, cfg.Package, methodTraceDevicePath, cfg.MethodTracePath); err != nil {
+			return nil, err
+		}
+		methodTraceDevicePath = ""
+		metrics.Artifacts = append(metrics.Artifacts, report.Artifact{Kind: "method-trace", Path: cfg.MethodTracePath})
+	}
+	if cfg.Navigation != nil {
+		events.Phase(ctx, "navigate")
+		navigationMs, err := navigate(ctx, adb, cfg.DeviceID, cfg.Package, cfg.Navigation)
+		if err != nil {
+			return nil, fmt.Errorf("navigate: %w", err)
+		}
+		metrics.NavigationMs = navigationMs
+		events.Metric(ctx, "navigationMs", navigationMs)
+	}
+	if cfg.Flow != nil {
+		events.Phase(ctx, "flow")
+		flow, err := runFlow(ctx, adb, cfg.DeviceID, cfg.Package, cfg.Flow)
+		if err != nil {
+			return nil, fmt.Errorf("flow: %w", err)
+		}
+		metrics.Flow = flow
+		events.Metric(ctx, "flowTimeMs", metrics.FlowTimeMs())
+	}
+	if cfg.Animation != "" {
+		events.Phase(ctx, "animation")
+		animation, err := measureAnimation(ctx, adb, cfg.DeviceID, cfg.Package, cfg.Animation, cfg.AnimationDuration)
+		if err != nil {
+			return nil, fmt.Errorf("animation: %w", err)
+		}
+		metrics.Animation = animation
+		events.Metric(ctx, "droppedFrames", float64(animation.DroppedFrames))
+	}
+	if cfg.Interact != nil {
+		events.Phase(ctx, "interact")
+		if err := cfg.Interact(ctx); err != nil {
+			return nil, fmt.Errorf("interact: %w", err)
+		}
+	}
+	if cfg.MonkeyEvents > 0 {
+		events.Phase(ctx, "monkey")
+		monkey, err := runMonkey(ctx, adb, cfg.DeviceID, cfg.Package, cfg.MonkeyEvents)
+		if err != nil {
+			return nil, err
+		}
+		metrics.Monkey = monkey
+		events.Metric(ctx, "monkeyCrashes", float64(monkey.Crashes))
+	}
+	if trace != nil {
+		events.Phase(ctx, "trace-stop")
+		if err := trace.stop(ctx, cfg.TracePath); err != nil {
+			return nil, err
+		}
+		trace = nil
+		metrics.Artifacts = append(metrics.Artifacts, report.Artifact{Kind: "perfetto-trace", Path: cfg.TracePath})
+	}
 	metrics.Component = component
 	metrics.Activity = cfg.Activity
 	metrics.Package = cfg.Package
 	metrics.BenchmarkComponent = cfg.BenchmarkComponent
-	metrics.Command = fmt.Sprintf("%s %s", adb, strings.Join(args, " "))
+	metrics.BuildType = buildType
+	metrics.Variant = cfg.Variant
+	metrics.Command = fmt.Sprintf("%s %s", adb, strings.Join(redact.Args(args), " "))
 	metrics.Timestamp = time.Now()
-	metrics.Device = fetchDeviceMetadata(ctx, adb, cfg.DeviceID)
+	if cfg.Settle > 0 {
+		events.Phase(ctx, "settle")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(cfg.Settle):
+		}
+	}
+	if sinceErr == nil {
+		if fullyDrawnMs, err := collectFullyDrawn(ctx, adb, cfg.DeviceID, logcatSince, cfg.Package); err == nil {
+			metrics.FullyDrawnMs = fullyDrawnMs
+			events.Metric(ctx, "fullyDrawnMs", fullyDrawnMs)
+		}
+	}
+	events.Phase(ctx, "device-metadata")
+	metrics.Device = fetchDeviceMetadata(ctx, adb, cfg.DeviceID, cfg.DisplayID)
+	metrics.BackgroundProcesses = noise
+	events.Phase(ctx, "memory")
 	if memoryMB, err := collectMemoryUsage(ctx, adb, cfg.DeviceID, cfg.Package); err == nil {
 		metrics.MemoryMB = memoryMB
+		events.Metric(ctx, "memoryMb", memoryMB)
+	} else {
+		events.Warn(ctx, fmt.Sprintf("memory unavailable: %v", err))
+	}
+	events.Phase(ctx, "frames")
+	if frames, err := collectFramestats(ctx, adb, cfg.DeviceID, cfg.Package); err == nil {
+		metrics.FrameDurationsMs = frameDurations(frames)
+		metrics.FramePipeline = framePipeline(frames)
+		events.Metric(ctx, "frames", float64(len(frames)))
+	}
+	if latency, err := collectSurfaceLatency(ctx, adb, cfg.DeviceID, cfg.Package); err == nil {
+		metrics.SurfaceLatency = latency
+		events.Metric(ctx, "missedVsyncs", float64(latency.MissedVsyncs))
+	}
+	events.Phase(ctx, "cpu")
+	pid, pidErr := resolveAndroidPID(ctx, adb, cfg.DeviceID, cfg.Package)
+	if pidErr == nil {
+		if cpuPercent, cpuTimeMs, err := collectCPUMetrics(ctx, adb, cfg.DeviceID, pid, cfg.Package); err == nil {
+			if cpuPercent > 0 {
+				metrics.CPUPercent = cpuPercent
+				events.Metric(ctx, "cpuPercent", cpuPercent)
+			}
+			if cpuTimeMs > 0 {
+				metrics.CPUTimeMs = cpuTimeMs
+				events.Metric(ctx, "cpuTimeMs", cpuTimeMs)
+			}
+		} else {
+			events.Warn(ctx, fmt.Sprintf("cpu unavailable: %v", err))
+		}
+		if threads, err := collectThreadMetrics(ctx, adb, cfg.DeviceID, pid); err == nil {
+			metrics.Threads = threads
+		}
+		if jank, err := collectFrameTimelineJank(ctx, adb, cfg.DeviceID, pid); err == nil {
+			metrics.Jank = jank
+		}
+	} else {
+		events.Warn(ctx, fmt.Sprintf("process not found: %v", pidErr))
 	}
-	if cpuPercent, cpuTimeMs, err := collectCPUMetrics(ctx, adb, cfg.DeviceID, cfg.Package); err == nil {
-		if cpuPercent > 0 {
-			metrics.CPUPercent = cpuPercent
+	events.Phase(ctx, "system-counters")
+	if powerErr == nil {
+		if powerAfter, err := capturePowerSnapshot(ctx, adb, cfg.DeviceID); err == nil {
+			metrics.PowerRails = powerRailDeltas(powerBefore, powerAfter)
 		}
-		if cpuTimeMs > 0 {
-			metrics.CPUTimeMs = cpuTimeMs
+	}
+	// Without a baseline the counters hold all traffic since boot, so the delta is left out.
+	if networkErr == nil {
+		if networkAfter, err := captureNetworkCounters(ctx, adb, cfg.DeviceID, uid); err == nil {
+			metrics.NetworkRxBytes = max(networkAfter.rxBytes-networkBefore.rxBytes, 0)
+			metrics.NetworkTxBytes = max(networkAfter.txBytes-networkBefore.txBytes, 0)
 		}
 	}
+	if cfg.ThemeSwitch {
+		events.Phase(ctx, "theme-switch")
+		themeSwitch, err := measureThemeSwitch(ctx, adb, cfg.DeviceID, cfg.Package)
+		if err != nil {
+			return nil, fmt.Errorf("theme switch: %w", err)
+		}
+		metrics.ThemeSwitch = themeSwitch
+	}
+	if cfg.InputLatencyTaps > 0 {
+		events.Phase(ctx, "input-latency")
+		latency, err := measureInputLatency(ctx, adb, cfg.DeviceID, cfg.DisplayID, cfg.Package, cfg.InputLatencyTaps)
+		if err != nil {
+			return nil, fmt.Errorf("input latency: %w", err)
+		}
+		metrics.InputLatency = latency
+		events.Metric(ctx, "inputLatencyP50Ms", latency.P50Ms)
+	}
+	if customMetrics {
+		events.Phase(ctx, "custom-metrics")
+		if custom, err := collectCustomMetrics(ctx, adb, cfg.DeviceID, cfg.Package); err == nil {
+			metrics.Custom = custom
+		} else {
+			events.Warn(ctx, fmt.Sprintf("custom metrics unavailable: %v", err))
+		}
+	}
+	if cfg.StrictMode {
+		events.Phase(ctx, "strict-mode")
+		violations, network, err := collectStrictModeViolations(ctx, adb, cfg.DeviceID, logcatSince, pid)
+		if err != nil {
+			return nil, err
+		}
+		metrics.StrictMode = violations
+		metrics.MainThreadNetwork = network
+		if network != nil && network.Detected {
+			events.Warn(ctx, fmt.Sprintf("%d network call(s) on the main thread before the first frame", network.Calls))
+		}
+	}
+	if cfg.LogcatPath != "" {
+		events.Phase(ctx, "logcat")
+		if err := captureLogcat(ctx, adb, cfg.DeviceID, logcatSince, pid, cfg.LogcatPath); err != nil {
+			return nil, err
+		}
+		metrics.Artifacts = append(metrics.Artifacts, report.Artifact{Kind: "logcat", Path: cfg.LogcatPath})
+	}
 
 	return metrics, nil
 }
@@ -146,7 +510,7 @@ func parseLaunchOutput(output []byte) *report.AndroidMetrics {
 	return result
 }
 
-func fetchDeviceMetadata(ctx context.Context, adbPath, deviceID string) *report.DeviceMetadata {
+func fetchDeviceMetadata(ctx context.Context, adbPath, deviceID, displayID string) *report.DeviceMetadata {
 	meta := &report.DeviceMetadata{
 		ID:       deviceID,
 		Platform: "android",
@@ -160,30 +524,81 @@ func fetchDeviceMetadata(ctx context.Context, adbPath, deviceID string) *report.
 	if err == nil {
 		meta.OSVersion = strings.TrimSpace(osVersion)
 	}
-	resolution, err := runADB(ctx, adbPath, deviceID, "shell", "wm", "size")
-	if err == nil {
-		meta.Resolution = strings.TrimSpace(resolution)
+	collectDisplayMetadata(ctx, adbPath, deviceID, displayID, meta)
+	meta.Renderer = detectRenderer(ctx, adbPath, deviceID)
+	meta.IsEmulator = detectEmulator(ctx, adbPath, deviceID)
+	// Both properties exist on Android 12 and later.
+	if class, err := runADB(ctx, adbPath, deviceID, "shell", "getprop", "ro.build.version.media_performance_class"); err == nil {
+		meta.PerformanceClass, _ = strconv.Atoi(strings.TrimSpace(class))
 	}
+	if soc, err := runADB(ctx, adbPath, deviceID, "shell", "getprop", "ro.soc.model"); err == nil {
+		meta.Chip = strings.TrimSpace(soc)
+	}
+	devices.Annotate(meta)
 	if meta.Model == "" && meta.OSVersion == "" && meta.Resolution == "" && meta.ID == "" {
 		return nil
 	}
 	return meta
 }
 
+// detectEmulator checks the qemu properties set by the Android emulator kernel (ro.kernel.qemu)
+// and by newer emulator images that boot without it (ro.boot.qemu).
+func detectEmulator(ctx context.Context, adbPath, deviceID string) bool {
+	for _, prop := range []string{"ro.kernel.qemu", "ro.boot.qemu"} {
+		value, err := runADB(ctx, adbPath, deviceID, "shell", "getprop", prop)
+		if err == nil && strings.TrimSpace(value) == "1" {
+			return true
+		}
+	}
+	return false
+}
+
+// detectRenderer reports the HWUI pipeline. debug.hwui.renderer overrides the build default,
+// which is Skia Vulkan when ro.hwui.use_vulkan is set and Skia GL otherwise.
+func detectRenderer(ctx context.Context, adbPath, deviceID string) string {
+	override, err := runADB(ctx, adbPath, deviceID, "shell", "getprop", "debug.hwui.renderer")
+	if err != nil {
+		return ""
+	}
+	switch strings.TrimSpace(override) {
+	case "skiavk":
+		return "Skia Vulkan"
+	case "skiagl":
+		return "Skia GL"
+	case "opengl":
+		return "OpenGL"
+	case "":
+	default:
+		return strings.TrimSpace(override)
+	}
+	useVulkan, err := runADB(ctx, adbPath, deviceID, "shell", "getprop", "ro.hwui.use_vulkan")
+	if err == nil && strings.TrimSpace(useVulkan) == "true" {
+		return "Skia Vulkan"
+	}
+	return "Skia GL"
+}
+
 func runADB(ctx context.Context, adbPath, deviceID string, args ...string) (string, error) {
 	baseArgs := make([]string, 0, len(args)+2)
 	if deviceID != "" {
 		baseArgs = append(baseArgs, "-s", deviceID)
 	}
 	baseArgs = append(baseArgs, args...)
-	cmd := exec.CommandContext(ctx, adbPath, baseArgs...)
+	cmd := adbCommand(ctx, adbPath, baseArgs...)
+	started := time.Now()
 	out, err := cmd.CombinedOutput()
+	events.Command(ctx, adbPath, baseArgs, started, err)
 	if err != nil {
 		return "", err
 	}
 	return string(out), nil
 }
 
+// shellQuote single-quotes value for the device's sh.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
 func collectMemoryUsage(ctx context.Context, adbPath, deviceID, packageName string) (float64, error) {
 	if packageName == "" {
 		return 0, errors.New("package name required for memory collection")
@@ -222,12 +637,7 @@ func parseMeminfoForMB(output string) (float64, error) {
 	return 0, errors.New("unable to locate TOTAL memory usage in dumpsys output")
 }
 
-func collectCPUMetrics(ctx context.Context, adbPath, deviceID, packageName string) (float64, float64, error) {
-	pid, err := resolveAndroidPID(ctx, adbPath, deviceID, packageName)
-	if err != nil {
-		return 0, 0, err
-	}
-
+func collectCPUMetrics(ctx context.Context, adbPath, deviceID, pid, packageName string) (float64, float64, error) {
 	cpuPercent, percentErr := androidCPUPercent(ctx, adbPath, deviceID, pid, packageName)
 	cpuTimeMs, timeErr := androidCPUTime(ctx, adbPath, deviceID, pid)
 
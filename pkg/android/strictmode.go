@@ -0,0 +1,87 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/harness"
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// collectStrictModeViolations counts the StrictMode violations logged since the given device time,
+// keyed by policy (e.g. DiskRead, Network), and the network calls the main thread made before the
+// harness drew its first frame. pid limits the count to the app when it is known.
+func collectStrictModeViolations(ctx context.Context, adbPath, deviceID, since, pid string) (map[string]int, *report.MainThreadNetwork, error) {
+	args := []string{"logcat", "-d", "-v", "threadtime", "-T", since}
+	if pid != "" {
+		args = append(args, "--pid="+pid)
+	}
+	args = append(args, "-s", "StrictMode:D", harness.MarkerTag+":I")
+	out, err := runADB(ctx, adbPath, deviceID, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read strictmode logcat: %w", err)
+	}
+	return parseStrictModeViolations(out), parseMainThreadNetwork(out), nil
+}
+
+// parseStrictModeViolations reads the first line of each logged violation, such as
+//
+//	D StrictMode: StrictMode policy violation; ~duration=45 ms: android.os.strictmode.DiskReadViolation
+//
+// Android 8.1 and older name the violation android.os.StrictMode$StrictModeDiskReadViolation.
+// The stack trace lines that follow are ignored.
+func parseStrictModeViolations(output string) map[string]int {
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		_, rest, ok := strings.Cut(scanner.Text(), "StrictMode policy violation")
+		if !ok {
+			continue
+		}
+		counts[violationPolicy(rest)]++
+	}
+	return counts
+}
+
+// violationPolicy names the policy of a violation from the text that follows "StrictMode policy
+// violation" on its first line.
+func violationPolicy(rest string) string {
+	for _, field := range strings.Fields(rest) {
+		field = strings.TrimSuffix(field, ":")
+		if !strings.HasSuffix(field, "Violation") {
+			continue
+		}
+		name := field[strings.LastIndexAny(field, ".$")+1:]
+		if name = strings.TrimSuffix(strings.TrimPrefix(name, "StrictMode"), "Violation"); name != "" {
+			return name
+		}
+		break
+	}
+	return "Unknown"
+}
+
+// parseMainThreadNetwork counts the Network violations in threadtime-formatted output that the
+// main thread, whose thread id equals the process id, logged before the harness's render-complete
+// marker. Without the marker the first frame cannot be placed, so the result is nil.
+func parseMainThreadNetwork(output string) *report.MainThreadNetwork {
+	network := &report.MainThreadNetwork{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, " "+harness.MarkerTag+":") && strings.Contains(line, harness.MarkerRendered) {
+			network.Detected = network.Calls > 0
+			return network
+		}
+		_, rest, ok := strings.Cut(line, "StrictMode policy violation")
+		if !ok || violationPolicy(rest) != "Network" {
+			continue
+		}
+		// threadtime lines start with the date, time, pid and tid.
+		if fields := strings.Fields(line); len(fields) > 3 && fields[2] == fields[3] {
+			network.Calls++
+		}
+	}
+	return nil
+}
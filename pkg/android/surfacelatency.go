@@ -0,0 +1,91 @@
+package android
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// pendingFenceTime is what SurfaceFlinger prints for a frame whose present fence has not signalled.
+const pendingFenceTime = math.MaxInt64
+
+// collectSurfaceLatency reads SurfaceFlinger's record of the last 128 frames presented from the
+// app's window, the compositor's view of the frames gfxinfo times inside the app.
+func collectSurfaceLatency(ctx context.Context, adbPath, deviceID, packageName string) (*report.SurfaceLatency, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "SurfaceFlinger", "--list")
+	if err != nil {
+		return nil, fmt.Errorf("dumpsys SurfaceFlinger --list: %w", err)
+	}
+	layer := findAppLayer(out, packageName)
+	if layer == "" {
+		return nil, fmt.Errorf("no SurfaceFlinger layer for %s", packageName)
+	}
+	out, err = runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "SurfaceFlinger", "--latency", layer)
+	if err != nil {
+		return nil, fmt.Errorf("dumpsys SurfaceFlinger --latency: %w", err)
+	}
+	latency, err := parseSurfaceLatency(out)
+	if err != nil {
+		return nil, err
+	}
+	latency.Layer = layer
+	return latency, nil
+}
+
+// findAppLayer picks the app's activity window, named `<package>/<activity>` (followed by `#<id>` on
+// Android 10+), from the layer list.
+func findAppLayer(output, packageName string) string {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, packageName+"/") {
+			return line
+		}
+	}
+	return ""
+}
+
+// parseSurfaceLatency reads `dumpsys SurfaceFlinger --latency` output: the refresh period on the
+// first line, then one `desiredPresent actualPresent frameReady` line per frame, all in nanoseconds.
+// A frame presented N refresh periods after its desired time missed N vsyncs.
+func parseSurfaceLatency(output string) (*report.SurfaceLatency, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	if !scanner.Scan() {
+		return nil, errors.New("empty SurfaceFlinger latency output")
+	}
+	periodNs, err := strconv.ParseInt(strings.TrimSpace(scanner.Text()), 10, 64)
+	if err != nil || periodNs <= 0 {
+		return nil, fmt.Errorf("invalid refresh period %q", scanner.Text())
+	}
+	latency := &report.SurfaceLatency{RefreshPeriodMs: float64(periodNs) / 1e6}
+	var delaysMs []float64
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		desired, err1 := strconv.ParseInt(fields[0], 10, 64)
+		actual, err2 := strconv.ParseInt(fields[1], 10, 64)
+		if err1 != nil || err2 != nil || desired == 0 || actual == 0 || actual == pendingFenceTime {
+			continue
+		}
+		delay := max(actual-desired, 0)
+		latency.Frames++
+		latency.MissedVsyncs += int(math.Round(float64(delay) / float64(periodNs)))
+		delaysMs = append(delaysMs, float64(delay)/1e6)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if latency.Frames == 0 {
+		return nil, errors.New("no presented frames in SurfaceFlinger latency output")
+	}
+	latency.PresentJitterMs = stats.StdDev(delaysMs)
+	return latency, nil
+}
@@ -0,0 +1,84 @@
+package android
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// themeSettleDelay is how long the app gets to recreate and redraw after a night-mode change.
+const themeSettleDelay = 1500 * time.Millisecond
+
+// measureThemeSwitch flips the system night mode twice (to the opposite theme and back) while the
+// component is on screen and measures each re-render from gfxinfo frame stats.
+func measureThemeSwitch(ctx context.Context, adbPath, deviceID, packageName string) (*report.ThemeSwitchMetrics, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "cmd", "uimode", "night")
+	if err != nil {
+		return nil, fmt.Errorf("read night mode: %w", err)
+	}
+	startDark := strings.Contains(strings.ToLower(out), "night mode: yes")
+
+	result := &report.ThemeSwitchMetrics{}
+	for _, dark := range []bool{!startDark, startDark} {
+		durationMs, frames, err := measureNightModeChange(ctx, adbPath, deviceID, packageName, dark)
+		if err != nil {
+			// Put the device back in its original theme even if ctx has expired.
+			cleanupCtx, cancel := cleanupContext(ctx)
+			_, _ = runADB(cleanupCtx, adbPath, deviceID, "shell", "cmd", "uimode", "night", nightModeArg(startDark))
+			cancel()
+			return nil, err
+		}
+		if dark {
+			result.ToDarkMs = durationMs
+		} else {
+			result.ToLightMs = durationMs
+		}
+		result.Frames += frames
+	}
+	return result, nil
+}
+
+func nightModeArg(dark bool) string {
+	if dark {
+		return "yes"
+	}
+	return "no"
+}
+
+// measureNightModeChange returns the span from the first frame's intended vsync to the last frame's
+// completion for the burst of frames rendered after the theme change.
+func measureNightModeChange(ctx context.Context, adbPath, deviceID, packageName string, dark bool) (float64, int, error) {
+	if _, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "gfxinfo", packageName, "reset"); err != nil {
+		return 0, 0, fmt.Errorf("reset gfxinfo: %w", err)
+	}
+	mode := nightModeArg(dark)
+	if _, err := runADB(ctx, adbPath, deviceID, "shell", "cmd", "uimode", "night", mode); err != nil {
+		return 0, 0, fmt.Errorf("set night mode %s: %w", mode, err)
+	}
+	select {
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
+	case <-time.After(themeSettleDelay):
+	}
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "dumpsys", "gfxinfo", packageName, "framestats")
+	if err != nil {
+		return 0, 0, fmt.Errorf("dumpsys gfxinfo framestats: %w", err)
+	}
+	frames, err := parseFramestatsTimeline(out)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(frames) == 0 {
+		return 0, 0, errors.New("no frames rendered after theme change")
+	}
+	start, end := frames[0].start, frames[0].end
+	for _, frame := range frames[1:] {
+		start = min(start, frame.start)
+		end = max(end, frame.end)
+	}
+	return (end - start) / 1e6, len(frames), nil
+}
@@ -0,0 +1,97 @@
+package android
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// collectThreadMetrics counts the process threads and reads the main thread's schedstat, whose
+// fields are cumulative nanoseconds spent running, nanoseconds spent runnable while waiting for a
+// CPU, and the number of timeslices. The main thread's tid equals the process pid.
+func collectThreadMetrics(ctx context.Context, adbPath, deviceID, pid string) (*report.ThreadMetrics, error) {
+	threads := &report.ThreadMetrics{}
+	tasks, taskErr := runADB(ctx, adbPath, deviceID, "shell", "ls", fmt.Sprintf("/proc/%s/task", pid))
+	if taskErr == nil {
+		threads.Count = len(strings.Fields(tasks))
+	}
+	runningMs, runnableMs, schedErr := readMainSchedstat(ctx, adbPath, deviceID, pid)
+	if schedErr == nil {
+		threads.MainRunningMs = runningMs
+		threads.MainRunnableMs = runnableMs
+	}
+	if taskErr != nil && schedErr != nil {
+		return nil, fmt.Errorf("thread metrics unavailable: %v; %v", taskErr, schedErr)
+	}
+	return threads, nil
+}
+
+// readMainSchedstat returns the main thread's cumulative running and runnable time in milliseconds.
+func readMainSchedstat(ctx context.Context, adbPath, deviceID, pid string) (float64, float64, error) {
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "cat", fmt.Sprintf("/proc/%s/task/%s/schedstat", pid, pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseSchedstat(out)
+}
+
+// schedSample is the main thread's cumulative schedstat at one point in time.
+type schedSample struct {
+	pid        string
+	runningMs  float64
+	runnableMs float64
+	at         time.Time
+}
+
+// sampleMainSchedstat samples the app's main thread. When the process is running but its
+// schedstat cannot be read, the error comes with a sample that only carries the pid.
+func sampleMainSchedstat(ctx context.Context, adbPath, deviceID, packageName string) (schedSample, error) {
+	pid, err := resolveAndroidPID(ctx, adbPath, deviceID, packageName)
+	if err != nil {
+		return schedSample{}, err
+	}
+	runningMs, runnableMs, err := readMainSchedstat(ctx, adbPath, deviceID, pid)
+	if err != nil {
+		return schedSample{pid: pid}, err
+	}
+	return schedSample{pid: pid, runningMs: runningMs, runnableMs: runnableMs, at: time.Now()}, nil
+}
+
+// startupScheduling attributes the main thread's time between two samples to the launch. A process
+// that the launch started has no earlier sample, so everything it has done since launchStarted
+// counts. The window is host time and includes adb round trips, so it bounds the thread's time.
+func startupScheduling(before, after schedSample, launchStarted time.Time) *report.StartupScheduling {
+	if before.pid != "" && before.pid == after.pid {
+		return &report.StartupScheduling{
+			WindowMs:   float64(after.at.Sub(before.at)) / float64(time.Millisecond),
+			RunningMs:  max(after.runningMs-before.runningMs, 0),
+			RunnableMs: max(after.runnableMs-before.runnableMs, 0),
+		}
+	}
+	return &report.StartupScheduling{
+		WindowMs:   float64(after.at.Sub(launchStarted)) / float64(time.Millisecond),
+		RunningMs:  after.runningMs,
+		RunnableMs: after.runnableMs,
+	}
+}
+
+func parseSchedstat(output string) (float64, float64, error) {
+	fields := strings.Fields(output)
+	if len(fields) < 2 {
+		return 0, 0, errors.New("unexpected schedstat format")
+	}
+	running, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	runnable, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return running / 1e6, runnable / 1e6, nil
+}
@@ -0,0 +1,169 @@
+package android
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+const deviceTraceDir = "/data/misc/perfetto-traces"
+
+// perfettoConfigTemplate records scheduling, app atrace sections, FrameTimeline and track events.
+// The track_event source carries Compose composition tracing emitted by androidx.tracing:tracing-perfetto.
+const perfettoConfigTemplate = `buffers {
+  size_kb: 65536
+  fill_policy: RING_BUFFER
+}
+data_sources {
+  config {
+    name: "linux.ftrace"
+    ftrace_config {
+      ftrace_events: "sched/sched_switch"
+      ftrace_events: "sched/sched_wakeup"
+      ftrace_events: "power/cpu_frequency"
+      atrace_categories: "am"
+      atrace_categories: "dalvik"
+      atrace_categories: "gfx"
+      atrace_categories: "view"
+      atrace_categories: "wm"
+      atrace_apps: "%s"
+    }
+  }
+}
+data_sources {
+  config {
+    name: "linux.process_stats"
+  }
+}
+data_sources {
+  config {
+    name: "android.surfaceflinger.frametimeline"
+  }
+}
+data_sources {
+  config {
+    name: "track_event"
+  }
+}
+duration_ms: %d
+`
+
+const maxTraceDuration = 2 * time.Minute
+
+// tracePackagePlaceholder in a custom trace config is replaced with the benchmarked app's package,
+// e.g. for atrace_apps.
+const tracePackagePlaceholder = "{package}"
+
+// LoadTraceConfig reads a Perfetto trace config in text format (a TraceConfig .pbtxt) to record
+// instead of the built-in one.
+func LoadTraceConfig(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	config := string(data)
+	if strings.TrimSpace(config) == "" {
+		return "", fmt.Errorf("%s: trace config is empty", path)
+	}
+	if !utf8.ValidString(config) || strings.ContainsRune(config, 0) {
+		return "", fmt.Errorf("%s: expected a Perfetto trace config in text format, not a binary one", path)
+	}
+	if !strings.Contains(config, "data_sources") {
+		return "", fmt.Errorf("%s: trace config has no data_sources", path)
+	}
+	return config, nil
+}
+
+// traceSession is a Perfetto recording running in the background on the device.
+type traceSession struct {
+	adbPath    string
+	deviceID   string
+	pid        string
+	devicePath string
+}
+
+// enableCompositionTracing asks androidx.tracing's receiver to emit composition trace sections on the
+// next cold start. Apps without the tracing-perfetto dependency simply ignore the broadcast.
+func enableCompositionTracing(ctx context.Context, adbPath, deviceID, packageName string) error {
+	receiver := packageName + "/androidx.tracing.perfetto.TracingReceiver"
+	_, err := runADB(ctx, adbPath, deviceID, "shell", "am", "broadcast",
+		"-a", "androidx.tracing.perfetto.action.ENABLE_TRACING_COLD_START", receiver)
+	return err
+}
+
+// startTrace starts recording with customConfig, or the built-in config when it is empty.
+func startTrace(ctx context.Context, adbPath, deviceID, packageName, customConfig string) (*traceSession, error) {
+	devicePath := fmt.Sprintf("%s/designbench-%d.perfetto-trace", deviceTraceDir, time.Now().UnixNano())
+	config := fmt.Sprintf(perfettoConfigTemplate, packageName, maxTraceDuration.Milliseconds())
+	if customConfig != "" {
+		config = strings.ReplaceAll(customConfig, tracePackagePlaceholder, packageName)
+	}
+
+	args := make([]string, 0, 10)
+	if deviceID != "" {
+		args = append(args, "-s", deviceID)
+	}
+	// Configs are piped over stdin because SELinux prevents perfetto from reading most device paths.
+	args = append(args, "shell", "perfetto", "--txt", "-c", "-", "-o", devicePath, "--background")
+	cmd := adbCommand(ctx, adbPath, args...)
+	cmd.Stdin = strings.NewReader(config)
+	started := time.Now()
+	out, err := cmd.CombinedOutput()
+	events.Command(ctx, adbPath, args, started, err)
+	if err != nil {
+		return nil, fmt.Errorf("start perfetto: %w: %s", err, string(out))
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return nil, errors.New("start perfetto: no pid reported")
+	}
+	return &traceSession{
+		adbPath:    adbPath,
+		deviceID:   deviceID,
+		pid:        fields[len(fields)-1],
+		devicePath: devicePath,
+	}, nil
+}
+
+// stop ends the recording, waits for perfetto to flush and pulls the trace to hostPath.
+func (t *traceSession) stop(ctx context.Context, hostPath string) error {
+	// A custom config's duration_ms may already have ended the recording, so a failed kill is only
+	// reported if the trace cannot be pulled either.
+	_, killErr := runADB(ctx, t.adbPath, t.deviceID, "shell", "kill", "-TERM", t.pid)
+	for {
+		if _, err := runADB(ctx, t.adbPath, t.deviceID, "shell", "kill", "-0", t.pid); err != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	if dir := filepath.Dir(hostPath); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create trace directory: %w", err)
+		}
+	}
+	if _, err := runADB(ctx, t.adbPath, t.deviceID, "pull", t.devicePath, hostPath); err != nil {
+		if killErr != nil {
+			return fmt.Errorf("stop perfetto: %w", killErr)
+		}
+		return fmt.Errorf("pull trace: %w", err)
+	}
+	_, _ = runADB(ctx, t.adbPath, t.deviceID, "shell", "rm", "-f", t.devicePath)
+	return nil
+}
+
+// abort ends a recording that will not be pulled and deletes its partial output from the device.
+func (t *traceSession) abort(ctx context.Context) {
+	_, _ = runADB(ctx, t.adbPath, t.deviceID, "shell", "kill", "-TERM", t.pid)
+	_, _ = runADB(ctx, t.adbPath, t.deviceID, "shell", "rm", "-f", t.devicePath)
+}
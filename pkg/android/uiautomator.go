@@ -0,0 +1,244 @@
+package android
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	uiDumpPath          = "/data/local/tmp/designbench-ui.xml"
+	defaultSelectorWait = 10 * time.Second
+	uiPollInterval      = 300 * time.Millisecond
+)
+
+// InteractionScript is a list of UI steps executed with uiautomator and `input` after launch, e.g.
+//
+//	steps:
+//	  - tap: {text: Settings}
+//	  - swipe: {from: [540, 1800], to: [540, 600], duration: 300ms}
+//	  - waitFor: {resourceId: com.example.app:id/list, timeout: 5s}
+type InteractionScript struct {
+	Steps []InteractionStep `yaml:"steps"`
+}
+
+// InteractionStep holds exactly one action.
+type InteractionStep struct {
+	Tap     *Selector `yaml:"tap,omitempty"`
+	Swipe   *Swipe    `yaml:"swipe,omitempty"`
+	WaitFor *Selector `yaml:"waitFor,omitempty"`
+}
+
+// Selector matches a node in the uiautomator hierarchy. Every non-empty field must match exactly.
+type Selector struct {
+	Text        string `yaml:"text,omitempty"`
+	ResourceID  string `yaml:"resourceId,omitempty"`
+	ContentDesc string `yaml:"contentDesc,omitempty"`
+	// Timeout bounds how long to wait for the node to appear (default 10s).
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// Swipe drags between two screen coordinates in pixels.
+type Swipe struct {
+	From     [2]int `yaml:"from"`
+	To       [2]int `yaml:"to"`
+	Duration string `yaml:"duration,omitempty"`
+}
+
+// LoadInteractionScript reads and validates a YAML interaction script.
+func LoadInteractionScript(path string) (*InteractionScript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var script InteractionScript
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, step := range script.Steps {
+		if err := step.validate(); err != nil {
+			return nil, fmt.Errorf("%s: step %d: %w", path, i+1, err)
+		}
+	}
+	return &script, nil
+}
+
+func (s InteractionStep) validate() error {
+	actions := 0
+	for _, set := range []bool{s.Tap != nil, s.Swipe != nil, s.WaitFor != nil} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return errors.New("each step needs exactly one of tap, swipe or waitFor")
+	}
+	for _, sel := range []*Selector{s.Tap, s.WaitFor} {
+		if sel == nil {
+			continue
+		}
+		if sel.Text == "" && sel.ResourceID == "" && sel.ContentDesc == "" {
+			return errors.New("selector needs text, resourceId or contentDesc")
+		}
+		if sel.Timeout != "" {
+			if _, err := time.ParseDuration(sel.Timeout); err != nil {
+				return fmt.Errorf("invalid timeout %q: %w", sel.Timeout, err)
+			}
+		}
+	}
+	if s.Swipe != nil && s.Swipe.Duration != "" {
+		if _, err := time.ParseDuration(s.Swipe.Duration); err != nil {
+			return fmt.Errorf("invalid swipe duration %q: %w", s.Swipe.Duration, err)
+		}
+	}
+	return nil
+}
+
+// RunInteractionScript executes script's steps on the device in order.
+func RunInteractionScript(ctx context.Context, adbPath, deviceID string, script *InteractionScript) error {
+	for i, step := range script.Steps {
+		if err := runInteractionStep(ctx, adbPath, deviceID, step); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func runInteractionStep(ctx context.Context, adbPath, deviceID string, step InteractionStep) error {
+	switch {
+	case step.Tap != nil:
+		node, err := waitForNode(ctx, adbPath, deviceID, *step.Tap)
+		if err != nil {
+			return err
+		}
+		x, y := node.center()
+		_, err = runADB(ctx, adbPath, deviceID, "shell", "input", "tap", strconv.Itoa(x), strconv.Itoa(y))
+		return err
+	case step.Swipe != nil:
+		duration := 300 * time.Millisecond
+		if step.Swipe.Duration != "" {
+			duration, _ = time.ParseDuration(step.Swipe.Duration)
+		}
+		_, err := runADB(ctx, adbPath, deviceID, "shell", "input", "swipe",
+			strconv.Itoa(step.Swipe.From[0]), strconv.Itoa(step.Swipe.From[1]),
+			strconv.Itoa(step.Swipe.To[0]), strconv.Itoa(step.Swipe.To[1]),
+			strconv.FormatInt(duration.Milliseconds(), 10))
+		return err
+	case step.WaitFor != nil:
+		_, err := waitForNode(ctx, adbPath, deviceID, *step.WaitFor)
+		return err
+	}
+	return nil
+}
+
+// uiNode is one element of a `uiautomator dump` hierarchy.
+type uiNode struct {
+	Text        string   `xml:"text,attr"`
+	ResourceID  string   `xml:"resource-id,attr"`
+	ContentDesc string   `xml:"content-desc,attr"`
+	Bounds      string   `xml:"bounds,attr"`
+	Children    []uiNode `xml:"node"`
+}
+
+var boundsPattern = regexp.MustCompile(`^\[(-?\d+),(-?\d+)\]\[(-?\d+),(-?\d+)\]$`)
+
+func (n uiNode) center() (int, int) {
+	m := boundsPattern.FindStringSubmatch(n.Bounds)
+	if m == nil {
+		return 0, 0
+	}
+	coords := make([]int, 4)
+	for i := range coords {
+		coords[i], _ = strconv.Atoi(m[i+1])
+	}
+	return (coords[0] + coords[2]) / 2, (coords[1] + coords[3]) / 2
+}
+
+func (s Selector) matches(n uiNode) bool {
+	return (s.Text == "" || n.Text == s.Text) &&
+		(s.ResourceID == "" || n.ResourceID == s.ResourceID) &&
+		(s.ContentDesc == "" || n.ContentDesc == s.ContentDesc)
+}
+
+func (s Selector) String() string {
+	parts := make([]string, 0, 3)
+	if s.Text != "" {
+		parts = append(parts, fmt.Sprintf("text=%q", s.Text))
+	}
+	if s.ResourceID != "" {
+		parts = append(parts, fmt.Sprintf("resourceId=%q", s.ResourceID))
+	}
+	if s.ContentDesc != "" {
+		parts = append(parts, fmt.Sprintf("contentDesc=%q", s.ContentDesc))
+	}
+	return strings.Join(parts, " ")
+}
+
+func waitForNode(ctx context.Context, adbPath, deviceID string, sel Selector) (uiNode, error) {
+	timeout := defaultSelectorWait
+	if sel.Timeout != "" {
+		timeout, _ = time.ParseDuration(sel.Timeout)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		root, err := dumpHierarchy(ctx, adbPath, deviceID)
+		if err == nil {
+			if node, ok := findNode(root, sel); ok {
+				return node, nil
+			}
+		}
+		if !time.Now().Before(deadline) {
+			if err != nil {
+				return uiNode{}, fmt.Errorf("%s not found after %s: %w", sel, timeout, err)
+			}
+			return uiNode{}, fmt.Errorf("%s not found after %s", sel, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return uiNode{}, ctx.Err()
+		case <-time.After(uiPollInterval):
+		}
+	}
+}
+
+func dumpHierarchy(ctx context.Context, adbPath, deviceID string) (uiNode, error) {
+	if _, err := runADB(ctx, adbPath, deviceID, "shell", "uiautomator", "dump", uiDumpPath); err != nil {
+		return uiNode{}, fmt.Errorf("uiautomator dump: %w", err)
+	}
+	out, err := runADB(ctx, adbPath, deviceID, "shell", "cat", uiDumpPath)
+	if err != nil {
+		return uiNode{}, fmt.Errorf("read ui dump: %w", err)
+	}
+	return parseHierarchy(out)
+}
+
+func parseHierarchy(output string) (uiNode, error) {
+	var hierarchy struct {
+		Nodes []uiNode `xml:"node"`
+	}
+	if err := xml.Unmarshal([]byte(output), &hierarchy); err != nil {
+		return uiNode{}, fmt.Errorf("parse ui dump: %w", err)
+	}
+	return uiNode{Children: hierarchy.Nodes}, nil
+}
+
+// findNode walks the hierarchy depth-first and returns the first node sel matches.
+func findNode(node uiNode, sel Selector) (uiNode, bool) {
+	for _, child := range node.Children {
+		if sel.matches(child) {
+			return child, true
+		}
+		if found, ok := findNode(child, sel); ok {
+			return found, true
+		}
+	}
+	return uiNode{}, false
+}
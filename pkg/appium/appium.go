@@ -0,0 +1,237 @@
+package appium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// elementKey is the W3C WebDriver key under which element references are returned.
+const elementKey = "element-6066-11e4-a52e-4f735466cecf"
+
+// defaultWaitTimeout bounds how long a step waits for its element to appear.
+const defaultWaitTimeout = 10 * time.Second
+
+// pollInterval is the delay between element lookups while waiting.
+const pollInterval = 250 * time.Millisecond
+
+// Options configures the Appium backend. Steps run first, then Script (if any).
+type Options struct {
+	ServerURL    string         `yaml:"serverUrl"`
+	Capabilities map[string]any `yaml:"capabilities,omitempty"`
+	Steps        []Step         `yaml:"steps,omitempty"`
+	// Script is an existing Appium script run after Steps. It attaches to the session through the
+	// APPIUM_SERVER_URL and APPIUM_SESSION_ID environment variables.
+	Script []string `yaml:"script,omitempty"`
+}
+
+// Step is one interaction performed through WebDriver.
+type Step struct {
+	// Action is tap, type or wait (wait only waits for the element to exist).
+	Action string `yaml:"action"`
+	// Using is the locator strategy, e.g. "accessibility id", "id" or "xpath". Defaults to accessibility id.
+	Using string `yaml:"using,omitempty"`
+	Value string `yaml:"value"`
+	// Text is typed into the element by the type action.
+	Text string `yaml:"text,omitempty"`
+	// Timeout bounds the element lookup, e.g. 5s.
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// Session is an open WebDriver session on an Appium server.
+type Session struct {
+	serverURL string
+	id        string
+	client    *http.Client
+	opts      Options
+}
+
+type response struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type responseError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// NewSession starts a session with opts' capabilities. Unless overridden, the session attaches to
+// the app DesignBench launches instead of installing or relaunching it.
+func NewSession(ctx context.Context, opts Options) (*Session, error) {
+	if strings.TrimSpace(opts.ServerURL) == "" {
+		return nil, errors.New("appium server URL is required")
+	}
+	for _, step := range opts.Steps {
+		if err := step.validate(); err != nil {
+			return nil, err
+		}
+	}
+	caps := map[string]any{
+		"appium:noReset":    true,
+		"appium:autoLaunch": false,
+	}
+	for key, value := range opts.Capabilities {
+		caps[key] = value
+	}
+	s := &Session{
+		serverURL: strings.TrimRight(opts.ServerURL, "/"),
+		client:    &http.Client{},
+		opts:      opts,
+	}
+	var created struct {
+		SessionID string `json:"sessionId"`
+	}
+	body := map[string]any{"capabilities": map[string]any{"alwaysMatch": caps}}
+	if err := s.do(ctx, http.MethodPost, "/session", body, &created); err != nil {
+		return nil, fmt.Errorf("create appium session: %w", err)
+	}
+	if created.SessionID == "" {
+		return nil, errors.New("create appium session: no session id returned")
+	}
+	s.id = created.SessionID
+	return s, nil
+}
+
+// ID returns the WebDriver session id.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Interact runs the configured steps and script against the app currently on screen.
+func (s *Session) Interact(ctx context.Context) error {
+	for i, step := range s.opts.Steps {
+		if err := s.runStep(ctx, step); err != nil {
+			return fmt.Errorf("appium step %d (%s %q): %w", i+1, step.Action, step.Value, err)
+		}
+	}
+	if len(s.opts.Script) == 0 {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, s.opts.Script[0], s.opts.Script[1:]...)
+	cmd.Env = append(os.Environ(), "APPIUM_SERVER_URL="+s.serverURL, "APPIUM_SESSION_ID="+s.id)
+	started := time.Now()
+	out, err := cmd.CombinedOutput()
+	events.Command(ctx, s.opts.Script[0], s.opts.Script[1:], started, err)
+	if err != nil {
+		return fmt.Errorf("appium script: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Close ends the session.
+func (s *Session) Close(ctx context.Context) error {
+	return s.do(ctx, http.MethodDelete, "/session/"+s.id, nil, nil)
+}
+
+func (s Step) validate() error {
+	switch s.Action {
+	case "tap", "type", "wait":
+	default:
+		return fmt.Errorf("unsupported appium step action %q (expected tap, type or wait)", s.Action)
+	}
+	if s.Value == "" {
+		return fmt.Errorf("appium %s step needs a value to locate the element", s.Action)
+	}
+	if s.Timeout != "" {
+		if _, err := time.ParseDuration(s.Timeout); err != nil {
+			return fmt.Errorf("invalid appium step timeout %q: %w", s.Timeout, err)
+		}
+	}
+	return nil
+}
+
+func (s *Session) runStep(ctx context.Context, step Step) error {
+	timeout := defaultWaitTimeout
+	if step.Timeout != "" {
+		timeout, _ = time.ParseDuration(step.Timeout)
+	}
+	using := step.Using
+	if using == "" {
+		using = "accessibility id"
+	}
+	element, err := s.waitForElement(ctx, using, step.Value, timeout)
+	if err != nil {
+		return err
+	}
+	base := "/session/" + s.id + "/element/" + element
+	switch step.Action {
+	case "tap":
+		return s.do(ctx, http.MethodPost, base+"/click", map[string]any{}, nil)
+	case "type":
+		return s.do(ctx, http.MethodPost, base+"/value", map[string]any{"text": step.Text}, nil)
+	}
+	return nil
+}
+
+func (s *Session) waitForElement(ctx context.Context, using, value string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		var found map[string]string
+		err := s.do(ctx, http.MethodPost, "/session/"+s.id+"/element", map[string]any{"using": using, "value": value}, &found)
+		if err == nil && found[elementKey] != "" {
+			return found[elementKey], nil
+		}
+		if err == nil {
+			err = errors.New("no element reference returned")
+		}
+		if !time.Now().Before(deadline) {
+			return "", fmt.Errorf("element not found after %s: %w", timeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// do sends a WebDriver request and decodes the response's value into out when non-nil.
+func (s *Session) do(ctx context.Context, method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.serverURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	started := time.Now()
+	resp, err := s.client.Do(req)
+	events.Command(ctx, "appium", []string{method, path}, started, err)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var decoded response
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return fmt.Errorf("decode %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var wdErr responseError
+		if json.Unmarshal(decoded.Value, &wdErr) == nil && wdErr.Error != "" {
+			return fmt.Errorf("%s: %s", wdErr.Error, wdErr.Message)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil || len(decoded.Value) == 0 || string(decoded.Value) == "null" {
+		return nil
+	}
+	return json.Unmarshal(decoded.Value, out)
+}
@@ -0,0 +1,80 @@
+// Package calibration stores a speed score per device model so that results measured on different
+// devices can be compared as rough, normalized estimates.
+package calibration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileName is the calibration store kept in the reports directory.
+const FileName = "calibration.json"
+
+// Script is the fixed single-threaded CPU workload timed on each device. It runs in the device
+// shell (or the simulator's), so its duration reflects the hardware rather than any app.
+const Script = `i=0; while [ $i -lt 200000 ]; do i=$((i+1)); done`
+
+// Entry is the calibration of one device model.
+type Entry struct {
+	Platform string `json:"platform"`
+	Model    string `json:"model"`
+	// ScoreMs is the median duration of Script; lower is faster.
+	ScoreMs float64   `json:"scoreMs"`
+	Rounds  int       `json:"rounds"`
+	Time    time.Time `json:"time"`
+}
+
+// Store maps Key(platform, model) to the device's latest calibration.
+type Store map[string]Entry
+
+// Key identifies a device model on a platform.
+func Key(platform, model string) string {
+	return platform + "/" + model
+}
+
+// Load reads the store at path. A missing store is empty.
+func Load(path string) (Store, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Store{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read calibration: %w", err)
+	}
+	store := Store{}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Save writes the store to path, creating its directory.
+func (s Store) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create calibration dir: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Factor returns the multiplier that converts a duration measured on the candidate model into an
+// estimate for the baseline model.
+func (s Store) Factor(platform, baselineModel, candidateModel string) (float64, error) {
+	base, ok := s[Key(platform, baselineModel)]
+	if !ok || base.ScoreMs <= 0 {
+		return 0, fmt.Errorf("%s %q is not calibrated (run designbench calibrate on it)", platform, baselineModel)
+	}
+	cand, ok := s[Key(platform, candidateModel)]
+	if !ok || cand.ScoreMs <= 0 {
+		return 0, fmt.Errorf("%s %q is not calibrated (run designbench calibrate on it)", platform, candidateModel)
+	}
+	return base.ScoreMs / cand.ScoreMs, nil
+}
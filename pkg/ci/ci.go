@@ -0,0 +1,166 @@
+// Package ci detects the continuous integration service a command runs on and knows where each one
+// picks up artifacts and how it passes variables to later steps.
+package ci
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Provider names.
+const (
+	Bitrise  = "bitrise"
+	CircleCI = "circleci"
+	GitHub   = "github"
+	GitLab   = "gitlab"
+	Local    = "local"
+)
+
+// Providers are the names accepted by Lookup.
+var Providers = []string{Bitrise, CircleCI, GitHub, GitLab, Local}
+
+// Provider describes the CI service of the current job.
+type Provider struct {
+	Name string `json:"provider"`
+	// ArtifactsDir is where the service picks up files for the job's artifacts: inside Bitrise's
+	// deploy directory, the path conventionally given to CircleCI's store_artifacts, or a directory
+	// in the workspace for the services whose artifact paths are set in the pipeline file.
+	ArtifactsDir string `json:"-"`
+	Build        Build  `json:"build"`
+}
+
+// Build identifies the CI job, as far as the service exposes it.
+type Build struct {
+	Number string `json:"number,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Job    string `json:"job,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	Commit string `json:"commit,omitempty"`
+}
+
+// artifactsSubdir keeps collected files apart from other artifacts of the job.
+const artifactsSubdir = "designbench"
+
+// workspaceArtifactsDir is used where artifact paths are configured per pipeline and must lie in
+// the checkout.
+const workspaceArtifactsDir = "designbench-artifacts"
+
+// Detect returns the provider of the current job from the variables each service sets, or Local.
+func Detect() Provider {
+	for _, name := range []string{Bitrise, CircleCI, GitHub, GitLab} {
+		p, _ := Lookup(name)
+		if isSet(p.marker()) {
+			return p
+		}
+	}
+	p, _ := Lookup(Local)
+	return p
+}
+
+// Lookup returns the named provider, reading its job details from the environment.
+func Lookup(name string) (Provider, error) {
+	env := os.Getenv
+	p := Provider{Name: name}
+	switch name {
+	case Bitrise:
+		deploy := env("BITRISE_DEPLOY_DIR")
+		if deploy == "" {
+			deploy = workspaceArtifactsDir
+		}
+		p.ArtifactsDir = filepath.Join(deploy, artifactsSubdir)
+		p.Build = Build{env("BITRISE_BUILD_NUMBER"), env("BITRISE_BUILD_URL"), env("BITRISE_TRIGGERED_WORKFLOW_ID"), env("BITRISE_GIT_BRANCH"), env("BITRISE_GIT_COMMIT")}
+	case CircleCI:
+		root := env("CIRCLE_ARTIFACTS")
+		if root == "" {
+			root = "/tmp/artifacts"
+		}
+		p.ArtifactsDir = filepath.Join(root, artifactsSubdir)
+		p.Build = Build{env("CIRCLE_BUILD_NUM"), env("CIRCLE_BUILD_URL"), env("CIRCLE_JOB"), env("CIRCLE_BRANCH"), env("CIRCLE_SHA1")}
+	case GitHub:
+		p.ArtifactsDir = workspaceArtifactsDir
+		p.Build = Build{Number: env("GITHUB_RUN_NUMBER"), Job: env("GITHUB_JOB"), Branch: env("GITHUB_REF_NAME"), Commit: env("GITHUB_SHA")}
+		if env("GITHUB_RUN_ID") != "" {
+			p.Build.URL = fmt.Sprintf("%s/%s/actions/runs/%s", env("GITHUB_SERVER_URL"), env("GITHUB_REPOSITORY"), env("GITHUB_RUN_ID"))
+		}
+	case GitLab:
+		p.ArtifactsDir = workspaceArtifactsDir
+		p.Build = Build{env("CI_PIPELINE_IID"), env("CI_JOB_URL"), env("CI_JOB_NAME"), env("CI_COMMIT_REF_NAME"), env("CI_COMMIT_SHA")}
+	case Local:
+		p.ArtifactsDir = workspaceArtifactsDir
+	default:
+		return Provider{}, fmt.Errorf("unknown CI provider %q (expected one of %s)", name, strings.Join(Providers, ", "))
+	}
+	return p, nil
+}
+
+// marker is the variable that is set to true in every job of the provider.
+func (p Provider) marker() string {
+	switch p.Name {
+	case Bitrise:
+		return "BITRISE_IO"
+	case CircleCI:
+		return "CIRCLECI"
+	case GitHub:
+		return "GITHUB_ACTIONS"
+	case GitLab:
+		return "GITLAB_CI"
+	}
+	return ""
+}
+
+func isSet(name string) bool {
+	if name == "" {
+		return false
+	}
+	ok, _ := strconv.ParseBool(os.Getenv(name))
+	return ok
+}
+
+// ExportEnv makes key=value available to the job's later steps the way the provider supports it:
+// envman on Bitrise, $BASH_ENV on CircleCI and $GITHUB_ENV on GitHub Actions. GitLab passes
+// variables through a dotenv report, which is written to dotenvPath. It returns the file written
+// or the mechanism used, or "" when the provider has none.
+func (p Provider) ExportEnv(key, value, dotenvPath string) (string, error) {
+	switch p.Name {
+	case Bitrise:
+		envman, err := exec.LookPath("envman")
+		if err != nil {
+			return "", nil
+		}
+		if out, err := exec.Command(envman, "add", "--key", key, "--value", value).CombinedOutput(); err != nil {
+			return "", fmt.Errorf("envman add %s: %w: %s", key, err, strings.TrimSpace(string(out)))
+		}
+		return "envman", nil
+	case CircleCI:
+		return appendLine(os.Getenv("BASH_ENV"), fmt.Sprintf("export %s=%s", key, shellQuote(value)))
+	case GitHub:
+		return appendLine(os.Getenv("GITHUB_ENV"), key+"="+value)
+	case GitLab:
+		return appendLine(dotenvPath, key+"="+value)
+	}
+	return "", nil
+}
+
+// appendLine appends line to the file at path, returning path, or does nothing when path is empty.
+func appendLine(path, line string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,138 @@
+package ci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MetadataFile is the summary of collected runs written next to them.
+const MetadataFile = "designbench-ci.json"
+
+// MetricsFile is the GitLab metrics report (artifacts:reports:metrics) written for GitLab jobs.
+const MetricsFile = "metrics.txt"
+
+// DotenvFile is the GitLab dotenv report (artifacts:reports:dotenv) that passes variables on.
+const DotenvFile = "designbench.env"
+
+// Run is one collected run directory.
+type Run struct {
+	ID        string `json:"runId,omitempty"`
+	Component string `json:"component,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	// Path is the run's directory relative to the artifacts directory.
+	Path   string  `json:"path"`
+	Metric string  `json:"metric,omitempty"`
+	Value  float64 `json:"value,omitempty"`
+}
+
+// Metadata describes a collection for tools that read the artifacts later.
+type Metadata struct {
+	Provider
+	Collected time.Time `json:"collected"`
+	Runs      []Run     `json:"runs"`
+}
+
+// WriteMetadata writes meta as MetadataFile in dir.
+func WriteMetadata(dir string, meta Metadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, MetadataFile), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", MetadataFile, err)
+	}
+	return nil
+}
+
+// WriteMetrics writes each run's primary metric in the OpenMetrics text format GitLab shows in
+// merge request widgets, named after the component, platform and metric. When several runs share a
+// name, the last one is kept.
+func WriteMetrics(path string, runs []Run) error {
+	var names []string
+	values := map[string]float64{}
+	for _, run := range runs {
+		if run.Metric == "" {
+			continue
+		}
+		name := "designbench_" + metricName(run.Component, run.Platform, run.Metric)
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = run.Value
+	}
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s %g\n", name, values[name])
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// metricName joins parts into a lower-case name of letters, digits and underscores.
+func metricName(parts ...string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.Join(parts, "_")) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else if !strings.HasSuffix(b.String(), "_") {
+			b.WriteByte('_')
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// Summarize adds markdown to the job's summary page where the provider has one (GitHub Actions'
+// $GITHUB_STEP_SUMMARY), returning the file written or "".
+func (p Provider) Summarize(markdown string) (string, error) {
+	if p.Name != GitHub {
+		return "", nil
+	}
+	return appendLine(os.Getenv("GITHUB_STEP_SUMMARY"), markdown)
+}
+
+// CopyDir copies the files under src to dst, creating directories as needed. Symbolic links are
+// skipped, since artifact uploads would not preserve them.
+func CopyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case entry.Type().IsRegular():
+			return copyFile(path, target)
+		}
+		return nil
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("copy %s: %w", src, err)
+	}
+	return out.Close()
+}
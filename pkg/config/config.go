@@ -0,0 +1,243 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tahatesser/designbench/pkg/appium"
+)
+
+// FileName is the project configuration file looked up in the working directory.
+const FileName = "designbench.yaml"
+
+// Config holds project defaults for the benchmark commands. Command-line flags take precedence.
+type Config struct {
+	Component  string `yaml:"component,omitempty"`
+	Iterations int    `yaml:"iterations,omitempty"`
+	Timeout    string `yaml:"timeout,omitempty"`
+	// LaunchTimeout bounds each launch command, e.g. "20s"; a launch that hangs longer fails only its
+	// iteration.
+	LaunchTimeout string `yaml:"launchTimeout,omitempty"`
+	// TargetCIWidth and RegressionThreshold are percentages, e.g. "5%". Percentiles lists the
+	// statistics shown for each metric across iterations, e.g. "p50,p90,p99".
+	TargetCIWidth       string  `yaml:"targetCIWidth,omitempty"`
+	RegressionThreshold string  `yaml:"regressionThreshold,omitempty"`
+	Percentiles         string  `yaml:"percentiles,omitempty"`
+	Android             Android `yaml:"android,omitempty"`
+	IOS                 IOS     `yaml:"ios,omitempty"`
+	// Budgets caps summary metrics such as totalTimeMs or memoryMb; the summary flags values over them.
+	Budgets map[string]float64 `yaml:"budgets,omitempty"`
+	// Thresholds are the regression thresholds per metric family (timing, memory, cpu, jank), e.g.
+	// "5%", "10MB" or "1pp", used by compare and the suite gates instead of RegressionThreshold.
+	Thresholds map[string]string `yaml:"thresholds,omitempty"`
+	// DerivedMetrics are computed from each measurement's fields when the report is written, e.g.
+	// cpuMsPerFrame: cpuTimeMs / frames.
+	DerivedMetrics map[string]string `yaml:"derivedMetrics,omitempty"`
+	// RecordEnv adds environment variables to those recorded in reports, by name or with a trailing
+	// * as a prefix. Variables with names such as *_TOKEN are never recorded.
+	RecordEnv []string `yaml:"recordEnv,omitempty"`
+	// Appium, when set, drives the app to a deep screen after each launch and before measurement.
+	Appium *appium.Options `yaml:"appium,omitempty"`
+	// Email, when set, has `designbench daemon` mail the outcome of every cycle.
+	Email *Email `yaml:"email,omitempty"`
+	// Suites name groups of runs executed together by `designbench daemon`.
+	Suites map[string][]SuiteRun `yaml:"suites,omitempty"`
+	// AVDs name the emulator images `designbench avd create` provisions.
+	AVDs map[string]AVD `yaml:"avds,omitempty"`
+	// Profiles are named overrides selected with --profile, e.g. local, ci or nightly.
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+}
+
+// Profile overrides the top-level settings of a Config; empty fields keep the top-level value.
+type Profile struct {
+	Iterations          int     `yaml:"iterations,omitempty"`
+	Timeout             string  `yaml:"timeout,omitempty"`
+	LaunchTimeout       string  `yaml:"launchTimeout,omitempty"`
+	TargetCIWidth       string  `yaml:"targetCIWidth,omitempty"`
+	RegressionThreshold string  `yaml:"regressionThreshold,omitempty"`
+	Percentiles         string  `yaml:"percentiles,omitempty"`
+	Android             Android `yaml:"android,omitempty"`
+	IOS                 IOS     `yaml:"ios,omitempty"`
+	// Budgets are merged into the top-level budgets, replacing metrics set in both.
+	Budgets map[string]float64 `yaml:"budgets,omitempty"`
+	// Thresholds are merged into the top-level thresholds, replacing families set in both.
+	Thresholds map[string]string `yaml:"thresholds,omitempty"`
+}
+
+// ApplyProfile overlays the named profile onto cfg.
+func (cfg *Config) ApplyProfile(name string) error {
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.Profiles))
+		for n := range cfg.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("profile %q not found (no profiles configured)", name)
+		}
+		return fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(names, ", "))
+	}
+	if profile.Iterations > 0 {
+		cfg.Iterations = profile.Iterations
+	}
+	override(&cfg.Timeout, profile.Timeout)
+	override(&cfg.LaunchTimeout, profile.LaunchTimeout)
+	override(&cfg.TargetCIWidth, profile.TargetCIWidth)
+	override(&cfg.RegressionThreshold, profile.RegressionThreshold)
+	override(&cfg.Percentiles, profile.Percentiles)
+	override(&cfg.Android.Package, profile.Android.Package)
+	override(&cfg.Android.Activity, profile.Android.Activity)
+	override(&cfg.Android.Device, profile.Android.Device)
+	override(&cfg.Android.ADBPath, profile.Android.ADBPath)
+	override(&cfg.IOS.BundleID, profile.IOS.BundleID)
+	override(&cfg.IOS.Device, profile.IOS.Device)
+	override(&cfg.IOS.XCRunPath, profile.IOS.XCRunPath)
+	override(&cfg.IOS.Scheme, profile.IOS.Scheme)
+	override(&cfg.IOS.Configuration, profile.IOS.Configuration)
+	if len(profile.Budgets) > 0 && cfg.Budgets == nil {
+		cfg.Budgets = make(map[string]float64, len(profile.Budgets))
+	}
+	for metric, budget := range profile.Budgets {
+		cfg.Budgets[metric] = budget
+	}
+	if len(profile.Thresholds) > 0 && cfg.Thresholds == nil {
+		cfg.Thresholds = make(map[string]string, len(profile.Thresholds))
+	}
+	for family, threshold := range profile.Thresholds {
+		cfg.Thresholds[family] = threshold
+	}
+	return nil
+}
+
+func override(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// SuiteRun is one `designbench android` or `designbench ios` invocation within a suite.
+type SuiteRun struct {
+	Platform  string `yaml:"platform"`
+	Component string `yaml:"component,omitempty"`
+	// Args are extra flags of the platform command, e.g. [--scenario, theme-switch].
+	Args []string `yaml:"args,omitempty"`
+	// Tags group entries across suites so that `designbench suite run --tags` can select them.
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// AVD declares an Android emulator image by API level, system image and hardware profile.
+type AVD struct {
+	APILevel int `yaml:"apiLevel"`
+	// Image is aosp, google_apis (the default) or play.
+	Image string `yaml:"image,omitempty"`
+	// Device is the hardware profile, e.g. pixel_6.
+	Device string `yaml:"device,omitempty"`
+	// ABI defaults to the host's, e.g. arm64-v8a on Apple silicon.
+	ABI string `yaml:"abi,omitempty"`
+}
+
+// Email is the SMTP server and recipients of `designbench daemon` result mails. The password is
+// read from DESIGNBENCH_SMTP_PASSWORD rather than stored in the file.
+type Email struct {
+	// Server is host:port, e.g. smtp.example.com:587.
+	Server   string   `yaml:"server"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	Username string   `yaml:"username,omitempty"`
+}
+
+// Android configures the `designbench android` command.
+type Android struct {
+	Package  string `yaml:"package,omitempty"`
+	Activity string `yaml:"activity,omitempty"`
+	Device   string `yaml:"device,omitempty"`
+	ADBPath  string `yaml:"adbPath,omitempty"`
+	// Variant (the build type) and Flavor are used by `designbench android --install` and
+	// recorded in the report.
+	Variant string `yaml:"variant,omitempty"`
+	Flavor  string `yaml:"flavor,omitempty"`
+}
+
+// IOS configures the `designbench ios` command.
+type IOS struct {
+	BundleID  string `yaml:"bundleId,omitempty"`
+	Device    string `yaml:"device,omitempty"`
+	XCRunPath string `yaml:"xcrunPath,omitempty"`
+	// Scheme and Configuration are used by `designbench ios --install`.
+	Scheme        string `yaml:"scheme,omitempty"`
+	Configuration string `yaml:"configuration,omitempty"`
+}
+
+// Load reads the configuration at path. A missing file yields an error wrapping os.ErrNotExist.
+func Load(path string) (*Config, error) {
+	return LoadOver(path, Config{})
+}
+
+// LoadOver reads the configuration at path on top of base: settings missing from the file keep
+// base's values.
+func LoadOver(path string, base Config) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := base
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+var starterTemplate = template.Must(template.New("starter").Parse(`# DesignBench project configuration. Command-line flags override these values.
+
+# Label used for reports when --component is not given.
+component: {{ printf "%q" .Component }}
+# Launches per benchmark; the median of each metric is reported.
+iterations: {{ .Iterations }}
+# Overall command timeout.
+timeout: {{ .Timeout }}
+
+android:
+  package: {{ printf "%q" .Android.Package }}
+  # Activity hosting the benchmark harness; it receives the component as the designbench_component extra.
+  activity: {{ printf "%q" .Android.Activity }}
+  # adb serial; leave empty to use the only connected device.
+  device: ""
+
+ios:
+  bundleId: {{ printf "%q" .IOS.BundleID }}
+  # Scheme built by ` + "`designbench ios --install`" + `; detected with xcodebuild -list when empty.
+  scheme: {{ printf "%q" .IOS.Scheme }}
+  # Simulator UDID; leave empty to use the booted simulator.
+  device: ""
+
+# Named overrides selected with --profile, e.g. designbench android --profile ci.
+# profiles:
+#   ci:
+#     iterations: 10
+#     targetCIWidth: 5%
+#     android:
+#       device: emulator-5554
+#   nightly:
+#     iterations: 30
+#     regressionThreshold: 5%
+`))
+
+// Starter renders a commented configuration file seeded with cfg's values.
+func Starter(cfg Config) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := starterTemplate.Execute(&buf, cfg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
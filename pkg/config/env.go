@@ -0,0 +1,30 @@
+package config
+
+import "os"
+
+// EnvPrefix starts every environment variable read by designbench. Command-line flags map to
+// DESIGNBENCH_<FLAG> (e.g. DESIGNBENCH_TIMEOUT for --timeout) and config-only settings to their
+// YAML path (e.g. DESIGNBENCH_ANDROID_ADB_PATH for android.adbPath).
+const EnvPrefix = "DESIGNBENCH_"
+
+// FromEnv returns the config-only settings set in the environment. Values in the config file
+// take precedence over them.
+func FromEnv() Config {
+	var cfg Config
+	for name, field := range map[string]*string{
+		"ANDROID_PACKAGE":   &cfg.Android.Package,
+		"ANDROID_ACTIVITY":  &cfg.Android.Activity,
+		"ANDROID_DEVICE":    &cfg.Android.Device,
+		"ANDROID_ADB_PATH":  &cfg.Android.ADBPath,
+		"ANDROID_VARIANT":   &cfg.Android.Variant,
+		"ANDROID_FLAVOR":    &cfg.Android.Flavor,
+		"IOS_BUNDLE_ID":     &cfg.IOS.BundleID,
+		"IOS_DEVICE":        &cfg.IOS.Device,
+		"IOS_XCRUN_PATH":    &cfg.IOS.XCRunPath,
+		"IOS_SCHEME":        &cfg.IOS.Scheme,
+		"IOS_CONFIGURATION": &cfg.IOS.Configuration,
+	} {
+		*field = os.Getenv(EnvPrefix + name)
+	}
+	return cfg
+}
@@ -0,0 +1,99 @@
+// Package devices holds a built-in registry of common Android models with their chip and a rough
+// performance tier, so that results from different but comparable hardware can be grouped.
+package devices
+
+import (
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// Performance tiers, from entry-level to flagship hardware of the last few years.
+const (
+	TierLow  = "low"
+	TierMid  = "mid"
+	TierHigh = "high"
+)
+
+// minDeclaredHighClass is the lowest media performance class (Android 12, API 31). Devices that
+// declare any class meet Google's high-end bar for their release.
+const minDeclaredHighClass = 31
+
+// Capability describes the hardware class of a device model.
+type Capability struct {
+	Chip string
+	// ChipYear is the year the chip generation shipped.
+	ChipYear int
+	Tier     string
+}
+
+type entry struct {
+	// models are matched case-insensitively; a trailing * matches any suffix, for the regional
+	// variants of Samsung model numbers.
+	models     []string
+	capability Capability
+}
+
+var registry = []entry{
+	{[]string{"Pixel 9", "Pixel 9 Pro", "Pixel 9 Pro XL", "Pixel 9 Pro Fold"}, Capability{"Tensor G4", 2024, TierHigh}},
+	{[]string{"Pixel 8", "Pixel 8 Pro", "Pixel 8a"}, Capability{"Tensor G3", 2023, TierHigh}},
+	{[]string{"Pixel 7", "Pixel 7 Pro", "Pixel 7a", "Pixel Fold", "Pixel Tablet"}, Capability{"Tensor G2", 2022, TierHigh}},
+	{[]string{"Pixel 6", "Pixel 6 Pro", "Pixel 6a"}, Capability{"Tensor", 2021, TierHigh}},
+	{[]string{"Pixel 5", "Pixel 5a", "Pixel 4a (5G)"}, Capability{"Snapdragon 765G", 2020, TierMid}},
+	{[]string{"Pixel 4a"}, Capability{"Snapdragon 730G", 2019, TierMid}},
+	{[]string{"Pixel 4", "Pixel 4 XL"}, Capability{"Snapdragon 855", 2019, TierHigh}},
+	{[]string{"SM-S921*", "SM-S926*", "SM-S928*"}, Capability{"Snapdragon 8 Gen 3 / Exynos 2400", 2024, TierHigh}},
+	{[]string{"SM-S911*", "SM-S916*", "SM-S918*"}, Capability{"Snapdragon 8 Gen 2", 2023, TierHigh}},
+	{[]string{"SM-S901*", "SM-S906*", "SM-S908*"}, Capability{"Snapdragon 8 Gen 1 / Exynos 2200", 2022, TierHigh}},
+	{[]string{"SM-F946*", "SM-F731*"}, Capability{"Snapdragon 8 Gen 2", 2023, TierHigh}},
+	{[]string{"SM-X710*", "SM-X716*", "SM-X810*", "SM-X910*"}, Capability{"Snapdragon 8 Gen 2", 2023, TierHigh}},
+	{[]string{"SM-A556*"}, Capability{"Exynos 1480", 2024, TierMid}},
+	{[]string{"SM-A546*"}, Capability{"Exynos 1380", 2023, TierMid}},
+	{[]string{"SM-A536*"}, Capability{"Exynos 1280", 2022, TierMid}},
+	{[]string{"SM-A346*"}, Capability{"Dimensity 1080", 2023, TierMid}},
+	{[]string{"SM-A155*", "SM-A156*"}, Capability{"Helio G99 / Dimensity 6100+", 2024, TierLow}},
+	{[]string{"SM-A145*", "SM-A146*"}, Capability{"Helio G80 / Exynos 850", 2023, TierLow}},
+	{[]string{"SM-A057*", "SM-A055*"}, Capability{"Snapdragon 680 / Helio G85", 2023, TierLow}},
+	{[]string{"OnePlus 12", "CPH2581", "CPH2583"}, Capability{"Snapdragon 8 Gen 3", 2024, TierHigh}},
+	{[]string{"OnePlus 11", "CPH2449", "CPH2451"}, Capability{"Snapdragon 8 Gen 2", 2023, TierHigh}},
+	{[]string{"Redmi Note 13", "23129RAA4G"}, Capability{"Snapdragon 685", 2024, TierLow}},
+	{[]string{"Redmi 9A", "M2006C3LG"}, Capability{"Helio G25", 2020, TierLow}},
+	{[]string{"moto g power (2022)"}, Capability{"Helio G37", 2022, TierLow}},
+	{[]string{"moto g play (2023)"}, Capability{"Helio G37", 2023, TierLow}},
+}
+
+// Lookup returns the registry entry for an Android model name as reported by ro.product.model.
+func Lookup(model string) (Capability, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" {
+		return Capability{}, false
+	}
+	for _, e := range registry {
+		for _, pattern := range e.models {
+			pattern = strings.ToLower(pattern)
+			if prefix, ok := strings.CutSuffix(pattern, "*"); (ok && strings.HasPrefix(model, prefix)) || pattern == model {
+				return e.capability, true
+			}
+		}
+	}
+	return Capability{}, false
+}
+
+// Annotate fills meta's chip and tier from the registry. Devices missing from it that declare a
+// media performance class are high tier; emulators and simulators are left alone, since their
+// speed depends on the host.
+func Annotate(meta *report.DeviceMetadata) {
+	if meta == nil || meta.IsVirtual() {
+		return
+	}
+	if capability, ok := Lookup(meta.Model); ok {
+		if meta.Chip == "" {
+			meta.Chip = capability.Chip
+		}
+		meta.Tier = capability.Tier
+		return
+	}
+	if meta.PerformanceClass >= minDeclaredHighClass {
+		meta.Tier = TierHigh
+	}
+}
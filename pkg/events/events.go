@@ -0,0 +1,128 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/redact"
+)
+
+// Kind identifies what a progress event reports.
+type Kind string
+
+// Event kinds emitted by the platform runners.
+const (
+	PhaseStarted    Kind = "phase-started"
+	CommandExecuted Kind = "command-executed"
+	MetricCollected Kind = "metric-collected"
+	Warning         Kind = "warning"
+)
+
+// Event describes a single step of a benchmark run as it happens.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Kind       Kind      `json:"kind"`
+	RunID      string    `json:"runId,omitempty"`
+	Platform   string    `json:"platform,omitempty"`
+	Phase      string    `json:"phase,omitempty"`
+	Command    string    `json:"command,omitempty"`
+	DurationMs float64   `json:"durationMs,omitempty"`
+	Metric     string    `json:"metric,omitempty"`
+	Value      float64   `json:"value,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Handler receives events synchronously; calls are serialised even when a runner samples from
+// several goroutines. It should return quickly.
+type Handler func(Event)
+
+// String renders the event as a single human-readable line.
+func (e Event) String() string {
+	var b strings.Builder
+	if e.Platform != "" {
+		fmt.Fprintf(&b, "[%s] ", e.Platform)
+	}
+	switch e.Kind {
+	case PhaseStarted:
+		fmt.Fprintf(&b, "%s...", e.Phase)
+	case CommandExecuted:
+		fmt.Fprintf(&b, "$ %s (%.0fms)", e.Command, e.DurationMs)
+	case MetricCollected:
+		fmt.Fprintf(&b, "%s=%.2f", e.Metric, e.Value)
+	default:
+		b.WriteString(string(e.Kind))
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, " %s", e.Message)
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&b, " error: %s", e.Error)
+	}
+	return b.String()
+}
+
+type emitterKey struct{}
+
+type emitter struct {
+	platform string
+	handler  Handler
+	mu       *sync.Mutex
+}
+
+// WithHandler returns a context whose Emit calls are delivered to handler, tagged with platform.
+// A nil handler leaves ctx unchanged.
+func WithHandler(ctx context.Context, platform string, handler Handler) context.Context {
+	if handler == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, emitterKey{}, emitter{platform: platform, handler: handler, mu: &sync.Mutex{}})
+}
+
+// Emit delivers event to the handler registered on ctx, if any.
+func Emit(ctx context.Context, event Event) {
+	e, ok := ctx.Value(emitterKey{}).(emitter)
+	if !ok {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Platform == "" {
+		event.Platform = e.platform
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handler(event)
+}
+
+// Phase emits a PhaseStarted event.
+func Phase(ctx context.Context, phase string) {
+	Emit(ctx, Event{Kind: PhaseStarted, Phase: phase})
+}
+
+// Command emits a CommandExecuted event for a finished external command.
+func Command(ctx context.Context, name string, args []string, started time.Time, err error) {
+	event := Event{
+		Kind:       CommandExecuted,
+		Command:    strings.TrimSpace(name + " " + strings.Join(redact.Args(args), " ")),
+		DurationMs: float64(time.Since(started)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	Emit(ctx, event)
+}
+
+// Metric emits a MetricCollected event.
+func Metric(ctx context.Context, name string, value float64) {
+	Emit(ctx, Event{Kind: MetricCollected, Metric: name, Value: value})
+}
+
+// Warn emits a Warning event.
+func Warn(ctx context.Context, message string) {
+	Emit(ctx, Event{Kind: Warning, Message: message})
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// bigQueryBatch stays well below the insertAll limit of 50,000 rows and 10 MB per request.
+const bigQueryBatch = 500
+
+// bigQueryEndpoint is the BigQuery REST API root.
+const bigQueryEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+
+type bigQueryTable struct {
+	project, dataset, table string
+}
+
+// parseBigQueryTable splits "project.dataset.table". Project IDs may contain a domain prefix
+// ("example.com:project"), which has no dots after the colon.
+func parseBigQueryTable(target string) (bigQueryTable, error) {
+	dot := strings.LastIndex(target, ".")
+	if dot < 0 {
+		return bigQueryTable{}, fmt.Errorf("invalid BigQuery table %q (expected project.dataset.table)", target)
+	}
+	rest, table := target[:dot], target[dot+1:]
+	dot = strings.LastIndex(rest, ".")
+	if dot < 0 || table == "" {
+		return bigQueryTable{}, fmt.Errorf("invalid BigQuery table %q (expected project.dataset.table)", target)
+	}
+	t := bigQueryTable{project: rest[:dot], dataset: rest[dot+1:], table: table}
+	if t.project == "" || t.dataset == "" {
+		return bigQueryTable{}, fmt.Errorf("invalid BigQuery table %q (expected project.dataset.table)", target)
+	}
+	return t, nil
+}
+
+type insertAllRequest struct {
+	Rows []insertAllRow `json:"rows"`
+}
+
+type insertAllRow struct {
+	// InsertID lets BigQuery drop duplicates when a request is retried.
+	InsertID string `json:"insertId"`
+	JSON     Row    `json:"json"`
+}
+
+type insertAllResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+// insertBigQuery streams rows into an existing table with tabledata.insertAll.
+func insertBigQuery(ctx context.Context, table bigQueryTable, rows []Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	token, err := googleAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll", bigQueryEndpoint,
+		url.PathEscape(table.project), url.PathEscape(table.dataset), url.PathEscape(table.table))
+	for start := 0; start < len(rows); start += bigQueryBatch {
+		batch := rows[start:min(start+bigQueryBatch, len(rows))]
+		body := insertAllRequest{Rows: make([]insertAllRow, len(batch))}
+		for i, row := range batch {
+			body.Rows[i] = insertAllRow{
+				InsertID: strings.Join([]string{row.RunID, row.Platform, row.Variant, row.Metric}, "/"),
+				JSON:     row,
+			}
+		}
+		if err := postInsertAll(ctx, endpoint, token, body); err != nil {
+			return fmt.Errorf("bigquery %s.%s.%s: %w", table.project, table.dataset, table.table, err)
+		}
+	}
+	return nil
+}
+
+func postInsertAll(ctx context.Context, endpoint, token string, body insertAllRequest) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("insertAll returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	var result insertAllResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("parse insertAll response: %w", err)
+	}
+	if len(result.InsertErrors) > 0 {
+		first := result.InsertErrors[0]
+		detail := "unknown error"
+		if len(first.Errors) > 0 {
+			detail = fmt.Sprintf("%s: %s", first.Errors[0].Reason, first.Errors[0].Message)
+		}
+		return fmt.Errorf("%d of %d rows rejected (row %d: %s)", len(result.InsertErrors), len(body.Rows), first.Index, detail)
+	}
+	return nil
+}
+
+// googleAccessToken uses GOOGLE_OAUTH_ACCESS_TOKEN when set, else asks gcloud for the active
+// account's token (a service account on CI after `gcloud auth activate-service-account`).
+func googleAccessToken(ctx context.Context) (string, error) {
+	if token := strings.TrimSpace(os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")); token != "" {
+		return token, nil
+	}
+	out, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("get Google access token (set GOOGLE_OAUTH_ACCESS_TOKEN or log in with gcloud): %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
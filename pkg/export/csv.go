@@ -0,0 +1,51 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// csvHeader names the columns of SaveCSV, one per Row field.
+var csvHeader = []string{"run_id", "timestamp", "component", "platform", "variant", "device", "os_version", "virtual", "tier", "build_type", "metric", "value"}
+
+// SaveCSV writes result's Rows to path as CSV for spreadsheets, creating its directory.
+func SaveCSV(path string, result report.Result) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create report directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create report file: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write(csvHeader)
+	for _, row := range Rows(result) {
+		_ = w.Write([]string{
+			row.RunID,
+			row.Timestamp.Format(time.RFC3339),
+			row.Component,
+			row.Platform,
+			row.Variant,
+			row.Device,
+			row.OSVersion,
+			strconv.FormatBool(row.Virtual),
+			row.Tier,
+			row.BuildType,
+			row.Metric,
+			strconv.FormatFloat(row.Value, 'f', -1, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("write report file: %w", err)
+	}
+	return f.Close()
+}
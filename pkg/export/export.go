@@ -0,0 +1,116 @@
+// Package export sends benchmark results to external stores named by a URI, e.g.
+// bigquery://project.dataset.table.
+package export
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// Row is one metric of one platform measurement, the long format that survives new metrics being
+// added without schema changes.
+type Row struct {
+	RunID     string    `json:"run_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Component string    `json:"component"`
+	Platform  string    `json:"platform"`
+	// Variant is the foldable posture, iPad layout or --os-matrix OS version, empty for a plain run.
+	Variant   string `json:"variant,omitempty"`
+	Device    string `json:"device,omitempty"`
+	OSVersion string `json:"os_version,omitempty"`
+	Virtual   bool   `json:"virtual"`
+	// Tier is the device's rough hardware class (low, mid or high), for grouping similar devices.
+	Tier      string  `json:"tier,omitempty"`
+	BuildType string  `json:"build_type,omitempty"`
+	Metric    string  `json:"metric"`
+	Value     float64 `json:"value"`
+}
+
+// Validate checks that uri names a supported sink, so that a typo fails before the benchmark runs.
+func Validate(uri string) error {
+	scheme, target, ok := strings.Cut(uri, "://")
+	if !ok {
+		return fmt.Errorf("invalid export %q (expected scheme://target, e.g. bigquery://project.dataset.table)", uri)
+	}
+	switch scheme {
+	case "bigquery":
+		_, err := parseBigQueryTable(target)
+		return err
+	}
+	return fmt.Errorf("unsupported export %q (supported: bigquery://)", uri)
+}
+
+// Export sends result's rows to the sink named by uri.
+func Export(ctx context.Context, uri string, result report.Result) error {
+	if err := Validate(uri); err != nil {
+		return err
+	}
+	scheme, target, _ := strings.Cut(uri, "://")
+	switch scheme {
+	case "bigquery":
+		table, _ := parseBigQueryTable(target)
+		return insertBigQuery(ctx, table, Rows(result))
+	}
+	return nil
+}
+
+// Rows flattens every numeric field of each platform measurement in result into a row, keyed by
+// its JSON path (e.g. "jank.jankFrames"). Arrays and device details are left out.
+func Rows(result report.Result) []Row {
+	var rows []Row
+	add := func(platform, variant string, metrics any, device *report.DeviceMetadata, buildType string, ts time.Time) {
+		base := Row{
+			RunID:     result.RunID,
+			Timestamp: ts,
+			Component: result.Component,
+			Platform:  platform,
+			Variant:   variant,
+			Virtual:   device.IsVirtual(),
+			BuildType: buildType,
+		}
+		if device != nil {
+			base.Device = device.Model
+			base.OSVersion = device.OSVersion
+			base.Tier = device.Tier
+		}
+		values := report.NumericFields(metrics)
+		names := make([]string, 0, len(values))
+		for name := range values {
+			if !strings.HasPrefix(name, "device.") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			row := base
+			row.Metric, row.Value = name, values[name]
+			rows = append(rows, row)
+		}
+	}
+	if m := result.Android; m != nil {
+		add("android", "", m, m.Device, m.BuildType, m.Timestamp)
+	}
+	for _, p := range result.Postures {
+		add("android", p.Posture, p.Android, p.Android.Device, p.Android.BuildType, p.Android.Timestamp)
+	}
+	if m := result.IOS; m != nil {
+		add("ios", "", m, m.Device, m.BuildType, m.Timestamp)
+	}
+	for _, l := range result.Layouts {
+		add("ios", l.Layout, l.IOS, l.IOS.Device, l.IOS.BuildType, l.IOS.Timestamp)
+	}
+	for _, v := range result.OSVersions {
+		if m := v.Android; m != nil {
+			add("android", v.OS, m, m.Device, m.BuildType, m.Timestamp)
+		}
+		if m := v.IOS; m != nil {
+			add("ios", v.OS, m, m.Device, m.BuildType, m.Timestamp)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,395 @@
+package harness
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Markers logged by generated harnesses. The Android harness writes them to logcat under MarkerTag.
+const (
+	MarkerTag      = "DesignBench"
+	MarkerStart    = "render-start"
+	MarkerRendered = "render-complete"
+	MarkerUnknown  = "unknown-component"
+)
+
+// MarkerComponents is logged, followed by names=<JSON array>, by a harness asked to list its
+// registered components. It then exits without rendering.
+const MarkerComponents = "components"
+
+// ListComponentsExtra is the boolean intent extra that makes the Android harness list its
+// components instead of rendering one. The iOS harness reads IOSListComponentsEnv instead.
+const ListComponentsExtra = "designbench_list_components"
+
+// IOSListComponentsEnv is the environment variable that makes the iOS harness list its components.
+const IOSListComponentsEnv = "DESIGNBENCH_LIST_COMPONENTS"
+
+// ParseComponentList reads the names from a components marker such as
+// `components names=["Card","PrimaryButton"]`. ok is false when message holds no such marker.
+func ParseComponentList(message string) (names []string, ok bool) {
+	_, rest, found := strings.Cut(message, MarkerComponents+" names=")
+	if !found {
+		return nil, false
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(rest)), &names); err != nil {
+		return nil, false
+	}
+	return names, true
+}
+
+// AnimationExtra is the intent extra naming the animation the Android harness plays after the first frame.
+const AnimationExtra = "designbench_animation"
+
+// StrictModeExtra is the boolean intent extra that makes the Android harness enable StrictMode,
+// logging every violation, before it renders.
+const StrictModeExtra = "designbench_strict_mode"
+
+// ResultFile is the JSON file, in the app's files directory (Android) or Documents directory (iOS),
+// where the harness stores custom metrics the app reports, as an object of metric names to numbers.
+const ResultFile = "designbench.json"
+
+// ParseResultFile reads a harness result file. Values that are not numbers are skipped; an empty
+// file holds no metrics and yields nil.
+func ParseResultFile(data []byte) (map[string]float64, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", ResultFile, err)
+	}
+	metrics := make(map[string]float64, len(raw))
+	for name, value := range raw {
+		if number, ok := value.(float64); ok {
+			metrics[name] = number
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+	return metrics, nil
+}
+
+// AnimationDelay is how long the Android harness waits after the first frame before playing the
+// requested animation, so the benchmark can reset frame stats in between.
+const AnimationDelay = time.Second
+
+// DefaultAndroidActivity is the class name of the generated Android harness activity.
+const DefaultAndroidActivity = "DesignBenchActivity"
+
+// AndroidOptions parameterises the generated Kotlin harness.
+type AndroidOptions struct {
+	// Package is the Kotlin package the activity is declared in, e.g. com.example.app.
+	Package string
+	// Activity is the activity class name; defaults to DefaultAndroidActivity.
+	Activity string
+}
+
+var kotlinIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var kotlinPackage = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// AndroidHarness renders a Compose activity that reads the designbench_component extra, renders the
+// matching composable from a registry and logs DesignBench markers once the first frame is drawn.
+func AndroidHarness(opts AndroidOptions) ([]byte, error) {
+	if opts.Activity == "" {
+		opts.Activity = DefaultAndroidActivity
+	}
+	if !kotlinPackage.MatchString(opts.Package) {
+		return nil, errors.New("a valid Kotlin package name is required")
+	}
+	if !kotlinIdentifier.MatchString(opts.Activity) {
+		return nil, errors.New("activity must be a valid Kotlin class name")
+	}
+	var buf bytes.Buffer
+	err := androidTemplate.Execute(&buf, struct {
+		AndroidOptions
+		Tag, Start, Rendered, Unknown, Components, AnimationExtra, StrictModeExtra, ListExtra, ResultFile string
+		AnimationDelayMs                                                                                  int64
+	}{opts, MarkerTag, MarkerStart, MarkerRendered, MarkerUnknown, MarkerComponents, AnimationExtra, StrictModeExtra, ListComponentsExtra, ResultFile, AnimationDelay.Milliseconds()})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var androidTemplate = template.Must(template.New("android").Parse(`package {{ .Package }}
+
+import android.content.Context
+import android.os.Bundle
+import android.os.StrictMode
+import android.util.Log
+import androidx.activity.ComponentActivity
+import androidx.activity.compose.setContent
+import androidx.compose.material3.Text
+import androidx.compose.runtime.Composable
+import androidx.compose.runtime.LaunchedEffect
+import androidx.compose.runtime.getValue
+import androidx.compose.runtime.mutableStateOf
+import androidx.compose.runtime.remember
+import androidx.compose.runtime.setValue
+import androidx.compose.runtime.withFrameNanos
+import java.io.File
+import kotlinx.coroutines.delay
+import org.json.JSONArray
+import org.json.JSONObject
+
+/**
+ * DesignBench harness generated by ` + "`designbench generate android-harness`" + `.
+ *
+ * DesignBench launches this activity with ` + "`am start -W -e designbench_component <name>`" + ` and renders the
+ * matching entry from [DesignBenchRegistry]. Markers are logged under the "{{ .Tag }}" tag.
+ */
+class {{ .Activity }} : ComponentActivity() {
+    override fun onCreate(savedInstanceState: Bundle?) {
+        super.onCreate(savedInstanceState)
+        if (intent.getBooleanExtra(LIST_COMPONENTS_EXTRA, false)) {
+            // Answers ` + "`designbench components`" + ` without rendering anything.
+            Log.i(TAG, "{{ .Components }} names=${JSONArray(DesignBenchRegistry.components.keys.sorted())}")
+            finish()
+            return
+        }
+        if (intent.getBooleanExtra(STRICT_MODE_EXTRA, false)) {
+            // DesignBench counts the logged violations of ` + "`--strict-mode`" + ` runs by policy.
+            StrictMode.setThreadPolicy(StrictMode.ThreadPolicy.Builder().detectAll().penaltyLog().build())
+            StrictMode.setVmPolicy(StrictMode.VmPolicy.Builder().detectAll().penaltyLog().build())
+        }
+        val name = intent.getStringExtra(COMPONENT_EXTRA).orEmpty()
+        val animationName = intent.getStringExtra(ANIMATION_EXTRA)
+        val content = DesignBenchRegistry.components[name]
+        val animation = animationName?.let { DesignBenchRegistry.animations[it] }
+        if (content == null && animation == null) {
+            Log.w(TAG, "{{ .Unknown }} component=$name")
+        }
+        Log.i(TAG, "{{ .Start }} component=$name")
+        setContent {
+            var play by remember { mutableStateOf(false) }
+            when {
+                animation != null -> animation(play)
+                content != null -> content()
+                else -> Text("Unknown DesignBench component: $name")
+            }
+            LaunchedEffect(Unit) {
+                // Resumes on the frame after the first composition, i.e. once it has been drawn.
+                withFrameNanos { }
+                Log.i(TAG, "{{ .Rendered }} component=$name")
+                reportFullyDrawn()
+                if (animation != null) {
+                    // DesignBench resets frame stats during this pause, then measures the animation.
+                    delay(ANIMATION_DELAY_MS)
+                    play = true
+                }
+            }
+        }
+    }
+
+    companion object {
+        const val COMPONENT_EXTRA = "designbench_component"
+        const val ANIMATION_EXTRA = "{{ .AnimationExtra }}"
+        const val STRICT_MODE_EXTRA = "{{ .StrictModeExtra }}"
+        const val LIST_COMPONENTS_EXTRA = "{{ .ListExtra }}"
+        const val ANIMATION_DELAY_MS = {{ .AnimationDelayMs }}L
+        const val TAG = "{{ .Tag }}"
+    }
+}
+
+/**
+ * Custom metrics the app reports, such as items loaded or time to a cached state. DesignBench reads
+ * them after the run (debuggable builds only, through run-as) into the report's custom map.
+ */
+object DesignBenchMetrics {
+    fun record(context: Context, name: String, value: Double) {
+        val file = File(context.filesDir, "{{ .ResultFile }}")
+        val metrics = if (file.exists()) JSONObject(file.readText()) else JSONObject()
+        metrics.put(name, value)
+        file.writeText(metrics.toString())
+    }
+}
+
+/** Components DesignBench can render, keyed by the name passed with --view. */
+object DesignBenchRegistry {
+    val components: Map<String, @Composable () -> Unit> = mapOf(
+        // "PrimaryButton" to { PrimaryButton(text = "Continue", onClick = {}) },
+    )
+
+    /** Animations for ` + "`--scenario animation`" + `, keyed by --animation. Start animating once play is true. */
+    val animations: Map<String, @Composable (play: Boolean) -> Unit> = mapOf(
+        // "ExpandCard" to { play -> ExpandableCard(expanded = play) },
+    )
+}
+`))
+
+// Unified-logging identifiers used by the generated iOS harness for its os_log and signpost markers.
+const (
+	IOSSubsystem    = "designbench"
+	IOSSignpostName = "Render"
+	// IOSSinceLaunchField is appended to the iOS render-complete marker with the milliseconds from
+	// process start to the first frame.
+	IOSSinceLaunchField = "sinceLaunchMs"
+	// IOSPrewarmedField is appended to the iOS render-complete marker with "true" when iOS started
+	// the process ahead of the launch (ActivePrewarm).
+	IOSPrewarmedField = "prewarmed"
+)
+
+// IOSHarnessFile is the file name the iOS harness is written to.
+const IOSHarnessFile = "DesignBenchHarness.swift"
+
+const (
+	iosComponentEnv   = "DESIGNBENCH_COMPONENT"
+	iosMultitaskEnv   = "DESIGNBENCH_MULTITASKING"
+	iosSlideOverWidth = 320
+)
+
+// IOSHarness renders a SwiftUI view that reads DESIGNBENCH_COMPONENT from the environment, renders
+// the matching view from a registry and emits os_log markers plus a signpost interval around the
+// first render.
+func IOSHarness() ([]byte, error) {
+	var buf bytes.Buffer
+	err := iosTemplate.Execute(&buf, map[string]any{
+		"Subsystem":      IOSSubsystem,
+		"Category":       MarkerTag,
+		"Signpost":       IOSSignpostName,
+		"Start":          MarkerStart,
+		"Rendered":       MarkerRendered,
+		"Unknown":        MarkerUnknown,
+		"SinceLaunch":    IOSSinceLaunchField,
+		"Prewarmed":      IOSPrewarmedField,
+		"ComponentEnv":   iosComponentEnv,
+		"MultitaskEnv":   iosMultitaskEnv,
+		"SlideOverWidth": iosSlideOverWidth,
+		"ResultFile":     ResultFile,
+		"Components":     MarkerComponents,
+		"ListEnv":        IOSListComponentsEnv,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var iosTemplate = template.Must(template.New("ios").Parse(`import SwiftUI
+import os
+
+// DesignBench harness generated by ` + "`designbench generate ios-harness`" + `.
+//
+// DesignBench launches the app with {{ .ComponentEnv }} set to the name passed with --view. Show
+// DesignBenchRootView from your App when DesignBenchHarness.isActive:
+//
+//     WindowGroup {
+//         if DesignBenchHarness.isActive { DesignBenchRootView() } else { ContentView() }
+//     }
+
+/// Views DesignBench can render, keyed by the name passed with --view.
+enum DesignBenchRegistry {
+    static let components: [String: () -> AnyView] = [
+        // "PrimaryButton": { AnyView(PrimaryButton(title: "Continue")) },
+    ]
+}
+
+enum DesignBenchHarness {
+    static let logger = Logger(subsystem: "{{ .Subsystem }}", category: "{{ .Category }}")
+    static let signposter = OSSignposter(subsystem: "{{ .Subsystem }}", category: .pointsOfInterest)
+
+    static var isActive: Bool {
+        let environment = ProcessInfo.processInfo.environment
+        return environment["{{ .ComponentEnv }}"] != nil || environment["{{ .ListEnv }}"] != nil
+    }
+    static var componentName: String { ProcessInfo.processInfo.environment["{{ .ComponentEnv }}"] ?? "" }
+
+    /// Width share for the iPad multitasking layout requested by ` + "`--scenario split-view`" + `.
+    static func width(in available: CGFloat) -> CGFloat {
+        switch ProcessInfo.processInfo.environment["{{ .MultitaskEnv }}"] {
+        case "split-half": return available / 2
+        case "split-third": return available / 3
+        case "slide-over": return min(available, {{ .SlideOverWidth }})
+        default: return available
+        }
+    }
+
+    /// Answers ` + "`designbench components`" + `: logs the registered names and exits without rendering.
+    static func listComponentsIfRequested() {
+        guard ProcessInfo.processInfo.environment["{{ .ListEnv }}"] != nil else { return }
+        let names = DesignBenchRegistry.components.keys.sorted()
+        let data = (try? JSONSerialization.data(withJSONObject: names)) ?? Data("[]".utf8)
+        logger.info("{{ .Components }} names=\(String(decoding: data, as: UTF8.self), privacy: .public)")
+        exit(0)
+    }
+
+    /// Whether iOS launched the process ahead of time, in which case it started long before the tap.
+    static var isPrewarmed: Bool { ProcessInfo.processInfo.environment["ActivePrewarm"] == "1" }
+
+    /// Milliseconds since the kernel started this process, so that DesignBench can time the first
+    /// frame without the overhead of simctl.
+    static var msSinceProcessStart: Double {
+        var info = kinfo_proc()
+        var size = MemoryLayout<kinfo_proc>.stride
+        var mib: [Int32] = [CTL_KERN, KERN_PROC, KERN_PROC_PID, getpid()]
+        guard sysctl(&mib, u_int(mib.count), &info, &size, nil, 0) == 0 else { return -1 }
+        let start = info.kp_proc.p_starttime
+        let started = Double(start.tv_sec) + Double(start.tv_usec) / 1_000_000
+        return (Date().timeIntervalSince1970 - started) * 1000
+    }
+}
+
+/// Metrics the app reports itself, such as render spans or view counts. DesignBench reads them
+/// from Documents/{{ .ResultFile }} after the run and adds them to the report's custom metrics:
+///
+///     DesignBenchMetrics.record("visibleRows", 24)
+enum DesignBenchMetrics {
+    private static let queue = DispatchQueue(label: "designbench.metrics")
+
+    static func record(_ name: String, _ value: Double) {
+        queue.sync {
+            guard let dir = FileManager.default.urls(for: .documentDirectory, in: .userDomainMask).first else { return }
+            let url = dir.appendingPathComponent("{{ .ResultFile }}")
+            var metrics = (try? Data(contentsOf: url))
+                .flatMap { try? JSONSerialization.jsonObject(with: $0) as? [String: Double] } ?? [:]
+            metrics[name] = value
+            if let data = try? JSONSerialization.data(withJSONObject: metrics) {
+                try? data.write(to: url, options: .atomic)
+            }
+        }
+    }
+}
+
+struct DesignBenchRootView: View {
+    private let name = DesignBenchHarness.componentName
+
+    init() {
+        DesignBenchHarness.listComponentsIfRequested()
+        DesignBenchHarness.logger.info("{{ .Start }} component=\(DesignBenchHarness.componentName, privacy: .public)")
+    }
+
+    var body: some View {
+        GeometryReader { proxy in
+            content
+                .frame(width: DesignBenchHarness.width(in: proxy.size.width), height: proxy.size.height)
+        }
+        .onAppear {
+            let state = DesignBenchHarness.signposter.beginInterval("{{ .Signpost }}", "\(name, privacy: .public)")
+            // The next main-queue turn runs after the first frame has been committed.
+            DispatchQueue.main.async {
+                DesignBenchHarness.signposter.endInterval("{{ .Signpost }}", state)
+                let sinceLaunch = DesignBenchHarness.msSinceProcessStart
+                DesignBenchHarness.logger.info("{{ .Rendered }} component=\(name, privacy: .public) {{ .SinceLaunch }}=\(sinceLaunch, format: .fixed(precision: 1), privacy: .public) {{ .Prewarmed }}=\(DesignBenchHarness.isPrewarmed, privacy: .public)")
+            }
+        }
+    }
+
+    @ViewBuilder private var content: some View {
+        if let make = DesignBenchRegistry.components[name] {
+            make()
+        } else {
+            Text("Unknown DesignBench component: \(name)")
+                .onAppear {
+                    DesignBenchHarness.logger.error("{{ .Unknown }} component=\(name, privacy: .public)")
+                }
+        }
+    }
+}
+`))
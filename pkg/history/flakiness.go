@@ -0,0 +1,96 @@
+package history
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// Flakiness describes how much the runs of one benchmark on one device disagree.
+type Flakiness struct {
+	Key       string `json:"key"`
+	Component string `json:"component,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	Device    string `json:"device,omitempty"`
+	Metric    string `json:"metric"`
+	// Runs is the number of runs considered, Failures how many of them failed.
+	Runs     int     `json:"runs"`
+	Failures int     `json:"failures,omitempty"`
+	Median   float64 `json:"median"`
+	// VariationPct is the coefficient of variation of the successful runs' values: their standard
+	// deviation as a percentage of their mean.
+	VariationPct float64 `json:"variationPct"`
+	// Score is VariationPct plus the percentage of runs that failed, so that a benchmark failing one
+	// run in ten ranks with one whose numbers swing by 10%. Higher is less trustworthy.
+	Score float64 `json:"score"`
+}
+
+type flakinessGroup struct {
+	key, device string
+}
+
+// ScoreFlakiness scores every benchmark and device with at least minRuns successful runs among its
+// last window runs, least stable first. Failed runs record no device, so they count against every
+// device of their key.
+func ScoreFlakiness(records []Record, window, minRuns int) []Flakiness {
+	runs := map[flakinessGroup][]Record{}
+	var order []flakinessGroup
+	for _, rec := range slices.Backward(records) {
+		if rec.Error != "" {
+			continue
+		}
+		group := flakinessGroup{rec.Key, rec.Device}
+		if _, ok := runs[group]; !ok {
+			order = append(order, group)
+		}
+		if len(runs[group]) < window {
+			runs[group] = append(runs[group], rec)
+		}
+	}
+	var scores []Flakiness
+	for _, group := range order {
+		latest := runs[group][0]
+		var values []float64
+		for _, rec := range runs[group] {
+			// A benchmark whose primary metric changed (e.g. a new scenario) is scored on the latest one.
+			if rec.Metric == latest.Metric {
+				values = append(values, rec.Value)
+			}
+		}
+		if len(values) < minRuns {
+			continue
+		}
+		failures := countFailures(records, group, runs[group][len(runs[group])-1].Time)
+		total := len(values) + failures
+		f := Flakiness{
+			Key:       group.key,
+			Component: latest.Component,
+			Platform:  latest.Platform,
+			Device:    group.device,
+			Metric:    latest.Metric,
+			Runs:      total,
+			Failures:  failures,
+			Median:    stats.Median(values),
+		}
+		if mean := stats.Mean(values); mean > 0 {
+			f.VariationPct = stats.StdDev(values) / mean * 100
+		}
+		f.Score = f.VariationPct + float64(failures)/float64(total)*100
+		scores = append(scores, f)
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores
+}
+
+// countFailures counts the failed runs of the group's key since the oldest run considered.
+func countFailures(records []Record, group flakinessGroup, since time.Time) int {
+	n := 0
+	for _, rec := range records {
+		if rec.Error != "" && rec.Key == group.key && (rec.Device == "" || rec.Device == group.device) && !rec.Time.Before(since) {
+			n++
+		}
+	}
+	return n
+}
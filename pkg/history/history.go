@@ -0,0 +1,101 @@
+// Package history keeps one line per benchmark run in a JSONL file so that new results can be
+// compared with the runs before them.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// FileName is the history store kept in the reports directory.
+const FileName = "history.jsonl"
+
+// Record summarises one run by its primary metric. Records with the same Key are comparable.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Key       string    `json:"key"`
+	Suite     string    `json:"suite,omitempty"`
+	Platform  string    `json:"platform"`
+	Component string    `json:"component,omitempty"`
+	RunID     string    `json:"runId,omitempty"`
+	Metric    string    `json:"metric,omitempty"`
+	Value     float64   `json:"value,omitempty"`
+	// Error is set for failed runs, which have no metric.
+	Error string `json:"error,omitempty"`
+	// Seed is the shuffle seed of the cycle the run belonged to, when the order was randomised.
+	Seed int64 `json:"seed,omitempty"`
+	// Device is the model the primary metric was measured on.
+	Device string `json:"device,omitempty"`
+	// Iterations is the count suite run --max-duration cut the run to from PlannedIterations; both
+	// are 0 when it ran as configured.
+	Iterations        int `json:"iterations,omitempty"`
+	PlannedIterations int `json:"plannedIterations,omitempty"`
+}
+
+// Append adds rec to the store at path, creating it if needed.
+func Append(path string, rec Record) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	if err := json.NewEncoder(f).Encode(rec); err != nil {
+		f.Close()
+		return fmt.Errorf("write history: %w", err)
+	}
+	return f.Close()
+}
+
+// Load reads every record in the store at path, oldest first. A missing store holds no records.
+func Load(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open history: %w", err)
+	}
+	defer f.Close()
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// Baseline returns the median value of the last window successful records with key and metric,
+// and how many records it used.
+func Baseline(records []Record, key, metric string, window int) (float64, int) {
+	values := make([]float64, 0, window)
+	for _, rec := range slices.Backward(records) {
+		if len(values) == window {
+			break
+		}
+		if rec.Key == key && rec.Metric == metric && rec.Error == "" {
+			values = append(values, rec.Value)
+		}
+	}
+	if len(values) == 0 {
+		return 0, 0
+	}
+	return stats.Median(values), len(values)
+}
@@ -0,0 +1,61 @@
+package ios
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// getTaskAllowRe matches the get-task-allow entitlement in the entitlements plist that codesign
+// embeds in the executable (and, for simulator builds, the __entitlements section).
+var getTaskAllowRe = regexp.MustCompile(`<key>get-task-allow</key>\s*<(true|false)\s*/>`)
+
+// detectBuildType inspects the installed app bundle. Xcode 16 and later link Debug builds against a
+// separate `<App>.debug.dylib` (and previews against `__preview.dylib`), which release builds never
+// contain. Otherwise the executable's entitlements decide: Debug builds are signed with
+// get-task-allow so that a debugger can attach. Debug builds are compiled without optimisation, so
+// their timings are not representative. Without either kind of evidence the build type is unknown.
+func detectBuildType(ctx context.Context, xcrunPath, deviceID, bundleID string) (string, error) {
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "get_app_container", deviceID, bundleID, "app")
+	if err != nil {
+		return "", fmt.Errorf("locate app bundle: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	bundle := strings.TrimSpace(string(out))
+	entries, err := os.ReadDir(bundle)
+	if err != nil {
+		return "", fmt.Errorf("read app bundle: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ".debug.dylib") || name == "__preview.dylib" {
+			return report.BuildTypeDebug, nil
+		}
+	}
+	executable, err := os.ReadFile(filepath.Join(bundle, strings.TrimSuffix(filepath.Base(bundle), ".app")))
+	if err != nil {
+		return "", fmt.Errorf("read app executable: %w", err)
+	}
+	return buildTypeFromEntitlements(executable)
+}
+
+// buildTypeFromEntitlements reads get-task-allow from the entitlements embedded in executable. A
+// build whose entitlements lack it, or set it to false, is a release build.
+func buildTypeFromEntitlements(executable []byte) (string, error) {
+	if match := getTaskAllowRe.FindSubmatch(executable); match != nil {
+		if string(match[1]) == "true" {
+			return report.BuildTypeDebug, nil
+		}
+		return report.BuildTypeRelease, nil
+	}
+	if bytes.Contains(executable, []byte("<plist")) && bytes.Contains(executable, []byte("<dict")) {
+		return report.BuildTypeRelease, nil
+	}
+	return "", errors.New("no debug dylib and no embedded entitlements to tell Debug from Release")
+}
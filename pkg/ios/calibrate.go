@@ -0,0 +1,45 @@
+package ios
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/calibration"
+	"github.com/tahatesser/designbench/pkg/report"
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// Calibrate runs calibration.Script in the simulator rounds times and returns the
+// simulator's metadata and the median duration in milliseconds, net of the cost of spawning an
+// empty shell. Simulators share the host CPU, so the score describes the host.
+func Calibrate(ctx context.Context, xcrunPath, deviceID string, rounds int) (*report.DeviceMetadata, float64, error) {
+	if xcrunPath == "" {
+		xcrunPath = "xcrun"
+	}
+	meta, err := ResolveDevice(ctx, xcrunPath, deviceID)
+	if err != nil {
+		return nil, 0, err
+	}
+	scores := make([]float64, 0, rounds)
+	for range rounds {
+		overhead, err := timeSpawn(ctx, xcrunPath, meta.ID, ":")
+		if err != nil {
+			return nil, 0, err
+		}
+		elapsed, err := timeSpawn(ctx, xcrunPath, meta.ID, calibration.Script)
+		if err != nil {
+			return nil, 0, err
+		}
+		scores = append(scores, max(elapsed-overhead, 0))
+	}
+	return meta, stats.Median(scores), nil
+}
+
+func timeSpawn(ctx context.Context, xcrunPath, deviceID, script string) (float64, error) {
+	started := time.Now()
+	if out, err := runXCRun(ctx, xcrunPath, "simctl", "spawn", deviceID, "/bin/sh", "-c", script); err != nil {
+		return 0, fmt.Errorf("run calibration workload: %w: %s", err, out)
+	}
+	return float64(time.Since(started).Microseconds()) / 1000, nil
+}
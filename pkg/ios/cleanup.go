@@ -0,0 +1,34 @@
+package ios
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// cleanupTimeout bounds the simctl commands issued to restore state once a run has been interrupted.
+const cleanupTimeout = 10 * time.Second
+
+// commandWaitDelay is how long a cancelled xcrun invocation may keep its output pipes open before
+// they are closed forcibly; simctl can leave spawned helpers holding them.
+const commandWaitDelay = 2 * time.Second
+
+// xcrunCommand builds an xcrun invocation that is killed when ctx is done without waiting
+// indefinitely for orphaned children to release its output.
+func xcrunCommand(ctx context.Context, xcrunPath string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, xcrunPath, args...)
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
+
+// cleanupContext detaches from ctx's cancellation (keeping its values, such as the event handler) so
+// the simulator can still be tidied up after a timeout.
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+}
+
+// terminateApp stops the app so an interrupted run does not leave it in the foreground.
+func terminateApp(ctx context.Context, xcrunPath, deviceID, bundleID string) error {
+	_, err := runXCRun(ctx, xcrunPath, "simctl", "terminate", deviceID, bundleID)
+	return err
+}
@@ -0,0 +1,85 @@
+package ios
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/harness"
+)
+
+// ListComponents launches the harness with harness.IOSListComponentsEnv set and returns the
+// component names it logs before exiting. ok is false when no list was logged in time, as with a
+// harness that predates the handshake. A running instance is terminated first, since resuming it
+// would not list anything.
+func ListComponents(ctx context.Context, xcrunPath, deviceID, bundleID string) (names []string, ok bool, err error) {
+	if xcrunPath == "" {
+		xcrunPath = "xcrun"
+	}
+	device, err := resolveDeviceMetadata(ctx, xcrunPath, deviceID)
+	if err != nil {
+		return nil, false, err
+	}
+	if device.ID == "" {
+		return nil, false, errors.New("no booted simulator found; provide --device to target a specific simulator")
+	}
+	defer func() {
+		stopCtx, cancel := cleanupContext(ctx)
+		defer cancel()
+		_ = terminateApp(stopCtx, xcrunPath, device.ID, bundleID)
+	}()
+
+	args := []string{"simctl", "launch", "--terminate-running-process", device.ID, bundleID}
+	cmd := xcrunCommand(ctx, xcrunPath, args...)
+	cmd.Env = append(os.Environ(), "SIMCTL_CHILD_"+harness.IOSListComponentsEnv+"=1")
+	launchStart := time.Now()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, false, fmt.Errorf("launch %s: %w: %s", bundleID, err, string(out))
+	}
+
+	logArgs := []string{
+		"simctl", "spawn", device.ID, "log", "show",
+		"--style", "ndjson",
+		"--info",
+		"--start", launchStart.Format("2006-01-02 15:04:05"),
+		"--predicate", fmt.Sprintf(`subsystem == %q AND eventMessage BEGINSWITH %q`, harness.IOSSubsystem, harness.MarkerComponents),
+	}
+	deadline := time.Now().Add(firstFrameTimeout)
+	for {
+		out, err := runXCRun(ctx, xcrunPath, logArgs...)
+		if err != nil {
+			return nil, false, fmt.Errorf("log show: %w: %s", err, string(out))
+		}
+		if names, ok := parseComponentList(out); ok {
+			return names, true, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-time.After(firstFramePollInterval):
+		}
+	}
+}
+
+// parseComponentList returns the last components marker in `log show --style ndjson` output.
+func parseComponentList(output []byte) (names []string, ok bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		var entry logEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if list, found := harness.ParseComponentList(entry.EventMessage); found {
+			names, ok = list, true
+		}
+	}
+	return names, ok
+}
@@ -0,0 +1,40 @@
+package ios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/harness"
+)
+
+// customMetricsPath returns where the harness's DesignBenchMetrics writes inside the app's data
+// container. Only simulator containers live on the host, so physical devices have no path.
+func customMetricsPath(ctx context.Context, xcrunPath, deviceID, bundleID string) (string, error) {
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "get_app_container", deviceID, bundleID, "data")
+	if err != nil {
+		return "", fmt.Errorf("get_app_container: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	container := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(container) {
+		return "", fmt.Errorf("unexpected app container %q", container)
+	}
+	return filepath.Join(container, "Documents", harness.ResultFile), nil
+}
+
+// readCustomMetrics reads the metrics the app reported. An app that reported none has no file,
+// which yields nil.
+func readCustomMetrics(path string) (map[string]float64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return harness.ParseResultFile(data)
+}
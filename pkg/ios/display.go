@@ -0,0 +1,85 @@
+package ios
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+type simctlDeviceType struct {
+	Identifier string `json:"identifier"`
+	Name       string `json:"name"`
+	BundlePath string `json:"bundlePath"`
+}
+
+type simctlDeviceTypeList struct {
+	DeviceTypes []simctlDeviceType `json:"devicetypes"`
+}
+
+// proMotionIPhoneRe matches iPhone device types with a 120 Hz ProMotion display: the Pro models
+// since the iPhone 13, and every model since the iPhone 17.
+var proMotionIPhoneRe = regexp.MustCompile(`^iPhone (1[3-6] Pro( Max)?|1[7-9]\b.*|[2-9]\d\b.*|Air\b.*)$`)
+
+// deviceTypeProfile mirrors the screen keys of a .simdevicetype bundle's profile.plist.
+type deviceTypeProfile struct {
+	MainScreenWidth  int     `json:"mainScreenWidth"`
+	MainScreenHeight int     `json:"mainScreenHeight"`
+	MainScreenScale  float64 `json:"mainScreenScale"`
+}
+
+// applyDisplayMetadata resolves the simulator's device type bundle and records its screen size,
+// scale factor and refresh rate.
+func applyDisplayMetadata(ctx context.Context, xcrunPath, deviceTypeID string, meta *report.DeviceMetadata) error {
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "list", "devicetypes", "--json")
+	if err != nil {
+		return fmt.Errorf("list device types: %w: %s", err, string(out))
+	}
+	var payload simctlDeviceTypeList
+	if err := json.Unmarshal(out, &payload); err != nil {
+		return fmt.Errorf("decode device types: %w", err)
+	}
+	for _, deviceType := range payload.DeviceTypes {
+		if deviceType.Identifier != deviceTypeID || deviceType.BundlePath == "" {
+			continue
+		}
+		profilePath := filepath.Join(deviceType.BundlePath, "Contents", "Resources", "profile.plist")
+		profileJSON, err := exec.CommandContext(ctx, "plutil", "-convert", "json", "-o", "-", profilePath).Output()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", profilePath, err)
+		}
+		var profile deviceTypeProfile
+		if err := json.Unmarshal(profileJSON, &profile); err != nil {
+			return fmt.Errorf("decode %s: %w", profilePath, err)
+		}
+		if profile.MainScreenWidth > 0 && profile.MainScreenHeight > 0 {
+			meta.Resolution = fmt.Sprintf("%dx%d", profile.MainScreenWidth, profile.MainScreenHeight)
+		}
+		meta.ScaleFactor = profile.MainScreenScale
+		meta.RefreshRateHz = deviceTypeRefreshRate(deviceType.Name)
+		return nil
+	}
+	return fmt.Errorf("device type %s not found", deviceTypeID)
+}
+
+// deviceTypeRefreshRate returns the display's maximum refresh rate for a simulator device type name
+// such as "iPhone 15 Pro": 120 Hz for ProMotion devices and 60 Hz for the rest. The profile.plist
+// of a device type does not record it.
+func deviceTypeRefreshRate(name string) float64 {
+	switch {
+	case proMotionIPhoneRe.MatchString(name):
+		return 120
+	case name == "iPad Pro (9.7-inch)" || name == "iPad Pro (12.9-inch)":
+		// The first iPad Pro models predate ProMotion.
+		return 60
+	case strings.HasPrefix(name, "iPad Pro"):
+		return 120
+	default:
+		return 60
+	}
+}
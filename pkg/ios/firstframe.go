@@ -0,0 +1,123 @@
+package ios
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/harness"
+)
+
+// firstFrameTimeout bounds how long the runner waits for the harness's render-complete marker.
+const firstFrameTimeout = 10 * time.Second
+
+// firstFramePollInterval is the pause between unified-log queries while waiting for the marker.
+const firstFramePollInterval = 250 * time.Millisecond
+
+// logTimestampLayout is the timestamp format of `log show --style ndjson`.
+const logTimestampLayout = "2006-01-02 15:04:05.000000-0700"
+
+// logEntry is the part of a `log show --style ndjson` line the runner reads.
+type logEntry struct {
+	Timestamp    string `json:"timestamp"`
+	EventMessage string `json:"eventMessage"`
+}
+
+// firstFrame is what the harness's render-complete marker reports about a launch.
+type firstFrame struct {
+	ms        float64
+	prewarmed bool
+}
+
+// waitForFirstFrame polls the unified log for the harness's render-complete marker and returns the
+// time from process start to the first frame. Prewarmed processes, and harnesses that predate the
+// sinceLaunchMs field, are timed from launchStart, the moment the launch command was issued, to the
+// marker's timestamp.
+func waitForFirstFrame(ctx context.Context, xcrunPath, deviceID, pid string, launchStart time.Time) (firstFrame, error) {
+	target := deviceID
+	if target == "" {
+		target = "booted"
+	}
+	predicate := fmt.Sprintf(`subsystem == %q AND eventMessage BEGINSWITH %q`, harness.IOSSubsystem, harness.MarkerRendered)
+	if pid != "" {
+		predicate = fmt.Sprintf("processID == %s AND %s", pid, predicate)
+	}
+	args := []string{
+		"simctl", "spawn", target, "log", "show",
+		"--style", "ndjson",
+		"--info",
+		"--start", launchStart.Format("2006-01-02 15:04:05"),
+		"--predicate", predicate,
+	}
+	deadline := time.Now().Add(firstFrameTimeout)
+	for {
+		out, err := runXCRun(ctx, xcrunPath, args...)
+		if err != nil {
+			return firstFrame{}, fmt.Errorf("log show: %w: %s", err, string(out))
+		}
+		if frame, ok := parseFirstFrame(out, launchStart); ok {
+			return frame, nil
+		}
+		if time.Now().After(deadline) {
+			return firstFrame{}, fmt.Errorf("no %s marker within %s (is the app running the DesignBench harness?)", harness.MarkerRendered, firstFrameTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return firstFrame{}, ctx.Err()
+		case <-time.After(firstFramePollInterval):
+		}
+	}
+}
+
+// parseFirstFrame reads the earliest render-complete marker in output.
+func parseFirstFrame(output []byte, launchStart time.Time) (firstFrame, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		var entry logEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || !strings.HasPrefix(entry.EventMessage, harness.MarkerRendered) {
+			continue
+		}
+		// --start has one-second granularity, so markers from a previous launch may be included.
+		logged, err := time.Parse(logTimestampLayout, entry.Timestamp)
+		if err != nil || logged.Before(launchStart) {
+			continue
+		}
+		// A prewarmed process started long before the launch, so its own age says nothing.
+		frame := firstFrame{prewarmed: markerField(entry.EventMessage, harness.IOSPrewarmedField) == "true"}
+		if ms, err := sinceLaunchMs(entry.EventMessage); err == nil && !frame.prewarmed {
+			frame.ms = ms
+		} else {
+			frame.ms = float64(logged.Sub(launchStart)) / float64(time.Millisecond)
+		}
+		return frame, true
+	}
+	return firstFrame{}, false
+}
+
+// markerField returns the value of a name=value field in a harness marker.
+func markerField(message, name string) string {
+	for _, field := range strings.Fields(message) {
+		if value, ok := strings.CutPrefix(field, name+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func sinceLaunchMs(message string) (float64, error) {
+	value := markerField(message, harness.IOSSinceLaunchField)
+	if value == "" {
+		return 0, errors.New("marker has no " + harness.IOSSinceLaunchField)
+	}
+	ms, err := strconv.ParseFloat(value, 64)
+	if err != nil || ms < 0 {
+		return 0, fmt.Errorf("invalid %s %q", harness.IOSSinceLaunchField, value)
+	}
+	return ms, nil
+}
@@ -0,0 +1,57 @@
+package ios
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// hangPattern matches the HangTracer console message, e.g. `Hang detected: 0.52s (debugger attached, not reporting)`.
+var hangPattern = regexp.MustCompile(`Hang detected:\s*([\d.]+)s`)
+
+// collectHangs scans the unified log since start for hang events raised by the app process. Without
+// the process id it fails, since the log would also hold every other process's hangs.
+func collectHangs(ctx context.Context, xcrunPath, deviceID, pid string, start time.Time) (int, float64, error) {
+	if pid == "" {
+		return 0, 0, errors.New("the app's process id is unknown")
+	}
+	target := deviceID
+	if target == "" {
+		target = "booted"
+	}
+	predicate := fmt.Sprintf(`processID == %s AND eventMessage CONTAINS "Hang detected"`, pid)
+	args := []string{
+		"simctl", "spawn", target, "log", "show",
+		"--style", "compact",
+		"--start", start.Format("2006-01-02 15:04:05"),
+		"--predicate", predicate,
+	}
+	out, err := runXCRun(ctx, xcrunPath, args...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("log show: %w: %s", err, string(out))
+	}
+	count, longest := parseHangEvents(out)
+	return count, longest, nil
+}
+
+func parseHangEvents(output []byte) (int, float64) {
+	count := 0
+	var longestMs float64
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		match := hangPattern.FindStringSubmatch(scanner.Text())
+		if len(match) < 2 {
+			continue
+		}
+		count++
+		if seconds, err := strconv.ParseFloat(match[1], 64); err == nil && seconds*1000 > longestMs {
+			longestMs = seconds * 1000
+		}
+	}
+	return count, longestMs
+}
@@ -0,0 +1,64 @@
+package ios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// defaultIdleTimeout caps the idle wait when Config.IdleTimeout is unset.
+const defaultIdleTimeout = 30 * time.Second
+
+// hostCPUIdlePattern matches the idle share in macOS top's header, e.g.
+// `CPU usage: 5.12% user, 8.10% sys, 86.76% idle`.
+var hostCPUIdlePattern = regexp.MustCompile(`CPU usage:.*?([0-9.]+)% idle`)
+
+// waitForIdle polls host CPU utilisation (simulators share the Mac's cores) until it drops to
+// thresholdPct or timeout elapses, returning the last reading.
+func waitForIdle(ctx context.Context, thresholdPct float64, timeout time.Duration) (float64, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		load, err := sampleHostCPU(ctx)
+		if err != nil {
+			return 0, err
+		}
+		events.Metric(ctx, "hostCpuPercent", load)
+		if load <= thresholdPct {
+			return load, nil
+		}
+		if !time.Now().Before(deadline) {
+			return load, fmt.Errorf("host CPU still at %.1f%% after %s (threshold %.1f%%)", load, timeout, thresholdPct)
+		}
+	}
+}
+
+// sampleHostCPU runs two one-second top samples; the first reports usage since boot, so only the
+// second is used.
+func sampleHostCPU(ctx context.Context) (float64, error) {
+	args := []string{"-l", "2", "-n", "0", "-s", "1"}
+	started := time.Now()
+	out, err := exec.CommandContext(ctx, "top", args...).Output()
+	events.Command(ctx, "top", args, started, err)
+	if err != nil {
+		return 0, fmt.Errorf("top: %w", err)
+	}
+	return parseHostCPU(string(out))
+}
+
+func parseHostCPU(output string) (float64, error) {
+	matches := hostCPUIdlePattern.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return 0, errors.New("cpu usage not found in top output")
+	}
+	idle, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse idle share: %w", err)
+	}
+	return max(100-idle, 0), nil
+}
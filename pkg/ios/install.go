@@ -0,0 +1,132 @@
+package ios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// InstallConfig describes how to build the app with xcodebuild and install it on the simulator.
+type InstallConfig struct {
+	XCRunPath string
+	DeviceID  string
+	// Container is the .xcworkspace or .xcodeproj to build.
+	Container     string
+	Scheme        string
+	Configuration string
+	OnEvent       events.Handler
+}
+
+// Install builds Scheme for the target simulator into a temporary DerivedData directory and
+// installs the resulting .app with `simctl install`. The DerivedData directory is always new, so
+// the build is always clean.
+func Install(ctx context.Context, cfg InstallConfig) (*report.InstallRecord, error) {
+	if cfg.Container == "" || cfg.Scheme == "" {
+		return nil, errors.New("an Xcode workspace or project and a scheme are required to install")
+	}
+	ctx = events.WithHandler(ctx, "ios", cfg.OnEvent)
+	xcrun := cfg.XCRunPath
+	if xcrun == "" {
+		xcrun = "xcrun"
+	}
+	configuration := cfg.Configuration
+	if configuration == "" {
+		configuration = "Release"
+	}
+	device, err := resolveDeviceMetadata(ctx, xcrun, cfg.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device.ID == "" {
+		return nil, errors.New("no booted simulator found; provide --device to target a specific simulator")
+	}
+
+	derivedData, err := os.MkdirTemp("", "designbench-derived-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(derivedData)
+
+	events.Phase(ctx, "build")
+	started := time.Now()
+	containerFlag := "-project"
+	if strings.HasSuffix(cfg.Container, ".xcworkspace") {
+		containerFlag = "-workspace"
+	}
+	out, err := runXCRun(ctx, xcrun, "xcodebuild",
+		containerFlag, cfg.Container,
+		"-scheme", cfg.Scheme,
+		"-configuration", configuration,
+		"-destination", "id="+device.ID,
+		"-derivedDataPath", derivedData,
+		"build")
+	if err != nil {
+		return nil, fmt.Errorf("xcodebuild %s (%s): %w: %s", cfg.Scheme, configuration, err, lastLines(string(out), 20))
+	}
+	apps, _ := filepath.Glob(filepath.Join(derivedData, "Build", "Products", configuration+"-iphonesimulator", "*.app"))
+	if len(apps) == 0 {
+		return nil, fmt.Errorf("xcodebuild produced no .app for scheme %s", cfg.Scheme)
+	}
+
+	events.Phase(ctx, "install")
+	if out, err := runXCRun(ctx, xcrun, "simctl", "install", device.ID, apps[0]); err != nil {
+		return nil, fmt.Errorf("simctl install: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return &report.InstallRecord{
+		Tool:       "xcodebuild",
+		Task:       fmt.Sprintf("%s (%s)", cfg.Scheme, configuration),
+		DurationMs: float64(time.Since(started)) / float64(time.Millisecond),
+		Cache:      report.BuildCacheClean,
+	}, nil
+}
+
+// lastLines keeps the tail of xcodebuild's output, where the failing step is reported.
+func lastLines(output string, n int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// InstallArtifact installs a prebuilt simulator .app with `simctl install`, for runners without
+// access to the Xcode project. Device builds (.ipa) are refused: runs launch and measure through
+// simctl, which cannot reach a physical device.
+func InstallArtifact(ctx context.Context, xcrunPath, deviceID, path string, onEvent events.Handler) (*report.InstallRecord, error) {
+	ctx = events.WithHandler(ctx, "ios", onEvent)
+	if xcrunPath == "" {
+		xcrunPath = "xcrun"
+	}
+	switch filepath.Ext(path) {
+	case ".app":
+	case ".ipa":
+		return nil, fmt.Errorf("cannot install %s: benchmarks run on simulators, which need a .app; .ipa device builds are not supported yet", filepath.Base(path))
+	default:
+		return nil, fmt.Errorf("cannot install %s: expected a simulator .app", filepath.Base(path))
+	}
+	device, err := resolveDeviceMetadata(ctx, xcrunPath, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device.ID == "" {
+		return nil, errors.New("no booted simulator found; provide --device to target a specific simulator")
+	}
+
+	events.Phase(ctx, "install")
+	started := time.Now()
+	if out, err := runXCRun(ctx, xcrunPath, "simctl", "install", device.ID, path); err != nil {
+		return nil, fmt.Errorf("simctl install: %w: %s", err, lastLines(string(out), 20))
+	}
+	return &report.InstallRecord{
+		Tool:       "simctl",
+		Task:       "install " + filepath.Base(path),
+		DurationMs: float64(time.Since(started)) / float64(time.Millisecond),
+	}, nil
+}
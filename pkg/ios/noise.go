@@ -0,0 +1,57 @@
+package ios
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+	"github.com/tahatesser/designbench/pkg/report"
+)
+
+// noiseProcesses is how many of the busiest processes a background-noise snapshot keeps.
+const noiseProcesses = 5
+
+// captureBackgroundNoise lists the processes using the most CPU on the Mac, whose cores simulators
+// share, so that an outlier iteration can be traced to e.g. Spotlight indexing alongside it. ps
+// reports a decaying average over the last minute, so no sampling interval is needed.
+func captureBackgroundNoise(ctx context.Context) ([]report.ProcessCPU, error) {
+	args := []string{"-Acro", "pid,%cpu,comm"}
+	started := time.Now()
+	out, err := exec.CommandContext(ctx, "ps", args...).Output()
+	events.Command(ctx, "ps", args, started, err)
+	if err != nil {
+		return nil, fmt.Errorf("ps: %w", err)
+	}
+	return parsePSProcesses(string(out), noiseProcesses), nil
+}
+
+// parsePSProcesses reads `ps -r` output, busiest first, and returns the first limit processes that
+// use any CPU, other than ps itself.
+func parsePSProcesses(output string, limit int) []report.ProcessCPU {
+	var processes []report.ProcessCPU
+	lines := strings.Split(output, "\n")
+	for _, line := range lines[min(1, len(lines)):] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		cpu, err := strconv.ParseFloat(fields[1], 64)
+		name := strings.Join(fields[2:], " ")
+		if err != nil || cpu <= 0 || name == "ps" {
+			continue
+		}
+		processes = append(processes, report.ProcessCPU{PID: pid, Name: name, CPUPercent: cpu})
+		if len(processes) == limit {
+			break
+		}
+	}
+	return processes
+}
@@ -0,0 +1,151 @@
+package ios
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// OSTarget is the simulator that runs one OS version of an OS matrix: a simulator of the reference
+// device's type on that version's runtime, created and booted as needed. Stop undoes both.
+type OSTarget struct {
+	// OS is the version as simctl names its runtime, e.g. "iOS 17.5".
+	OS   string
+	UDID string
+	// booted and created record what StartOSTarget did, so that Stop only undoes that.
+	booted  bool
+	created bool
+	xcrun   string
+}
+
+type simctlRuntime struct {
+	Identifier  string `json:"identifier"`
+	Name        string `json:"name"`
+	IsAvailable bool   `json:"isAvailable"`
+}
+
+type simctlRuntimeList struct {
+	Runtimes []simctlRuntime `json:"runtimes"`
+}
+
+// StartOSTarget returns a booted simulator of the same device type as the reference simulator
+// (referenceID, or the booted one when empty) running osVersion, e.g. "iOS 17.5". It reuses an
+// existing simulator where there is one and otherwise creates one on the installed runtime.
+func StartOSTarget(ctx context.Context, xcrunPath, referenceID, osVersion string, onEvent events.Handler) (*OSTarget, error) {
+	ctx = events.WithHandler(ctx, "ios", onEvent)
+	if xcrunPath == "" {
+		xcrunPath = "xcrun"
+	}
+	reference, err := ResolveDevice(ctx, xcrunPath, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	if !reference.IsSimulator || reference.DeviceType == "" {
+		return nil, fmt.Errorf("--os-matrix needs a simulator to take the device type from; %s is not one", reference.ID)
+	}
+	devices, err := listSimctlDevices(ctx, xcrunPath)
+	if err != nil {
+		return nil, err
+	}
+	target := &OSTarget{OS: osVersion, xcrun: xcrunPath}
+	var state string
+	if device, ok := devices[reference.ID]; ok && strings.EqualFold(runtimeToVersion(device.Runtime), osVersion) {
+		target.UDID, state = device.UDID, device.State
+	} else {
+		for _, device := range devices {
+			if device.IsAvailable && device.DeviceTypeIdentifier == reference.DeviceType && strings.EqualFold(runtimeToVersion(device.Runtime), osVersion) {
+				// A booted simulator needs no boot, so it wins over the others.
+				if target.UDID == "" || strings.EqualFold(device.State, "Booted") {
+					target.UDID, state = device.UDID, device.State
+				}
+			}
+		}
+	}
+	if target.UDID == "" {
+		runtime, err := findRuntime(ctx, xcrunPath, osVersion)
+		if err != nil {
+			return nil, err
+		}
+		events.Phase(ctx, "os-target-create")
+		name := simulatorName(reference.Model, runtime.Name)
+		out, err := runXCRun(ctx, xcrunPath, "simctl", "create", name, reference.DeviceType, runtime.Identifier)
+		if err != nil {
+			return nil, fmt.Errorf("create %s simulator: %w: %s", osVersion, err, strings.TrimSpace(string(out)))
+		}
+		target.UDID, target.created = strings.TrimSpace(string(out)), true
+	}
+	if !strings.EqualFold(state, "Booted") {
+		events.Phase(ctx, "os-target-boot")
+		if out, err := runXCRun(ctx, xcrunPath, "simctl", "boot", target.UDID); err != nil {
+			target.Stop(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("boot %s simulator: %w: %s", osVersion, err, strings.TrimSpace(string(out)))
+		}
+		target.booted = true
+		if out, err := runXCRun(ctx, xcrunPath, "simctl", "bootstatus", target.UDID, "-b"); err != nil {
+			target.Stop(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("wait for %s simulator to boot: %w: %s", osVersion, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return target, nil
+}
+
+// findRuntime returns the installed runtime named osVersion.
+func findRuntime(ctx context.Context, xcrunPath, osVersion string) (simctlRuntime, error) {
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "list", "runtimes", "--json")
+	if err != nil {
+		return simctlRuntime{}, fmt.Errorf("list runtimes: %w: %s", err, string(out))
+	}
+	var payload simctlRuntimeList
+	if err := json.Unmarshal(out, &payload); err != nil {
+		return simctlRuntime{}, fmt.Errorf("decode simctl json: %w", err)
+	}
+	var installed []string
+	for _, runtime := range payload.Runtimes {
+		if !runtime.IsAvailable {
+			continue
+		}
+		if strings.EqualFold(runtime.Name, osVersion) {
+			return runtime, nil
+		}
+		installed = append(installed, runtime.Name)
+	}
+	return simctlRuntime{}, fmt.Errorf("no %s runtime is installed (installed: %s); add it in Xcode's Platforms settings", osVersion, strings.Join(installed, ", "))
+}
+
+// Stop shuts down the simulator if StartOSTarget booted it and deletes it if it created it.
+func (t *OSTarget) Stop(ctx context.Context) {
+	if t.booted {
+		_, _ = runXCRun(ctx, t.xcrun, "simctl", "shutdown", t.UDID)
+	}
+	if t.created {
+		_, _ = runXCRun(ctx, t.xcrun, "simctl", "delete", t.UDID)
+	}
+}
+
+// CopyApp installs bundleID on dst from the app bundle installed on src, unless dst has it already.
+// It reports whether it installed the app.
+func CopyApp(ctx context.Context, xcrunPath, src, dst, bundleID string, onEvent events.Handler) (bool, error) {
+	ctx = events.WithHandler(ctx, "ios", onEvent)
+	if xcrunPath == "" {
+		xcrunPath = "xcrun"
+	}
+	if _, err := runXCRun(ctx, xcrunPath, "simctl", "get_app_container", dst, bundleID, "app"); err == nil {
+		return false, nil
+	}
+	if src == "" {
+		return false, errors.New("no simulator to copy the app from; pass --app or --device")
+	}
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "get_app_container", src, bundleID, "app")
+	if err != nil {
+		return false, fmt.Errorf("%s is not installed on %s to copy to %s; install it there or pass --app", bundleID, src, dst)
+	}
+	events.Phase(ctx, "os-target-install")
+	if out, err := runXCRun(ctx, xcrunPath, "simctl", "install", dst, strings.TrimSpace(string(out))); err != nil {
+		return false, fmt.Errorf("simctl install: %w: %s", err, lastLines(string(out), 20))
+	}
+	return true, nil
+}
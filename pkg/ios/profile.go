@@ -0,0 +1,122 @@
+package ios
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// cpuProfileTemplate is the Instruments template recorded by --cpu-profile.
+const cpuProfileTemplate = "Time Profiler"
+
+// cpuProfileTimeLimit ends a recording that is never stopped, e.g. when designbench is killed.
+const cpuProfileTimeLimit = 2 * time.Minute
+
+// cpuProfileStartTimeout bounds how long xctrace may take to attach before recording.
+const cpuProfileStartTimeout = 30 * time.Second
+
+// cpuProfileStopTimeout bounds how long xctrace may take to save the trace once stopped.
+const cpuProfileStopTimeout = time.Minute
+
+// cpuProfile is an xctrace Time Profiler recording of every process on a simulator.
+type cpuProfile struct {
+	cmd    *exec.Cmd
+	path   string
+	done   chan error
+	output *strings.Builder
+}
+
+// startCPUProfile starts recording to path (a .trace bundle) and returns once xctrace reports that
+// it is recording. The recording is not tied to ctx so that it can be stopped gracefully.
+func startCPUProfile(ctx context.Context, xcrunPath, deviceID, path string) (*cpuProfile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create cpu profile directory: %w", err)
+	}
+	args := []string{
+		"xctrace", "record",
+		"--template", cpuProfileTemplate,
+		"--device", deviceID,
+		"--all-processes",
+		"--time-limit", fmt.Sprintf("%ds", int(cpuProfileTimeLimit.Seconds())),
+		"--output", path,
+		"--no-prompt",
+	}
+	cmd := exec.Command(xcrunPath, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = cmd.Stdout
+	started := time.Now()
+	err = cmd.Start()
+	events.Command(ctx, xcrunPath, args, started, err)
+	if err != nil {
+		return nil, fmt.Errorf("start xctrace: %w", err)
+	}
+
+	profile := &cpuProfile{cmd: cmd, path: path, done: make(chan error, 1), output: &strings.Builder{}}
+	recording := make(chan struct{})
+	go func() {
+		signalled := false
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			line := scanner.Text()
+			profile.output.WriteString(line + "\n")
+			if !signalled && strings.Contains(line, "Ctrl-C to stop") {
+				signalled = true
+				close(recording)
+			}
+		}
+		profile.done <- cmd.Wait()
+	}()
+
+	select {
+	case <-recording:
+		return profile, nil
+	case err := <-profile.done:
+		return nil, fmt.Errorf("xctrace exited before recording: %v: %s", err, strings.TrimSpace(profile.output.String()))
+	case <-ctx.Done():
+		profile.abort()
+		return nil, ctx.Err()
+	case <-time.After(cpuProfileStartTimeout):
+		profile.abort()
+		return nil, fmt.Errorf("xctrace did not start recording within %s", cpuProfileStartTimeout)
+	}
+}
+
+// stop ends the recording like Ctrl-C does and waits for xctrace to save the trace.
+func (p *cpuProfile) stop(ctx context.Context) error {
+	if err := p.cmd.Process.Signal(os.Interrupt); err != nil {
+		return fmt.Errorf("stop xctrace: %w", err)
+	}
+	select {
+	case err := <-p.done:
+		if err != nil {
+			return fmt.Errorf("xctrace: %w: %s", err, strings.TrimSpace(p.output.String()))
+		}
+	case <-ctx.Done():
+		p.abort()
+		return ctx.Err()
+	case <-time.After(cpuProfileStopTimeout):
+		p.abort()
+		return fmt.Errorf("xctrace did not save %s within %s", p.path, cpuProfileStopTimeout)
+	}
+	if _, err := os.Stat(p.path); err != nil {
+		return errors.New("xctrace wrote no trace")
+	}
+	return nil
+}
+
+// abort kills a recording that will not be kept and deletes its partial output.
+func (p *cpuProfile) abort() {
+	_ = p.cmd.Process.Kill()
+	_ = os.RemoveAll(p.path)
+}
@@ -0,0 +1,37 @@
+package ios
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// Reboot shuts down and boots the simulator (deviceID, or the booted one when empty) and waits until
+// it has finished booting, so that a run starts without state left over from earlier runs. It
+// returns the simulator's UDID. Physical devices cannot be restarted through xcrun.
+func Reboot(ctx context.Context, xcrunPath, deviceID string, onEvent events.Handler) (string, error) {
+	ctx = events.WithHandler(ctx, "ios", onEvent)
+	if xcrunPath == "" {
+		xcrunPath = "xcrun"
+	}
+	device, err := ResolveDevice(ctx, xcrunPath, deviceID)
+	if err != nil {
+		return "", err
+	}
+	if !device.IsSimulator {
+		return "", fmt.Errorf("--reboot only restarts simulators; restart %s by hand", device.ID)
+	}
+	events.Phase(ctx, "reboot")
+	if out, err := runXCRun(ctx, xcrunPath, "simctl", "shutdown", device.ID); err != nil {
+		return "", fmt.Errorf("shut down simulator: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	if out, err := runXCRun(ctx, xcrunPath, "simctl", "boot", device.ID); err != nil {
+		return "", fmt.Errorf("boot simulator: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	if out, err := runXCRun(ctx, xcrunPath, "simctl", "bootstatus", device.ID, "-b"); err != nil {
+		return "", fmt.Errorf("wait for simulator to boot: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return device.ID, nil
+}
@@ -8,13 +8,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/tahatesser/designbench/pkg/events"
+	"github.com/tahatesser/designbench/pkg/redact"
 	"github.com/tahatesser/designbench/pkg/report"
+	"github.com/tahatesser/designbench/pkg/watchdog"
 )
 
 // Config controls an iOS render benchmark invocation.
@@ -25,14 +27,53 @@ type Config struct {
 	LaunchArgs         []string
 	XCRunPath          string
 	BenchmarkComponent string
+	// TerminateRunning relaunches the app from scratch when a previous instance, including one iOS
+	// prewarmed, is still running. Without it such a launch only resumes that instance.
+	TerminateRunning bool
+	// CPUProfilePath, when set, records an xctrace Time Profiler session from just before the launch
+	// until the first frame and writes the .trace bundle to this host path.
+	CPUProfilePath string
+	// Env holds extra environment variables for the app process (forwarded via SIMCTL_CHILD_).
+	Env map[string]string
+	// IdleCPUThreshold, when positive, waits before launching until host CPU utilisation (percent)
+	// is at or below this value, for up to IdleTimeout (default 30s).
+	IdleCPUThreshold float64
+	IdleTimeout      time.Duration
+	// Settle is how long to wait after launch before snapshotting memory and CPU.
+	Settle time.Duration
+	// LaunchTimeout bounds `simctl launch`. A launch that takes longer is killed together with its
+	// children, the app is terminated, and Run returns a watchdog.HungError. Zero waits as long as
+	// the run's context allows.
+	LaunchTimeout time.Duration
+	// Interact, when set, runs right after launch to drive the app to the screen under test (for
+	// example through an Appium session) before metrics are collected.
+	Interact func(ctx context.Context) error
+	// AllowDebugBuild measures debuggable/Debug builds instead of refusing them; the report is
+	// tagged with buildType "debug" either way.
+	AllowDebugBuild bool
+	// OnEvent, when set, receives progress events (phases, commands, metrics) as the run proceeds.
+	OnEvent events.Handler
 }
 
-// Run executes a simple launch benchmark by invoking `xcrun simctl launch` and timing its duration.
+// Launch states recorded in IOSMetrics.LaunchState, named like the ones `am start -W` reports.
+const (
+	// LaunchStateCold is a launch that started a new process.
+	LaunchStateCold = "COLD"
+	// LaunchStateWarm is a launch that resumed a process which was already running.
+	LaunchStateWarm = "WARM"
+	// LaunchStatePrewarmed is a launch whose process iOS had already started ahead of time.
+	LaunchStatePrewarmed = "PREWARMED"
+)
+
+// Run launches the app with `xcrun simctl launch` and times its first frame from the harness's
+// render-complete marker in the unified log.
 func Run(ctx context.Context, cfg Config) (*report.IOSMetrics, error) {
 	if cfg.BundleID == "" {
 		return nil, errors.New("ios bundle id is required")
 	}
 
+	ctx = events.WithHandler(ctx, "ios", cfg.OnEvent)
+
 	xcrun := cfg.XCRunPath
 	if xcrun == "" {
 		xcrun = "xcrun"
@@ -43,6 +84,7 @@ func Run(ctx context.Context, cfg Config) (*report.IOSMetrics, error) {
 		component = cfg.BundleID
 	}
 
+	events.Phase(ctx, "device-metadata")
 	deviceMetadata, err := resolveDeviceMetadata(ctx, xcrun, cfg.DeviceID)
 	if err != nil {
 		return nil, err
@@ -52,15 +94,98 @@ func Run(ctx context.Context, cfg Config) (*report.IOSMetrics, error) {
 		return nil, errors.New("no booted simulator found; provide --device to target a specific simulator or device")
 	}
 
-	args := append([]string{"simctl", "launch", deviceID, cfg.BundleID}, cfg.LaunchArgs...)
-	cmd := exec.CommandContext(ctx, xcrun, args...)
-	if cfg.BenchmarkComponent != "" {
-		env := append(os.Environ(), "SIMCTL_CHILD_DESIGNBENCH_COMPONENT="+cfg.BenchmarkComponent)
+	// A timeout or cancellation must not leave the app running or a recording in progress.
+	var profile *cpuProfile
+	defer func() {
+		if profile != nil {
+			profile.abort()
+		}
+		if ctx.Err() == nil {
+			return
+		}
+		cleanupCtx, cancel := cleanupContext(ctx)
+		defer cancel()
+		events.Warn(cleanupCtx, fmt.Sprintf("run interrupted (%v); terminating %s", ctx.Err(), cfg.BundleID))
+		_ = terminateApp(cleanupCtx, xcrun, deviceID, cfg.BundleID)
+	}()
+
+	events.Phase(ctx, "build-type")
+	buildType, err := detectBuildType(ctx, xcrun, deviceID, cfg.BundleID)
+	if err != nil {
+		events.Warn(ctx, fmt.Sprintf("build type unknown: %v", err))
+	} else if buildType == report.BuildTypeDebug && !cfg.AllowDebugBuild {
+		return nil, fmt.Errorf("%s is a Debug build, which produces misleading numbers; build the Release configuration or pass --allow-debug", cfg.BundleID)
+	}
+
+	if cfg.IdleCPUThreshold > 0 {
+		events.Phase(ctx, "idle")
+		timeout := cfg.IdleTimeout
+		if timeout <= 0 {
+			timeout = defaultIdleTimeout
+		}
+		if _, err := waitForIdle(ctx, cfg.IdleCPUThreshold, timeout); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			events.Warn(ctx, fmt.Sprintf("continuing without idle host: %v", err))
+		}
+	}
+	// The snapshot only explains outliers, so the iteration runs without one if ps fails.
+	noise, _ := captureBackgroundNoise(ctx)
+
+	// A process that is already running, whether from an earlier launch or prewarmed by iOS, makes
+	// the launch resume it instead of starting a fresh one.
+	events.Phase(ctx, "process-state")
+	runningPID, _ := resolveIOSPID(ctx, xcrun, deviceID, cfg.BundleID)
+	launchState := LaunchStateCold
+	if runningPID != "" && !cfg.TerminateRunning {
+		launchState = LaunchStateWarm
+		events.Warn(ctx, fmt.Sprintf("%s is already running (pid %s), so the launch resumes it; pass --terminate-running to start a fresh process", cfg.BundleID, runningPID))
+	}
+
+	// Metrics the app reports are read back from its container; an earlier run's must not count.
+	resultPath, resultErr := customMetricsPath(ctx, xcrun, deviceID, cfg.BundleID)
+	if resultErr == nil {
+		_ = os.Remove(resultPath)
+	}
+
+	args := []string{"simctl", "launch"}
+	if cfg.TerminateRunning {
+		args = append(args, "--terminate-running-process")
+	}
+	args = append(args, deviceID, cfg.BundleID)
+	args = append(args, cfg.LaunchArgs...)
+	launchCtx, cancelLaunch := watchdog.Start(ctx, cfg.LaunchTimeout)
+	defer cancelLaunch()
+	cmd := xcrunCommand(launchCtx, xcrun, args...)
+	watchdog.KillTree(cmd)
+	if cfg.BenchmarkComponent != "" || len(cfg.Env) > 0 {
+		env := os.Environ()
+		if cfg.BenchmarkComponent != "" {
+			env = append(env, "SIMCTL_CHILD_DESIGNBENCH_COMPONENT="+cfg.BenchmarkComponent)
+		}
+		for key, value := range cfg.Env {
+			env = append(env, "SIMCTL_CHILD_"+key+"="+value)
+		}
 		cmd.Env = env
 	}
+	if cfg.CPUProfilePath != "" {
+		events.Phase(ctx, "cpu-profile")
+		if profile, err = startCPUProfile(ctx, xcrun, deviceID, cfg.CPUProfilePath); err != nil {
+			return nil, fmt.Errorf("cpu profile: %w", err)
+		}
+	}
+	events.Phase(ctx, "launch")
 	start := time.Now()
 	output, err := cmd.CombinedOutput()
 	elapsed := time.Since(start)
+	cancelLaunch()
+	events.Command(ctx, xcrun, args, start, err)
+	if err != nil && watchdog.Fired(ctx, launchCtx) {
+		events.Warn(ctx, fmt.Sprintf("simctl launch hung for more than %s; terminating %s", cfg.LaunchTimeout, cfg.BundleID))
+		_ = terminateApp(ctx, xcrun, deviceID, cfg.BundleID)
+		return nil, &watchdog.HungError{Command: "simctl launch", Deadline: cfg.LaunchTimeout}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("run xcrun: %w: %s", err, string(output))
 	}
@@ -68,29 +193,122 @@ func Run(ctx context.Context, cfg Config) (*report.IOSMetrics, error) {
 	metrics := &report.IOSMetrics{
 		Component:          component,
 		BundleID:           cfg.BundleID,
-		LaunchArgs:         cfg.LaunchArgs,
+		LaunchArgs:         redact.Args(cfg.LaunchArgs),
 		BenchmarkComponent: cfg.BenchmarkComponent,
-		RenderTimeMs:       float64(elapsed) / float64(time.Millisecond),
-		Command:            fmt.Sprintf("%s %s", xcrun, strings.Join(args, " ")),
+		BuildType:          buildType,
+		LaunchCommandMs:    float64(elapsed) / float64(time.Millisecond),
+		LaunchState:        launchState,
+		Command:            fmt.Sprintf("%s %s", xcrun, strings.Join(redact.Args(args), " ")),
 		Timestamp:          time.Now(),
 		Device:             deviceMetadata,
 	}
+	metrics.BackgroundProcesses = noise
+
+	events.Phase(ctx, "first-frame")
+	if launchState == LaunchStateWarm {
+		// A resumed process does not render its first frame again.
+		metrics.RenderTimeMs = metrics.LaunchCommandMs
+	} else if frame, err := waitForFirstFrame(ctx, xcrun, deviceID, parseLaunchPID(output, cfg.BundleID), start); err == nil {
+		metrics.RenderTimeMs = frame.ms
+		if frame.prewarmed {
+			metrics.LaunchState = LaunchStatePrewarmed
+			events.Warn(ctx, "iOS prewarmed the process before the launch; its first frame is timed from the launch command")
+		}
+	} else {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// simctl returns once the process is spawned, so this mostly measures simctl itself.
+		metrics.RenderTimeMs = metrics.LaunchCommandMs
+		events.Warn(ctx, fmt.Sprintf("first frame unavailable, using the launch command time: %v", err))
+	}
+	events.Metric(ctx, "renderTimeMs", metrics.RenderTimeMs)
+	if profile != nil {
+		events.Phase(ctx, "cpu-profile")
+		err := profile.stop(ctx)
+		profile = nil
+		if err != nil {
+			return nil, fmt.Errorf("cpu profile: %w", err)
+		}
+		metrics.Artifacts = append(metrics.Artifacts, report.Artifact{Kind: "time-profile", Path: cfg.CPUProfilePath})
+	}
+
+	if cfg.Interact != nil {
+		events.Phase(ctx, "interact")
+		if err := cfg.Interact(ctx); err != nil {
+			return nil, fmt.Errorf("interact: %w", err)
+		}
+	}
 
+	if cfg.Settle > 0 {
+		events.Phase(ctx, "settle")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(cfg.Settle):
+		}
+	}
+
+	events.Phase(ctx, "memory")
 	if memoryMB, err := collectMemoryUsage(ctx, xcrun, deviceID, cfg.BundleID); err == nil {
 		metrics.MemoryMB = memoryMB
-	}
-	if cpuPercent, cpuTimeMs, err := collectIOSCPUMetrics(ctx, xcrun, deviceID, cfg.BundleID); err == nil {
-		if cpuPercent > 0 {
-			metrics.CPUPercent = cpuPercent
+		events.Metric(ctx, "memoryMb", memoryMB)
+	} else {
+		events.Warn(ctx, fmt.Sprintf("memory usage unavailable: %v", err))
+	}
+	events.Phase(ctx, "cpu")
+	pid, pidErr := resolveIOSPID(ctx, xcrun, deviceID, cfg.BundleID)
+	if pidErr == nil {
+		if cpuPercent, cpuTimeMs, err := iosProcessMetrics(ctx, xcrun, deviceID, pid); err == nil {
+			if cpuPercent > 0 {
+				metrics.CPUPercent = cpuPercent
+				events.Metric(ctx, "cpuPercent", cpuPercent)
+			}
+			if cpuTimeMs > 0 {
+				metrics.CPUTimeMs = cpuTimeMs
+				events.Metric(ctx, "cpuTimeMs", cpuTimeMs)
+			}
 		}
-		if cpuTimeMs > 0 {
-			metrics.CPUTimeMs = cpuTimeMs
+		// Per-thread scheduler statistics need Instruments; ps only exposes the thread count.
+		if count, err := iosThreadCount(ctx, xcrun, deviceID, pid); err == nil {
+			metrics.Threads = &report.ThreadMetrics{Count: count}
+		}
+	} else {
+		events.Warn(ctx, fmt.Sprintf("process id unavailable: %v", pidErr))
+	}
+	events.Phase(ctx, "hangs")
+	if hangs, longestMs, err := collectHangs(ctx, xcrun, deviceID, pid, start); err == nil {
+		metrics.HangCount = hangs
+		metrics.LongestHangMs = longestMs
+		events.Metric(ctx, "hangCount", float64(hangs))
+	} else {
+		events.Warn(ctx, fmt.Sprintf("hangs unavailable: %v", err))
+	}
+	if resultErr == nil {
+		events.Phase(ctx, "custom-metrics")
+		if custom, err := readCustomMetrics(resultPath); err == nil {
+			metrics.Custom = custom
+		} else {
+			events.Warn(ctx, fmt.Sprintf("custom metrics unavailable: %v", err))
 		}
 	}
 
 	return metrics, nil
 }
 
+// parseLaunchPID reads the process id from `simctl launch` output, e.g. "com.example.app: 5151".
+func parseLaunchPID(output []byte, bundleID string) string {
+	for _, line := range strings.Split(string(output), "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), bundleID+":"); ok {
+			pid := strings.TrimSpace(value)
+			if _, err := strconv.Atoi(pid); err == nil {
+				return pid
+			}
+		}
+	}
+	return ""
+}
+
 type simctlDevice struct {
 	UDID                 string `json:"udid"`
 	Name                 string `json:"name"`
@@ -105,6 +323,21 @@ type simctlList struct {
 	Devices map[string][]simctlDevice `json:"devices"`
 }
 
+// ResolveDevice returns metadata for the requested simulator/device, or the booted simulator when deviceID is empty.
+func ResolveDevice(ctx context.Context, xcrunPath, deviceID string) (*report.DeviceMetadata, error) {
+	if xcrunPath == "" {
+		xcrunPath = "xcrun"
+	}
+	meta, err := resolveDeviceMetadata(ctx, xcrunPath, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if meta.ID == "" {
+		return nil, errors.New("no booted simulator found; provide --device to target a specific simulator or device")
+	}
+	return meta, nil
+}
+
 func resolveDeviceMetadata(ctx context.Context, xcrunPath, requestedID string) (*report.DeviceMetadata, error) {
 	devices, err := listSimctlDevices(ctx, xcrunPath)
 	if err != nil && requestedID == "" {
@@ -116,27 +349,35 @@ func resolveDeviceMetadata(ctx context.Context, xcrunPath, requestedID string) (
 
 	if requestedID != "" {
 		if dev, ok := devices[requestedID]; ok {
-			return simctlToMetadata(dev), nil
+			return simulatorMetadata(ctx, xcrunPath, dev), nil
 		}
 		// fallback to minimal metadata if device not in simulator list (likely physical)
 		return &report.DeviceMetadata{
 			ID:       requestedID,
 			Platform: "ios",
+			Renderer: "Metal",
 		}, nil
 	}
 
 	for _, dev := range devices {
 		if strings.EqualFold(dev.State, "Booted") {
-			return simctlToMetadata(dev), nil
+			return simulatorMetadata(ctx, xcrunPath, dev), nil
 		}
 	}
 
 	return &report.DeviceMetadata{Platform: "ios"}, nil
 }
 
+// runXCRun executes xcrun with args and reports the invocation as a progress event.
+func runXCRun(ctx context.Context, xcrunPath string, args ...string) ([]byte, error) {
+	started := time.Now()
+	out, err := xcrunCommand(ctx, xcrunPath, args...).CombinedOutput()
+	events.Command(ctx, xcrunPath, args, started, err)
+	return out, err
+}
+
 func listSimctlDevices(ctx context.Context, xcrunPath string) (map[string]simctlDevice, error) {
-	cmd := exec.CommandContext(ctx, xcrunPath, "simctl", "list", "devices", "--json")
-	out, err := cmd.CombinedOutput()
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "list", "devices", "--json")
 	if err != nil {
 		return nil, fmt.Errorf("list simulators: %w: %s", err, string(out))
 	}
@@ -154,17 +395,29 @@ func listSimctlDevices(ctx context.Context, xcrunPath string) (map[string]simctl
 	return result, nil
 }
 
+func simulatorMetadata(ctx context.Context, xcrunPath string, device simctlDevice) *report.DeviceMetadata {
+	meta := simctlToMetadata(device)
+	if meta.DeviceType != "" {
+		// Screen details are optional; older Xcode bundles may lack profile.plist.
+		_ = applyDisplayMetadata(ctx, xcrunPath, meta.DeviceType, meta)
+	}
+	return meta
+}
+
 func simctlToMetadata(device simctlDevice) *report.DeviceMetadata {
 	meta := &report.DeviceMetadata{
 		ID:       device.UDID,
 		Model:    device.Name,
 		Platform: "ios",
+		// SwiftUI and Core Animation always render through Metal on supported simulators and devices.
+		Renderer:    "Metal",
+		IsSimulator: true,
 	}
 	if device.Runtime != "" {
 		meta.OSVersion = runtimeToVersion(device.Runtime)
 	}
 	if device.DeviceTypeIdentifier != "" {
-		meta.Resolution = device.DeviceTypeIdentifier
+		meta.DeviceType = device.DeviceTypeIdentifier
 	}
 	return meta
 }
@@ -210,8 +463,7 @@ func collectMemoryUsage(ctx context.Context, xcrunPath, deviceID, bundleID strin
 		return 0, errors.New("bundle id required for memory collection")
 	}
 	args := []string{"simctl", "spawn", target, "memory_usage", "-b", bundleID}
-	cmd := exec.CommandContext(ctx, xcrunPath, args...)
-	out, err := cmd.CombinedOutput()
+	out, err := runXCRun(ctx, xcrunPath, args...)
 	if err != nil {
 		return 0, fmt.Errorf("memory_usage: %w: %s", err, string(out))
 	}
@@ -262,24 +514,12 @@ func parseSizeToMB(line string) (float64, error) {
 	}
 }
 
-func collectIOSCPUMetrics(ctx context.Context, xcrunPath, deviceID, bundleID string) (float64, float64, error) {
-	pid, err := resolveIOSPID(ctx, xcrunPath, deviceID, bundleID)
-	if err != nil {
-		return 0, 0, err
-	}
-	percent, timeMs, metricsErr := iosProcessMetrics(ctx, xcrunPath, deviceID, pid)
-	if metricsErr != nil {
-		return 0, 0, metricsErr
-	}
-	return percent, timeMs, nil
-}
-
 func resolveIOSPID(ctx context.Context, xcrunPath, deviceID, bundleID string) (string, error) {
 	target := deviceID
 	if target == "" {
 		target = "booted"
 	}
-	out, err := exec.CommandContext(ctx, xcrunPath, "simctl", "spawn", target, "launchctl", "list").CombinedOutput()
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "spawn", target, "launchctl", "list")
 	if err != nil {
 		return "", fmt.Errorf("launchctl list: %w: %s", err, string(out))
 	}
@@ -316,13 +556,39 @@ func iosProcessMetrics(ctx context.Context, xcrunPath, deviceID, pid string) (fl
 	if target == "" {
 		target = "booted"
 	}
-	out, err := exec.CommandContext(ctx, xcrunPath, "simctl", "spawn", target, "ps", "-o", "pid,pcpu,time", "-p", pid).CombinedOutput()
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "spawn", target, "ps", "-o", "pid,pcpu,time", "-p", pid)
 	if err != nil {
 		return 0, 0, fmt.Errorf("ps metrics: %w: %s", err, string(out))
 	}
 	return parseIOSPSMetrics(out, pid)
 }
 
+// iosThreadCount relies on `ps -M`, which prints a header followed by one row per thread.
+func iosThreadCount(ctx context.Context, xcrunPath, deviceID, pid string) (int, error) {
+	target := deviceID
+	if target == "" {
+		target = "booted"
+	}
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "spawn", target, "ps", "-M", "-p", pid)
+	if err != nil {
+		return 0, fmt.Errorf("ps threads: %w: %s", err, string(out))
+	}
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if count < 2 {
+		return 0, errors.New("no threads listed in ps output")
+	}
+	return count - 1, nil
+}
+
 func parseIOSPSMetrics(output []byte, pid string) (float64, float64, error) {
 	scanner := bufio.NewScanner(bytes.NewReader(output))
 	parsedHeader := false
@@ -0,0 +1,83 @@
+package ios
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// Simulator is a simulator provisioned by CreateSimulator.
+type Simulator struct {
+	UDID       string `json:"udid"`
+	Name       string `json:"name"`
+	DeviceType string `json:"deviceType"`
+	Runtime    string `json:"runtime"`
+	// Created is false when a simulator of that name, type and runtime already existed.
+	Created bool `json:"created"`
+}
+
+// CreateSimulator creates a simulator of deviceType, by name (e.g. "iPhone 15") or identifier, on
+// the installed osVersion runtime, e.g. "iOS 17.5". It is named name, or "<type> (<runtime>,
+// DesignBench)" when empty, and an available simulator with that name, type and runtime is reused,
+// so that provisioning can run on every CI job.
+func CreateSimulator(ctx context.Context, xcrunPath, deviceType, osVersion, name string, onEvent events.Handler) (*Simulator, error) {
+	ctx = events.WithHandler(ctx, "ios", onEvent)
+	if xcrunPath == "" {
+		xcrunPath = "xcrun"
+	}
+	dt, err := findDeviceType(ctx, xcrunPath, deviceType)
+	if err != nil {
+		return nil, err
+	}
+	runtime, err := findRuntime(ctx, xcrunPath, osVersion)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = simulatorName(dt.Name, runtime.Name)
+	}
+	sim := &Simulator{Name: name, DeviceType: dt.Identifier, Runtime: runtime.Identifier}
+	devices, err := listSimctlDevices(ctx, xcrunPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		if device.IsAvailable && device.Name == name && device.DeviceTypeIdentifier == dt.Identifier && device.Runtime == runtime.Identifier {
+			sim.UDID = device.UDID
+			return sim, nil
+		}
+	}
+	events.Phase(ctx, "simulator-create")
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "create", name, dt.Identifier, runtime.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("create %s simulator: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	sim.UDID, sim.Created = strings.TrimSpace(string(out)), true
+	return sim, nil
+}
+
+// simulatorName names the simulators DesignBench creates, e.g. "iPhone 15 (iOS 17.5, DesignBench)".
+func simulatorName(model, runtimeName string) string {
+	return fmt.Sprintf("%s (%s, DesignBench)", model, runtimeName)
+}
+
+// findDeviceType returns the device type whose name or identifier is deviceType.
+func findDeviceType(ctx context.Context, xcrunPath, deviceType string) (simctlDeviceType, error) {
+	out, err := runXCRun(ctx, xcrunPath, "simctl", "list", "devicetypes", "--json")
+	if err != nil {
+		return simctlDeviceType{}, fmt.Errorf("list device types: %w: %s", err, string(out))
+	}
+	var payload simctlDeviceTypeList
+	if err := json.Unmarshal(out, &payload); err != nil {
+		return simctlDeviceType{}, fmt.Errorf("decode device types: %w", err)
+	}
+	for _, dt := range payload.DeviceTypes {
+		if strings.EqualFold(dt.Name, deviceType) || dt.Identifier == deviceType {
+			return dt, nil
+		}
+	}
+	return simctlDeviceType{}, fmt.Errorf("unknown device type %q; xcrun simctl list devicetypes lists the installed ones", deviceType)
+}
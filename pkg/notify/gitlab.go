@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// GitLabMR identifies a merge request and the token used to write notes on it.
+type GitLabMR struct {
+	// APIURL is the GitLab REST API root, e.g. https://gitlab.com/api/v4.
+	APIURL string
+	// Project is the numeric project ID or the URL-encoded namespace/project path.
+	Project string
+	IID     string
+	Token   string
+}
+
+// GitLabMRFromEnv returns the merge request of the current GitLab CI merge request pipeline. The
+// token comes from DESIGNBENCH_GITLAB_TOKEN or GITLAB_TOKEN, since CI_JOB_TOKEN cannot write notes.
+func GitLabMRFromEnv() (GitLabMR, error) {
+	mr := GitLabMR{
+		APIURL:  strings.TrimRight(os.Getenv("CI_API_V4_URL"), "/"),
+		Project: os.Getenv("CI_PROJECT_ID"),
+		IID:     os.Getenv("CI_MERGE_REQUEST_IID"),
+		Token:   os.Getenv("DESIGNBENCH_GITLAB_TOKEN"),
+	}
+	if mr.Token == "" {
+		mr.Token = os.Getenv("GITLAB_TOKEN")
+	}
+	switch {
+	case mr.APIURL == "" || mr.Project == "":
+		return GitLabMR{}, errors.New("--gitlab-mr needs CI_API_V4_URL and CI_PROJECT_ID; run it in a GitLab CI job")
+	case mr.IID == "":
+		return GitLabMR{}, errors.New("--gitlab-mr needs CI_MERGE_REQUEST_IID; run it in a merge request pipeline (rules: if: $CI_PIPELINE_SOURCE == \"merge_request_event\")")
+	case mr.Token == "":
+		return GitLabMR{}, errors.New("--gitlab-mr needs an access token with the api scope in DESIGNBENCH_GITLAB_TOKEN or GITLAB_TOKEN")
+	}
+	return mr, nil
+}
+
+type gitlabNote struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// GitLabNote posts body as a note on the merge request, or edits the note posted earlier with the
+// same key, so that every pipeline keeps one up-to-date note per key instead of adding another.
+func GitLabNote(ctx context.Context, mr GitLabMR, key, body string) error {
+	marker := fmt.Sprintf("<!-- designbench:%s -->", key)
+	body = marker + "\n" + body
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	notes := fmt.Sprintf("%s/projects/%s/merge_requests/%s/notes", mr.APIURL, url.PathEscape(mr.Project), url.PathEscape(mr.IID))
+
+	for page := "1"; page != ""; {
+		var existing []gitlabNote
+		next, err := gitlabRequest(ctx, mr, http.MethodGet, notes+"?per_page=100&sort=asc&page="+page, nil, &existing)
+		if err != nil {
+			return err
+		}
+		for _, note := range existing {
+			if strings.HasPrefix(note.Body, marker) {
+				_, err := gitlabRequest(ctx, mr, http.MethodPut, fmt.Sprintf("%s/%d", notes, note.ID), map[string]string{"body": body}, nil)
+				return err
+			}
+		}
+		page = next
+	}
+	_, err := gitlabRequest(ctx, mr, http.MethodPost, notes, map[string]string{"body": body}, nil)
+	return err
+}
+
+// gitlabRequest calls the GitLab API, decoding the response into out when it is not nil, and
+// returns the next page number from the X-Next-Page header.
+func gitlabRequest(ctx context.Context, mr GitLabMR, method, endpoint string, payload, out any) (nextPage string, err error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("PRIVATE-TOKEN", mr.Token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("gitlab %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("gitlab %s %s returned %s: %s", method, resp.Request.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return "", fmt.Errorf("decode gitlab response: %w", err)
+		}
+	}
+	return resp.Header.Get("X-Next-Page"), nil
+}
@@ -0,0 +1,112 @@
+// Package notify delivers short messages about benchmark results to chat webhooks, by email and as
+// merge request notes.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds a single webhook delivery.
+const requestTimeout = 15 * time.Second
+
+// Webhook payload formats.
+const (
+	FormatSlack = "slack"
+	FormatTeams = "teams"
+)
+
+// teamsHosts are the host suffixes of Microsoft Teams incoming webhooks: Workflows (Power Automate)
+// and the older Office 365 connectors.
+var teamsHosts = []string{".webhook.office.com", ".logic.azure.com", ".powerplatform.com"}
+
+// DetectFormat guesses the payload format a webhook URL expects from its host: Teams for Microsoft's
+// webhook hosts, Slack's payload, which most other chat integrations accept, otherwise.
+func DetectFormat(webhookURL string) string {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return FormatSlack
+	}
+	host := "." + strings.ToLower(u.Hostname())
+	for _, suffix := range teamsHosts {
+		if strings.HasSuffix(host, suffix) {
+			return FormatTeams
+		}
+	}
+	return FormatSlack
+}
+
+// Send posts text to the webhook in the given format.
+func Send(ctx context.Context, format, webhookURL, text string) error {
+	switch format {
+	case FormatSlack:
+		return Webhook(ctx, webhookURL, text)
+	case FormatTeams:
+		return Teams(ctx, webhookURL, text)
+	}
+	return fmt.Errorf("unknown webhook format %q (expected %s or %s)", format, FormatSlack, FormatTeams)
+}
+
+// Webhook posts text as `{"text": ...}`, the payload Slack incoming webhooks and most chat
+// integrations accept.
+func Webhook(ctx context.Context, url, text string) error {
+	return post(ctx, url, map[string]string{"text": text})
+}
+
+// Teams posts text as an Adaptive Card, which Teams Workflows and connector webhooks accept where
+// they reject the plain text payload. The first line becomes the card's title.
+func Teams(ctx context.Context, url, text string) error {
+	var body []map[string]any
+	for i, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		block := map[string]any{"type": "TextBlock", "text": line, "wrap": true}
+		if i == 0 {
+			block["weight"] = "Bolder"
+			block["size"] = "Medium"
+		}
+		body = append(body, block)
+	}
+	return post(ctx, url, map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	})
+}
+
+// post sends payload as JSON and fails unless the webhook answers with a 2xx status.
+func post(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
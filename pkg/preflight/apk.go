@@ -0,0 +1,235 @@
+package preflight
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// r8Marker prefixes the marker string R8 embeds in every dex file it writes; D8 (used when
+// minification is off) writes "~~D8{" instead.
+const r8Marker = "~~R8{"
+
+// AndroidBuild describes how an APK was built and signed.
+type AndroidBuild struct {
+	APKPath string
+	// Installed is true when the APK was pulled from the device rather than given with --apk.
+	Installed   bool
+	Debuggable  bool
+	Signer      string
+	DebugSigned bool
+	// Minified is true when the dex files were produced by R8 in release mode.
+	Minified bool
+	Warnings []string
+}
+
+// InspectAndroidBuild examines apkPath or, when it is empty, the APK installed for packageName on
+// the device. Checks whose build tool (aapt/aapt2, apksigner) cannot be found are skipped with a
+// warning.
+func InspectAndroidBuild(ctx context.Context, adbPath, deviceID, packageName, apkPath string) (*AndroidBuild, error) {
+	build := &AndroidBuild{APKPath: apkPath}
+	local := apkPath
+	if apkPath == "" {
+		if packageName == "" {
+			return nil, fmt.Errorf("no package to inspect (pass --apk or set android.package)")
+		}
+		dir, err := os.MkdirTemp("", "designbench-apk-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		remote, pulled, err := pullInstalledAPK(ctx, adbPath, deviceID, packageName, dir)
+		if err != nil {
+			return nil, err
+		}
+		build.APKPath = remote
+		build.Installed = true
+		local = pulled
+	}
+
+	if aapt := findBuildTool("aapt", "aapt2"); aapt != "" {
+		out, err := exec.CommandContext(ctx, aapt, "dump", "badging", local).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("%s dump badging: %w: %s", filepath.Base(aapt), err, strings.TrimSpace(string(out)))
+		}
+		build.Debuggable = parseBadgingDebuggable(string(out))
+	} else {
+		build.Warnings = append(build.Warnings, "aapt not found; debuggable flag not checked")
+	}
+
+	if apksigner := findBuildTool("apksigner"); apksigner != "" {
+		out, err := exec.CommandContext(ctx, apksigner, "verify", "--print-certs", local).CombinedOutput()
+		if err != nil {
+			build.Warnings = append(build.Warnings, fmt.Sprintf("signature verification failed: %s", strings.TrimSpace(string(out))))
+		} else {
+			build.Signer = parseSignerDN(string(out))
+			build.DebugSigned = strings.Contains(build.Signer, "CN=Android Debug")
+		}
+	} else {
+		build.Warnings = append(build.Warnings, "apksigner not found; signature not checked")
+	}
+
+	minified, err := apkMinified(local)
+	if err != nil {
+		return nil, err
+	}
+	build.Minified = minified
+	return build, nil
+}
+
+// pullInstalledAPK copies the installed base APK of packageName into dir and returns its device and
+// local paths.
+func pullInstalledAPK(ctx context.Context, adbPath, deviceID, packageName, dir string) (string, string, error) {
+	out, err := exec.CommandContext(ctx, adbPath, adbArgs(deviceID, "shell", "pm", "path", packageName)...).CombinedOutput()
+	if err != nil {
+		return "", "", fmt.Errorf("pm path %s: %w: %s", packageName, err, strings.TrimSpace(string(out)))
+	}
+	remote := parsePMPath(string(out))
+	if remote == "" {
+		return "", "", fmt.Errorf("%s is not installed on the device", packageName)
+	}
+	local := filepath.Join(dir, "base.apk")
+	if out, err := exec.CommandContext(ctx, adbPath, adbArgs(deviceID, "pull", remote, local)...).CombinedOutput(); err != nil {
+		return "", "", fmt.Errorf("pull %s: %w: %s", remote, err, strings.TrimSpace(string(out)))
+	}
+	return remote, local, nil
+}
+
+func adbArgs(deviceID string, args ...string) []string {
+	if deviceID == "" {
+		return args
+	}
+	return append([]string{"-s", deviceID}, args...)
+}
+
+// parsePMPath returns the base APK from `pm path` output, falling back to the first split.
+func parsePMPath(output string) string {
+	var first string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		path, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "package:")
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(path, "/base.apk") {
+			return path
+		}
+		if first == "" {
+			first = path
+		}
+	}
+	return first
+}
+
+func parseBadgingDebuggable(output string) bool {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "application-debuggable" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSignerDN reads the first `Signer #1 certificate DN: ...` line of `apksigner verify --print-certs`.
+func parseSignerDN(output string) string {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if _, dn, ok := strings.Cut(line, "certificate DN: "); ok && strings.HasPrefix(line, "Signer #1") {
+			return dn
+		}
+	}
+	return ""
+}
+
+// apkMinified looks for R8's release-mode marker in the APK's dex files.
+func apkMinified(apkPath string) (bool, error) {
+	archive, err := zip.OpenReader(apkPath)
+	if err != nil {
+		return false, fmt.Errorf("open %s: %w", apkPath, err)
+	}
+	defer archive.Close()
+	for _, file := range archive.File {
+		if !strings.HasPrefix(file.Name, "classes") || !strings.HasSuffix(file.Name, ".dex") {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return false, fmt.Errorf("read %s: %w", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return false, fmt.Errorf("read %s: %w", file.Name, err)
+		}
+		if idx := bytes.Index(data, []byte(r8Marker)); idx >= 0 {
+			marker := data[idx:min(len(data), idx+512)]
+			return bytes.Contains(marker, []byte(`"compilation-mode":"release"`)), nil
+		}
+	}
+	return false, nil
+}
+
+// findBuildTool returns the first of names found on PATH or in the newest Android SDK build-tools
+// directory (from ANDROID_HOME or ANDROID_SDK_ROOT), or "" when none is installed.
+func findBuildTool(names ...string) string {
+	for _, name := range names {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	for _, env := range []string{"ANDROID_HOME", "ANDROID_SDK_ROOT"} {
+		sdk := os.Getenv(env)
+		if sdk == "" {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(sdk, "build-tools"))
+		if err != nil {
+			continue
+		}
+		versions := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if entry.IsDir() {
+				versions = append(versions, entry.Name())
+			}
+		}
+		slices.SortFunc(versions, compareVersions)
+		for i := len(versions) - 1; i >= 0; i-- {
+			for _, name := range names {
+				path := filepath.Join(sdk, "build-tools", versions[i], name)
+				if info, err := os.Stat(path); err == nil && !info.IsDir() {
+					return path
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// compareVersions orders dotted version strings numerically, e.g. 9.0.0 before 34.0.0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !linux
+
+package preflight
+
+import (
+	"errors"
+	"runtime"
+)
+
+// HostDiskFree is not implemented on this platform.
+func HostDiskFree(path string) (uint64, error) {
+	return 0, errors.New("free disk space is not supported on " + runtime.GOOS)
+}
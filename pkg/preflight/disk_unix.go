@@ -0,0 +1,14 @@
+//go:build darwin || linux
+
+package preflight
+
+import "syscall"
+
+// HostDiskFree returns the space available to unprivileged users on the filesystem holding path.
+func HostDiskFree(path string) (uint64, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return 0, err
+	}
+	return fs.Bavail * uint64(fs.Bsize), nil
+}
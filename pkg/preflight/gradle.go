@@ -0,0 +1,142 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	gradleDistributionRe = regexp.MustCompile(`gradle-([\d.]+(?:-rc-\d+)?)-(?:bin|all)\.zip`)
+	javaVersionRe        = regexp.MustCompile(`version "([^"]+)"`)
+	agpCatalogRe         = regexp.MustCompile(`(?m)^\s*(?:agp|androidGradlePlugin|android-gradle-plugin|androidGradle)\s*=\s*"([^"]+)"`)
+	agpClasspathRe       = regexp.MustCompile(`com\.android\.tools\.build:gradle:([\d.]+)`)
+	agpPluginRe          = regexp.MustCompile(`id\s*\(?\s*["']com\.android\.(?:application|library)["']\s*\)?\s*version\s*["']([\d.]+)["']`)
+	applicationPluginRe  = regexp.MustCompile(`com\.android\.application|plugins\.android\.application`)
+)
+
+// GradleSetup describes the Gradle wrapper, JDK and Android module used to build the app.
+type GradleSetup struct {
+	WrapperPath   string
+	GradleVersion string
+	JavaHome      string
+	JDKVersion    string
+	// AGPVersion is the Android Gradle Plugin version, when it could be found in the build files.
+	AGPVersion string
+	// RequiredJDK is the minimum JDK major version for AGPVersion.
+	RequiredJDK int
+	// Failures are problems that will make `installRelease` fail; Warnings may not.
+	Failures []string
+	Warnings []string
+}
+
+// InspectGradle checks that root has an executable Gradle wrapper, that JAVA_HOME (or java on PATH)
+// is new enough for the Android Gradle Plugin, and that moduleDir is an application module whose
+// release build type is signed, without which Gradle does not create an installRelease task.
+func InspectGradle(ctx context.Context, root, moduleDir string) *GradleSetup {
+	setup := &GradleSetup{WrapperPath: filepath.Join(root, "gradlew")}
+	if info, err := os.Stat(setup.WrapperPath); err != nil {
+		setup.Failures = append(setup.Failures, "gradlew not found; generate it with `gradle wrapper`")
+	} else if info.Mode()&0o111 == 0 {
+		setup.Failures = append(setup.Failures, "gradlew is not executable; run `chmod +x gradlew`")
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "gradle", "wrapper", "gradle-wrapper.properties")); err == nil {
+		if match := gradleDistributionRe.FindStringSubmatch(string(data)); match != nil {
+			setup.GradleVersion = match[1]
+		}
+	}
+
+	setup.AGPVersion = detectAGPVersion(root)
+	setup.RequiredJDK = requiredJDK(setup.AGPVersion)
+	java := "java"
+	if home := os.Getenv("JAVA_HOME"); home != "" {
+		setup.JavaHome = home
+		java = filepath.Join(home, "bin", "java")
+	}
+	out, err := exec.CommandContext(ctx, java, "-version").CombinedOutput()
+	if err != nil {
+		if setup.JavaHome != "" {
+			setup.Failures = append(setup.Failures, fmt.Sprintf("JAVA_HOME=%s does not contain a working JDK: %v", setup.JavaHome, err))
+		} else {
+			setup.Failures = append(setup.Failures, "JAVA_HOME is not set and java is not on PATH")
+		}
+	} else if match := javaVersionRe.FindStringSubmatch(string(out)); match != nil {
+		setup.JDKVersion = match[1]
+		if major := javaMajor(setup.JDKVersion); major < setup.RequiredJDK {
+			setup.Failures = append(setup.Failures, fmt.Sprintf("JDK %s is too old; Android Gradle Plugin %s needs JDK %d or newer",
+				setup.JDKVersion, displayVersion(setup.AGPVersion), setup.RequiredJDK))
+		}
+	}
+
+	buildFile, content := readGradleBuildFile(filepath.Join(root, moduleDir))
+	switch {
+	case buildFile == "":
+		setup.Failures = append(setup.Failures, fmt.Sprintf("no build.gradle(.kts) in module %q", moduleDir))
+	case !applicationPluginRe.MatchString(content):
+		setup.Failures = append(setup.Failures, fmt.Sprintf("%s does not apply com.android.application, so it has no install tasks", buildFile))
+	case !strings.Contains(content, "signingConfig"):
+		setup.Warnings = append(setup.Warnings, fmt.Sprintf("%s sets no signingConfig; installRelease only exists when the release build type is signed", buildFile))
+	}
+	return setup
+}
+
+func readGradleBuildFile(dir string) (string, string) {
+	for _, name := range []string{"build.gradle.kts", "build.gradle"} {
+		path := filepath.Join(dir, name)
+		if data, err := os.ReadFile(path); err == nil {
+			return path, string(data)
+		}
+	}
+	return "", ""
+}
+
+// detectAGPVersion looks in the version catalog, then the root build file.
+func detectAGPVersion(root string) string {
+	if data, err := os.ReadFile(filepath.Join(root, "gradle", "libs.versions.toml")); err == nil {
+		if match := agpCatalogRe.FindStringSubmatch(string(data)); match != nil {
+			return match[1]
+		}
+	}
+	_, content := readGradleBuildFile(root)
+	for _, re := range []*regexp.Regexp{agpPluginRe, agpClasspathRe} {
+		if match := re.FindStringSubmatch(content); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// requiredJDK returns the minimum JDK for an Android Gradle Plugin version, assuming the current
+// requirement (JDK 17, since AGP 8.0) when the version is unknown.
+func requiredJDK(agpVersion string) int {
+	switch major := majorVersion(agpVersion); {
+	case major >= 8 || major == 0:
+		return 17
+	case major == 7:
+		return 11
+	default:
+		return 8
+	}
+}
+
+// javaMajor handles both "1.8.0_392" and "17.0.9" version strings.
+func javaMajor(version string) int {
+	parts := strings.Split(version, ".")
+	major, _ := strconv.Atoi(parts[0])
+	if major == 1 && len(parts) > 1 {
+		major, _ = strconv.Atoi(parts[1])
+	}
+	return major
+}
+
+func displayVersion(version string) string {
+	if version == "" {
+		return "(version unknown)"
+	}
+	return version
+}
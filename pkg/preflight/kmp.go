@@ -0,0 +1,105 @@
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	gradleIncludeRe = regexp.MustCompile(`(?m)^\s*include\s*\(?((?:\s*["'][^"']+["']\s*,?)+)\)?`)
+	gradleQuotedRe  = regexp.MustCompile(`["']([^"']+)["']`)
+	kmpPluginRe     = regexp.MustCompile(`kotlin\(\s*"multiplatform"\s*\)|org\.jetbrains\.kotlin\.multiplatform|plugins\.kotlin\.?[Mm]ultiplatform`)
+	// kmpFrameworkPhaseRe matches the Xcode build phase that has Gradle build the shared framework,
+	// either the direct-integration task or the CocoaPods plugin's sync task.
+	kmpFrameworkPhaseRe = regexp.MustCompile(`embedAndSignAppleFrameworkForXcode|syncFramework`)
+)
+
+// KMPProject describes a Kotlin Multiplatform layout: modules applying the multiplatform plugin
+// (usually "shared") consumed by an Android app module and an Xcode project.
+type KMPProject struct {
+	SettingsPath string
+	// SharedModules are the included Gradle modules, as directories relative to the root, that
+	// apply the Kotlin Multiplatform plugin.
+	SharedModules []string
+	Android       *AndroidProject
+	IOS           *IOSProject
+	// EmbedsShared is true when the Xcode project has a build phase that builds the shared framework.
+	EmbedsShared bool
+	Warnings     []string
+}
+
+// DetectKMPProject reads settings.gradle(.kts) under root and returns nil without an error when no
+// included module applies the Kotlin Multiplatform plugin. The Android and iOS apps are located
+// with DetectAndroidProject and DetectIOSProject; a missing app is reported as a warning.
+func DetectKMPProject(root string) (*KMPProject, error) {
+	var settingsPath string
+	var content string
+	for _, name := range []string{"settings.gradle.kts", "settings.gradle"} {
+		path := filepath.Join(root, name)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			settingsPath, content = path, string(data)
+			break
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+	}
+	if settingsPath == "" {
+		return nil, nil
+	}
+
+	project := &KMPProject{SettingsPath: settingsPath}
+	for _, module := range parseGradleIncludes(content) {
+		if _, build := readGradleBuildFile(filepath.Join(root, module)); kmpPluginRe.MatchString(build) {
+			project.SharedModules = append(project.SharedModules, module)
+		}
+	}
+	if len(project.SharedModules) == 0 {
+		return nil, nil
+	}
+
+	if android, err := DetectAndroidProject(root); err == nil {
+		project.Android = android
+	} else {
+		project.Warnings = append(project.Warnings, fmt.Sprintf("no Android app module: %v", err))
+	}
+	if ios, err := DetectIOSProject(root); err == nil {
+		project.IOS = ios
+		if pbxproj, err := findPBXProj(root); err == nil {
+			if data, err := os.ReadFile(pbxproj); err == nil {
+				project.EmbedsShared = kmpFrameworkPhaseRe.Match(data)
+			}
+		}
+		if !project.EmbedsShared {
+			project.Warnings = append(project.Warnings, fmt.Sprintf("the Xcode project does not build %s; add a Run Script phase calling ./gradlew :%s:embedAndSignAppleFrameworkForXcode",
+				project.SharedModules[0], strings.ReplaceAll(filepath.ToSlash(project.SharedModules[0]), "/", ":")))
+		}
+	} else {
+		project.Warnings = append(project.Warnings, fmt.Sprintf("no iOS app: %v", err))
+	}
+	return project, nil
+}
+
+// parseGradleIncludes turns `include(":shared", ":androidApp")` and `include ':core:ui'` lines into
+// module directories ("shared", "androidApp", "core/ui").
+func parseGradleIncludes(content string) []string {
+	var modules []string
+	for _, include := range gradleIncludeRe.FindAllStringSubmatch(content, -1) {
+		for _, quoted := range gradleQuotedRe.FindAllStringSubmatch(include[1], -1) {
+			path := strings.Trim(quoted[1], ":")
+			if path == "" {
+				continue
+			}
+			module := filepath.Join(strings.Split(path, ":")...)
+			if !slices.Contains(modules, module) {
+				modules = append(modules, module)
+			}
+		}
+	}
+	return modules
+}
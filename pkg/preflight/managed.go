@@ -0,0 +1,75 @@
+package preflight
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	managedDeviceRe   = regexp.MustCompile(`device\s*=?\s*["']([^"']+)["']`)
+	managedAPILevelRe = regexp.MustCompile(`apiLevel\s*=?\s*(\d+)`)
+)
+
+// ManagedDevice is a Gradle Managed Device declared in a module's testOptions.managedDevices block.
+type ManagedDevice struct {
+	Name string
+	// Device is the hardware profile, e.g. "Pixel 6".
+	Device   string
+	APILevel int
+}
+
+// FindManagedDevice reads the managed device called name from moduleDir's build file. Both the
+// Kotlin `create<ManagedVirtualDevice>("pixel6Api34")` and the Groovy `pixel6Api34(ManagedVirtualDevice)` forms are
+// recognised.
+func FindManagedDevice(root, moduleDir, name string) (*ManagedDevice, error) {
+	buildFile, content := readGradleBuildFile(filepath.Join(root, moduleDir))
+	if buildFile == "" {
+		return nil, fmt.Errorf("no build.gradle(.kts) in %s", filepath.Join(root, moduleDir))
+	}
+	quoted := regexp.QuoteMeta(name)
+	declRe := regexp.MustCompile(`(?:create|maybeCreate|register)(?:<[^>]*>)?\(\s*["']` + quoted + `["']\s*\)|\b` + quoted + `\s*\([\w.]*ManagedVirtualDevice\)`)
+	loc := declRe.FindStringIndex(content)
+	if loc == nil {
+		return nil, fmt.Errorf("%s declares no managed device %q", buildFile, name)
+	}
+	block, ok := braceBlock(content[loc[1]:])
+	if !ok {
+		return nil, fmt.Errorf("%s: managed device %q has no configuration block", buildFile, name)
+	}
+	device := &ManagedDevice{Name: name}
+	if match := managedDeviceRe.FindStringSubmatch(block); match != nil {
+		device.Device = match[1]
+	}
+	if match := managedAPILevelRe.FindStringSubmatch(block); match != nil {
+		device.APILevel, _ = strconv.Atoi(match[1])
+	}
+	if device.Device == "" || device.APILevel == 0 {
+		return nil, fmt.Errorf("%s: managed device %q must set device and apiLevel", buildFile, name)
+	}
+	return device, nil
+}
+
+// braceBlock returns the contents of the first {...} block in s, which must open before any other
+// statement.
+func braceBlock(s string) (string, bool) {
+	open := strings.IndexByte(s, '{')
+	if open < 0 || strings.TrimSpace(s[:open]) != "" {
+		return "", false
+	}
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return s[open+1 : i], true
+			}
+		}
+	}
+	return "", false
+}
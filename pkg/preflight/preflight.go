@@ -49,10 +49,12 @@ type AndroidDevice struct {
 	Description string
 }
 
-// IOSProject captures bundle identifier details from Info.plist.
+// IOSProject captures bundle identifier details from Info.plist and the Xcode container that builds it.
 type IOSProject struct {
 	BundleID      string
 	InfoPlistPath string
+	// Container is the .xcworkspace or .xcodeproj under the project root, if any.
+	Container string
 }
 
 // IOSDevice describes a booted simulator or connected device.
@@ -372,8 +374,20 @@ func DetectAndroidDevice(ctx context.Context, adbPath string) (*AndroidDevice, e
 	return nil, fmt.Errorf("no Android devices found (ensure adb device is connected)")
 }
 
-// DetectIOSProject attempts to locate an Info.plist and extract the CFBundleIdentifier.
+// DetectIOSProject attempts to locate an Info.plist and extract the CFBundleIdentifier, then looks
+// for the Xcode workspace or project next to it.
 func DetectIOSProject(root string) (*IOSProject, error) {
+	proj, err := detectInfoPlist(root)
+	if err != nil {
+		return nil, err
+	}
+	if container, err := findXcodeContainer(root); err == nil {
+		proj.Container = container
+	}
+	return proj, nil
+}
+
+func detectInfoPlist(root string) (*IOSProject, error) {
 	paths := []string{
 		"iosApp/iosApp/Info.plist",
 		"iosApp/Info.plist",
@@ -460,14 +474,18 @@ func DetectIOSDevice(ctx context.Context, xcrunPath string) (*IOSDevice, error)
 	if err := json.Unmarshal(output, &payload); err != nil {
 		return nil, fmt.Errorf("parse simctl output: %w", err)
 	}
-	for _, devices := range payload.Devices {
+	for runtime, devices := range payload.Devices {
 		for _, device := range devices {
 			if strings.EqualFold(device.State, "Booted") {
+				// Recent simctl versions only report the runtime as the key of the device list.
+				if device.Runtime != "" {
+					runtime = device.Runtime
+				}
 				return &IOSDevice{
 					UDID:    device.UDID,
 					Name:    device.Name,
 					State:   device.State,
-					Runtime: device.Runtime,
+					Runtime: runtime,
 				}, nil
 			}
 		}
@@ -0,0 +1,138 @@
+package preflight
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Thresholds below which installs start failing or the OS starts reclaiming memory from the app
+// under test.
+const (
+	MinHostDiskBytes      = 10 << 30
+	MinHostMemoryBytes    = 2 << 30
+	MinDeviceStorageBytes = 1 << 30
+	MinDeviceMemoryBytes  = 512 << 20
+)
+
+var vmStatPageSizeRe = regexp.MustCompile(`page size of (\d+) bytes`)
+
+// SimulatorDataDir returns where CoreSimulator keeps simulator disks, or "" when it does not exist.
+func SimulatorDataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Join(home, "Library", "Developer", "CoreSimulator")
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+// HostMemoryAvailable returns memory the host can hand out without swapping: MemAvailable from
+// /proc/meminfo on Linux, free plus inactive pages from vm_stat on macOS.
+func HostMemoryAvailable(ctx context.Context) (uint64, error) {
+	if runtime.GOOS == "darwin" {
+		out, err := exec.CommandContext(ctx, "vm_stat").CombinedOutput()
+		if err != nil {
+			return 0, fmt.Errorf("vm_stat: %w", err)
+		}
+		return parseVMStat(string(out))
+	}
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	return parseMemAvailable(string(data))
+}
+
+// AndroidStorageFree returns the free space on the device's /data partition from `df -k`.
+func AndroidStorageFree(ctx context.Context, adbPath, deviceID string) (uint64, error) {
+	out, err := exec.CommandContext(ctx, adbPath, adbArgs(deviceID, "shell", "df", "-k", "/data")...).CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("df /data: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return parseDFAvailable(string(out))
+}
+
+// AndroidMemoryAvailable returns MemAvailable from the device's /proc/meminfo.
+func AndroidMemoryAvailable(ctx context.Context, adbPath, deviceID string) (uint64, error) {
+	out, err := exec.CommandContext(ctx, adbPath, adbArgs(deviceID, "shell", "cat", "/proc/meminfo")...).CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("read device meminfo: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return parseMemAvailable(string(out))
+}
+
+func parseMemAvailable(output string) (uint64, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse MemAvailable %q: %w", fields[1], err)
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, errors.New("MemAvailable not reported")
+}
+
+// parseDFAvailable reads the Available column (1K blocks) of the last `df -k` row.
+func parseDFAvailable(output string) (uint64, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return 0, errors.New("unexpected df output")
+	}
+	header := strings.Fields(lines[0])
+	fields := strings.Fields(lines[len(lines)-1])
+	for i, name := range header {
+		if (name == "Available" || name == "Free") && i < len(fields) {
+			kb, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse df %s %q: %w", name, fields[i], err)
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, errors.New("df did not report available space")
+}
+
+func parseVMStat(output string) (uint64, error) {
+	pageSize := uint64(4096)
+	if match := vmStatPageSizeRe.FindStringSubmatch(output); match != nil {
+		pageSize, _ = strconv.ParseUint(match[1], 10, 64)
+	}
+	var pages uint64
+	found := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Pages free", "Pages inactive", "Pages speculative":
+			n, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), "."), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse vm_stat %s: %w", key, err)
+			}
+			pages += n
+			found = true
+		}
+	}
+	if !found {
+		return 0, errors.New("vm_stat did not report free pages")
+	}
+	return pages * pageSize, nil
+}
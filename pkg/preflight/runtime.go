@@ -0,0 +1,135 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var deploymentTargetRe = regexp.MustCompile(`IPHONEOS_DEPLOYMENT_TARGET = ([\d.]+);`)
+
+// IOSRuntime is a simulator runtime installed on the host.
+type IOSRuntime struct {
+	Identifier string
+	Name       string
+	Version    string
+	Available  bool
+	// Devices counts the available simulators created for this runtime.
+	Devices int
+}
+
+// DetectIOSDeploymentTarget returns the highest IPHONEOS_DEPLOYMENT_TARGET set in the first Xcode
+// project found under root, i.e. the oldest iOS release every target can run on.
+func DetectIOSDeploymentTarget(root string) (string, error) {
+	projectPath, err := findPBXProj(root)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(projectPath)
+	if err != nil {
+		return "", fmt.Errorf("read xcode project: %w", err)
+	}
+	var target string
+	for _, match := range deploymentTargetRe.FindAllStringSubmatch(string(data), -1) {
+		if target == "" || compareVersions(match[1], target) > 0 {
+			target = match[1]
+		}
+	}
+	if target == "" {
+		return "", fmt.Errorf("IPHONEOS_DEPLOYMENT_TARGET not set in %s", projectPath)
+	}
+	return target, nil
+}
+
+// findPBXProj returns the project.pbxproj of the first Xcode project found under root.
+func findPBXProj(root string) (string, error) {
+	var projectPath string
+	stopErr := errors.New("designbench:found-pbxproj")
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "build", "DerivedData", ".idea", "Pods", "node_modules":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "project.pbxproj" && strings.HasSuffix(filepath.Dir(path), ".xcodeproj") {
+			projectPath = path
+			return stopErr
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, stopErr) {
+		return "", err
+	}
+	if projectPath == "" {
+		return "", errors.New("xcode project not found")
+	}
+	return projectPath, nil
+}
+
+type simctlRuntimeList struct {
+	Runtimes []struct {
+		Identifier  string `json:"identifier"`
+		Name        string `json:"name"`
+		Version     string `json:"version"`
+		Platform    string `json:"platform"`
+		IsAvailable bool   `json:"isAvailable"`
+	} `json:"runtimes"`
+}
+
+type simctlDeviceAvailability struct {
+	Devices map[string][]struct {
+		IsAvailable bool `json:"isAvailable"`
+	} `json:"devices"`
+}
+
+// ListIOSRuntimes returns the installed iOS simulator runtimes with the number of available
+// simulators for each, from `xcrun simctl list runtimes --json` and `xcrun simctl list devices --json`.
+func ListIOSRuntimes(ctx context.Context, xcrunPath string) ([]IOSRuntime, error) {
+	out, err := exec.CommandContext(ctx, xcrunPath, "simctl", "list", "runtimes", "--json").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("list simulator runtimes: %w", err)
+	}
+	var runtimes simctlRuntimeList
+	if err := json.Unmarshal(out, &runtimes); err != nil {
+		return nil, fmt.Errorf("parse simctl runtimes: %w", err)
+	}
+	out, err = exec.CommandContext(ctx, xcrunPath, "simctl", "list", "devices", "--json").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("list simulators: %w", err)
+	}
+	var devices simctlDeviceAvailability
+	if err := json.Unmarshal(out, &devices); err != nil {
+		return nil, fmt.Errorf("parse simctl output: %w", err)
+	}
+	result := make([]IOSRuntime, 0, len(runtimes.Runtimes))
+	for _, rt := range runtimes.Runtimes {
+		// Older simctl versions omit platform; their identifiers still name it.
+		if rt.Platform != "iOS" && !strings.Contains(rt.Identifier, ".iOS-") {
+			continue
+		}
+		runtime := IOSRuntime{Identifier: rt.Identifier, Name: rt.Name, Version: rt.Version, Available: rt.IsAvailable}
+		for _, device := range devices.Devices[rt.Identifier] {
+			if device.IsAvailable {
+				runtime.Devices++
+			}
+		}
+		result = append(result, runtime)
+	}
+	return result, nil
+}
+
+// RuntimeSupports reports whether an iOS runtime version can run an app with the given deployment target.
+func RuntimeSupports(runtimeVersion, deploymentTarget string) bool {
+	return compareVersions(runtimeVersion, deploymentTarget) >= 0
+}
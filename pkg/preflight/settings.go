@@ -0,0 +1,74 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// AndroidSettings holds the device settings that routinely invalidate benchmark numbers.
+type AndroidSettings struct {
+	// AnimationScales maps window_animation_scale, transition_animation_scale and
+	// animator_duration_scale to their values; unset scales default to 1.
+	AnimationScales map[string]float64
+	// DontKeepActivities is the "Don't keep activities" developer option (always_finish_activities).
+	DontKeepActivities bool
+	// StayAwake is true when the screen stays on while charging (stay_on_while_plugged_in).
+	StayAwake bool
+	// BatterySaver is true when low-power mode is on.
+	BatterySaver bool
+}
+
+// AnimationScaleKeys lists the global settings behind the developer-options animation scales.
+var AnimationScaleKeys = []string{"window_animation_scale", "transition_animation_scale", "animator_duration_scale"}
+
+// ReadAndroidSettings reads developer options and power settings with `adb shell settings get global`.
+func ReadAndroidSettings(ctx context.Context, adbPath, deviceID string) (*AndroidSettings, error) {
+	settings := &AndroidSettings{AnimationScales: make(map[string]float64, len(AnimationScaleKeys))}
+	for _, key := range AnimationScaleKeys {
+		value, err := readGlobalSetting(ctx, adbPath, deviceID, key)
+		if err != nil {
+			return nil, err
+		}
+		scale := 1.0
+		if value != "" {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				scale = parsed
+			}
+		}
+		settings.AnimationScales[key] = scale
+	}
+	flags := []struct {
+		key    string
+		target *bool
+	}{
+		{"always_finish_activities", &settings.DontKeepActivities},
+		{"stay_on_while_plugged_in", &settings.StayAwake},
+		{"low_power", &settings.BatterySaver},
+	}
+	for _, flag := range flags {
+		value, err := readGlobalSetting(ctx, adbPath, deviceID, flag.key)
+		if err != nil {
+			return nil, err
+		}
+		// stay_on_while_plugged_in is a bit mask of charger types; any non-zero value keeps the screen on.
+		n, _ := strconv.Atoi(value)
+		*flag.target = n != 0
+	}
+	return settings, nil
+}
+
+// readGlobalSetting returns "" for settings that were never written (reported as "null").
+func readGlobalSetting(ctx context.Context, adbPath, deviceID, key string) (string, error) {
+	out, err := exec.CommandContext(ctx, adbPath, adbArgs(deviceID, "shell", "settings", "get", "global", key)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("read setting %s: %w: %s", key, err, strings.TrimSpace(string(out)))
+	}
+	value := strings.TrimSpace(string(out))
+	if value == "null" {
+		return "", nil
+	}
+	return value, nil
+}
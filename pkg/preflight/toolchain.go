@@ -0,0 +1,129 @@
+package preflight
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	adbVersionRe    = regexp.MustCompile(`Android Debug Bridge version (\S+)`)
+	platformToolsRe = regexp.MustCompile(`(?m)^Version (\d+(?:\.\d+)*)`)
+	xcodeVersionRe  = regexp.MustCompile(`(?m)^Xcode (\S+)`)
+	xcodeBuildRe    = regexp.MustCompile(`(?m)^Build version (\S+)`)
+	iosRuntimeRe    = regexp.MustCompile(`iOS-(\d+)`)
+)
+
+// AndroidToolchain describes the host adb and the API level of the connected device.
+type AndroidToolchain struct {
+	ADBVersion           string
+	PlatformToolsVersion string
+	// DeviceSDK is the device's ro.build.version.sdk, or 0 when no device was queried.
+	DeviceSDK int
+	Warnings  []string
+}
+
+// ReadAndroidToolchain runs `adb version` and, when deviceID is set, reads the device API level.
+// Platform-tools releases track API levels, so a device newer than the installed platform-tools
+// may rely on adb features (or fixes) the host does not have.
+func ReadAndroidToolchain(ctx context.Context, adbPath, deviceID string) (*AndroidToolchain, error) {
+	out, err := exec.CommandContext(ctx, adbPath, "version").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("adb version: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	toolchain := &AndroidToolchain{}
+	if match := adbVersionRe.FindStringSubmatch(string(out)); match != nil {
+		toolchain.ADBVersion = match[1]
+	}
+	if match := platformToolsRe.FindStringSubmatch(string(out)); match != nil {
+		toolchain.PlatformToolsVersion = match[1]
+	}
+	if deviceID == "" {
+		return toolchain, nil
+	}
+	sdkOut, err := exec.CommandContext(ctx, adbPath, adbArgs(deviceID, "shell", "getprop", "ro.build.version.sdk")...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("read device API level: %w: %s", err, strings.TrimSpace(string(sdkOut)))
+	}
+	toolchain.DeviceSDK, _ = strconv.Atoi(strings.TrimSpace(string(sdkOut)))
+	if major := majorVersion(toolchain.PlatformToolsVersion); major > 0 && toolchain.DeviceSDK > major {
+		toolchain.Warnings = append(toolchain.Warnings, fmt.Sprintf(
+			"platform-tools %s is older than the device's API level %d; update with `sdkmanager platform-tools`",
+			toolchain.PlatformToolsVersion, toolchain.DeviceSDK))
+	}
+	return toolchain, nil
+}
+
+// XcodeToolchain describes the selected Xcode installation.
+type XcodeToolchain struct {
+	Version      string
+	Build        string
+	DeveloperDir string
+	Warnings     []string
+}
+
+// ReadXcodeToolchain runs `xcode-select -p` and `xcodebuild -version`. runtime is the identifier of
+// the simulator runtime that will be benchmarked (e.g. com.apple.CoreSimulator.SimRuntime.iOS-17-5)
+// and may be empty.
+func ReadXcodeToolchain(ctx context.Context, runtime string) (*XcodeToolchain, error) {
+	toolchain := &XcodeToolchain{}
+	if out, err := exec.CommandContext(ctx, "xcode-select", "-p").CombinedOutput(); err == nil {
+		toolchain.DeveloperDir = strings.TrimSpace(string(out))
+	}
+	if strings.Contains(toolchain.DeveloperDir, "CommandLineTools") {
+		toolchain.Warnings = append(toolchain.Warnings,
+			"the Command Line Tools are selected; simctl needs Xcode (`sudo xcode-select -s /Applications/Xcode.app`)")
+	}
+	out, err := exec.CommandContext(ctx, "xcodebuild", "-version").CombinedOutput()
+	if err != nil {
+		if detail := firstLine(string(out)); detail != "" {
+			return nil, fmt.Errorf("xcodebuild -version: %w: %s", err, detail)
+		}
+		return nil, fmt.Errorf("xcodebuild -version: %w", err)
+	}
+	if match := xcodeVersionRe.FindStringSubmatch(string(out)); match != nil {
+		toolchain.Version = match[1]
+	}
+	if match := xcodeBuildRe.FindStringSubmatch(string(out)); match != nil {
+		toolchain.Build = match[1]
+	}
+	if match := iosRuntimeRe.FindStringSubmatch(runtime); match != nil {
+		iosMajor, _ := strconv.Atoi(match[1])
+		if newest := newestSupportedIOS(majorVersion(toolchain.Version)); newest > 0 && iosMajor > newest {
+			toolchain.Warnings = append(toolchain.Warnings, fmt.Sprintf(
+				"simulator runs iOS %d but Xcode %s only supports up to iOS %d", iosMajor, toolchain.Version, newest))
+		}
+	}
+	return toolchain, nil
+}
+
+// newestSupportedIOS maps an Xcode major version to the newest iOS major it ships an SDK for. Xcode
+// 26 adopted year-based numbering shared with iOS; before that iOS was two majors ahead.
+func newestSupportedIOS(xcodeMajor int) int {
+	switch {
+	case xcodeMajor <= 0:
+		return 0
+	case xcodeMajor >= 26:
+		return xcodeMajor
+	default:
+		return xcodeMajor + 2
+	}
+}
+
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
+
+func firstLine(s string) string {
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// XcodeSchemes lists what `xcodebuild -list -json` reports for a workspace or project.
+type XcodeSchemes struct {
+	Schemes        []string
+	Configurations []string
+}
+
+type xcodebuildList struct {
+	Workspace *struct {
+		Schemes []string `json:"schemes"`
+	} `json:"workspace"`
+	Project *struct {
+		Schemes        []string `json:"schemes"`
+		Configurations []string `json:"configurations"`
+	} `json:"project"`
+}
+
+// findXcodeContainer returns the .xcworkspace under root, or the .xcodeproj when there is no
+// standalone workspace (every project embeds one, which is skipped).
+func findXcodeContainer(root string) (string, error) {
+	var workspace, project string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		switch name := d.Name(); {
+		case name == ".git" || name == "build" || name == "DerivedData" || name == "Pods" || name == "node_modules":
+			return filepath.SkipDir
+		case strings.HasSuffix(name, ".xcworkspace"):
+			if !strings.HasSuffix(filepath.Dir(path), ".xcodeproj") && workspace == "" {
+				workspace = path
+			}
+			return filepath.SkipDir
+		case strings.HasSuffix(name, ".xcodeproj"):
+			if project == "" {
+				project = path
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if workspace != "" {
+		return workspace, nil
+	}
+	if project != "" {
+		return project, nil
+	}
+	return "", errors.New("no .xcworkspace or .xcodeproj found")
+}
+
+// ListXcodeSchemes runs `xcodebuild -list -json` for container.
+func ListXcodeSchemes(ctx context.Context, container string) (*XcodeSchemes, error) {
+	out, err := exec.CommandContext(ctx, "xcodebuild", "-list", "-json", XcodeContainerFlag(container), container).Output()
+	if err != nil {
+		return nil, fmt.Errorf("xcodebuild -list: %w", err)
+	}
+	var list xcodebuildList
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("parse xcodebuild -list: %w", err)
+	}
+	switch {
+	case list.Workspace != nil:
+		return &XcodeSchemes{Schemes: list.Workspace.Schemes}, nil
+	case list.Project != nil:
+		return &XcodeSchemes{Schemes: list.Project.Schemes, Configurations: list.Project.Configurations}, nil
+	}
+	return nil, errors.New("xcodebuild -list reported neither a workspace nor a project")
+}
+
+// XcodeContainerFlag returns the xcodebuild flag selecting container.
+func XcodeContainerFlag(container string) string {
+	if strings.HasSuffix(container, ".xcworkspace") {
+		return "-workspace"
+	}
+	return "-project"
+}
+
+// PickScheme chooses the scheme that builds the app: one named after the container, else the first
+// that is not a test or Pods scheme.
+func PickScheme(schemes []string, container string) string {
+	base := strings.TrimSuffix(filepath.Base(container), filepath.Ext(container))
+	for _, scheme := range schemes {
+		if scheme == base {
+			return scheme
+		}
+	}
+	for _, scheme := range schemes {
+		if !strings.HasSuffix(scheme, "Tests") && !strings.HasPrefix(scheme, "Pods") {
+			return scheme
+		}
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+// Package redact keeps secrets out of what designbench records: it scrubs the values of sensitive
+// flags from commands and limits the environment variables written to reports to an allowlist.
+package redact
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Placeholder replaces every scrubbed value.
+const Placeholder = "REDACTED"
+
+// sensitiveWords mark flag, extra and variable names whose values are secrets, matched without
+// regard to case, dashes or underscores.
+var sensitiveWords = []string{"token", "secret", "password", "passwd", "credential", "apikey", "auth", "webhook", "privatekey", "signingkey"}
+
+// notSensitive are names that contain a sensitive word but whose values are not secrets.
+var notSensitive = map[string]bool{"--webhook-format": true, "DESIGNBENCH_WEBHOOK_FORMAT": true}
+
+// stringExtras are the am start options followed by an extra's key and its value.
+var stringExtras = map[string]bool{"-e": true, "--es": true}
+
+// Sensitive reports whether name, e.g. "--token", "DESIGNBENCH_WEBHOOK" or "signing.password",
+// names a secret.
+func Sensitive(name string) bool {
+	if notSensitive[name] {
+		return false
+	}
+	normalized := strings.NewReplacer("-", "", "_", "", ".", "").Replace(strings.ToLower(name))
+	for _, word := range sensitiveWords {
+		if strings.Contains(normalized, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Args returns a copy of a command's arguments with secrets replaced by Placeholder: the values
+// of sensitive flags ("--token x", "--token=x", "-Psigning.password=x"), of sensitive string
+// extras ("-e apiToken x"), and credentials in URLs. Flag values are scanned too, so
+// "--gradle-args=-Pa=1 -Psigning.password=x" loses the password.
+func Args(args []string) []string {
+	out := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		out[i] = arg
+		switch {
+		case stringExtras[arg] && i+2 < len(args) && Sensitive(args[i+1]):
+			out[i+1], out[i+2] = args[i+1], Placeholder
+			i += 2
+		case strings.HasPrefix(arg, "-") && !strings.Contains(arg, "="):
+			if Sensitive(arg) && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				out[i+1] = Placeholder
+				i++
+			}
+		default:
+			out[i] = value(arg)
+		}
+	}
+	return out
+}
+
+// value scrubs an argument that may hold several space- or comma-separated settings, such as
+// "-Pa=1 -Psigning.password=x" or "url=https://ci/hook,token=x".
+func value(arg string) string {
+	var b strings.Builder
+	hideNext := false
+	for arg != "" {
+		token, sep := arg, ""
+		if end := strings.IndexAny(arg, " ,"); end >= 0 {
+			token, sep = arg[:end], arg[end:end+1]
+			arg = arg[end+1:]
+		} else {
+			arg = ""
+		}
+		switch {
+		case token == "":
+		case hideNext:
+			token, hideNext = Placeholder, false
+		default:
+			token, hideNext = setting(token)
+		}
+		b.WriteString(token + sep)
+	}
+	return b.String()
+}
+
+// setting scrubs one key=value pair, -P/-D property or flag. hideNext reports a sensitive flag
+// whose value is the next word.
+func setting(token string) (scrubbed string, hideNext bool) {
+	name, val, ok := strings.Cut(token, "=")
+	switch {
+	case !ok || strings.Contains(name, "://"):
+		return URL(token), !ok && strings.HasPrefix(token, "-") && Sensitive(token)
+	case Sensitive(name):
+		return name + "=" + Placeholder, false
+	default:
+		// The value may itself be a setting, as in "--gradle-args=-Ptoken=x".
+		return name + "=" + value(val), false
+	}
+}
+
+// URL replaces the password and sensitive query parameters of an absolute URL, returning other
+// values unchanged.
+func URL(value string) string {
+	if !strings.Contains(value, "://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return value
+	}
+	changed := false
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Placeholder)
+		changed = true
+	}
+	query := u.Query()
+	for key := range query {
+		if Sensitive(key) {
+			query.Set(key, Placeholder)
+			changed = true
+		}
+	}
+	if !changed {
+		return value
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// DefaultEnv are the environment variables recorded in reports unless configured otherwise: the
+// CI job's identity and designbench's own settings. A trailing * matches any suffix.
+var DefaultEnv = []string{
+	"CI",
+	"DESIGNBENCH_*",
+	"GITHUB_REPOSITORY", "GITHUB_SHA", "GITHUB_REF_NAME", "GITHUB_RUN_ID", "GITHUB_WORKFLOW",
+	"CI_PROJECT_PATH", "CI_COMMIT_SHA", "CI_COMMIT_REF_NAME", "CI_PIPELINE_ID", "CI_JOB_NAME",
+	"BITRISE_BUILD_NUMBER", "BITRISE_GIT_BRANCH", "BITRISE_GIT_COMMIT",
+	"CIRCLE_BUILD_NUM", "CIRCLE_BRANCH", "CIRCLE_SHA1",
+}
+
+// Env returns the environment variables matching allow, or nil when none are set. Variables with
+// sensitive names are left out even when allowed.
+func Env(allow []string) map[string]string {
+	var env map[string]string
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if !allowed(name, allow) || Sensitive(name) {
+			continue
+		}
+		if env == nil {
+			env = map[string]string{}
+		}
+		env[name] = URL(value)
+	}
+	return env
+}
+
+func allowed(name string, allow []string) bool {
+	for _, pattern := range allow {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Budgets maps summary metric names, one of BudgetMetrics, to the highest acceptable value.
+type Budgets map[string]float64
+
+// BudgetMetrics are the summary metrics that can have a budget.
+var BudgetMetrics = []string{"totalTimeMs", "firstFrameMs", "waitTimeMs", "fullyDrawnMs", "renderTimeMs", "memoryMb", "cpuPercent", "cpuTimeMs"}
+
+// Paint wraps text in the ANSI color for a passing or failing check.
+func Paint(text string, failed bool) string {
+	if failed {
+		return ansiRed + text + ansiReset
+	}
+	return ansiGreen + text + ansiReset
+}
+
+// budgetWarnRatio is the share of a budget above which a metric is shown as close to it.
+const budgetWarnRatio = 0.9
+
+// ANSI escape sequences for summary colors.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+type summaryStyle struct {
+	budgets Budgets
+	color   bool
+}
+
+// paint colors text, the formatted value of metric, by how close value is to its budget. Metrics
+// without a budget and unmeasured values are left alone.
+func (s summaryStyle) paint(metric string, value float64, text string) string {
+	budget, ok := s.budgets[metric]
+	if !s.color || !ok || budget <= 0 || value <= 0 {
+		return text
+	}
+	color := ansiGreen
+	switch {
+	case value > budget:
+		color = ansiRed
+	case value > budget*budgetWarnRatio:
+		color = ansiYellow
+	}
+	return color + text + ansiReset
+}
+
+// overBudget returns one line per metric in values that exceeds its budget, so that regressions
+// stand out in logs without color too.
+func (s summaryStyle) overBudget(values map[string]float64) string {
+	names := make([]string, 0, len(values))
+	for name, value := range values {
+		if budget, ok := s.budgets[name]; ok && budget > 0 && value > budget {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	out := ""
+	for _, name := range names {
+		budget := s.budgets[name]
+		line := fmt.Sprintf("OVER BUDGET: %s=%.1f exceeds %.1f by %.1f%%", name, values[name], budget, (values[name]-budget)/budget*100)
+		if s.color {
+			line = ansiRed + line + ansiReset
+		}
+		out += "    " + line + "\n"
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// SignificanceLevel is the p-value below which a change between two reports counts as significant.
+const SignificanceLevel = 0.05
+
+// Comparison holds the headline metrics of one measurement (e.g. Android or iOS/split-half) that
+// appears in both a baseline and a candidate result.
+type Comparison struct {
+	Name            string
+	BaselineDevice  *DeviceMetadata
+	CandidateDevice *DeviceMetadata
+	Metrics         []MetricDelta
+	// Factor, when set, is the calibration multiplier applied to the candidate's durations.
+	Factor float64
+}
+
+// MetricDelta is one metric of a Comparison.
+type MetricDelta struct {
+	Metric    string
+	Unit      string
+	Baseline  float64
+	Candidate float64
+	// BaselineValues and CandidateValues are each side's iteration values, when it ran more than once.
+	BaselineValues  []float64
+	CandidateValues []float64
+}
+
+// Significance returns the Mann-Whitney p-value of the change between the two sides' iterations.
+// ok is false unless both sides ran more than once.
+func (d MetricDelta) Significance() (p float64, ok bool) {
+	if len(d.BaselineValues) < 2 || len(d.CandidateValues) < 2 {
+		return 0, false
+	}
+	return stats.MannWhitneyP(d.BaselineValues, d.CandidateValues), true
+}
+
+// ChangePct returns the candidate's change relative to the baseline in percent.
+func (d MetricDelta) ChangePct() float64 {
+	if d.Baseline == 0 {
+		return 0
+	}
+	return (d.Candidate - d.Baseline) / d.Baseline * 100
+}
+
+// Platform returns "android" or "ios" for the measurement.
+func (c Comparison) Platform() string {
+	if strings.HasPrefix(c.Name, "iOS") {
+		return "ios"
+	}
+	return "android"
+}
+
+// DevicesDiffer reports whether the two sides ran on different device models.
+func (c Comparison) DevicesDiffer() bool {
+	return deviceModel(c.BaselineDevice) != deviceModel(c.CandidateDevice)
+}
+
+// Normalize scales the candidate's durations by factor, turning them into estimates for the
+// baseline's device. Memory and CPU share are left as measured.
+func (c *Comparison) Normalize(factor float64) {
+	c.Factor = factor
+	for i := range c.Metrics {
+		if c.Metrics[i].Unit == "ms" {
+			c.Metrics[i].Candidate *= factor
+			scaled := make([]float64, len(c.Metrics[i].CandidateValues))
+			for j, v := range c.Metrics[i].CandidateValues {
+				scaled[j] = v * factor
+			}
+			c.Metrics[i].CandidateValues = scaled
+		}
+	}
+}
+
+// Compare pairs the measurements present in both results by name.
+func Compare(baseline, candidate Result) []Comparison {
+	var out []Comparison
+	addAndroid := func(name string, base, cand *AndroidMetrics) {
+		if base != nil && cand != nil {
+			c := compareMetrics(name, base.Device, cand.Device, base, cand, base.IterationValues, cand.IterationValues, androidIterationMetrics)
+			if base.Jank != nil && cand.Jank != nil && base.Jank.TotalFrames > 0 && cand.Jank.TotalFrames > 0 {
+				c.Metrics = append(c.Metrics, MetricDelta{Metric: "jankPercent", Unit: "%", Baseline: base.Jank.JankPercent(), Candidate: cand.Jank.JankPercent()})
+			}
+			out = append(out, c)
+		}
+	}
+	addIOS := func(name string, base, cand *IOSMetrics) {
+		if base != nil && cand != nil {
+			out = append(out, compareMetrics(name, base.Device, cand.Device, base, cand, base.IterationValues, cand.IterationValues, iosIterationMetrics))
+		}
+	}
+	addAndroid("Android", baseline.Android, candidate.Android)
+	for _, base := range baseline.Postures {
+		for _, cand := range candidate.Postures {
+			if base.Posture == cand.Posture {
+				addAndroid("Android/"+base.Posture, base.Android, cand.Android)
+			}
+		}
+	}
+	addIOS("iOS", baseline.IOS, candidate.IOS)
+	for _, base := range baseline.Layouts {
+		for _, cand := range candidate.Layouts {
+			if base.Layout == cand.Layout {
+				addIOS("iOS/"+base.Layout, base.IOS, cand.IOS)
+			}
+		}
+	}
+	for _, base := range baseline.OSVersions {
+		for _, cand := range candidate.OSVersions {
+			if base.OS == cand.OS {
+				addAndroid("Android/"+base.OS, base.Android, cand.Android)
+				addIOS("iOS/"+base.OS, base.IOS, cand.IOS)
+			}
+		}
+	}
+	return out
+}
+
+func compareMetrics[T any](name string, baseDevice, candDevice *DeviceMetadata, base, cand T, baseValues, candValues map[string][]float64, metrics []iterationMetric[T]) Comparison {
+	c := Comparison{Name: name, BaselineDevice: baseDevice, CandidateDevice: candDevice}
+	for _, metric := range metrics {
+		b, v := metric.value(base), metric.value(cand)
+		if b == 0 && v == 0 {
+			continue
+		}
+		c.Metrics = append(c.Metrics, MetricDelta{
+			Metric:          metric.name,
+			Unit:            metric.unit,
+			Baseline:        b,
+			Candidate:       v,
+			BaselineValues:  baseValues[metric.name],
+			CandidateValues: candValues[metric.name],
+		})
+	}
+	return c
+}
+
+// FormatComparison renders comparisons as an aligned table per measurement. Normalized
+// measurements are labeled as estimates.
+func FormatComparison(comparisons []Comparison) string {
+	out := ""
+	for _, c := range comparisons {
+		out += fmt.Sprintf("%s: %s -> %s\n", c.Name, formatDevice(c.BaselineDevice), formatDevice(c.CandidateDevice))
+		if c.Factor > 0 {
+			out += fmt.Sprintf("  NORMALIZED ESTIMATE: candidate durations scaled by %.2f from device calibration\n", c.Factor)
+		} else if c.DevicesDiffer() {
+			out += "  WARNING: different devices; raw numbers are not directly comparable (see --normalize)\n"
+		}
+		if MixesVirtualAndPhysical(c.BaselineDevice, c.CandidateDevice) {
+			out += "  WARNING: compares an emulator/simulator with physical hardware\n"
+		}
+		width := 0
+		for _, m := range c.Metrics {
+			width = max(width, len(m.Metric))
+		}
+		for _, m := range c.Metrics {
+			out += fmt.Sprintf("  %-*s %10.1f%s -> %10.1f%s  %+6.1f%%\n", width, m.Metric, m.Baseline, m.Unit, m.Candidate, m.Unit, m.ChangePct())
+		}
+	}
+	return out
+}
+
+func deviceModel(device *DeviceMetadata) string {
+	if device == nil {
+		return ""
+	}
+	return device.Model
+}
@@ -0,0 +1,220 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// Box plots are drawn on a plotWidth-wide axis with plotMargin left free at either end.
+const (
+	plotWidth  = 560
+	plotMargin = 8
+)
+
+type comparisonPage struct {
+	Baseline  Result
+	Candidate Result
+	Level     float64
+	PlotWidth int
+	Sections  []comparisonSection
+}
+
+// comparisonSection is one measurement present in both reports, e.g. Android or iOS/split-half.
+type comparisonSection struct {
+	Title    string
+	Devices  string
+	Warnings []string
+	Rows     []comparisonRow
+}
+
+type comparisonRow struct {
+	Metric    string
+	Baseline  string
+	Candidate string
+	Change    string
+	Verdict   string
+	// Class is regression or improvement for significant changes; lower is better for every metric.
+	Class         string
+	BaselinePlot  boxPlot
+	CandidatePlot boxPlot
+	Low, High     string
+}
+
+// boxPlot holds the x coordinates of one side's distribution: whiskers at the extremes, the box
+// between the quartiles and every iteration as a point.
+type boxPlot struct {
+	Min, Q1, Median, Q3, Max float64
+	Points                   []float64
+}
+
+var comparisonTemplate = template.Must(template.New("compare").Funcs(template.FuncMap{
+	"add": func(a, b float64) float64 { return a + b },
+	"sub": func(a, b float64) float64 { return a - b },
+	"args": func(plot boxPlot, y float64, color string) map[string]any {
+		return map[string]any{"Plot": plot, "Y": y, "Color": color}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>designbench: {{ .Baseline.Component }} vs {{ .Candidate.Component }}</title>
+<style>
+  body { font: 14px/1.4 -apple-system, "Segoe UI", Roboto, sans-serif; color: #1f2328; margin: 32px; max-width: 960px; }
+  h1 { font-size: 22px; margin-bottom: 4px; }
+  h2 { font-size: 17px; margin: 28px 0 4px; border-bottom: 1px solid #d0d7de; padding-bottom: 4px; }
+  .meta { color: #59636e; margin: 0 0 8px; }
+  .warning { background: #fff8c5; border: 1px solid #d4a72c; padding: 6px 10px; border-radius: 4px; }
+  table { border-collapse: collapse; margin: 8px 0; }
+  td, th { padding: 3px 16px 3px 0; vertical-align: middle; text-align: left; }
+  th { color: #59636e; font-weight: normal; }
+  td { font-variant-numeric: tabular-nums; }
+  .regression { color: #cf222e; font-weight: 600; }
+  .improvement { color: #1a7f37; font-weight: 600; }
+  .axis { font-size: 11px; color: #59636e; display: flex; justify-content: space-between; width: {{ .PlotWidth }}px; }
+  .legend span { display: inline-block; width: 10px; height: 10px; margin: 0 4px 0 12px; }
+  code { font-size: 12px; }
+  @media print { body { margin: 0; } h2 { break-after: avoid; } tr { break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{ .Baseline.Component }} vs {{ .Candidate.Component }}</h1>
+<p class="meta">Baseline{{ if .Baseline.RunID }} run {{ .Baseline.RunID }}{{ end }}{{ if .Baseline.CLICommand }}: <code>{{ .Baseline.CLICommand }}</code>{{ end }}<br>
+Candidate{{ if .Candidate.RunID }} run {{ .Candidate.RunID }}{{ end }}{{ if .Candidate.CLICommand }}: <code>{{ .Candidate.CLICommand }}</code>{{ end }}</p>
+<p class="meta legend"><span style="background: #8c959f; margin-left: 0"></span>baseline<span style="background: #0969da"></span>candidate.
+Changes are tested with the Mann-Whitney U test and are significant below p={{ .Level }}; lower is better for every metric.</p>
+{{ range .Sections }}
+<h2>{{ .Title }}</h2>
+<p class="meta">{{ .Devices }}</p>
+{{ range .Warnings }}<p class="warning">{{ . }}</p>
+{{ end }}
+<table>
+<tr><th>Metric</th><th>Baseline</th><th>Candidate</th><th>Change</th><th>Distribution</th></tr>
+{{ range .Rows }}<tr>
+<td>{{ .Metric }}</td><td>{{ .Baseline }}</td><td>{{ .Candidate }}</td>
+<td><span class="{{ .Class }}">{{ .Change }}</span><br><span class="meta">{{ .Verdict }}</span></td>
+<td><svg width="{{ $.PlotWidth }}" height="48" role="img" aria-label="{{ .Metric }} distributions">
+{{ template "box" (args .BaselinePlot 4 "#8c959f") }}
+{{ template "box" (args .CandidatePlot 26 "#0969da") }}
+</svg><div class="axis"><span>{{ .Low }}</span><span>{{ .High }}</span></div></td>
+</tr>
+{{ end }}</table>
+{{ end }}
+</body>
+</html>
+{{ define "box" }}{{ $y := .Y }}{{ $color := .Color }}{{ with .Plot }}<g stroke="{{ $color }}" fill="none">
+<line x1="{{ .Min }}" x2="{{ .Q1 }}" y1="{{ add $y 9 }}" y2="{{ add $y 9 }}"/>
+<line x1="{{ .Q3 }}" x2="{{ .Max }}" y1="{{ add $y 9 }}" y2="{{ add $y 9 }}"/>
+<line x1="{{ .Min }}" x2="{{ .Min }}" y1="{{ add $y 4 }}" y2="{{ add $y 14 }}"/>
+<line x1="{{ .Max }}" x2="{{ .Max }}" y1="{{ add $y 4 }}" y2="{{ add $y 14 }}"/>
+<rect x="{{ .Q1 }}" y="{{ $y }}" width="{{ sub .Q3 .Q1 }}" height="18" fill="{{ $color }}" fill-opacity="0.15"/>
+<line x1="{{ .Median }}" x2="{{ .Median }}" y1="{{ $y }}" y2="{{ add $y 18 }}" stroke-width="2"/>
+{{ range .Points }}<circle cx="{{ . }}" cy="{{ add $y 9 }}" r="2" fill="{{ $color }}" stroke="none"/>{{ end }}
+</g>{{ end }}{{ end }}
+`))
+
+// ComparisonHTML renders comparisons as a standalone page with each metric's baseline and
+// candidate distributions as box plots, annotated with the significance of the change.
+func ComparisonHTML(baseline, candidate Result, comparisons []Comparison) ([]byte, error) {
+	page := comparisonPage{Baseline: baseline, Candidate: candidate, Level: SignificanceLevel, PlotWidth: plotWidth}
+	for _, c := range comparisons {
+		section := comparisonSection{
+			Title:   c.Name,
+			Devices: fmt.Sprintf("%s → %s", describeDevice(c.BaselineDevice), describeDevice(c.CandidateDevice)),
+		}
+		if c.Factor > 0 {
+			section.Warnings = append(section.Warnings, fmt.Sprintf("Normalized estimate: candidate durations scaled by %.2f from device calibration.", c.Factor))
+		} else if c.DevicesDiffer() {
+			section.Warnings = append(section.Warnings, "Different devices: raw numbers are not directly comparable.")
+		}
+		if MixesVirtualAndPhysical(c.BaselineDevice, c.CandidateDevice) {
+			section.Warnings = append(section.Warnings, "Compares an emulator or simulator with physical hardware.")
+		}
+		for _, m := range c.Metrics {
+			section.Rows = append(section.Rows, comparisonRowOf(m))
+		}
+		page.Sections = append(page.Sections, section)
+	}
+	var buf bytes.Buffer
+	if err := comparisonTemplate.Execute(&buf, page); err != nil {
+		return nil, fmt.Errorf("render comparison html: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SaveComparisonHTML writes the comparison page to path, creating its directory.
+func SaveComparisonHTML(path string, baseline, candidate Result, comparisons []Comparison) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create comparison directory: %w", err)
+	}
+	data, err := ComparisonHTML(baseline, candidate, comparisons)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write comparison file: %w", err)
+	}
+	return nil
+}
+
+func comparisonRowOf(m MetricDelta) comparisonRow {
+	row := comparisonRow{
+		Metric:    m.Metric,
+		Baseline:  fmt.Sprintf("%.1f %s", m.Baseline, m.Unit),
+		Candidate: fmt.Sprintf("%.1f %s", m.Candidate, m.Unit),
+		Change:    fmt.Sprintf("%+.1f%%", m.ChangePct()),
+	}
+	if p, ok := m.Significance(); !ok {
+		row.Verdict = "not tested: needs more than one iteration on each side"
+	} else if p < SignificanceLevel {
+		row.Verdict = fmt.Sprintf("significant (p=%.3f)", p)
+		row.Class = "improvement"
+		if m.Candidate > m.Baseline {
+			row.Class = "regression"
+		}
+	} else {
+		row.Verdict = fmt.Sprintf("not significant (p=%.2f)", p)
+	}
+
+	// A single run is drawn as one point at its value.
+	base, cand := m.BaselineValues, m.CandidateValues
+	if len(base) == 0 {
+		base = []float64{m.Baseline}
+	}
+	if len(cand) == 0 {
+		cand = []float64{m.Candidate}
+	}
+	lo := min(slices.Min(base), slices.Min(cand))
+	hi := max(slices.Max(base), slices.Max(cand))
+	if hi == lo {
+		lo, hi = lo-1, hi+1
+	}
+	x := func(v float64) float64 {
+		return math.Round((plotMargin+(v-lo)/(hi-lo)*(plotWidth-2*plotMargin))*10) / 10
+	}
+	row.BaselinePlot = boxPlotOf(base, x)
+	row.CandidatePlot = boxPlotOf(cand, x)
+	row.Low = fmt.Sprintf("%.1f %s", lo, m.Unit)
+	row.High = fmt.Sprintf("%.1f %s", hi, m.Unit)
+	return row
+}
+
+func boxPlotOf(values []float64, x func(float64) float64) boxPlot {
+	plot := boxPlot{
+		Min:    x(slices.Min(values)),
+		Q1:     x(stats.Percentile(values, 25)),
+		Median: x(stats.Percentile(values, 50)),
+		Q3:     x(stats.Percentile(values, 75)),
+		Max:    x(slices.Max(values)),
+	}
+	for _, v := range values {
+		plot.Points = append(plot.Points, x(v))
+	}
+	return plot
+}
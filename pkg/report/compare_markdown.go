@@ -0,0 +1,137 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Markdown verdicts, one per metric row.
+const (
+	verdictRegression      = "🔴 regression"
+	verdictWithinThreshold = "🟡 worse, within threshold"
+	verdictImprovement     = "🟢 improvement"
+	verdictUnchanged       = "⚪ no significant change"
+	verdictUntested        = "➖ not tested"
+)
+
+// FormatComparisonMarkdown renders comparisons as GitHub-flavored Markdown for a pull request
+// comment: a table per measurement with the change, its significance and a verdict. A metric is a
+// regression when it exceeds its family's threshold or, for families without one, when it got
+// significantly worse.
+func FormatComparisonMarkdown(baseline, candidate Result, comparisons []Comparison, thresholds Thresholds) string {
+	var b strings.Builder
+	var body strings.Builder
+	regressions, improvements := 0, 0
+	for _, c := range comparisons {
+		fmt.Fprintf(&body, "\n#### %s\n\n", escapeMarkdown(c.Name))
+		fmt.Fprintf(&body, "%s → %s\n\n", escapeMarkdown(describeDevice(c.BaselineDevice)), escapeMarkdown(describeDevice(c.CandidateDevice)))
+		if c.Factor > 0 {
+			fmt.Fprintf(&body, "> ⚠️ Normalized estimate: candidate durations scaled by %.2f from device calibration.\n\n", c.Factor)
+		} else if c.DevicesDiffer() {
+			body.WriteString("> ⚠️ Different devices: raw numbers are not directly comparable.\n\n")
+		}
+		if MixesVirtualAndPhysical(c.BaselineDevice, c.CandidateDevice) {
+			body.WriteString("> ⚠️ Compares an emulator or simulator with physical hardware.\n\n")
+		}
+		body.WriteString("| Metric | Baseline | Candidate | Change | p | Verdict |\n")
+		body.WriteString("| --- | ---: | ---: | ---: | ---: | --- |\n")
+		for _, m := range c.Metrics {
+			p, tested := m.Significance()
+			pText := "-"
+			if tested {
+				pText = fmt.Sprintf("%.3f", p)
+			}
+			verdict := markdownVerdict(m, thresholds)
+			switch verdict {
+			case verdictRegression:
+				regressions++
+			case verdictImprovement:
+				improvements++
+			}
+			fmt.Fprintf(&body, "| %s | %.1f %s | %.1f %s | %+.1f%%%s | %s | %s |\n",
+				escapeMarkdown(m.Metric), m.Baseline, m.Unit, m.Candidate, m.Unit, m.ChangePct(), significanceStars(p, tested), pText, verdict)
+		}
+	}
+
+	fmt.Fprintf(&b, "### designbench: %s\n\n", escapeMarkdown(candidate.Component))
+	switch {
+	case regressions > 0:
+		fmt.Fprintf(&b, "🔴 **%d regression%s**, %d improvement%s\n", regressions, plural(regressions), improvements, plural(improvements))
+	case improvements > 0:
+		fmt.Fprintf(&b, "🟢 **%d improvement%s**, no regressions\n", improvements, plural(improvements))
+	default:
+		b.WriteString("⚪ No significant changes\n")
+	}
+	b.WriteString(body.String())
+
+	fmt.Fprintf(&b, "\nLower is better for every metric. Changes are tested with the Mann-Whitney U test: `*` p < %.2f, `**` p < 0.01, `***` p < 0.001.", SignificanceLevel)
+	b.WriteString(" Testing needs more than one iteration on each side.\n")
+	var runs []string
+	if baseline.RunID != "" {
+		runs = append(runs, fmt.Sprintf("baseline run `%s`", baseline.RunID))
+	}
+	if candidate.RunID != "" {
+		runs = append(runs, fmt.Sprintf("candidate run `%s`", candidate.RunID))
+	}
+	if len(runs) > 0 {
+		b.WriteString("\n" + strings.Join(runs, " · ") + "\n")
+	}
+	return b.String()
+}
+
+// SaveComparisonMarkdown writes FormatComparisonMarkdown's output to path.
+func SaveComparisonMarkdown(path string, baseline, candidate Result, comparisons []Comparison, thresholds Thresholds) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create comparison directory: %w", err)
+	}
+	data := FormatComparisonMarkdown(baseline, candidate, comparisons, thresholds)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		return fmt.Errorf("write comparison file: %w", err)
+	}
+	return nil
+}
+
+// markdownVerdict judges m by its family's threshold when it has one, and otherwise by the
+// significance test alone.
+func markdownVerdict(m MetricDelta, thresholds Thresholds) string {
+	threshold, hasThreshold := thresholds.For(m.Metric)
+	if hasThreshold && threshold.Exceeded(m.Baseline, m.Candidate) {
+		return verdictRegression
+	}
+	p, ok := m.Significance()
+	switch {
+	case !ok:
+		return verdictUntested
+	case p >= SignificanceLevel:
+		return verdictUnchanged
+	case m.Candidate > m.Baseline && hasThreshold:
+		return verdictWithinThreshold
+	case m.Candidate > m.Baseline:
+		return verdictRegression
+	default:
+		return verdictImprovement
+	}
+}
+
+// significanceStars marks a tested change by how far p falls below the significance level.
+func significanceStars(p float64, tested bool) string {
+	switch {
+	case !tested || p >= SignificanceLevel:
+		return ""
+	case p < 0.001:
+		return " \\*\\*\\*"
+	case p < 0.01:
+		return " \\*\\*"
+	default:
+		return " \\*"
+	}
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
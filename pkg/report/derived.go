@@ -0,0 +1,309 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// DerivedMetric is a metric computed from the other fields of a measurement with an arithmetic
+// expression, e.g. cpuMsPerFrame = cpuTimeMs / frames.
+type DerivedMetric struct {
+	Name string
+	Expr string
+	eval expr
+}
+
+// expr evaluates a parsed expression against a measurement's fields.
+type expr func(fields map[string]float64) (float64, error)
+
+// ParseDerivedMetrics parses expressions by metric name. Expressions combine numbers and fields
+// with + - * / and parentheses. Fields are named by their JSON path in the report, e.g. memoryMb,
+// jank.jankyFrames or custom.itemsRendered; frames is the number of frames measured. Metrics are
+// returned sorted by name.
+func ParseDerivedMetrics(defs map[string]string) ([]DerivedMetric, error) {
+	metrics := make([]DerivedMetric, 0, len(defs))
+	for name, text := range defs {
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("invalid derived metric name %q (expected letters, digits and underscores)", name)
+		}
+		p := &exprParser{text: text}
+		eval, err := p.parse()
+		if err != nil {
+			return nil, fmt.Errorf("derived metric %s: %w", name, err)
+		}
+		metrics = append(metrics, DerivedMetric{Name: name, Expr: text, eval: eval})
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	return metrics, nil
+}
+
+// ApplyDerived computes metrics for every measurement in res and stores them in its Derived field.
+// A metric whose fields were not measured, or that divides by zero, is left out; the returned
+// warnings say which and why.
+func ApplyDerived(res *Result, metrics []DerivedMetric) []string {
+	if len(metrics) == 0 {
+		return nil
+	}
+	var warnings []string
+	apply := func(label string, m any, frames int, derived *map[string]float64) {
+		*derived = nil
+		fields := NumericFields(m)
+		if _, ok := fields["frames"]; !ok && frames > 0 {
+			fields["frames"] = float64(frames)
+		}
+		for _, metric := range metrics {
+			value, err := metric.eval(fields)
+			if err == nil && (math.IsNaN(value) || math.IsInf(value, 0)) {
+				err = errors.New("division by zero")
+			}
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: derived metric %s left out: %v", label, metric.Name, err))
+				continue
+			}
+			if *derived == nil {
+				*derived = make(map[string]float64, len(metrics))
+			}
+			(*derived)[metric.Name] = value
+		}
+	}
+	android := func(label string, m *AndroidMetrics) {
+		frames := len(m.FrameDurationsMs)
+		if frames == 0 && m.Jank != nil {
+			frames = m.Jank.TotalFrames
+		}
+		apply(label, m, frames, &m.Derived)
+	}
+	if res.Android != nil {
+		android("Android", res.Android)
+	}
+	for _, p := range res.Postures {
+		if p.Android != nil {
+			android("Android/"+p.Posture, p.Android)
+		}
+	}
+	if res.IOS != nil {
+		apply("iOS", res.IOS, 0, &res.IOS.Derived)
+	}
+	for _, l := range res.Layouts {
+		if l.IOS != nil {
+			apply("iOS/"+l.Layout, l.IOS, 0, &l.IOS.Derived)
+		}
+	}
+	for _, v := range res.OSVersions {
+		if v.Android != nil {
+			android("Android/"+v.OS, v.Android)
+		}
+		if v.IOS != nil {
+			apply("iOS/"+v.OS, v.IOS, 0, &v.IOS.Derived)
+		}
+	}
+	return warnings
+}
+
+// NumericFields flattens every number in v's JSON form into a map keyed by its JSON path, e.g.
+// "jank.jankFrames". Arrays are left out.
+func NumericFields(v any) map[string]float64 {
+	fields := map[string]float64{}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	var m map[string]any
+	_ = json.Unmarshal(data, &m)
+	flattenNumbers("", m, fields)
+	return fields
+}
+
+func flattenNumbers(prefix string, m map[string]any, out map[string]float64) {
+	for key, value := range m {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case float64:
+			out[name] = v
+		case map[string]any:
+			flattenNumbers(name, v, out)
+		}
+	}
+}
+
+// exprParser is a recursive descent parser for
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = factor { ("*" | "/") factor }
+//	factor = number | field | "(" expr ")" | "-" factor
+type exprParser struct {
+	text string
+	pos  int
+}
+
+func (p *exprParser) parse() (expr, error) {
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.text) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.text[p.pos:], p.pos+1)
+	}
+	return e, nil
+}
+
+func (p *exprParser) expr() (expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(op, left, right)
+	}
+}
+
+func (p *exprParser) term() (expr, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(op, left, right)
+	}
+}
+
+func (p *exprParser) factor() (expr, error) {
+	switch c := p.peek(); {
+	case c == 0:
+		return nil, errors.New("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) at position %d", p.pos+1)
+		}
+		p.pos++
+		return e, nil
+	case c == '-':
+		p.pos++
+		e, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return func(fields map[string]float64) (float64, error) {
+			v, err := e(fields)
+			return -v, err
+		}, nil
+	case c == '.' || isDigit(c):
+		start := p.pos
+		for p.pos < len(p.text) && (p.text[p.pos] == '.' || isDigit(p.text[p.pos])) {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.text[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.text[start:p.pos])
+		}
+		return func(map[string]float64) (float64, error) { return v, nil }, nil
+	case isIdentifierByte(c, true):
+		start := p.pos
+		for p.pos < len(p.text) && (isIdentifierByte(p.text[p.pos], false) || p.text[p.pos] == '.') {
+			p.pos++
+		}
+		name := p.text[start:p.pos]
+		return func(fields map[string]float64) (float64, error) {
+			v, ok := fields[name]
+			if !ok {
+				return 0, fmt.Errorf("%s was not measured", name)
+			}
+			return v, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", c, p.pos+1)
+	}
+}
+
+// peek skips spaces and returns the next byte, or 0 at the end.
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.text) {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func binary(op byte, left, right expr) expr {
+	return func(fields map[string]float64) (float64, error) {
+		a, err := left(fields)
+		if err != nil {
+			return 0, err
+		}
+		b, err := right(fields)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '+':
+			return a + b, nil
+		case '-':
+			return a - b, nil
+		case '*':
+			return a * b, nil
+		}
+		return a / b, nil
+	}
+}
+
+func isIdentifier(name string) bool {
+	if name == "" || !isIdentifierByte(name[0], true) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isIdentifierByte(name[i], false) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifierByte(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// displayValue rounds a named metric to two decimals for summaries; reports keep full precision.
+func displayValue(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,373 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// frameBucketMs is the width of one bar in the frame-duration histogram; the last bar collects
+// every frame of frameBucketMs*frameBuckets or longer.
+const (
+	frameBucketMs = 4
+	frameBuckets  = 12
+)
+
+type htmlReport struct {
+	Result   Result
+	Sections []htmlSection
+}
+
+// htmlSection is one platform measurement, e.g. Android, Android/folded or iOS/split-half.
+type htmlSection struct {
+	Title     string
+	Device    string
+	Debug     bool
+	Metrics   [][2]string
+	Frames    []htmlBar
+	Artifacts []Artifact
+}
+
+type htmlBar struct {
+	Label     string
+	Count     int
+	HeightPct float64
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>designbench: {{ .Result.Component }}</title>
+<style>
+  body { font: 14px/1.4 -apple-system, "Segoe UI", Roboto, sans-serif; color: #1f2328; margin: 32px; max-width: 960px; }
+  h1 { font-size: 22px; margin-bottom: 4px; }
+  h2 { font-size: 17px; margin: 28px 0 4px; border-bottom: 1px solid #d0d7de; padding-bottom: 4px; }
+  .meta { color: #59636e; margin: 0 0 8px; }
+  .warning { background: #fff8c5; border: 1px solid #d4a72c; padding: 6px 10px; border-radius: 4px; }
+  table { border-collapse: collapse; margin: 8px 0; }
+  td { padding: 3px 16px 3px 0; vertical-align: top; }
+  td:first-child { color: #59636e; }
+  td:last-child { font-variant-numeric: tabular-nums; }
+  .histogram { display: flex; align-items: flex-end; gap: 4px; height: 120px; margin: 8px 0 0; }
+  .bar { background: #0969da; width: 36px; }
+  .labels { display: flex; gap: 4px; font-size: 11px; color: #59636e; }
+  .labels span { width: 36px; text-align: center; }
+  code { font-size: 12px; }
+  @media print { body { margin: 0; } h2 { break-after: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{ .Result.Component }}</h1>
+<p class="meta">{{ if .Result.RunID }}Run {{ .Result.RunID }}{{ end }}{{ if .Result.CLICommand }}<br><code>{{ .Result.CLICommand }}</code>{{ end }}{{ with .Result.Install }}<br>Install: {{ .String }}{{ end }}</p>
+{{ range .Sections }}
+<h2>{{ .Title }}</h2>
+<p class="meta">{{ .Device }}</p>
+{{ if .Debug }}<p class="warning">Debug build: timings are not representative of release performance.</p>{{ end }}
+<table>
+{{ range .Metrics }}<tr><td>{{ index . 0 }}</td><td>{{ index . 1 }}</td></tr>
+{{ end }}</table>
+{{ if .Frames }}
+<p class="meta">Frame durations</p>
+<div class="histogram">{{ range .Frames }}<div class="bar" style="height: {{ printf "%.1f" .HeightPct }}%" title="{{ .Count }} frames"></div>{{ end }}</div>
+<div class="labels">{{ range .Frames }}<span>{{ .Label }}</span>{{ end }}</div>
+{{ end }}
+{{ if .Artifacts }}
+<p class="meta">Artifacts</p>
+<ul>{{ range .Artifacts }}<li>{{ .Kind }}: <a href="{{ .Path }}">{{ .Path }}</a></li>{{ end }}</ul>
+{{ end }}
+{{ end }}
+</body>
+</html>
+`))
+
+// HTML renders result as a standalone page for sharing in design reviews. Artifact links are
+// relative to dir, where the page is saved.
+func HTML(result Result, dir string) ([]byte, error) {
+	result = relativeArtifacts(result, dir)
+	page := htmlReport{Result: result}
+	if result.Android != nil {
+		page.Sections = append(page.Sections, androidSection("Android", result.Android))
+	}
+	for _, posture := range result.Postures {
+		if posture.Android != nil {
+			page.Sections = append(page.Sections, androidSection("Android/"+posture.Posture, posture.Android))
+		}
+	}
+	if result.IOS != nil {
+		page.Sections = append(page.Sections, iosSection("iOS", result.IOS))
+	}
+	for _, layout := range result.Layouts {
+		if layout.IOS != nil {
+			page.Sections = append(page.Sections, iosSection("iOS/"+layout.Layout, layout.IOS))
+		}
+	}
+	for _, version := range result.OSVersions {
+		if version.Android != nil {
+			page.Sections = append(page.Sections, androidSection("Android/"+version.OS, version.Android))
+		}
+		if version.IOS != nil {
+			page.Sections = append(page.Sections, iosSection("iOS/"+version.OS, version.IOS))
+		}
+	}
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, page); err != nil {
+		return nil, fmt.Errorf("render html report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SaveHTML writes the HTML report to path, creating its directory.
+func SaveHTML(path string, result Result) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create report directory: %w", err)
+	}
+	data, err := HTML(result, dir)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write report file: %w", err)
+	}
+	return nil
+}
+
+func androidSection(title string, m *AndroidMetrics) htmlSection {
+	section := htmlSection{
+		Title:     title,
+		Device:    describeDevice(m.Device),
+		Debug:     m.BuildType == BuildTypeDebug,
+		Frames:    frameHistogram(m.FrameDurationsMs),
+		Artifacts: m.Artifacts,
+	}
+	add := func(name, format string, args ...any) {
+		section.Metrics = append(section.Metrics, [2]string{name, fmt.Sprintf(format, args...)})
+	}
+	add("Total launch time", "%.1f ms", m.TotalTimeMs)
+	add("First frame", "%.1f ms", m.FirstFrameMs)
+	add("Wait time", "%.1f ms", m.WaitTimeMs)
+	if m.Variant != "" {
+		add("Build variant", "%s", m.Variant)
+	}
+	if m.FullyDrawnMs > 0 {
+		add("Fully drawn", "%.1f ms", m.FullyDrawnMs)
+	}
+	if m.NavigationMs > 0 {
+		add("Navigation", "%.1f ms", m.NavigationMs)
+	}
+	addResources(add, m.MemoryMB, m.CPUPercent, m.CPUTimeMs, m.Threads)
+	if sched := m.StartupScheduling; sched != nil {
+		add("Startup main thread running / runnable", "%.1f / %.1f ms (%.0f%% of launch waiting for CPU)", sched.RunningMs, sched.RunnableMs, sched.RunnablePct())
+	}
+	if b := m.Binder; b != nil {
+		add("Binder calls during launch", "%d, %.1f ms total", b.Calls, b.LatencyMs)
+		if b.MaxLatencyMs > 0 {
+			add("Longest binder call", "%.1f ms", b.MaxLatencyMs)
+		}
+		for _, call := range b.Top {
+			add("  "+call.Name, "%d calls, %.1f ms", call.Calls, call.LatencyMs)
+		}
+	}
+	if len(m.PowerRails) > 0 {
+		add("Energy", "%.1f mJ across %d rails", m.TotalEnergyUJ()/1000, len(m.PowerRails))
+	}
+	if jank := m.Jank; jank != nil {
+		add("Jank", "%d of %d frames (%.1f%%)", jank.JankyFrames, jank.TotalFrames, jank.JankPercent())
+		causes := make([]string, 0, len(jank.ByType))
+		for cause := range jank.ByType {
+			causes = append(causes, cause)
+		}
+		sort.Strings(causes)
+		for _, cause := range causes {
+			add("  "+cause, "%d", jank.ByType[cause])
+		}
+	}
+	if sl := m.SurfaceLatency; sl != nil {
+		add("Compositor frames", "%d, %d missed vsyncs, %.1f ms present jitter", sl.Frames, sl.MissedVsyncs, sl.PresentJitterMs)
+	}
+	if m.StrictMode != nil {
+		policies := make([]string, 0, len(m.StrictMode))
+		for policy := range m.StrictMode {
+			policies = append(policies, policy)
+		}
+		sort.Strings(policies)
+		add("StrictMode violations", "%d", sumCounts(m.StrictMode))
+		for _, policy := range policies {
+			add("  "+policy, "%d", m.StrictMode[policy])
+		}
+	}
+	if n := m.MainThreadNetwork; n != nil {
+		if n.Detected {
+			add("Main-thread network before first frame", "yes, %d calls", n.Calls)
+		} else {
+			add("Main-thread network before first frame", "no")
+		}
+	}
+	if frames := m.FrameDurationsMs; len(frames) > 0 {
+		add("Frame time p50 / p90 / p99", "%.1f / %.1f / %.1f ms", stats.Percentile(frames, 50), stats.Percentile(frames, 90), stats.Percentile(frames, 99))
+	}
+	if p := m.FramePipeline; p != nil {
+		add("UI thread per frame", "%.1f ms (measure/layout %.1f ms, draw %.1f ms)", p.UIThreadMs, p.MeasureLayoutMs, p.DrawMs)
+		add("RenderThread per frame", "%.1f ms (sync %.1f ms, GPU %.1f ms)", p.RenderThreadMs, p.SyncMs, p.GPUMs)
+	}
+	if len(m.Flow) > 0 {
+		add("Navigation flow", "%.1f ms over %d transitions", m.FlowTimeMs(), len(m.Flow))
+		for _, t := range m.Flow {
+			add("  "+t.Name, "%.1f ms", t.TotalTimeMs)
+		}
+	}
+	if t := m.ThemeSwitch; t != nil {
+		add("Theme switch to dark / light", "%.1f / %.1f ms", t.ToDarkMs, t.ToLightMs)
+	}
+	if a := m.Animation; a != nil {
+		add("Animation "+a.Name, "%d of %d frames, %d dropped, longest pause %.1f ms", a.RenderedFrames, a.ExpectedFrames, a.DroppedFrames, a.LongestPauseMs)
+	}
+	if il := m.InputLatency; il != nil {
+		add("Input latency p50 / p95", "%.1f / %.1f ms (%d taps, %d missed)", il.P50Ms, il.P95Ms, il.Taps, il.Missed)
+	}
+	if mk := m.Monkey; mk != nil {
+		add("Monkey", "%d/%d events, %d crashes, %d ANRs, peak memory %.1f MB", mk.EventsInjected, mk.Events, mk.Crashes, mk.ANRs, mk.PeakMemoryMB)
+		if mk.TotalFrames > 0 {
+			add("Monkey jank", "%d of %d frames", mk.JankyFrames, mk.TotalFrames)
+		}
+	}
+	if m.NetworkRxBytes > 0 || m.NetworkTxBytes > 0 {
+		add("Network during launch", "%s received, %s sent", formatBytes(m.NetworkRxBytes), formatBytes(m.NetworkTxBytes))
+	}
+	addCustom(add, m.Custom)
+	addCustom(add, m.Derived)
+	addIterations(add, m.Iterations, m.CIWidthPct)
+	addSpread(add, m.Spread, androidIterationMetrics)
+	return section
+}
+
+func iosSection(title string, m *IOSMetrics) htmlSection {
+	section := htmlSection{
+		Title:     title,
+		Device:    describeDevice(m.Device),
+		Debug:     m.BuildType == BuildTypeDebug,
+		Artifacts: m.Artifacts,
+	}
+	add := func(name, format string, args ...any) {
+		section.Metrics = append(section.Metrics, [2]string{name, fmt.Sprintf(format, args...)})
+	}
+	add("Time to first frame", "%.1f ms", m.RenderTimeMs)
+	if m.LaunchCommandMs > 0 {
+		add("Launch command", "%.1f ms", m.LaunchCommandMs)
+	}
+	addResources(add, m.MemoryMB, m.CPUPercent, m.CPUTimeMs, m.Threads)
+	if m.HangCount > 0 {
+		add("Hangs", "%d, longest %.0f ms", m.HangCount, m.LongestHangMs)
+	}
+	addCustom(add, m.Custom)
+	addCustom(add, m.Derived)
+	addIterations(add, m.Iterations, m.CIWidthPct)
+	addSpread(add, m.Spread, iosIterationMetrics)
+	return section
+}
+
+func addResources(add func(string, string, ...any), memoryMB, cpuPercent, cpuTimeMs float64, threads *ThreadMetrics) {
+	if memoryMB > 0 {
+		add("Memory", "%.1f MB", memoryMB)
+	}
+	if cpuPercent > 0 {
+		add("CPU", "%.1f%%", cpuPercent)
+	}
+	if cpuTimeMs > 0 {
+		add("CPU time", "%.0f ms", cpuTimeMs)
+	}
+	if threads != nil {
+		add("Threads", "%d", threads.Count)
+		if total := threads.MainRunningMs + threads.MainRunnableMs; total > 0 {
+			add("Main thread running / runnable", "%.1f / %.1f ms", threads.MainRunningMs, threads.MainRunnableMs)
+		}
+	}
+}
+
+func addIterations(add func(string, string, ...any), iterations int, ciWidthPct float64) {
+	if iterations <= 1 {
+		return
+	}
+	if ciWidthPct > 0 {
+		add("Iterations", "%d (95%% CI ±%.1f%%)", iterations, ciWidthPct)
+		return
+	}
+	add("Iterations", "%d", iterations)
+}
+
+func describeDevice(device *DeviceMetadata) string {
+	if device == nil {
+		return ""
+	}
+	out := formatDevice(device)
+	if device.OSVersion != "" {
+		out += ", OS " + device.OSVersion
+	}
+	if device.Resolution != "" {
+		out += ", " + device.Resolution
+	}
+	return out
+}
+
+func frameHistogram(frames []float64) []htmlBar {
+	if len(frames) == 0 {
+		return nil
+	}
+	bars := make([]htmlBar, frameBuckets)
+	for i := range bars {
+		bars[i].Label = fmt.Sprintf("%d", i*frameBucketMs)
+	}
+	bars[frameBuckets-1].Label += "+"
+	most := 0
+	for _, ms := range frames {
+		bucket := min(int(ms)/frameBucketMs, frameBuckets-1)
+		bars[bucket].Count++
+		most = max(most, bars[bucket].Count)
+	}
+	for i := range bars {
+		bars[i].HeightPct = float64(bars[i].Count) / float64(most) * 100
+	}
+	return bars
+}
+
+// addCustom adds one row per app-reported or derived metric, sorted by name.
+func addCustom(add func(string, string, ...any), custom map[string]float64) {
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(name, "%g", displayValue(custom[name]))
+	}
+}
+
+// addSpread adds a row per metric with its statistics across iterations, e.g. "p50 12.1 ms, p99 14.0 ms".
+func addSpread[T any](add func(string, string, ...any), spread map[string][]Statistic, metrics []iterationMetric[T]) {
+	for _, metric := range metrics {
+		values, ok := spread[metric.name]
+		if !ok {
+			continue
+		}
+		parts := make([]string, len(values))
+		for i, s := range values {
+			parts[i] = fmt.Sprintf("%s %.1f %s", s.Name, s.Value, metric.unit)
+		}
+		add(metric.name+" across iterations", "%s", strings.Join(parts, ", "))
+	}
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
@@ -0,0 +1,171 @@
+package report
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/stats"
+)
+
+// DefaultStatistics are the aggregate statistics shown for each metric's spread across iterations
+// unless others are chosen with --percentiles.
+var DefaultStatistics = []string{"min", "p50", "p95"}
+
+// Statistic is one aggregate statistic of a metric's values across iterations.
+type Statistic struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// ParseStatistics reads a comma-separated list of statistics: min, max, mean, or a percentile such
+// as p90 or p99.9. Names are returned in the given order without duplicates.
+func ParseStatistics(value string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || slices.Contains(names, name) {
+			continue
+		}
+		if _, ok := statistic(name); !ok {
+			return nil, fmt.Errorf("unknown statistic %q (expected min, max, mean or a percentile such as p90)", name)
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no statistics in %q", value)
+	}
+	return names, nil
+}
+
+// statistic returns the function computing the named statistic.
+func statistic(name string) (func([]float64) float64, bool) {
+	switch name {
+	case "min":
+		return slices.Min[[]float64], true
+	case "max":
+		return slices.Max[[]float64], true
+	case "mean":
+		return stats.Mean, true
+	}
+	raw, ok := strings.CutPrefix(name, "p")
+	if !ok {
+		return nil, false
+	}
+	p, err := strconv.ParseFloat(raw, 64)
+	if err != nil || p <= 0 || p > 100 {
+		return nil, false
+	}
+	return func(values []float64) float64 { return stats.Percentile(values, p) }, true
+}
+
+// Spread computes the named statistics of each metric's iteration values. Names must have been
+// accepted by ParseStatistics.
+func Spread(values map[string][]float64, names []string) map[string][]Statistic {
+	if len(values) == 0 {
+		return nil
+	}
+	spread := make(map[string][]Statistic, len(values))
+	for metric, series := range values {
+		for _, name := range names {
+			compute, ok := statistic(name)
+			if !ok {
+				continue
+			}
+			spread[metric] = append(spread[metric], Statistic{Name: name, Value: compute(series)})
+		}
+	}
+	return spread
+}
+
+// iterationMetric is a headline measurement whose per-iteration values are kept in
+// IterationValues so that the summary can show their spread.
+type iterationMetric[T any] struct {
+	name  string
+	unit  string
+	value func(T) float64
+}
+
+var androidIterationMetrics = []iterationMetric[*AndroidMetrics]{
+	{"totalTimeMs", "ms", func(m *AndroidMetrics) float64 { return m.TotalTimeMs }},
+	{"firstFrameMs", "ms", func(m *AndroidMetrics) float64 { return m.FirstFrameMs }},
+	{"waitTimeMs", "ms", func(m *AndroidMetrics) float64 { return m.WaitTimeMs }},
+	{"fullyDrawnMs", "ms", func(m *AndroidMetrics) float64 { return m.FullyDrawnMs }},
+	{"navigationMs", "ms", func(m *AndroidMetrics) float64 { return m.NavigationMs }},
+	{"flowTimeMs", "ms", func(m *AndroidMetrics) float64 { return m.FlowTimeMs() }},
+	{"memoryMb", "MB", func(m *AndroidMetrics) float64 { return m.MemoryMB }},
+	{"cpuPercent", "%", func(m *AndroidMetrics) float64 { return m.CPUPercent }},
+	{"cpuTimeMs", "ms", func(m *AndroidMetrics) float64 { return m.CPUTimeMs }},
+}
+
+var iosIterationMetrics = []iterationMetric[*IOSMetrics]{
+	{"renderTimeMs", "ms", func(m *IOSMetrics) float64 { return m.RenderTimeMs }},
+	{"memoryMb", "MB", func(m *IOSMetrics) float64 { return m.MemoryMB }},
+	{"cpuPercent", "%", func(m *IOSMetrics) float64 { return m.CPUPercent }},
+	{"cpuTimeMs", "ms", func(m *IOSMetrics) float64 { return m.CPUTimeMs }},
+}
+
+// collectIterations returns each metric's values in run order, skipping metrics that were never
+// measured. A single run has no spread, so it yields nil.
+func collectIterations[T any](runs []T, metrics []iterationMetric[T]) map[string][]float64 {
+	if len(runs) < 2 {
+		return nil
+	}
+	values := make(map[string][]float64, len(metrics))
+	for _, metric := range metrics {
+		series := make([]float64, len(runs))
+		measured := false
+		for i, run := range runs {
+			series[i] = metric.value(run)
+			measured = measured || series[i] != 0
+		}
+		if measured {
+			values[metric.name] = series
+		}
+	}
+	return values
+}
+
+// formatSpread renders one line per metric with a sparkline of the iterations in run order and the
+// statistics in spread. Reports that predate spread get the default statistics.
+func formatSpread[T any](values map[string][]float64, spread map[string][]Statistic, metrics []iterationMetric[T]) string {
+	if spread == nil {
+		spread = Spread(values, DefaultStatistics)
+	}
+	width := 0
+	for _, metric := range metrics {
+		if _, ok := values[metric.name]; ok {
+			width = max(width, len(metric.name))
+		}
+	}
+	out := ""
+	for _, metric := range metrics {
+		series, ok := values[metric.name]
+		if !ok {
+			continue
+		}
+		out += fmt.Sprintf("    %-*s %s", width, metric.name, sparkline(series))
+		for _, s := range spread[metric.name] {
+			out += fmt.Sprintf(" %s=%.1f%s", s.Name, s.Value, metric.unit)
+		}
+		out += "\n"
+	}
+	return out
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline scales values between their minimum and maximum onto eight block heights.
+func sparkline(values []float64) string {
+	lo, hi := slices.Min(values), slices.Max(values)
+	out := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		out[i] = sparkBlocks[level]
+	}
+	return string(out)
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatMarkdown renders the headline metrics of each measurement in res as a Markdown table, for
+// merge request notes and other places that render GitLab- or GitHub-flavored Markdown. Values
+// over their budget are marked in bold.
+func FormatMarkdown(res Result, budgets Budgets) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### designbench: %s\n\n", escapeMarkdown(res.Component))
+	b.WriteString("| Measurement | Device | Time | Memory | CPU | CPU time |\n")
+	b.WriteString("| --- | --- | ---: | ---: | ---: | ---: |\n")
+	row := func(label string, device *DeviceMetadata, timeName, timeMetric string, timeValue, memory, cpu, cpuTime float64) {
+		fmt.Fprintf(&b, "| %s | %s | %s %s | %s | %s | %s |\n",
+			escapeMarkdown(label),
+			escapeMarkdown(formatDevice(device)),
+			timeName,
+			markdownValue(budgets, timeMetric, timeValue, "%.1f ms"),
+			markdownValue(budgets, "memoryMb", memory, "%.1f MB"),
+			markdownValue(budgets, "cpuPercent", cpu, "%.1f%%"),
+			markdownValue(budgets, "cpuTimeMs", cpuTime, "%.0f ms"))
+	}
+	android := func(label string, m *AndroidMetrics) {
+		row(label, m.Device, "launch", "totalTimeMs", m.TotalTimeMs, m.MemoryMB, m.CPUPercent, m.CPUTimeMs)
+	}
+	ios := func(label string, m *IOSMetrics) {
+		row(label, m.Device, "render", "renderTimeMs", m.RenderTimeMs, m.MemoryMB, m.CPUPercent, m.CPUTimeMs)
+	}
+	if res.Android != nil {
+		android("Android", res.Android)
+	}
+	for _, posture := range res.Postures {
+		if posture.Android != nil {
+			android("Android/"+posture.Posture, posture.Android)
+		}
+	}
+	if res.IOS != nil {
+		ios("iOS", res.IOS)
+	}
+	for _, layout := range res.Layouts {
+		if layout.IOS != nil {
+			ios("iOS/"+layout.Layout, layout.IOS)
+		}
+	}
+	for _, version := range res.OSVersions {
+		if version.Android != nil {
+			android("Android/"+version.OS, version.Android)
+		}
+		if version.IOS != nil {
+			ios("iOS/"+version.OS, version.IOS)
+		}
+	}
+
+	var notes []string
+	if name, value, ok := PrimaryMetric(res); ok && name != "totalTimeMs" && name != "renderTimeMs" {
+		notes = append(notes, fmt.Sprintf("Scenario metric `%s`: %.1f", name, value))
+	}
+	if res.RunID != "" {
+		notes = append(notes, fmt.Sprintf("Run `%s`", res.RunID))
+	}
+	if res.CLICommand != "" {
+		notes = append(notes, fmt.Sprintf("`%s`", strings.ReplaceAll(res.CLICommand, "`", "'")))
+	}
+	if len(notes) > 0 {
+		b.WriteString("\n" + strings.Join(notes, " · ") + "\n")
+	}
+	return b.String()
+}
+
+// markdownValue formats value, or "-" when it was not measured, in bold when it exceeds the budget
+// for metric.
+func markdownValue(budgets Budgets, metric string, value float64, format string) string {
+	if value <= 0 {
+		return "-"
+	}
+	text := fmt.Sprintf(format, value)
+	if budget, ok := budgets[metric]; ok && budget > 0 && value > budget {
+		return fmt.Sprintf("**%s** (budget %.1f)", text, budget)
+	}
+	return text
+}
+
+// escapeMarkdown keeps device and component names from breaking out of a table cell.
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(text)
+}
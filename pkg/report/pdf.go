@@ -0,0 +1,82 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// chromeNames are the executables searched on PATH for headless PDF rendering.
+var chromeNames = []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome", "msedge"}
+
+// macChrome is where Chrome lives on macOS, which does not put it on PATH.
+const macChrome = "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome"
+
+// SavePDF renders the HTML report to a PDF at path with headless Chrome. DESIGNBENCH_CHROME
+// overrides which browser is used.
+func SavePDF(ctx context.Context, path string, result Result) error {
+	chrome, err := FindChrome()
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(abs)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create report directory: %w", err)
+	}
+	// The page is written next to the PDF so that relative artifact links resolve the same way.
+	page, err := os.CreateTemp(dir, ".designbench-*.html")
+	if err != nil {
+		return fmt.Errorf("create temporary html: %w", err)
+	}
+	defer os.Remove(page.Name())
+	data, err := HTML(result, dir)
+	if err != nil {
+		page.Close()
+		return err
+	}
+	if _, err := page.Write(data); err != nil {
+		page.Close()
+		return fmt.Errorf("write temporary html: %w", err)
+	}
+	if err := page.Close(); err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, chrome,
+		"--headless",
+		"--disable-gpu",
+		"--no-pdf-header-footer",
+		"--print-to-pdf="+abs,
+		"file://"+filepath.ToSlash(page.Name()),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("render pdf with %s: %w: %s", chrome, err, strings.TrimSpace(string(out)))
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return fmt.Errorf("render pdf with %s: no output written", chrome)
+	}
+	return nil
+}
+
+// FindChrome returns the browser SavePDF uses, so that a missing one is reported before a run starts.
+func FindChrome() (string, error) {
+	if chrome := os.Getenv("DESIGNBENCH_CHROME"); chrome != "" {
+		return chrome, nil
+	}
+	for _, name := range chromeNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	if _, err := os.Stat(macChrome); err == nil {
+		return macChrome, nil
+	}
+	return "", errors.New("pdf output needs Chrome or Chromium; install one or set DESIGNBENCH_CHROME")
+}
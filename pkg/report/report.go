@@ -3,62 +3,891 @@ package report
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
+
+	"github.com/tahatesser/designbench/pkg/stats"
 )
 
 // DeviceMetadata captures basic information about the device that produced a benchmark.
 type DeviceMetadata struct {
-	ID         string `json:"id,omitempty"`
-	Model      string `json:"model,omitempty"`
-	OSVersion  string `json:"osVersion,omitempty"`
-	Platform   string `json:"platform,omitempty"`
-	Resolution string `json:"resolution,omitempty"`
+	ID            string  `json:"id,omitempty"`
+	Model         string  `json:"model,omitempty"`
+	OSVersion     string  `json:"osVersion,omitempty"`
+	Platform      string  `json:"platform,omitempty"`
+	Resolution    string  `json:"resolution,omitempty"`
+	DensityDPI    int     `json:"densityDpi,omitempty"`
+	ScaleFactor   float64 `json:"scaleFactor,omitempty"`
+	RefreshRateHz float64 `json:"refreshRateHz,omitempty"`
+	DeviceType    string  `json:"deviceType,omitempty"`
+	Renderer      string  `json:"renderer,omitempty"`
+	IsEmulator    bool    `json:"isEmulator,omitempty"`
+	IsSimulator   bool    `json:"isSimulator,omitempty"`
+
+	// PerformanceClass is the Android media performance class the device declares, as an API
+	// level (31 = Android 12); 0 when it declares none.
+	PerformanceClass int `json:"performanceClass,omitempty"`
+	// Chip is the SoC, from ro.soc.model or the device registry.
+	Chip string `json:"chip,omitempty"`
+	// Tier is the rough hardware class (low, mid or high) for comparing results across devices.
+	Tier string `json:"tier,omitempty"`
+	// DisplayID is the Android logical display the app was launched on when it was not the
+	// default one; resolution, density and refresh rate then describe that display.
+	DisplayID   string `json:"displayId,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	// DisplayType is INTERNAL, EXTERNAL, WIFI, OVERLAY or VIRTUAL.
+	DisplayType string `json:"displayType,omitempty"`
+}
+
+// IsVirtual reports whether the metrics came from an emulator or simulator rather than physical hardware.
+func (d *DeviceMetadata) IsVirtual() bool {
+	return d != nil && (d.IsEmulator || d.IsSimulator)
+}
+
+// MixesVirtualAndPhysical reports whether two devices differ in being virtual, which makes their
+// results unsuitable for direct comparison.
+func MixesVirtualAndPhysical(a, b *DeviceMetadata) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.IsVirtual() != b.IsVirtual()
+}
+
+// Artifact references a file captured alongside a benchmark run, such as a trace.
+type Artifact struct {
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+}
+
+// PowerRail reports the energy drawn from a single on-device power rail (ODPM) during a benchmark.
+type PowerRail struct {
+	Name      string  `json:"name"`
+	Subsystem string  `json:"subsystem,omitempty"`
+	EnergyUJ  float64 `json:"energyUj"`
+}
+
+// ThreadMetrics describes process threading and how the main thread was scheduled during a benchmark.
+type ThreadMetrics struct {
+	Count          int     `json:"count,omitempty"`
+	MainRunningMs  float64 `json:"mainRunningMs,omitempty"`
+	MainRunnableMs float64 `json:"mainRunnableMs,omitempty"`
+}
+
+// StartupScheduling splits the main thread's time during launch into running on a CPU, which is
+// the app's own cost, and runnable while waiting for a CPU, which is contention on the device.
+type StartupScheduling struct {
+	WindowMs   float64 `json:"windowMs"`
+	RunningMs  float64 `json:"runningMs"`
+	RunnableMs float64 `json:"runnableMs"`
+}
+
+// RunnablePct returns the share of the launch window the main thread spent waiting for a CPU.
+func (s StartupScheduling) RunnablePct() float64 {
+	if s.WindowMs <= 0 {
+		return 0
+	}
+	return s.RunnableMs / s.WindowMs * 100
+}
+
+// BinderMetrics summarizes the binder calls an app made to system services during launch.
+// LatencyMs is extrapolated from the calls system_server sampled.
+type BinderMetrics struct {
+	Calls        int64        `json:"calls"`
+	LatencyMs    float64      `json:"latencyMs"`
+	MaxLatencyMs float64      `json:"maxLatencyMs,omitempty"`
+	Top          []BinderCall `json:"top,omitempty"`
+}
+
+// BinderCall is one binder method, e.g. android.app.IActivityManager#getRunningAppProcesses.
+type BinderCall struct {
+	Name      string  `json:"name"`
+	Calls     int64   `json:"calls"`
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+// JankBreakdown classifies an app's frames by jank cause as reported by FrameTimeline (Android 12+).
+type JankBreakdown struct {
+	TotalFrames int            `json:"totalFrames"`
+	JankyFrames int            `json:"jankyFrames"`
+	ByType      map[string]int `json:"byType,omitempty"`
+}
+
+// JankPercent returns the share of janky frames.
+func (j *JankBreakdown) JankPercent() float64 {
+	if j == nil || j.TotalFrames == 0 {
+		return 0
+	}
+	return float64(j.JankyFrames) / float64(j.TotalFrames) * 100
+}
+
+// FramePipeline splits the average frame between the UI thread, which handles input and animations,
+// measures, lays out and records the frame, and the RenderThread, which syncs it and issues the
+// draw commands to the GPU. Times are means per frame from gfxinfo framestats.
+type FramePipeline struct {
+	Frames          int     `json:"frames"`
+	UIThreadMs      float64 `json:"uiThreadMs"`
+	MeasureLayoutMs float64 `json:"measureLayoutMs"`
+	DrawMs          float64 `json:"drawMs"`
+	RenderThreadMs  float64 `json:"renderThreadMs"`
+	SyncMs          float64 `json:"syncMs"`
+	// GPUMs covers issuing the draw commands and waiting for the GPU and buffer swap.
+	GPUMs float64 `json:"gpuMs"`
+}
+
+// SurfaceLatency is SurfaceFlinger's view of the frames the app's window presented after launch.
+type SurfaceLatency struct {
+	Layer           string  `json:"layer"`
+	RefreshPeriodMs float64 `json:"refreshPeriodMs"`
+	Frames          int     `json:"frames"`
+	// MissedVsyncs counts the refresh periods by which frames missed their desired present time.
+	MissedVsyncs int `json:"missedVsyncs"`
+	// PresentJitterMs is the standard deviation of how late frames were presented.
+	PresentJitterMs float64 `json:"presentJitterMs"`
+}
+
+// MainThreadNetwork records synchronous network calls made on the main thread before the first
+// frame, detected through StrictMode. Such calls are a common cause of slow launches.
+type MainThreadNetwork struct {
+	Detected bool `json:"detected"`
+	Calls    int  `json:"calls"`
+}
+
+// ThemeSwitchMetrics measures how long the UI takes to re-render after toggling the system theme.
+type ThemeSwitchMetrics struct {
+	ToDarkMs  float64 `json:"toDarkMs,omitempty"`
+	ToLightMs float64 `json:"toLightMs,omitempty"`
+	Frames    int     `json:"frames,omitempty"`
+}
+
+// MeanMs averages the two switch directions.
+func (t *ThemeSwitchMetrics) MeanMs() float64 {
+	return (t.ToDarkMs + t.ToLightMs) / 2
+}
+
+// FlowTransition is one step of a navigation flow: the activity or deep link opened from the
+// previous screen and how long `am start -W` took until it drew its first frame.
+type FlowTransition struct {
+	Name        string  `json:"name"`
+	TotalTimeMs float64 `json:"totalTimeMs"`
+	WaitTimeMs  float64 `json:"waitTimeMs,omitempty"`
+}
+
+// AnimationMetrics compares the frames a harness animation rendered with the frames the display
+// could have shown over the animation's duration.
+type AnimationMetrics struct {
+	Name           string  `json:"name"`
+	DurationMs     float64 `json:"durationMs"`
+	ExpectedFrames int     `json:"expectedFrames"`
+	RenderedFrames int     `json:"renderedFrames"`
+	DroppedFrames  int     `json:"droppedFrames"`
+	LongestPauseMs float64 `json:"longestPauseMs,omitempty"`
+}
+
+// InputLatencyMetrics measures how long the app takes to show its response to a tap, from the vsync
+// that dispatched the input to the frame being presented on the display.
+type InputLatencyMetrics struct {
+	Taps      int       `json:"taps"`
+	Missed    int       `json:"missed,omitempty"`
+	SamplesMs []float64 `json:"samplesMs,omitempty"`
+	P50Ms     float64   `json:"p50Ms"`
+	P95Ms     float64   `json:"p95Ms"`
+
+	// NotPresented counts samples measured to frame completion instead, on devices that report no
+	// present time (before Android 12, and some emulators).
+	NotPresented int `json:"notPresented,omitempty"`
+}
+
+// MonkeyMetrics records stability and resource usage while `adb shell monkey` exercises the app.
+type MonkeyMetrics struct {
+	Events         int     `json:"events"`
+	EventsInjected int     `json:"eventsInjected"`
+	Crashes        int     `json:"crashes"`
+	ANRs           int     `json:"anrs"`
+	DurationMs     float64 `json:"durationMs,omitempty"`
+	PeakMemoryMB   float64 `json:"peakMemoryMb,omitempty"`
+	MeanCPUPercent float64 `json:"meanCpuPercent,omitempty"`
+	Samples        int     `json:"samples,omitempty"`
+	TotalFrames    int     `json:"totalFrames,omitempty"`
+	JankyFrames    int     `json:"jankyFrames,omitempty"`
 }
 
 // AndroidMetrics represents render/startup timing measurements collected from an Android device.
 type AndroidMetrics struct {
-	Component          string          `json:"component"`
-	Activity           string          `json:"activity"`
-	Package            string          `json:"package"`
-	BenchmarkComponent string          `json:"benchmarkComponent,omitempty"`
-	FirstFrameMs       float64         `json:"firstFrameMs,omitempty"`
-	TotalTimeMs        float64         `json:"totalTimeMs,omitempty"`
-	WaitTimeMs         float64         `json:"waitTimeMs,omitempty"`
-	MemoryMB           float64         `json:"memoryMb,omitempty"`
-	CPUPercent         float64         `json:"cpuPercent,omitempty"`
-	CPUTimeMs          float64         `json:"cpuTimeMs,omitempty"`
-	LaunchState        string          `json:"launchState,omitempty"`
-	Device             *DeviceMetadata `json:"device,omitempty"`
-	Command            string          `json:"command,omitempty"`
-	Timestamp          time.Time       `json:"timestamp"`
+	Component          string               `json:"component"`
+	Activity           string               `json:"activity"`
+	Package            string               `json:"package"`
+	BenchmarkComponent string               `json:"benchmarkComponent,omitempty"`
+	BuildType          string               `json:"buildType,omitempty"`
+	Variant            string               `json:"variant,omitempty"`
+	FirstFrameMs       float64              `json:"firstFrameMs,omitempty"`
+	TotalTimeMs        float64              `json:"totalTimeMs,omitempty"`
+	WaitTimeMs         float64              `json:"waitTimeMs,omitempty"`
+	FullyDrawnMs       float64              `json:"fullyDrawnMs,omitempty"`
+	NavigationMs       float64              `json:"navigationMs,omitempty"`
+	MemoryMB           float64              `json:"memoryMb,omitempty"`
+	CPUPercent         float64              `json:"cpuPercent,omitempty"`
+	CPUTimeMs          float64              `json:"cpuTimeMs,omitempty"`
+	PowerRails         []PowerRail          `json:"powerRails,omitempty"`
+	NetworkRxBytes     int64                `json:"networkRxBytes,omitempty"`
+	NetworkTxBytes     int64                `json:"networkTxBytes,omitempty"`
+	Threads            *ThreadMetrics       `json:"threads,omitempty"`
+	StartupScheduling  *StartupScheduling   `json:"startupScheduling,omitempty"`
+	Binder             *BinderMetrics       `json:"binder,omitempty"`
+	Jank               *JankBreakdown       `json:"jank,omitempty"`
+	SurfaceLatency     *SurfaceLatency      `json:"surfaceLatency,omitempty"`
+	StrictMode         map[string]int       `json:"strictModeViolations,omitempty"`
+	MainThreadNetwork  *MainThreadNetwork   `json:"mainThreadNetwork,omitempty"`
+	Custom             map[string]float64   `json:"custom,omitempty"`
+	FrameDurationsMs   []float64            `json:"frameDurationsMs,omitempty"`
+	FramePipeline      *FramePipeline       `json:"framePipeline,omitempty"`
+	ThemeSwitch        *ThemeSwitchMetrics  `json:"themeSwitch,omitempty"`
+	Monkey             *MonkeyMetrics       `json:"monkey,omitempty"`
+	InputLatency       *InputLatencyMetrics `json:"inputLatency,omitempty"`
+	Animation          *AnimationMetrics    `json:"animation,omitempty"`
+	LaunchState        string               `json:"launchState,omitempty"`
+	Artifacts          []Artifact           `json:"artifacts,omitempty"`
+	Iterations         int                  `json:"iterations,omitempty"`
+	CIWidthPct         float64              `json:"ciWidthPct,omitempty"`
+	Device             *DeviceMetadata      `json:"device,omitempty"`
+	Command            string               `json:"command,omitempty"`
+	Timestamp          time.Time            `json:"timestamp"`
+
+	// IterationValues holds each iteration's value of the headline metrics in run order, when
+	// more than one iteration ran.
+	IterationValues map[string][]float64 `json:"iterationValues,omitempty"`
+	// Spread holds the statistics chosen with --percentiles of each metric in IterationValues.
+	Spread map[string][]Statistic `json:"spread,omitempty"`
+	// Derived holds the config's derived metrics computed from the fields above.
+	Derived map[string]float64 `json:"derived,omitempty"`
+	// HungIterations counts the iterations whose launch hung past --launch-timeout and was killed;
+	// they are left out of the metrics above.
+	HungIterations int `json:"hungIterations,omitempty"`
+	// BackgroundProcesses are the processes using the most CPU when the iteration started, to explain
+	// outliers caused by syncs or indexing. Multi-iteration results keep them per iteration only.
+	BackgroundProcesses []ProcessCPU `json:"backgroundProcesses,omitempty"`
+	// Flow holds each transition of a --flow run, in order.
+	Flow []FlowTransition `json:"flow,omitempty"`
+}
+
+// FlowTimeMs sums the transitions of a navigation flow.
+func (m *AndroidMetrics) FlowTimeMs() float64 {
+	total := 0.0
+	for _, t := range m.Flow {
+		total += t.TotalTimeMs
+	}
+	return total
+}
+
+// Build types recorded in reports. Debug builds produce misleading numbers and are only measured
+// when explicitly allowed.
+const (
+	BuildTypeDebug   = "debug"
+	BuildTypeRelease = "release"
+)
+
+// Build cache states recorded for --install. A clean build ran every task, a cached one reused the
+// outputs of all of them, and a partial one some.
+const (
+	BuildCacheClean   = "clean"
+	BuildCachePartial = "partial"
+	BuildCacheCached  = "cached"
+)
+
+// InstallRecord describes the build and install step that ran before the benchmark. Install time
+// and even the installed app's behavior differ between clean and cached builds.
+type InstallRecord struct {
+	// Tool is gradle or xcodebuild.
+	Tool       string  `json:"tool"`
+	Task       string  `json:"task"`
+	DurationMs float64 `json:"durationMs"`
+	// Cache is one of the BuildCache states, empty when the build output did not tell.
+	Cache          string `json:"cache,omitempty"`
+	TasksExecuted  int    `json:"tasksExecuted,omitempty"`
+	TasksFromCache int    `json:"tasksFromCache,omitempty"`
+	TasksUpToDate  int    `json:"tasksUpToDate,omitempty"`
+	// ConfigurationCache is reused or stored when Gradle's configuration cache is enabled.
+	ConfigurationCache string `json:"configurationCache,omitempty"`
+}
+
+// String summarises the install on one line, e.g. "gradle :app:installRelease 42.1s, partial
+// (3 executed, 10 from cache, 30 up-to-date), configuration cache reused".
+func (r *InstallRecord) String() string {
+	out := fmt.Sprintf("%s %s %.1fs", r.Tool, r.Task, r.DurationMs/1000)
+	if r.Cache != "" {
+		out += ", " + r.Cache
+	}
+	if total := r.TasksExecuted + r.TasksFromCache + r.TasksUpToDate; total > 0 {
+		out += fmt.Sprintf(" (%d executed, %d from cache, %d up-to-date)", r.TasksExecuted, r.TasksFromCache, r.TasksUpToDate)
+	}
+	if r.ConfigurationCache != "" {
+		out += ", configuration cache " + r.ConfigurationCache
+	}
+	return out
 }
 
 // IOSMetrics represents render/startup measurements captured from an iOS simulator/device.
 type IOSMetrics struct {
-	Component          string          `json:"component"`
-	BundleID           string          `json:"bundleId"`
-	LaunchArgs         []string        `json:"launchArgs,omitempty"`
-	BenchmarkComponent string          `json:"benchmarkComponent,omitempty"`
-	RenderTimeMs       float64         `json:"renderTimeMs,omitempty"`
-	MemoryMB           float64         `json:"memoryMb,omitempty"`
-	CPUPercent         float64         `json:"cpuPercent,omitempty"`
-	CPUTimeMs          float64         `json:"cpuTimeMs,omitempty"`
-	Device             *DeviceMetadata `json:"device,omitempty"`
-	Command            string          `json:"command,omitempty"`
-	Timestamp          time.Time       `json:"timestamp"`
+	Component          string             `json:"component"`
+	BundleID           string             `json:"bundleId"`
+	LaunchArgs         []string           `json:"launchArgs,omitempty"`
+	BenchmarkComponent string             `json:"benchmarkComponent,omitempty"`
+	BuildType          string             `json:"buildType,omitempty"`
+	RenderTimeMs       float64            `json:"renderTimeMs,omitempty"`
+	LaunchCommandMs    float64            `json:"launchCommandMs,omitempty"`
+	LaunchState        string             `json:"launchState,omitempty"`
+	MemoryMB           float64            `json:"memoryMb,omitempty"`
+	CPUPercent         float64            `json:"cpuPercent,omitempty"`
+	CPUTimeMs          float64            `json:"cpuTimeMs,omitempty"`
+	Threads            *ThreadMetrics     `json:"threads,omitempty"`
+	HangCount          int                `json:"hangCount,omitempty"`
+	LongestHangMs      float64            `json:"longestHangMs,omitempty"`
+	Custom             map[string]float64 `json:"custom,omitempty"`
+	Artifacts          []Artifact         `json:"artifacts,omitempty"`
+	Iterations         int                `json:"iterations,omitempty"`
+	CIWidthPct         float64            `json:"ciWidthPct,omitempty"`
+	Device             *DeviceMetadata    `json:"device,omitempty"`
+	Command            string             `json:"command,omitempty"`
+	Timestamp          time.Time          `json:"timestamp"`
+
+	// IterationValues holds each iteration's value of the headline metrics in run order, when
+	// more than one iteration ran.
+	IterationValues map[string][]float64 `json:"iterationValues,omitempty"`
+	// Spread holds the statistics chosen with --percentiles of each metric in IterationValues.
+	Spread map[string][]Statistic `json:"spread,omitempty"`
+	// Derived holds the config's derived metrics computed from the fields above.
+	Derived map[string]float64 `json:"derived,omitempty"`
+	// HungIterations counts the iterations whose launch hung past --launch-timeout and was killed;
+	// they are left out of the metrics above.
+	HungIterations int `json:"hungIterations,omitempty"`
+	// BackgroundProcesses are the processes using the most CPU when the iteration started, to explain
+	// outliers caused by syncs or indexing. Multi-iteration results keep them per iteration only.
+	BackgroundProcesses []ProcessCPU `json:"backgroundProcesses,omitempty"`
+}
+
+// ProcessCPU is a process's CPU use in a background-noise snapshot, in percent of one core.
+type ProcessCPU struct {
+	PID        int     `json:"pid,omitempty"`
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpuPercent"`
+}
+
+// PostureMetrics holds Android metrics measured with a foldable held in one posture.
+type PostureMetrics struct {
+	Posture string          `json:"posture"`
+	Android *AndroidMetrics `json:"android"`
+}
+
+// LayoutMetrics holds iOS metrics measured in one iPad multitasking layout.
+type LayoutMetrics struct {
+	Layout string      `json:"layout"`
+	IOS    *IOSMetrics `json:"ios"`
+}
+
+// OSMetrics holds the metrics measured on one OS version of an --os-matrix run, e.g. "API 34" or
+// "iOS 17.5".
+type OSMetrics struct {
+	OS      string          `json:"os"`
+	Android *AndroidMetrics `json:"android,omitempty"`
+	IOS     *IOSMetrics     `json:"ios,omitempty"`
+}
+
+// IterationRecord holds the raw metrics of one iteration before they were aggregated. Posture,
+// Layout or OS names the configuration it belongs to, if any.
+type IterationRecord struct {
+	Iteration int             `json:"iteration"`
+	Posture   string          `json:"posture,omitempty"`
+	Layout    string          `json:"layout,omitempty"`
+	Android   *AndroidMetrics `json:"android,omitempty"`
+	IOS       *IOSMetrics     `json:"ios,omitempty"`
+	// Hung marks an iteration whose launch hung past --launch-timeout; it has no metrics.
+	Hung bool   `json:"hung,omitempty"`
+	OS   string `json:"os,omitempty"`
 }
 
 // Result aggregates metrics for a single component across supported platforms.
 type Result struct {
-	Component  string          `json:"component"`
-	Android    *AndroidMetrics `json:"android,omitempty"`
-	IOS        *IOSMetrics     `json:"ios,omitempty"`
-	CLICommand string          `json:"cliCommand,omitempty"`
+	Component  string           `json:"component"`
+	RunID      string           `json:"runId,omitempty"`
+	Android    *AndroidMetrics  `json:"android,omitempty"`
+	IOS        *IOSMetrics      `json:"ios,omitempty"`
+	Postures   []PostureMetrics `json:"postures,omitempty"`
+	Layouts    []LayoutMetrics  `json:"layouts,omitempty"`
+	CLICommand string           `json:"cliCommand,omitempty"`
+	// Environment holds the allowlisted environment variables set for the run, e.g. the CI job's
+	// commit and designbench's own DESIGNBENCH_* settings.
+	Environment map[string]string `json:"environment,omitempty"`
+	// Install describes the build and install that ran before the benchmark, with --install.
+	Install *InstallRecord `json:"install,omitempty"`
+	// OSVersions holds the metrics per OS version of an --os-matrix run, in the order given.
+	OSVersions []OSMetrics `json:"osVersions,omitempty"`
+
+	// Iterations holds every iteration's raw metrics, in run order, when more than one iteration
+	// ran, so that nothing measured is lost to aggregation.
+	Iterations []IterationRecord `json:"iterations,omitempty"`
+}
+
+// primaryMetrics returns the measurement PrimaryMetric reads.
+func primaryMetrics(res Result) (*AndroidMetrics, *IOSMetrics) {
+	android, ios := res.Android, res.IOS
+	if android == nil && len(res.Postures) > 0 {
+		android = res.Postures[0].Android
+	}
+	if ios == nil && len(res.Layouts) > 0 {
+		ios = res.Layouts[0].IOS
+	}
+	if android == nil && ios == nil && len(res.OSVersions) > 0 {
+		android, ios = res.OSVersions[0].Android, res.OSVersions[0].IOS
+	}
+	return android, ios
+}
+
+// PrimaryDevice returns the device PrimaryMetric was measured on, nil when it is unknown.
+func PrimaryDevice(res Result) *DeviceMetadata {
+	switch android, ios := primaryMetrics(res); {
+	case android != nil:
+		return android.Device
+	case ios != nil:
+		return ios.Device
+	}
+	return nil
+}
+
+// PrimaryMetric returns the headline number of a result, where lower is better: the scenario's own
+// measurement when one ran, else launch time (Android) or render time (iOS). Posture, layout and
+// OS matrix runs use their first configuration. ok is false when the result has no metrics.
+func PrimaryMetric(res Result) (name string, value float64, ok bool) {
+	android, ios := primaryMetrics(res)
+	switch {
+	case android != nil && android.ThemeSwitch != nil:
+		return "themeSwitchMeanMs", android.ThemeSwitch.MeanMs(), true
+	case android != nil && android.InputLatency != nil:
+		return "inputLatencyP50Ms", android.InputLatency.P50Ms, true
+	case android != nil && android.Animation != nil:
+		return "animationDroppedFrames", float64(android.Animation.DroppedFrames), true
+	case android != nil && android.Monkey != nil:
+		return "monkeyPeakMemoryMb", android.Monkey.PeakMemoryMB, true
+	case android != nil && len(android.Flow) > 0:
+		return "flowTimeMs", android.FlowTimeMs(), true
+	case android != nil:
+		return "totalTimeMs", android.TotalTimeMs, true
+	case ios != nil:
+		return "renderTimeMs", ios.RenderTimeMs, true
+	}
+	return "", 0, false
+}
+
+// AggregateAndroid folds repeated runs into a single metrics value holding the median of each measurement.
+// Jank frame counts and missed vsyncs are summed; per-frame durations and artifacts are concatenated across runs.
+func AggregateAndroid(runs []*AndroidMetrics) *AndroidMetrics {
+	if len(runs) == 0 {
+		return nil
+	}
+	agg := *runs[len(runs)-1]
+	agg.FirstFrameMs = medianOf(runs, func(m *AndroidMetrics) float64 { return m.FirstFrameMs })
+	agg.TotalTimeMs = medianOf(runs, func(m *AndroidMetrics) float64 { return m.TotalTimeMs })
+	agg.WaitTimeMs = medianOf(runs, func(m *AndroidMetrics) float64 { return m.WaitTimeMs })
+	agg.FullyDrawnMs = medianOf(runs, func(m *AndroidMetrics) float64 { return m.FullyDrawnMs })
+	agg.NavigationMs = medianOf(runs, func(m *AndroidMetrics) float64 { return m.NavigationMs })
+	agg.MemoryMB = medianOf(runs, func(m *AndroidMetrics) float64 { return m.MemoryMB })
+	agg.CPUPercent = medianOf(runs, func(m *AndroidMetrics) float64 { return m.CPUPercent })
+	agg.CPUTimeMs = medianOf(runs, func(m *AndroidMetrics) float64 { return m.CPUTimeMs })
+	agg.PowerRails = aggregatePowerRails(runs)
+	agg.NetworkRxBytes = int64(medianOf(runs, func(m *AndroidMetrics) float64 { return float64(m.NetworkRxBytes) }))
+	agg.NetworkTxBytes = int64(medianOf(runs, func(m *AndroidMetrics) float64 { return float64(m.NetworkTxBytes) }))
+	agg.Threads = aggregateThreads(runs, func(m *AndroidMetrics) *ThreadMetrics { return m.Threads })
+	agg.StartupScheduling = aggregateStartupScheduling(runs)
+	agg.Binder = aggregateBinder(runs)
+	agg.Jank = aggregateJank(runs)
+	agg.SurfaceLatency = aggregateSurfaceLatency(runs)
+	agg.FramePipeline = aggregateFramePipeline(runs)
+	agg.StrictMode = aggregateStrictMode(runs)
+	agg.MainThreadNetwork = aggregateMainThreadNetwork(runs)
+	agg.Custom = aggregateCustom(runs, func(m *AndroidMetrics) map[string]float64 { return m.Custom })
+	agg.ThemeSwitch = aggregateThemeSwitch(runs)
+	agg.Monkey = aggregateMonkey(runs)
+	agg.InputLatency = aggregateInputLatency(runs)
+	agg.Animation = aggregateAnimation(runs)
+	agg.Flow = aggregateFlow(runs)
+	agg.FrameDurationsMs = nil
+	agg.Artifacts = nil
+	for _, run := range runs {
+		agg.FrameDurationsMs = append(agg.FrameDurationsMs, run.FrameDurationsMs...)
+		agg.Artifacts = append(agg.Artifacts, run.Artifacts...)
+	}
+	if len(runs) > 1 {
+		agg.BackgroundProcesses = nil
+	}
+	agg.Iterations = len(runs)
+	agg.IterationValues = collectIterations(runs, androidIterationMetrics)
+	return &agg
+}
+
+// AggregateIOS folds repeated runs into a single metrics value holding the median of each measurement.
+// Hangs are summed across runs, keeping the longest one observed; artifacts are concatenated.
+func AggregateIOS(runs []*IOSMetrics) *IOSMetrics {
+	if len(runs) == 0 {
+		return nil
+	}
+	agg := *runs[len(runs)-1]
+	agg.RenderTimeMs = medianOf(runs, func(m *IOSMetrics) float64 { return m.RenderTimeMs })
+	agg.LaunchCommandMs = medianOf(runs, func(m *IOSMetrics) float64 { return m.LaunchCommandMs })
+	agg.MemoryMB = medianOf(runs, func(m *IOSMetrics) float64 { return m.MemoryMB })
+	agg.CPUPercent = medianOf(runs, func(m *IOSMetrics) float64 { return m.CPUPercent })
+	agg.CPUTimeMs = medianOf(runs, func(m *IOSMetrics) float64 { return m.CPUTimeMs })
+	agg.Threads = aggregateThreads(runs, func(m *IOSMetrics) *ThreadMetrics { return m.Threads })
+	agg.Custom = aggregateCustom(runs, func(m *IOSMetrics) map[string]float64 { return m.Custom })
+	agg.HangCount = 0
+	agg.LongestHangMs = 0
+	agg.Artifacts = nil
+	for _, run := range runs {
+		agg.HangCount += run.HangCount
+		agg.LongestHangMs = max(agg.LongestHangMs, run.LongestHangMs)
+		agg.Artifacts = append(agg.Artifacts, run.Artifacts...)
+	}
+	if len(runs) > 1 {
+		agg.BackgroundProcesses = nil
+	}
+	agg.Iterations = len(runs)
+	agg.IterationValues = collectIterations(runs, iosIterationMetrics)
+	return &agg
+}
+
+func aggregatePowerRails(runs []*AndroidMetrics) []PowerRail {
+	last := runs[len(runs)-1].PowerRails
+	if len(runs) == 1 || len(last) == 0 {
+		return last
+	}
+	rails := make([]PowerRail, 0, len(last))
+	for _, rail := range last {
+		name := rail.Name
+		rail.EnergyUJ = medianOf(runs, func(m *AndroidMetrics) float64 {
+			for _, r := range m.PowerRails {
+				if r.Name == name {
+					return r.EnergyUJ
+				}
+			}
+			return 0
+		})
+		rails = append(rails, rail)
+	}
+	return rails
+}
+
+func aggregateThreads[T any](runs []T, threads func(T) *ThreadMetrics) *ThreadMetrics {
+	samples := make([]*ThreadMetrics, 0, len(runs))
+	for _, run := range runs {
+		if t := threads(run); t != nil {
+			samples = append(samples, t)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	return &ThreadMetrics{
+		Count:          int(medianOf(samples, func(t *ThreadMetrics) float64 { return float64(t.Count) })),
+		MainRunningMs:  medianOf(samples, func(t *ThreadMetrics) float64 { return t.MainRunningMs }),
+		MainRunnableMs: medianOf(samples, func(t *ThreadMetrics) float64 { return t.MainRunnableMs }),
+	}
+}
+
+func aggregateStartupScheduling(runs []*AndroidMetrics) *StartupScheduling {
+	samples := make([]*StartupScheduling, 0, len(runs))
+	for _, run := range runs {
+		if run.StartupScheduling != nil {
+			samples = append(samples, run.StartupScheduling)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	return &StartupScheduling{
+		WindowMs:   medianOf(samples, func(s *StartupScheduling) float64 { return s.WindowMs }),
+		RunningMs:  medianOf(samples, func(s *StartupScheduling) float64 { return s.RunningMs }),
+		RunnableMs: medianOf(samples, func(s *StartupScheduling) float64 { return s.RunnableMs }),
+	}
+}
+
+// aggregateBinder takes the median call count and latency and the longest call of any run. The
+// slowest calls are listed from the last run.
+func aggregateBinder(runs []*AndroidMetrics) *BinderMetrics {
+	samples := make([]*BinderMetrics, 0, len(runs))
+	for _, run := range runs {
+		if run.Binder != nil {
+			samples = append(samples, run.Binder)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	agg := &BinderMetrics{
+		Calls:     int64(medianOf(samples, func(b *BinderMetrics) float64 { return float64(b.Calls) })),
+		LatencyMs: medianOf(samples, func(b *BinderMetrics) float64 { return b.LatencyMs }),
+		Top:       samples[len(samples)-1].Top,
+	}
+	for _, sample := range samples {
+		agg.MaxLatencyMs = max(agg.MaxLatencyMs, sample.MaxLatencyMs)
+	}
+	return agg
+}
+
+// aggregateCustom takes the median of each app-reported metric across the runs that reported it.
+func aggregateCustom[T any](runs []T, custom func(T) map[string]float64) map[string]float64 {
+	var agg map[string]float64
+	for _, run := range runs {
+		for name := range custom(run) {
+			if agg == nil {
+				agg = make(map[string]float64)
+			}
+			if _, done := agg[name]; done {
+				continue
+			}
+			var values []float64
+			for _, other := range runs {
+				if value, ok := custom(other)[name]; ok {
+					values = append(values, value)
+				}
+			}
+			agg[name] = stats.Median(values)
+		}
+	}
+	return agg
+}
+
+// aggregateMainThreadNetwork sums the calls across runs; any run with a call marks the result.
+func aggregateMainThreadNetwork(runs []*AndroidMetrics) *MainThreadNetwork {
+	var total *MainThreadNetwork
+	for _, run := range runs {
+		if run.MainThreadNetwork == nil {
+			continue
+		}
+		if total == nil {
+			total = &MainThreadNetwork{}
+		}
+		total.Calls += run.MainThreadNetwork.Calls
+		total.Detected = total.Detected || run.MainThreadNetwork.Detected
+	}
+	return total
+}
+
+// aggregateStrictMode sums the violations of each policy across runs.
+func aggregateStrictMode(runs []*AndroidMetrics) map[string]int {
+	var agg map[string]int
+	for _, run := range runs {
+		if run.StrictMode == nil {
+			continue
+		}
+		if agg == nil {
+			agg = make(map[string]int)
+		}
+		for policy, count := range run.StrictMode {
+			agg[policy] += count
+		}
+	}
+	return agg
+}
+
+// aggregateMonkey sums event, stability and frame counts across runs, keeps the highest memory peak and
+// takes the median of the other measurements.
+func aggregateMonkey(runs []*AndroidMetrics) *MonkeyMetrics {
+	samples := make([]*MonkeyMetrics, 0, len(runs))
+	for _, run := range runs {
+		if run.Monkey != nil {
+			samples = append(samples, run.Monkey)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	agg := &MonkeyMetrics{
+		DurationMs:     medianOf(samples, func(m *MonkeyMetrics) float64 { return m.DurationMs }),
+		MeanCPUPercent: medianOf(samples, func(m *MonkeyMetrics) float64 { return m.MeanCPUPercent }),
+	}
+	for _, sample := range samples {
+		agg.Events += sample.Events
+		agg.EventsInjected += sample.EventsInjected
+		agg.Crashes += sample.Crashes
+		agg.ANRs += sample.ANRs
+		agg.Samples += sample.Samples
+		agg.TotalFrames += sample.TotalFrames
+		agg.JankyFrames += sample.JankyFrames
+		agg.PeakMemoryMB = max(agg.PeakMemoryMB, sample.PeakMemoryMB)
+	}
+	return agg
+}
+
+// aggregateInputLatency pools the tap samples of every run so the percentiles cover all taps.
+func aggregateInputLatency(runs []*AndroidMetrics) *InputLatencyMetrics {
+	var agg *InputLatencyMetrics
+	for _, run := range runs {
+		if run.InputLatency == nil {
+			continue
+		}
+		if agg == nil {
+			agg = &InputLatencyMetrics{}
+		}
+		agg.Taps += run.InputLatency.Taps
+		agg.Missed += run.InputLatency.Missed
+		agg.NotPresented += run.InputLatency.NotPresented
+		agg.SamplesMs = append(agg.SamplesMs, run.InputLatency.SamplesMs...)
+	}
+	if agg == nil {
+		return nil
+	}
+	agg.P50Ms = stats.Percentile(agg.SamplesMs, 50)
+	agg.P95Ms = stats.Percentile(agg.SamplesMs, 95)
+	return agg
+}
+
+func aggregateAnimation(runs []*AndroidMetrics) *AnimationMetrics {
+	samples := make([]*AnimationMetrics, 0, len(runs))
+	for _, run := range runs {
+		if run.Animation != nil {
+			samples = append(samples, run.Animation)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	return &AnimationMetrics{
+		Name:           samples[0].Name,
+		DurationMs:     samples[0].DurationMs,
+		ExpectedFrames: samples[0].ExpectedFrames,
+		RenderedFrames: int(medianOf(samples, func(a *AnimationMetrics) float64 { return float64(a.RenderedFrames) })),
+		DroppedFrames:  int(medianOf(samples, func(a *AnimationMetrics) float64 { return float64(a.DroppedFrames) })),
+		LongestPauseMs: medianOf(samples, func(a *AnimationMetrics) float64 { return a.LongestPauseMs }),
+	}
+}
+
+// aggregateFlow takes the median of each transition across the runs that completed the flow.
+func aggregateFlow(runs []*AndroidMetrics) []FlowTransition {
+	samples := make([]*AndroidMetrics, 0, len(runs))
+	for _, run := range runs {
+		if len(run.Flow) > 0 {
+			samples = append(samples, run)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	flow := make([]FlowTransition, len(samples[0].Flow))
+	for i, t := range samples[0].Flow {
+		flow[i] = FlowTransition{
+			Name:        t.Name,
+			TotalTimeMs: medianOf(samples, func(m *AndroidMetrics) float64 { return m.Flow[i].TotalTimeMs }),
+			WaitTimeMs:  medianOf(samples, func(m *AndroidMetrics) float64 { return m.Flow[i].WaitTimeMs }),
+		}
+	}
+	return flow
+}
+
+func aggregateThemeSwitch(runs []*AndroidMetrics) *ThemeSwitchMetrics {
+	samples := make([]*ThemeSwitchMetrics, 0, len(runs))
+	for _, run := range runs {
+		if run.ThemeSwitch != nil {
+			samples = append(samples, run.ThemeSwitch)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	return &ThemeSwitchMetrics{
+		ToDarkMs:  medianOf(samples, func(t *ThemeSwitchMetrics) float64 { return t.ToDarkMs }),
+		ToLightMs: medianOf(samples, func(t *ThemeSwitchMetrics) float64 { return t.ToLightMs }),
+		Frames:    int(medianOf(samples, func(t *ThemeSwitchMetrics) float64 { return float64(t.Frames) })),
+	}
 }
 
-// SaveJSON writes the aggregated result to the provided file path.
+func aggregateJank(runs []*AndroidMetrics) *JankBreakdown {
+	var total *JankBreakdown
+	for _, run := range runs {
+		if run.Jank == nil {
+			continue
+		}
+		if total == nil {
+			total = &JankBreakdown{}
+		}
+		total.TotalFrames += run.Jank.TotalFrames
+		total.JankyFrames += run.Jank.JankyFrames
+		for cause, count := range run.Jank.ByType {
+			if total.ByType == nil {
+				total.ByType = make(map[string]int)
+			}
+			total.ByType[cause] += count
+		}
+	}
+	return total
+}
+
+func aggregateFramePipeline(runs []*AndroidMetrics) *FramePipeline {
+	samples := make([]*FramePipeline, 0, len(runs))
+	for _, run := range runs {
+		if run.FramePipeline != nil {
+			samples = append(samples, run.FramePipeline)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	agg := &FramePipeline{
+		UIThreadMs:      medianOf(samples, func(p *FramePipeline) float64 { return p.UIThreadMs }),
+		MeasureLayoutMs: medianOf(samples, func(p *FramePipeline) float64 { return p.MeasureLayoutMs }),
+		DrawMs:          medianOf(samples, func(p *FramePipeline) float64 { return p.DrawMs }),
+		RenderThreadMs:  medianOf(samples, func(p *FramePipeline) float64 { return p.RenderThreadMs }),
+		SyncMs:          medianOf(samples, func(p *FramePipeline) float64 { return p.SyncMs }),
+		GPUMs:           medianOf(samples, func(p *FramePipeline) float64 { return p.GPUMs }),
+	}
+	for _, sample := range samples {
+		agg.Frames += sample.Frames
+	}
+	return agg
+}
+
+func aggregateSurfaceLatency(runs []*AndroidMetrics) *SurfaceLatency {
+	samples := make([]*SurfaceLatency, 0, len(runs))
+	for _, run := range runs {
+		if run.SurfaceLatency != nil {
+			samples = append(samples, run.SurfaceLatency)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	total := *samples[len(samples)-1]
+	total.Frames, total.MissedVsyncs = 0, 0
+	for _, sample := range samples {
+		total.Frames += sample.Frames
+		total.MissedVsyncs += sample.MissedVsyncs
+	}
+	total.RefreshPeriodMs = medianOf(samples, func(s *SurfaceLatency) float64 { return s.RefreshPeriodMs })
+	total.PresentJitterMs = medianOf(samples, func(s *SurfaceLatency) float64 { return s.PresentJitterMs })
+	return &total
+}
+
+// TotalEnergyUJ sums the energy reported across all power rails.
+func (m *AndroidMetrics) TotalEnergyUJ() float64 {
+	var total float64
+	for _, rail := range m.PowerRails {
+		total += rail.EnergyUJ
+	}
+	return total
+}
+
+// medianOf ignores zero samples so that a metric missing from some runs does not drag the median down.
+func medianOf[T any](runs []T, field func(T) float64) float64 {
+	values := make([]float64, 0, len(runs))
+	for _, run := range runs {
+		if v := field(run); v != 0 {
+			values = append(values, v)
+		}
+	}
+	return stats.Median(values)
+}
+
+// SaveJSON writes the aggregated result to the provided file path. Artifact paths are stored
+// relative to the report's directory so a run directory can be moved or archived as a whole.
 func SaveJSON(path string, result Result) error {
 	dir := filepath.Dir(path)
 	if dir != "." && dir != "" {
@@ -66,13 +895,41 @@ func SaveJSON(path string, result Result) error {
 			return fmt.Errorf("create report directory: %w", err)
 		}
 	}
+	result = relativeArtifacts(result, dir)
 	f, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("create report file: %w", err)
 	}
 	defer f.Close()
+	return WriteJSON(f, result)
+}
+
+// AppendJSONL appends result as a single JSON line to path, creating the file and its directory if
+// needed, so that many runs can be collected in one file.
+func AppendJSONL(path string, result Result) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create report directory: %w", err)
+	}
+	line, err := json.Marshal(relativeArtifacts(result, dir))
+	if err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open report file: %w", err)
+	}
+	// One write per line keeps concurrent appenders from interleaving.
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("append report: %w", err)
+	}
+	return f.Close()
+}
 
-	enc := json.NewEncoder(f)
+// WriteJSON writes result as indented JSON to w.
+func WriteJSON(w io.Writer, result Result) error {
+	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
 	if err := enc.Encode(result); err != nil {
 		return fmt.Errorf("encode report: %w", err)
@@ -82,56 +939,507 @@ func SaveJSON(path string, result Result) error {
 
 // FormatSummary returns a concise, human-readable summary for terminal output.
 func FormatSummary(res Result) string {
+	return FormatBudgetSummary(res, nil, false)
+}
+
+// FormatBudgetSummary is FormatSummary with each metric checked against its budget. Metrics over
+// budget get an extra line; with color, values are green within 90% of their budget, yellow up to
+// it and red beyond.
+func FormatBudgetSummary(res Result, budgets Budgets, color bool) string {
+	style := summaryStyle{budgets: budgets, color: color}
 	out := fmt.Sprintf("Component: %s\n", res.Component)
+	if res.Install != nil {
+		out += fmt.Sprintf("  install: %s\n", res.Install)
+	}
 	if res.Android != nil {
-		model := "-"
-		if res.Android.Device != nil && res.Android.Device.Model != "" {
-			model = res.Android.Device.Model
-		}
-		mem := "-"
-		if res.Android.MemoryMB > 0 {
-			mem = fmt.Sprintf("%.1fMB", res.Android.MemoryMB)
-		}
-		cpu := "-"
-		if res.Android.CPUPercent > 0 {
-			cpu = fmt.Sprintf("%.1f%%", res.Android.CPUPercent)
-		}
-		cpuTime := "-"
-		if res.Android.CPUTimeMs > 0 {
-			cpuTime = fmt.Sprintf("%.0fms", res.Android.CPUTimeMs)
-		}
-		out += fmt.Sprintf("  Android[%s]: total=%.1fms firstFrame=%.1fms wait=%.1fms memory=%s cpu=%s cpuTime=%s\n",
-			model,
-			res.Android.TotalTimeMs,
-			res.Android.FirstFrameMs,
-			res.Android.WaitTimeMs,
-			mem,
-			cpu,
-			cpuTime)
+		out += formatAndroid("Android", res.Android, style)
+	}
+	for _, posture := range res.Postures {
+		if posture.Android != nil {
+			out += formatAndroid(fmt.Sprintf("Android/%s", posture.Posture), posture.Android, style)
+		}
 	}
 	if res.IOS != nil {
-		model := "-"
-		if res.IOS.Device != nil && res.IOS.Device.Model != "" {
-			model = res.IOS.Device.Model
+		out += formatIOS("iOS", res.IOS, style)
+	}
+	for _, layout := range res.Layouts {
+		if layout.IOS != nil {
+			out += formatIOS(fmt.Sprintf("iOS/%s", layout.Layout), layout.IOS, style)
+		}
+	}
+	for _, version := range res.OSVersions {
+		if version.Android != nil {
+			out += formatAndroid("Android/"+version.OS, version.Android, style)
+		}
+		if version.IOS != nil {
+			out += formatIOS("iOS/"+version.OS, version.IOS, style)
+		}
+	}
+	out += formatOSComparison(res.OSVersions)
+	return out
+}
+
+// formatOSComparison lines up the primary metric of each OS version of an --os-matrix run against
+// the first one.
+func formatOSComparison(versions []OSMetrics) string {
+	if len(versions) < 2 {
+		return ""
+	}
+	var (
+		out          string
+		metric       string
+		baseOS       string
+		first        float64
+		haveBaseline bool
+	)
+	for _, version := range versions {
+		name, value, ok := PrimaryMetric(Result{Android: version.Android, IOS: version.IOS})
+		switch {
+		case !ok:
+			continue
+		case !haveBaseline:
+			metric, baseOS, first, haveBaseline = name, version.OS, value, true
+			out += fmt.Sprintf("    %s: %.1f\n", version.OS, value)
+		case name == metric:
+			change := MetricDelta{Baseline: first, Candidate: value}.ChangePct()
+			out += fmt.Sprintf("    %s: %.1f (%+.1f%% vs %s)\n", version.OS, value, change, baseOS)
+		}
+	}
+	if !haveBaseline {
+		return ""
+	}
+	return fmt.Sprintf("  By OS version (%s):\n", metric) + out
+}
+
+func formatAndroid(label string, m *AndroidMetrics, style summaryStyle) string {
+	model := formatDevice(m.Device)
+	mem := "-"
+	if m.MemoryMB > 0 {
+		mem = fmt.Sprintf("%.1fMB", m.MemoryMB)
+	}
+	cpu := "-"
+	if m.CPUPercent > 0 {
+		cpu = fmt.Sprintf("%.1f%%", m.CPUPercent)
+	}
+	cpuTime := "-"
+	if m.CPUTimeMs > 0 {
+		cpuTime = fmt.Sprintf("%.0fms", m.CPUTimeMs)
+	}
+	out := fmt.Sprintf("  %s[%s]: total=%s firstFrame=%s wait=%s memory=%s cpu=%s cpuTime=%s\n",
+		label,
+		model,
+		style.paint("totalTimeMs", m.TotalTimeMs, fmt.Sprintf("%.1fms", m.TotalTimeMs)),
+		style.paint("firstFrameMs", m.FirstFrameMs, fmt.Sprintf("%.1fms", m.FirstFrameMs)),
+		style.paint("waitTimeMs", m.WaitTimeMs, fmt.Sprintf("%.1fms", m.WaitTimeMs)),
+		style.paint("memoryMb", m.MemoryMB, mem),
+		style.paint("cpuPercent", m.CPUPercent, cpu),
+		style.paint("cpuTimeMs", m.CPUTimeMs, cpuTime))
+	out += style.overBudget(map[string]float64{
+		"totalTimeMs":  m.TotalTimeMs,
+		"firstFrameMs": m.FirstFrameMs,
+		"waitTimeMs":   m.WaitTimeMs,
+		"fullyDrawnMs": m.FullyDrawnMs,
+		"memoryMb":     m.MemoryMB,
+		"cpuPercent":   m.CPUPercent,
+		"cpuTimeMs":    m.CPUTimeMs,
+	})
+	out += formatBuildType(m.BuildType)
+	if m.Variant != "" {
+		out += "    variant=" + m.Variant + "\n"
+	}
+	if m.FullyDrawnMs > 0 {
+		out += fmt.Sprintf("    fullyDrawn=%s\n", style.paint("fullyDrawnMs", m.FullyDrawnMs, fmt.Sprintf("%.1fms", m.FullyDrawnMs)))
+	}
+	if m.NavigationMs > 0 {
+		out += fmt.Sprintf("    navigation=%.1fms\n", m.NavigationMs)
+	}
+	if len(m.PowerRails) > 0 {
+		out += fmt.Sprintf("    energy=%.1fmJ across %d rails\n", m.TotalEnergyUJ()/1000, len(m.PowerRails))
+	}
+	out += formatThreads(m.Threads)
+	if sched := m.StartupScheduling; sched != nil {
+		out += fmt.Sprintf("    startup mainThread: running=%.1fms runnable=%.1fms (%.0f%% of launch waiting for CPU)\n",
+			sched.RunningMs,
+			sched.RunnableMs,
+			sched.RunnablePct())
+	}
+	out += formatBinder(m.Binder)
+	out += formatJank(m.Jank)
+	if sl := m.SurfaceLatency; sl != nil {
+		out += fmt.Sprintf("    surfaceFlinger: frames=%d missedVsyncs=%d presentJitter=%.1fms (refresh %.1fms)\n",
+			sl.Frames,
+			sl.MissedVsyncs,
+			sl.PresentJitterMs,
+			sl.RefreshPeriodMs)
+	}
+	out += formatStrictMode(m.StrictMode)
+	if n := m.MainThreadNetwork; n != nil && n.Detected {
+		out += fmt.Sprintf("    WARNING: %d network call(s) on the main thread before the first frame\n", n.Calls)
+	}
+	out += formatNamed("custom", m.Custom)
+	out += formatNamed("derived", m.Derived)
+	if frames := m.FrameDurationsMs; len(frames) > 0 {
+		out += fmt.Sprintf("    frames=%d p50=%.1fms p90=%.1fms p99=%.1fms\n",
+			len(frames),
+			stats.Percentile(frames, 50),
+			stats.Percentile(frames, 90),
+			stats.Percentile(frames, 99))
+	}
+	if p := m.FramePipeline; p != nil {
+		out += fmt.Sprintf("    uiThread=%.1fms (measure/layout=%.1fms draw=%.1fms) renderThread=%.1fms (sync=%.1fms gpu=%.1fms) per frame\n",
+			p.UIThreadMs,
+			p.MeasureLayoutMs,
+			p.DrawMs,
+			p.RenderThreadMs,
+			p.SyncMs,
+			p.GPUMs)
+	}
+	out += formatFlow(m.Flow)
+	if m.ThemeSwitch != nil {
+		out += fmt.Sprintf("    themeSwitch: toDark=%.1fms toLight=%.1fms frames=%d\n",
+			m.ThemeSwitch.ToDarkMs,
+			m.ThemeSwitch.ToLightMs,
+			m.ThemeSwitch.Frames)
+	}
+	if a := m.Animation; a != nil {
+		out += fmt.Sprintf("    animation %s: frames=%d/%d dropped=%d longestPause=%.1fms\n",
+			a.Name,
+			a.RenderedFrames,
+			a.ExpectedFrames,
+			a.DroppedFrames,
+			a.LongestPauseMs)
+	}
+	if il := m.InputLatency; il != nil {
+		out += fmt.Sprintf("    inputLatency: p50=%.1fms p95=%.1fms taps=%d missed=%d\n",
+			il.P50Ms,
+			il.P95Ms,
+			il.Taps,
+			il.Missed)
+		if il.NotPresented > 0 {
+			out += fmt.Sprintf("    inputLatency: %d samples end at frame completion; the device reported no present time\n", il.NotPresented)
 		}
-		mem := "-"
-		if res.IOS.MemoryMB > 0 {
-			mem = fmt.Sprintf("%.1fMB", res.IOS.MemoryMB)
+	}
+	if mk := m.Monkey; mk != nil {
+		out += fmt.Sprintf("    monkey: events=%d/%d crashes=%d anrs=%d peakMemory=%.1fMB cpu=%.1f%%\n",
+			mk.EventsInjected,
+			mk.Events,
+			mk.Crashes,
+			mk.ANRs,
+			mk.PeakMemoryMB,
+			mk.MeanCPUPercent)
+		if mk.TotalFrames > 0 {
+			out += fmt.Sprintf("    monkey: jank=%d/%d frames (%.1f%%)\n", mk.JankyFrames, mk.TotalFrames, float64(mk.JankyFrames)/float64(mk.TotalFrames)*100)
 		}
-		cpu := "-"
-		if res.IOS.CPUPercent > 0 {
-			cpu = fmt.Sprintf("%.1f%%", res.IOS.CPUPercent)
+	}
+	if m.NetworkRxBytes > 0 || m.NetworkTxBytes > 0 {
+		out += fmt.Sprintf("    network: rx=%s tx=%s (component fetched data during launch)\n",
+			formatBytes(m.NetworkRxBytes),
+			formatBytes(m.NetworkTxBytes))
+	}
+	out += formatIterations(m.Iterations, m.CIWidthPct)
+	out += formatHung(m.HungIterations)
+	out += formatBackground(m.BackgroundProcesses)
+	out += formatSpread(m.IterationValues, m.Spread, androidIterationMetrics)
+	out += formatArtifacts(m.Artifacts)
+	return out
+}
+
+func formatBuildType(buildType string) string {
+	if buildType != BuildTypeDebug {
+		return ""
+	}
+	return "    WARNING: debug build; timings are not representative of release performance\n"
+}
+
+func formatIOS(label string, m *IOSMetrics, style summaryStyle) string {
+	model := formatDevice(m.Device)
+	mem := "-"
+	if m.MemoryMB > 0 {
+		mem = fmt.Sprintf("%.1fMB", m.MemoryMB)
+	}
+	cpu := "-"
+	if m.CPUPercent > 0 {
+		cpu = fmt.Sprintf("%.1f%%", m.CPUPercent)
+	}
+	cpuTime := "-"
+	if m.CPUTimeMs > 0 {
+		cpuTime = fmt.Sprintf("%.0fms", m.CPUTimeMs)
+	}
+	out := fmt.Sprintf("  %s[%s]: render=%s memory=%s cpu=%s cpuTime=%s\n",
+		label,
+		model,
+		style.paint("renderTimeMs", m.RenderTimeMs, fmt.Sprintf("%.1fms", m.RenderTimeMs)),
+		style.paint("memoryMb", m.MemoryMB, mem),
+		style.paint("cpuPercent", m.CPUPercent, cpu),
+		style.paint("cpuTimeMs", m.CPUTimeMs, cpuTime))
+	out += style.overBudget(map[string]float64{
+		"renderTimeMs": m.RenderTimeMs,
+		"memoryMb":     m.MemoryMB,
+		"cpuPercent":   m.CPUPercent,
+		"cpuTimeMs":    m.CPUTimeMs,
+	})
+	out += formatBuildType(m.BuildType)
+	if m.LaunchCommandMs > 0 {
+		out += fmt.Sprintf("    launchCommand=%.1fms", m.LaunchCommandMs)
+		if m.LaunchState != "" {
+			out += " launchState=" + m.LaunchState
 		}
-		cpuTime := "-"
-		if res.IOS.CPUTimeMs > 0 {
-			cpuTime = fmt.Sprintf("%.0fms", res.IOS.CPUTimeMs)
+		out += "\n"
+	}
+	out += formatThreads(m.Threads)
+	if m.HangCount > 0 {
+		out += fmt.Sprintf("    hangs=%d longest=%.0fms\n", m.HangCount, m.LongestHangMs)
+	}
+	out += formatNamed("custom", m.Custom)
+	out += formatNamed("derived", m.Derived)
+	out += formatIterations(m.Iterations, m.CIWidthPct)
+	out += formatHung(m.HungIterations)
+	out += formatBackground(m.BackgroundProcesses)
+	out += formatSpread(m.IterationValues, m.Spread, iosIterationMetrics)
+	out += formatArtifacts(m.Artifacts)
+	return out
+}
+
+func formatDevice(device *DeviceMetadata) string {
+	if device == nil {
+		return "-"
+	}
+	model := device.Model
+	if model == "" {
+		model = "-"
+	}
+	if device.RefreshRateHz > 0 {
+		model += fmt.Sprintf(", %.0fHz", device.RefreshRateHz)
+	}
+	if device.Renderer != "" {
+		model += ", " + device.Renderer
+	}
+	if device.Chip != "" {
+		model += ", " + device.Chip
+	}
+	if device.Tier != "" {
+		model += ", " + device.Tier + " tier"
+	}
+	if device.DisplayID != "" {
+		model += ", display " + device.DisplayID
+		if device.DisplayName != "" {
+			model += fmt.Sprintf(" %q", device.DisplayName)
 		}
-		out += fmt.Sprintf("  iOS[%s]: render=%.1fms memory=%s cpu=%s cpuTime=%s\n",
-			model,
-			res.IOS.RenderTimeMs,
-			mem,
-			cpu,
-			cpuTime)
+	}
+	switch {
+	case device.IsEmulator:
+		model += ", emulator"
+	case device.IsSimulator:
+		model += ", simulator"
+	}
+	return model
+}
+
+func formatThreads(threads *ThreadMetrics) string {
+	if threads == nil {
+		return ""
+	}
+	out := fmt.Sprintf("    threads=%d", threads.Count)
+	if total := threads.MainRunningMs + threads.MainRunnableMs; total > 0 {
+		out += fmt.Sprintf(" mainThread: running=%.1fms runnable=%.1fms (%.0f%% waiting for CPU)",
+			threads.MainRunningMs,
+			threads.MainRunnableMs,
+			threads.MainRunnableMs/total*100)
+	}
+	return out + "\n"
+}
+
+func formatBinder(binder *BinderMetrics) string {
+	if binder == nil {
+		return ""
+	}
+	out := fmt.Sprintf("    binder=%d calls latency=%.1fms", binder.Calls, binder.LatencyMs)
+	if binder.MaxLatencyMs > 0 {
+		out += fmt.Sprintf(" longest=%.1fms", binder.MaxLatencyMs)
+	}
+	out += "\n"
+	for _, call := range binder.Top {
+		out += fmt.Sprintf("      %s calls=%d latency=%.1fms\n", call.Name, call.Calls, call.LatencyMs)
 	}
 	return out
 }
+
+// formatNamed lists app-reported or derived metrics by name on a line starting with label.
+func formatNamed(label string, values map[string]float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := "    " + label + ":"
+	for _, name := range names {
+		out += fmt.Sprintf(" %s=%g", name, displayValue(values[name]))
+	}
+	return out + "\n"
+}
+
+func formatStrictMode(violations map[string]int) string {
+	if violations == nil {
+		return ""
+	}
+	if len(violations) == 0 {
+		return "    strictMode: no violations\n"
+	}
+	policies := make([]string, 0, len(violations))
+	for policy := range violations {
+		policies = append(policies, policy)
+	}
+	sort.Strings(policies)
+	out := "    strictMode:"
+	for _, policy := range policies {
+		out += fmt.Sprintf(" %s=%d", policy, violations[policy])
+	}
+	return out + "\n"
+}
+
+func formatJank(jank *JankBreakdown) string {
+	if jank == nil {
+		return ""
+	}
+	out := fmt.Sprintf("    jank=%d/%d frames (%.1f%%)", jank.JankyFrames, jank.TotalFrames, jank.JankPercent())
+	causes := make([]string, 0, len(jank.ByType))
+	for cause := range jank.ByType {
+		causes = append(causes, cause)
+	}
+	sort.Strings(causes)
+	for _, cause := range causes {
+		out += fmt.Sprintf(" %s=%d", cause, jank.ByType[cause])
+	}
+	return out + "\n"
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1fKB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
+
+func formatArtifacts(artifacts []Artifact) string {
+	out := ""
+	for _, artifact := range artifacts {
+		out += fmt.Sprintf("    %s: %s\n", artifact.Kind, artifact.Path)
+	}
+	return out
+}
+
+func formatIterations(iterations int, ciWidthPct float64) string {
+	if iterations <= 1 {
+		return ""
+	}
+	if ciWidthPct > 0 {
+		return fmt.Sprintf("    iterations=%d ci95=±%.1f%%\n", iterations, ciWidthPct)
+	}
+	return fmt.Sprintf("    iterations=%d\n", iterations)
+}
+
+func formatHung(hung int) string {
+	if hung == 0 {
+		return ""
+	}
+	return fmt.Sprintf("    hung=%d launches killed after --launch-timeout and left out\n", hung)
+}
+
+func formatFlow(flow []FlowTransition) string {
+	if len(flow) == 0 {
+		return ""
+	}
+	out := ""
+	total := 0.0
+	for _, t := range flow {
+		out += fmt.Sprintf(" %s=%.1fms", t.Name, t.TotalTimeMs)
+		total += t.TotalTimeMs
+	}
+	return fmt.Sprintf("    flow=%.1fms:%s\n", total, out)
+}
+
+func formatBackground(processes []ProcessCPU) string {
+	if len(processes) == 0 {
+		return ""
+	}
+	out := "    background:"
+	for _, p := range processes {
+		out += fmt.Sprintf(" %s=%.1f%%", p.Name, p.CPUPercent)
+	}
+	return out + "\n"
+}
+
+// relativeArtifacts returns a copy of result whose artifact paths are relative to base. The
+// caller's metrics are left untouched so the terminal summary keeps showing usable paths.
+func relativeArtifacts(result Result, base string) Result {
+	rebaseAll := func(artifacts []Artifact) []Artifact {
+		rebased := make([]Artifact, len(artifacts))
+		for i, artifact := range artifacts {
+			if rel, err := filepath.Rel(base, artifact.Path); err == nil {
+				artifact.Path = filepath.ToSlash(rel)
+			}
+			rebased[i] = artifact
+		}
+		return rebased
+	}
+	rebase := func(m *AndroidMetrics) *AndroidMetrics {
+		if m == nil || len(m.Artifacts) == 0 {
+			return m
+		}
+		copied := *m
+		copied.Artifacts = rebaseAll(m.Artifacts)
+		return &copied
+	}
+	rebaseIOS := func(m *IOSMetrics) *IOSMetrics {
+		if m == nil || len(m.Artifacts) == 0 {
+			return m
+		}
+		copied := *m
+		copied.Artifacts = rebaseAll(m.Artifacts)
+		return &copied
+	}
+	result.Android = rebase(result.Android)
+	if len(result.Postures) > 0 {
+		postures := make([]PostureMetrics, len(result.Postures))
+		for i, posture := range result.Postures {
+			posture.Android = rebase(posture.Android)
+			postures[i] = posture
+		}
+		result.Postures = postures
+	}
+	result.IOS = rebaseIOS(result.IOS)
+	if len(result.Layouts) > 0 {
+		layouts := make([]LayoutMetrics, len(result.Layouts))
+		for i, layout := range result.Layouts {
+			layout.IOS = rebaseIOS(layout.IOS)
+			layouts[i] = layout
+		}
+		result.Layouts = layouts
+	}
+	if len(result.OSVersions) > 0 {
+		versions := make([]OSMetrics, len(result.OSVersions))
+		for i, version := range result.OSVersions {
+			version.Android = rebase(version.Android)
+			version.IOS = rebaseIOS(version.IOS)
+			versions[i] = version
+		}
+		result.OSVersions = versions
+	}
+	if len(result.Iterations) > 0 {
+		iterations := make([]IterationRecord, len(result.Iterations))
+		for i, record := range result.Iterations {
+			record.Android = rebase(record.Android)
+			record.IOS = rebaseIOS(record.IOS)
+			iterations[i] = record
+		}
+		result.Iterations = iterations
+	}
+	return result
+}
@@ -0,0 +1,168 @@
+package report
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tahatesser/designbench/pkg/redact"
+)
+
+// reproCommitEnv are the CI variables that record the commit a run measured, in order of preference.
+var reproCommitEnv = []string{"GITHUB_SHA", "CI_COMMIT_SHA", "BITRISE_GIT_COMMIT", "CIRCLE_SHA1"}
+
+// ReproScript reconstructs a shell script that repeats the recorded run: the device and build it
+// needs, as comments, then designbench's environment and the command line that produced the report.
+// Values scrubbed from the recording are flagged for the reader to fill in.
+func ReproScript(res Result) string {
+	var b strings.Builder
+	b.WriteString("#!/usr/bin/env bash\n")
+	fmt.Fprintf(&b, "# Reproduces DesignBench run %s", valueOr(res.RunID, "(no run ID)"))
+	if res.Component != "" {
+		fmt.Fprintf(&b, " of %s", res.Component)
+	}
+	b.WriteString(".\n")
+
+	android, ios := primaryMetrics(res)
+	switch {
+	case android != nil:
+		if !android.Timestamp.IsZero() {
+			fmt.Fprintf(&b, "# Recorded %s.\n", android.Timestamp.UTC().Format("2006-01-02 15:04 MST"))
+		}
+		b.WriteString("#\n# App: " + android.Package)
+		if android.Activity != "" {
+			b.WriteString(" (" + android.Activity + ")")
+		}
+		b.WriteString(reproBuild(android.BuildType, android.Variant) + "\n")
+	case ios != nil:
+		if !ios.Timestamp.IsZero() {
+			fmt.Fprintf(&b, "# Recorded %s.\n", ios.Timestamp.UTC().Format("2006-01-02 15:04 MST"))
+		}
+		b.WriteString("#\n# App: " + ios.BundleID + reproBuild(ios.BuildType, "") + "\n")
+	}
+	if res.Install != nil {
+		b.WriteString("# Installed with: " + res.Install.String() + "\n")
+	}
+	for _, line := range reproDevices(res) {
+		b.WriteString("# " + line + "\n")
+	}
+	b.WriteString("#\n# Defaults from designbench.yaml at that revision apply as well; they are not recorded.\n")
+	b.WriteString("set -euo pipefail\n")
+
+	for _, name := range reproCommitEnv {
+		if sha := res.Environment[name]; sha != "" {
+			fmt.Fprintf(&b, "\n# The run measured commit %s (%s); check it out first:\n# git checkout %s\n", sha, name, sha)
+			break
+		}
+	}
+
+	var names []string
+	for name := range res.Environment {
+		if strings.HasPrefix(name, "DESIGNBENCH_") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	if len(names) > 0 {
+		b.WriteString("\n")
+	}
+	scrubbed := false
+	for _, name := range names {
+		value := res.Environment[name]
+		scrubbed = scrubbed || strings.Contains(value, redact.Placeholder)
+		fmt.Fprintf(&b, "export %s=%s\n", name, shellQuote(value))
+	}
+
+	b.WriteString("\n")
+	if res.CLICommand == "" {
+		b.WriteString("# The report records no command line; it predates cliCommand or was not written by the CLI.\n")
+		return b.String()
+	}
+	scrubbed = scrubbed || strings.Contains(res.CLICommand, redact.Placeholder)
+	if scrubbed {
+		b.WriteString("# Secret values were scrubbed from the recording; replace " + redact.Placeholder + " before running.\n")
+	}
+	b.WriteString(res.CLICommand + "\n")
+	return b.String()
+}
+
+// reproBuild describes the build type and variant, e.g. ", release build, variant benchmarkRelease".
+func reproBuild(buildType, variant string) string {
+	out := ""
+	if buildType != "" {
+		out += ", " + buildType + " build"
+	}
+	if variant != "" {
+		out += ", variant " + variant
+	}
+	return out
+}
+
+// reproDevices describes the device of each configuration the result measured.
+func reproDevices(res Result) []string {
+	if len(res.OSVersions) > 0 {
+		lines := []string{"Devices (--os-matrix):"}
+		for _, v := range res.OSVersions {
+			device := PrimaryDevice(Result{Android: v.Android, IOS: v.IOS})
+			lines = append(lines, "  "+v.OS+": "+reproDevice(device))
+		}
+		return lines
+	}
+	if device := PrimaryDevice(res); device != nil {
+		return []string{"Device: " + reproDevice(device)}
+	}
+	return []string{"Device: not recorded"}
+}
+
+// reproDevice summarises what a device must match, e.g. "Pixel 8 (emulator), Android 14,
+// 1080x2400 @ 420dpi, 120Hz, serial emulator-5554".
+func reproDevice(d *DeviceMetadata) string {
+	if d == nil {
+		return "not recorded"
+	}
+	parts := []string{valueOr(d.Model, "unknown model")}
+	switch {
+	case d.IsEmulator:
+		parts[0] += " (emulator)"
+	case d.IsSimulator:
+		parts[0] += " (simulator)"
+	}
+	if d.OSVersion != "" {
+		version := d.OSVersion
+		if d.Platform == "android" {
+			version = "Android " + version
+		}
+		parts = append(parts, version)
+	}
+	if d.Resolution != "" {
+		screen := d.Resolution
+		if d.DensityDPI > 0 {
+			screen += fmt.Sprintf(" @ %ddpi", d.DensityDPI)
+		} else if d.ScaleFactor > 0 {
+			screen += fmt.Sprintf(" @ %gx", d.ScaleFactor)
+		}
+		parts = append(parts, screen)
+	}
+	if d.RefreshRateHz > 0 {
+		parts = append(parts, fmt.Sprintf("%gHz", d.RefreshRateHz))
+	}
+	if d.DisplayID != "" {
+		parts = append(parts, "display "+d.DisplayID)
+	}
+	if d.ID != "" {
+		parts = append(parts, "id "+d.ID)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// shellQuote single-quotes value for a POSIX shell.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Metric families, which share a regression threshold.
+const (
+	FamilyTiming = "timing"
+	FamilyMemory = "memory"
+	FamilyCPU    = "cpu"
+	FamilyJank   = "jank"
+)
+
+// Families are the metric families a threshold can be set for.
+var Families = []string{FamilyTiming, FamilyMemory, FamilyCPU, FamilyJank}
+
+// Threshold units: a relative change, a change in percentage points, or an absolute change in
+// the metric's own unit.
+const (
+	unitRelative = "%"
+	unitPoints   = "pp"
+)
+
+// familyUnits are the units a family's threshold may use besides a relative change.
+var familyUnits = map[string][]string{
+	FamilyTiming: {"ms"},
+	FamilyMemory: {"MB"},
+	FamilyCPU:    {unitPoints},
+	FamilyJank:   {unitPoints},
+}
+
+// Threshold is the largest acceptable increase of a metric: relative to the baseline ("5%"), in
+// percentage points for metrics that are percentages ("1pp"), or absolute ("10MB", "20ms").
+type Threshold struct {
+	Value float64
+	Unit  string
+}
+
+// String formats the threshold as it is written in the config.
+func (t Threshold) String() string {
+	return strconv.FormatFloat(t.Value, 'f', -1, 64) + t.Unit
+}
+
+// Thresholds holds a Threshold per metric family.
+type Thresholds map[string]Threshold
+
+// ParseThresholds reads thresholds per family, e.g. {"timing": "5%", "memory": "10MB"}.
+func ParseThresholds(values map[string]string) (Thresholds, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	thresholds := make(Thresholds, len(values))
+	for family, value := range values {
+		units, ok := familyUnits[family]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric family %q (expected one of %s)", family, strings.Join(Families, ", "))
+		}
+		t, err := parseThreshold(value, units)
+		if err != nil {
+			return nil, fmt.Errorf("%s threshold: %w", family, err)
+		}
+		thresholds[family] = t
+	}
+	return thresholds, nil
+}
+
+func parseThreshold(value string, units []string) (Threshold, error) {
+	value = strings.TrimSpace(value)
+	allowed := append([]string{unitRelative}, units...)
+	for _, unit := range allowed {
+		number, ok := strings.CutSuffix(value, unit)
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil || v <= 0 {
+			break
+		}
+		return Threshold{Value: v, Unit: unit}, nil
+	}
+	examples := make([]string, len(allowed))
+	for i, unit := range allowed {
+		examples[i] = "5" + unit
+	}
+	return Threshold{}, fmt.Errorf("invalid value %q (expected a positive number with a unit: %s)", value, strings.Join(examples, ", "))
+}
+
+// MetricFamily returns the family of a summary, comparison or primary metric, or "" for metrics
+// that belong to none.
+func MetricFamily(metric string) string {
+	switch {
+	case metric == "cpuPercent":
+		return FamilyCPU
+	case metric == "jankPercent" || metric == "animationDroppedFrames":
+		return FamilyJank
+	case strings.HasSuffix(metric, "Mb"):
+		return FamilyMemory
+	case strings.HasSuffix(metric, "Ms"):
+		return FamilyTiming
+	}
+	return ""
+}
+
+// metricUnit returns the unit a metric is measured in, judging by its name.
+func metricUnit(metric string) string {
+	switch {
+	case strings.HasSuffix(metric, "Percent"):
+		return "%"
+	case strings.HasSuffix(metric, "Mb"):
+		return "MB"
+	case strings.HasSuffix(metric, "Ms"):
+		return "ms"
+	}
+	return ""
+}
+
+// For returns the threshold that applies to metric: its family's, unless that is in a unit the
+// metric cannot be compared in, e.g. percentage points for dropped frames. ok is false when none
+// applies, so that callers fall back to their default.
+func (t Thresholds) For(metric string) (Threshold, bool) {
+	threshold, ok := t[MetricFamily(metric)]
+	if !ok {
+		return Threshold{}, false
+	}
+	switch unit := metricUnit(metric); threshold.Unit {
+	case unitRelative:
+		return threshold, true
+	case unitPoints:
+		return threshold, unit == "%"
+	default:
+		return threshold, threshold.Unit == unit
+	}
+}
+
+// Exceeded reports whether the change from baseline to value is larger than the threshold allows.
+func (t Threshold) Exceeded(baseline, value float64) bool {
+	if t.Unit == unitRelative {
+		return baseline > 0 && (value-baseline)/baseline*100 > t.Value
+	}
+	return value-baseline > t.Value
+}
+
+// Regressions returns the metrics of comparisons whose change exceeds their family's threshold, as
+// "<measurement> <metric>" with the threshold.
+func (t Thresholds) Regressions(comparisons []Comparison) []string {
+	var out []string
+	for _, c := range comparisons {
+		for _, m := range c.Metrics {
+			if threshold, ok := t.For(m.Metric); ok && threshold.Exceeded(m.Baseline, m.Candidate) {
+				out = append(out, fmt.Sprintf("%s %s (threshold %s)", c.Name, m.Metric, threshold))
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+// Package reviewdog writes benchmark findings in reviewdog's diagnostic format (rdjsonl), placed
+// in the component's source file so that review tools can show them next to its code.
+package reviewdog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities of a Finding.
+const (
+	SeverityError   = "ERROR"
+	SeverityWarning = "WARNING"
+)
+
+// Finding is one problem with a component, e.g. a metric that regressed.
+type Finding struct {
+	Component string
+	// Platform is android or ios, for picking the platform's source file.
+	Platform string
+	// Metric names the metric concerned; it becomes the diagnostic's code. Empty for failed runs.
+	Metric   string
+	Message  string
+	Severity string
+}
+
+// Source is a location in a component's source: a path relative to the repository root and
+// optionally a line, e.g. the line declaring the composable or view.
+type Source struct {
+	Path string
+	Line int
+}
+
+// SourceMap maps component names to their sources, per platform where they differ, e.g.
+//
+//	Card: shared/Card.kt
+//	PrimaryButton:
+//	  android: app/src/main/java/com/example/ui/PrimaryButton.kt:42
+//	  ios: Sources/UI/PrimaryButton.swift:10
+type SourceMap map[string]map[string]Source
+
+// anyPlatform keys the source used for every platform.
+const anyPlatform = ""
+
+// LoadSourceMap reads a YAML source map.
+func LoadSourceMap(path string) (SourceMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	sources := SourceMap{}
+	for component, node := range raw {
+		byPlatform := map[string]string{}
+		switch node.Kind {
+		case yaml.ScalarNode:
+			byPlatform[anyPlatform] = node.Value
+		case yaml.MappingNode:
+			if err := node.Decode(&byPlatform); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, component, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s: %s: expected a path or a map of platforms to paths", path, component)
+		}
+		sources[component] = map[string]Source{}
+		for platform, location := range byPlatform {
+			if platform != anyPlatform && platform != "android" && platform != "ios" {
+				return nil, fmt.Errorf("%s: %s: unknown platform %q (expected android or ios)", path, component, platform)
+			}
+			source, err := parseSource(location)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, component, err)
+			}
+			sources[component][platform] = source
+		}
+	}
+	return sources, nil
+}
+
+// parseSource splits "path" or "path:line".
+func parseSource(location string) (Source, error) {
+	location = strings.TrimSpace(location)
+	if location == "" {
+		return Source{}, errors.New("empty source path")
+	}
+	if i := strings.LastIndex(location, ":"); i > 0 {
+		if line, err := strconv.Atoi(location[i+1:]); err == nil {
+			if line < 1 {
+				return Source{}, fmt.Errorf("invalid line in %q (lines start at 1)", location)
+			}
+			return Source{Path: location[:i], Line: line}, nil
+		}
+	}
+	return Source{Path: location}, nil
+}
+
+// Lookup returns the source of component on platform, falling back to its source for every platform.
+func (m SourceMap) Lookup(component, platform string) (Source, bool) {
+	byPlatform := m[component]
+	if source, ok := byPlatform[platform]; ok {
+		return source, true
+	}
+	source, ok := byPlatform[anyPlatform]
+	return source, ok
+}
+
+type diagnostic struct {
+	Message  string   `json:"message"`
+	Location location `json:"location"`
+	Severity string   `json:"severity,omitempty"`
+	Source   struct {
+		Name string `json:"name"`
+	} `json:"source"`
+	Code *code `json:"code,omitempty"`
+}
+
+type location struct {
+	Path string `json:"path"`
+	// Range is left out for findings without a line, which reviewdog treats as file-level.
+	Range *lineRange `json:"range,omitempty"`
+}
+
+type lineRange struct {
+	Start struct {
+		Line int `json:"line"`
+	} `json:"start"`
+}
+
+type code struct {
+	Value string `json:"value"`
+}
+
+// Write writes one rdjsonl diagnostic per finding whose component is in sources and returns the
+// components that had none, which cannot be placed in the code.
+func Write(w io.Writer, findings []Finding, sources SourceMap) (unmapped []string, err error) {
+	enc := json.NewEncoder(w)
+	for _, f := range findings {
+		source, ok := sources.Lookup(f.Component, f.Platform)
+		if !ok {
+			unmapped = append(unmapped, f.Component)
+			continue
+		}
+		d := diagnostic{Message: f.Message, Location: location{Path: source.Path}, Severity: f.Severity}
+		d.Source.Name = "designbench"
+		if source.Line > 0 {
+			d.Location.Range = &lineRange{}
+			d.Location.Range.Start.Line = source.Line
+		}
+		if f.Metric != "" {
+			d.Code = &code{Value: f.Metric}
+		}
+		if err := enc.Encode(d); err != nil {
+			return unmapped, err
+		}
+	}
+	return unmapped, nil
+}
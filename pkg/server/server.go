@@ -0,0 +1,545 @@
+// Package server exposes benchmark runs over a small JSON API so that dashboards and bots can
+// schedule them on the machine the devices are attached to.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tahatesser/designbench/pkg/events"
+)
+
+// Status is the lifecycle state of a run.
+type Status string
+
+// Run statuses reported by the API.
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// followInterval is how often Follow checks a run's event log for new lines.
+const followInterval = 250 * time.Millisecond
+
+// queueSize bounds how many runs can wait for the device; further requests get 503.
+const queueSize = 64
+
+// reservedArgs are set by the server for every run and may not appear in a trusted request either.
+var reservedArgs = []string{"-o", "--output", "--format", "--append", "--no-save", "--log-file"}
+
+// clientArgs are the flags a client may pass, mapped to whether they take a value. Everything else
+// is refused: flags such as --gradle-args, --config, --trace=<file> or --export read or write
+// files on the host or run code there.
+var clientArgs = map[string]bool{
+	"--view":               true,
+	"--component":          true,
+	"--iterations":         true,
+	"--scenario":           true,
+	"--timeout":            true,
+	"--target-ci-width":    true,
+	"--percentiles":        true,
+	"--idle-cpu":           true,
+	"--idle-timeout":       true,
+	"--settle":             true,
+	"--launch-timeout":     true,
+	"--events":             true,
+	"--taps":               true,
+	"--animation":          true,
+	"--animation-duration": true,
+	"--allow-debug":        false,
+}
+
+// maxClientIterations caps --iterations from clients, so that one run cannot hold the device for
+// everyone queued behind it.
+const maxClientIterations = 100
+
+// nameArgs are the client flags whose values reach the device's shell as intent extras, so they
+// must be plain names matching nameRe.
+var nameArgs = map[string]bool{"--view": true, "--component": true, "--animation": true}
+
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Config configures a Server.
+type Config struct {
+	// Executable is the designbench binary started for each run.
+	Executable string
+	// Dir holds one directory per run with its report, NDJSON events and console output.
+	Dir string
+	// BaseArgs are passed to every run before the request's arguments, e.g. --config.
+	BaseArgs []string
+	// Token, when set, must be sent as `Authorization: Bearer <token>`.
+	Token string
+}
+
+// Errors returned by Submit and Follow.
+var (
+	ErrQueueFull   = fmt.Errorf("%d runs already queued", queueSize)
+	ErrRunNotFound = errors.New("run not found")
+)
+
+// Run is a benchmark requested through the API.
+type Run struct {
+	ID         string     `json:"id"`
+	Platform   string     `json:"platform"`
+	Component  string     `json:"component,omitempty"`
+	Args       []string   `json:"args,omitempty"`
+	Status     Status     `json:"status"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Finished reports whether the run has succeeded or failed.
+func (r Run) Finished() bool {
+	return r.Status == StatusSucceeded || r.Status == StatusFailed
+}
+
+// RunRequest is the body of POST /api/runs. Args are extra designbench flags such as
+// ["--iterations", "5", "--scenario", "theme-switch"].
+type RunRequest struct {
+	Platform  string   `json:"platform"`
+	Component string   `json:"component,omitempty"`
+	Args      []string `json:"args,omitempty"`
+}
+
+// Server queues runs and executes them one at a time, since they share the attached devices.
+type Server struct {
+	cfg     Config
+	started time.Time
+
+	mu    sync.Mutex
+	seq   int
+	runs  map[string]*Run
+	order []string
+	queue chan string
+}
+
+// New returns a server; call Run to start executing queued runs.
+func New(cfg Config) *Server {
+	return &Server{
+		cfg:     cfg,
+		started: time.Now(),
+		runs:    make(map[string]*Run),
+		queue:   make(chan string, queueSize),
+	}
+}
+
+// Run executes queued runs until ctx is cancelled; a run in progress is stopped with it.
+func (s *Server) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			s.execute(ctx, id)
+		}
+	}
+}
+
+// Handler serves the API:
+//
+//	POST /api/runs              queue a run (RunRequest body), returns the Run
+//	GET  /api/runs              list runs, newest first
+//	GET  /api/runs/{id}         run status
+//	GET  /api/runs/{id}/report  JSON report of a succeeded run
+//	GET  /api/runs/{id}/events  NDJSON progress events so far
+//	GET  /api/runs/{id}/output  console output so far
+//	GET  /api/health            queue length and the running run
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/runs", s.createRun)
+	mux.HandleFunc("GET /api/runs", s.listRuns)
+	mux.HandleFunc("GET /api/runs/{id}", s.getRun)
+	mux.HandleFunc("GET /api/runs/{id}/report", s.serveRunFile("report.json", "application/json", true))
+	mux.HandleFunc("GET /api/runs/{id}/events", s.serveRunFile("events.ndjson", "application/x-ndjson", false))
+	mux.HandleFunc("GET /api/runs/{id}/output", s.serveRunFile("output.log", "text/plain; charset=utf-8", false))
+	mux.HandleFunc("GET /api/health", s.health)
+	if s.cfg.Token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.cfg.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) createRun(w http.ResponseWriter, r *http.Request) {
+	var req RunRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	run, err := s.Submit(req)
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/api/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+// Submit validates a client's req, whose args may only hold the flags in clientArgs, and queues
+// it. It fails with ErrQueueFull when queueSize runs are waiting.
+func (s *Server) Submit(req RunRequest) (Run, error) {
+	if err := req.validate(); err != nil {
+		return Run{}, err
+	}
+	if req.Component != "" && !nameRe.MatchString(req.Component) {
+		return Run{}, fmt.Errorf("invalid component %q: only letters, digits, '.', '_' and '-' are allowed", req.Component)
+	}
+	if err := validateClientArgs(req.Args); err != nil {
+		return Run{}, err
+	}
+	return s.enqueue(req)
+}
+
+// SubmitTrusted queues req from the host's own configuration, e.g. a suite entry, whose args may
+// hold any flag the server does not set itself.
+func (s *Server) SubmitTrusted(req RunRequest) (Run, error) {
+	if err := req.validate(); err != nil {
+		return Run{}, err
+	}
+	for _, arg := range req.Args {
+		if arg == "--" {
+			return Run{}, errors.New("args cannot contain --")
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		if slices.Contains(reservedArgs, name) || (strings.HasPrefix(arg, "-o") && !strings.HasPrefix(arg, "--")) {
+			return Run{}, fmt.Errorf("%s is set by the server", name)
+		}
+	}
+	return s.enqueue(req)
+}
+
+func (s *Server) enqueue(req RunRequest) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	run := &Run{
+		ID:        fmt.Sprintf("%s-%d", s.started.Format("20060102-150405"), s.seq),
+		Platform:  req.Platform,
+		Component: req.Component,
+		Args:      req.Args,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+	}
+	select {
+	case s.queue <- run.ID:
+	default:
+		return Run{}, ErrQueueFull
+	}
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+	return *run, nil
+}
+
+func (req RunRequest) validate() error {
+	if req.Platform != "android" && req.Platform != "ios" {
+		return fmt.Errorf("platform must be android or ios, got %q", req.Platform)
+	}
+	return nil
+}
+
+// validateClientArgs accepts only the flags in clientArgs, as --flag value or --flag=value. A value
+// may not start with a dash, so it cannot smuggle in another flag. Names must match nameRe, the
+// timeout must be positive and iterations are capped at maxClientIterations.
+func validateClientArgs(args []string) error {
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		takesValue, ok := clientArgs[name]
+		if !ok {
+			return fmt.Errorf("%s is not allowed; runs accept only %s", name, strings.Join(slices.Sorted(maps.Keys(clientArgs)), ", "))
+		}
+		switch {
+		case hasValue:
+		case takesValue && i+1 < len(args):
+			i++
+			value = args[i]
+		case takesValue:
+			return fmt.Errorf("%s needs a value", name)
+		default:
+			continue
+		}
+		if strings.HasPrefix(value, "-") {
+			return fmt.Errorf("invalid value %q for %s", value, name)
+		}
+		if err := validateClientValue(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateClientValue checks the flags whose values could reach the device's shell or keep the
+// single worker busy indefinitely.
+func validateClientValue(name, value string) error {
+	switch {
+	case nameArgs[name]:
+		if !nameRe.MatchString(value) {
+			return fmt.Errorf("invalid value %q for %s: only letters, digits, '.', '_' and '-' are allowed", value, name)
+		}
+	case name == "--timeout":
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid value %q for %s: must be a positive duration", value, name)
+		}
+	case name == "--iterations":
+		if n, err := strconv.Atoi(value); err != nil || n < 1 || n > maxClientIterations {
+			return fmt.Errorf("invalid value %q for %s: must be between 1 and %d", value, name, maxClientIterations)
+		}
+	}
+	return nil
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	runs := make([]Run, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		runs = append(runs, *s.runs[s.order[i]])
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.Lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrRunNotFound.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// serveRunFile returns a handler for one of the files in a run's directory. Files that only exist
+// once the run has finished (the report) answer 409 while it is queued or running.
+func (s *Server) serveRunFile(name, contentType string, final bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		run, ok := s.Lookup(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, ErrRunNotFound.Error())
+			return
+		}
+		if final && !run.Finished() {
+			writeError(w, http.StatusConflict, fmt.Sprintf("run is %s", run.Status))
+			return
+		}
+		data, err := os.ReadFile(s.RunFile(run.ID, name))
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("%s not available", name))
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(data)
+	}
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := struct {
+		Status  string `json:"status"`
+		Queued  int    `json:"queued"`
+		Running string `json:"running,omitempty"`
+	}{Status: "ok"}
+	for _, run := range s.runs {
+		switch run.Status {
+		case StatusQueued:
+			status.Queued++
+		case StatusRunning:
+			status.Running = run.ID
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, status)
+}
+
+// Lookup returns a snapshot of the run with the given ID.
+func (s *Server) Lookup(id string) (Run, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return Run{}, false
+	}
+	return *run, true
+}
+
+// RunFile returns the path of a file in the run's directory: report.json, events.ndjson or output.log.
+func (s *Server) RunFile(id, name string) string {
+	return filepath.Join(s.cfg.Dir, id, name)
+}
+
+// Follow calls fn with every progress event of the run, in order, until the run has finished and
+// all its events were delivered, fn returns an error, or ctx is cancelled.
+func (s *Server) Follow(ctx context.Context, id string, fn func(events.Event) error) error {
+	var offset int64
+	for {
+		// Read the status before the log so that events written just before the run finished
+		// are still delivered.
+		run, ok := s.Lookup(id)
+		if !ok {
+			return ErrRunNotFound
+		}
+		n, err := s.deliverEvents(id, offset, fn)
+		offset += n
+		if err != nil {
+			return err
+		}
+		if run.Finished() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(followInterval):
+		}
+	}
+}
+
+// deliverEvents passes the complete lines of the event log after offset to fn and returns how many
+// bytes it consumed; a line still being written is left for the next call.
+func (s *Server) deliverEvents(id string, offset int64, fn func(events.Event) error) (int64, error) {
+	f, err := os.Open(s.RunFile(id, "events.ndjson"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return 0, err
+	}
+	end := bytes.LastIndexByte(data, '\n') + 1
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var event events.Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return int64(end), fmt.Errorf("parse event log: %w", err)
+		}
+		if err := fn(event); err != nil {
+			return int64(end), err
+		}
+	}
+	return int64(end), nil
+}
+
+func (s *Server) update(id string, apply func(*Run)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	apply(s.runs[id])
+}
+
+// execute starts `designbench <platform>` for the run with the report, event log and console
+// output redirected into the run's directory.
+func (s *Server) execute(ctx context.Context, id string) {
+	run, _ := s.Lookup(id)
+	started := time.Now()
+	s.update(id, func(r *Run) {
+		r.Status = StatusRunning
+		r.StartedAt = &started
+	})
+
+	err := s.runCommand(ctx, run)
+	finished := time.Now()
+	s.update(id, func(r *Run) {
+		r.FinishedAt = &finished
+		if err != nil {
+			r.Status = StatusFailed
+			r.Error = err.Error()
+			return
+		}
+		r.Status = StatusSucceeded
+	})
+}
+
+func (s *Server) runCommand(ctx context.Context, run Run) error {
+	dir, err := filepath.Abs(filepath.Join(s.cfg.Dir, run.ID))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create run dir: %w", err)
+	}
+	output, err := os.Create(filepath.Join(dir, "output.log"))
+	if err != nil {
+		return fmt.Errorf("create output log: %w", err)
+	}
+	defer output.Close()
+
+	args := append([]string{run.Platform}, s.cfg.BaseArgs...)
+	if run.Component != "" {
+		args = append(args, "--component="+run.Component)
+	}
+	args = append(args, run.Args...)
+	args = append(args,
+		"--output", filepath.Join(dir, "report.json"),
+		"--format", "json",
+		"--log-file", filepath.Join(dir, "events.ndjson"))
+	cmd := exec.CommandContext(ctx, s.cfg.Executable, args...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("designbench %s exited with status %d: %s", run.Platform, exitErr.ExitCode(), lastLine(filepath.Join(dir, "output.log")))
+		}
+		return fmt.Errorf("start designbench %s: %w", run.Platform, err)
+	}
+	return nil
+}
+
+// lastLine returns the final non-empty line of the file at path, which holds the command's error.
+func lastLine(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,143 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// tCritical95 holds two-sided 95% Student's t critical values indexed by degrees of freedom.
+var tCritical95 = []float64{
+	0, 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262,
+	2.228, 2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093,
+	2.086, 2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045,
+	2.042,
+}
+
+// Mean returns the arithmetic mean of values, or 0 when values is empty.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// StdDev returns the sample standard deviation of values.
+func StdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := Mean(values)
+	var sq float64
+	for _, v := range values {
+		d := v - mean
+		sq += d * d
+	}
+	return math.Sqrt(sq / float64(len(values)-1))
+}
+
+// Median returns the middle value of values, averaging the two central values for even counts.
+func Median(values []float64) float64 {
+	return Percentile(values, 50)
+}
+
+// Percentile returns the p-th percentile (0-100) of values using linear interpolation.
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[len(sorted)-1]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	frac := rank - float64(lower)
+	return sorted[lower] + (sorted[upper]-sorted[lower])*frac
+}
+
+// CIHalfWidth returns the half-width of the 95% confidence interval for the mean of values.
+func CIHalfWidth(values []float64) float64 {
+	n := len(values)
+	if n < 2 {
+		return math.Inf(1)
+	}
+	df := n - 1
+	t := 1.96
+	if df < len(tCritical95) {
+		t = tCritical95[df]
+	}
+	return t * StdDev(values) / math.Sqrt(float64(n))
+}
+
+// RelativeCIHalfWidth returns CIHalfWidth as a fraction of the mean (0.05 == ±5%).
+func RelativeCIHalfWidth(values []float64) float64 {
+	mean := Mean(values)
+	if mean == 0 {
+		return math.Inf(1)
+	}
+	return CIHalfWidth(values) / math.Abs(mean)
+}
+
+// MannWhitneyP returns the two-sided p-value of the Mann-Whitney U test that a and b come from the
+// same distribution. It uses the normal approximation with a tie correction, which is coarse for
+// fewer than about eight samples per side. Samples without any spread give 1.
+func MannWhitneyP(a, b []float64) float64 {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	type sample struct {
+		value float64
+		first bool
+	}
+	pooled := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		pooled = append(pooled, sample{v, true})
+	}
+	for _, v := range b {
+		pooled = append(pooled, sample{v, false})
+	}
+	sort.Slice(pooled, func(i, j int) bool { return pooled[i].value < pooled[j].value })
+
+	// Tied values share the mean of their ranks.
+	var rankSum, ties float64
+	for i := 0; i < len(pooled); {
+		j := i
+		for j < len(pooled) && pooled[j].value == pooled[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if pooled[k].first {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	u := rankSum - n1*(n1+1)/2
+	n := n1 + n2
+	variance := n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+	// Continuity correction towards the mean.
+	z := (math.Abs(u-n1*n2/2) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		return 1
+	}
+	return math.Erfc(z / math.Sqrt2)
+}
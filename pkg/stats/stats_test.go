@@ -0,0 +1,32 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRelativeCIHalfWidth(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{name: "no spread", values: []float64{10, 10, 10}, want: 0},
+		{name: "two samples", values: []float64{9, 11}, want: 12.706 / 10},
+		{name: "zero mean", values: []float64{0, 0, 0}, want: math.Inf(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RelativeCIHalfWidth(tt.values)
+			if math.IsInf(tt.want, 1) {
+				if !math.IsInf(got, 1) {
+					t.Fatalf("RelativeCIHalfWidth(%v) = %v, want +Inf", tt.values, got)
+				}
+				return
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("RelativeCIHalfWidth(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
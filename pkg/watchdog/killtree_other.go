@@ -0,0 +1,8 @@
+//go:build !darwin && !linux
+
+package watchdog
+
+import "os/exec"
+
+// killTree keeps exec's default of killing only the process, which is all this platform offers.
+func killTree(cmd *exec.Cmd) {}
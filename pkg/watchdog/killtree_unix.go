@@ -0,0 +1,15 @@
+//go:build darwin || linux
+
+package watchdog
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func killTree(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,49 @@
+// Package watchdog bounds launch commands that can hang, such as `am start -W` waiting for an
+// activity that never draws, so that one stuck iteration fails on its own instead of using up the
+// whole run's timeout.
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// HungError reports a command that was killed for running past its deadline.
+type HungError struct {
+	Command  string
+	Deadline time.Duration
+}
+
+func (e *HungError) Error() string {
+	return fmt.Sprintf("%s hung for more than %s and was killed", e.Command, e.Deadline)
+}
+
+// IsHung reports whether err is or wraps a HungError.
+func IsHung(err error) bool {
+	var hung *HungError
+	return errors.As(err, &hung)
+}
+
+// Start returns a context for a command that ends deadline from now, or a plain child of ctx when
+// deadline is not positive.
+func Start(ctx context.Context, deadline time.Duration) (context.Context, context.CancelFunc) {
+	if deadline <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, deadline)
+}
+
+// Fired reports whether cmdCtx, made by Start from ctx, ended because of its own deadline rather
+// than ctx's cancellation or timeout.
+func Fired(ctx, cmdCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(cmdCtx.Err(), context.DeadlineExceeded)
+}
+
+// KillTree makes cmd kill its whole process group when its context ends, so that children such as
+// adb's shell or simctl's helpers do not outlive it. It must be called before cmd starts.
+func KillTree(cmd *exec.Cmd) {
+	killTree(cmd)
+}
@@ -25,6 +25,84 @@ EOF
 			echo "Load: 5.00 / 3.00 / 2.00"
 			echo " 10% 4242/com.example.app"
 			;;
+		display)
+			echo 'DisplayDeviceInfo{"Built-in Screen": uniqueId="local:0", 1080 x 2400, modeId 2, renderFrameRate 90.0, defaultModeId 1}'
+			echo '  mBaseDisplayInfo=DisplayInfo{"Built-in Screen", displayId 0, displayGroupId 0, real 1080 x 2400, type INTERNAL, modeId 2, renderFrameRate 90.0}'
+			echo '  mBaseDisplayInfo=DisplayInfo{"HDMI Screen", displayId 2, displayGroupId 1, real 1920 x 1080, type EXTERNAL, modeId 1, renderFrameRate 60.0}'
+			;;
+		gfxinfo)
+			cat <<'EOF'
+Applications Graphics Acceleration Info:
+---PROFILEDATA---
+Flags,FrameTimelineVsyncId,IntendedVsync,Vsync,HandleInputStart,PerformTraversalsStart,DrawStart,SyncQueued,SyncStart,IssueDrawCommandsStart,FrameCompleted,
+1,1,1000000000,1000000000,0,1001000000,1040000000,1060000000,1061000000,1070000000,1090000000,
+0,2,1016000000,1016000000,1016400000,1017000000,1018000000,1019500000,1019600000,1020000000,1024000000,
+0,3,1033000000,1033000000,0,1033500000,1036000000,1039000000,1039200000,1040000000,1045000000,
+0,4,1050000000,1050000000,0,1050400000,1051500000,1053000000,1053100000,1054000000,1059500000,
+---PROFILEDATA---
+EOF
+			;;
+		package)
+			cat <<EOF
+Packages:
+  Package [${1:-com.example.app}] (5f3c2a1):
+    userId=10123
+    flags=[ ${MOCK_ADB_DEBUGGABLE:+DEBUGGABLE }HAS_CODE ALLOW_CLEAR_USER_DATA ALLOW_BACKUP ]
+EOF
+			;;
+		binder_calls_stats)
+			# Counters grow on every read, so a launch window sees new calls.
+			local counter="${TMPDIR:-/tmp}/mock-adb-binder-reads"
+			local n=$(( $(cat "$counter" 2>/dev/null || echo 0) + 1 ))
+			echo "$n" >"$counter"
+			cat <<EOF
+Start time: 2024-01-01 12:00:00
+Sampling interval period: 1000
+Raw data (uid, call_desc, screen_interactive, cpu_time_micros, max_cpu_time_micros, latency_time_micros, max_latency_time_micros, exception_count, max_request_size_bytes, max_reply_size_bytes, recorded_call_count, call_count):
+    10123,android.app.IActivityManager#getRunningAppProcesses,true,$((n * 300)),300,$((n * 1800)),1800,0,120,880,$n,$((n * 2))
+    10123,android.content.pm.IPackageManager#getPackageInfo,true,$((n * 150)),150,$((n * 400)),600,0,96,2048,$n,$((n * 5))
+    1000,android.os.IPowerManager#isInteractive,true,$((n * 20)),20,$((n * 30)),30,0,64,64,$n,$n
+
+Per-UID Summary(UID: time, % of total time, calls_count, exceptions):
+EOF
+			;;
+		SurfaceFlinger)
+			case "${1:-}" in
+				--list)
+					cat <<'EOF'
+Display 0 (active) HWC layers:
+com.example.app/com.example.app.BenchmarkActivity#0
+StatusBar#0
+EOF
+					return
+					;;
+				--latency)
+					cat <<'EOF'
+16666666
+1000000000 1000000000 999000000
+1016666666 1033333332 1020000000
+1049999998 1049999998 1045000000
+1066666664 9223372036854775807 1060000000
+EOF
+					return
+					;;
+			esac
+			cat <<'EOF'
+Display Frame 1
+	Token: 100
+	Jank Type : None
+	Layer - com.example.app/com.example.app.BenchmarkActivity#0
+		Token: 101
+		Owner Pid : 4242
+		Present State : Presented
+		Jank Type : None
+	Layer - com.example.app/com.example.app.BenchmarkActivity#0 [*]
+		Token: 102
+		Owner Pid : 4242
+		Present State : Presented
+		Jank Type : App Deadline Missed, Buffer Stuffing
+EOF
+			;;
 		*)
 			echo ""
 			;;
@@ -39,6 +117,12 @@ mock_getprop() {
 		ro.build.version.release)
 			echo "14"
 			;;
+		ro.build.version.sdk)
+			echo "34"
+			;;
+		sys.boot_completed)
+			echo "1"
+			;;
 		*)
 			echo ""
 			;;
@@ -68,6 +152,15 @@ handle_shell() {
 			echo "4242"
 			;;
 		top)
+			if [[ " $* " == *" -o "* ]]; then
+				echo "Tasks: 812 total,   1 running, 811 sleeping,   0 stopped,   0 zombie"
+				echo "  PID [%CPU] NAME"
+				echo " 2211 38.4 com.google.android.gms.persistent"
+				echo " 9001  3.8 top"
+				echo "  612  7.6 surfaceflinger"
+				echo " 1503  0.0 system_server"
+				return
+			fi
 			echo "Tasks: 1 total"
 			echo "PID   CPU%   NAME"
 			echo "4242  10%   com.example.app"
@@ -81,16 +174,139 @@ handle_shell() {
 			;;
 		wm)
 			if [[ "${1:-}" == "size" ]]; then
-				echo "Physical size: 1080x2400"
+				if [[ "${2:-}" == "-d" && "${3:-}" != "0" ]]; then
+					echo "Physical size: 1920x1080"
+				else
+					echo "Physical size: 1080x2400"
+				fi
+				return
+			fi
+			if [[ "${1:-}" == "density" ]]; then
+				if [[ "${2:-}" == "-d" && "${3:-}" != "0" ]]; then
+					echo "Physical density: 160"
+				else
+					echo "Physical density: 420"
+				fi
 				return
 			fi
 			usage "wm $*"
 			;;
 		cat)
+			if [[ "${1:-}" == "/proc/stat" ]]; then
+				local ticks=$(( $(date +%s) * 100 ))
+				echo "cpu  $(( ticks / 10 )) 0 0 $(( ticks * 9 / 10 )) 0 0 0 0 0 0"
+				return
+			fi
+			if [[ "${1:-}" == "/proc/meminfo" ]]; then
+				echo "MemTotal:        7864320 kB"
+				echo "MemAvailable:    3145728 kB"
+				return
+			fi
+			if [[ "${1:-}" == /data/local/tmp/designbench-ui.xml ]]; then
+				cat <<'EOF'
+<?xml version='1.0' encoding='UTF-8' standalone='yes' ?><hierarchy rotation="0"><node index="0" text="" resource-id="" content-desc="" bounds="[0,0][1080,2400]"><node index="0" text="Settings" resource-id="com.example.app:id/settings" content-desc="" bounds="[40,200][1040,320]" /><node index="1" text="" resource-id="" content-desc="Primary button" bounds="[40,400][1040,520]" /></node></hierarchy>
+EOF
+				return
+			fi
 			if [[ "${1:-}" =~ ^/proc/([0-9]+)/stat$ ]]; then
 				echo "4242 (mock) S 0 0 0 0 0 0 0 0 0 0 100 50 0 0 0 0 0 0 0 0 0 0 0 0"
 				return
 			fi
+			if [[ "${1:-}" =~ ^/proc/([0-9]+)/task/([0-9]+)/schedstat$ ]]; then
+				echo "420000000 35000000 812"
+				return
+			fi
+			return 0
+			;;
+		cmd)
+			if [[ "${1:-}" == "device_state" && "${2:-}" == "print-states" ]]; then
+				echo "Supported states: ["
+				echo "  DeviceState{identifier=0, name='CLOSED', app_accessible=true},"
+				echo "  DeviceState{identifier=1, name='HALF_OPENED', app_accessible=true},"
+				echo "  DeviceState{identifier=2, name='OPENED', app_accessible=true},"
+				echo "]"
+			fi
+			return 0
+			;;
+		perfetto)
+			cat >/dev/null
+			echo "4300"
+			;;
+		kill)
+			# Report the background perfetto session as already finished.
+			[[ "${1:-}" != "-0" ]]
+			;;
+		rm)
+			return 0
+			;;
+		date)
+			echo "01-01 12:00:00.000"
+			;;
+		uiautomator)
+			echo "UI hierchary dumped to: ${2:-}"
+			;;
+		input)
+			return 0
+			;;
+		run-as)
+			if [[ " $* " == *" cat "* ]]; then
+				echo '{"itemsLoaded": 24, "cacheHitMs": 3.5, "label": "ignored"}'
+			fi
+			return 0
+			;;
+		settings)
+			case "${3:-}" in
+				*_scale)
+					echo "1.0"
+					;;
+				stay_on_while_plugged_in)
+					echo "3"
+					;;
+				always_finish_activities | low_power)
+					echo "0"
+					;;
+				*)
+					echo "null"
+					;;
+			esac
+			;;
+		df)
+			echo "Filesystem     1K-blocks    Used Available Use% Mounted on"
+			echo "/dev/block/dm-5 52428800 20971520  31457280  41% /data"
+			;;
+		pm)
+			case "${1:-}" in
+				path)
+					echo "package:/data/app/~~mock==/${2:-com.example.app}-1/base.apk"
+					;;
+				list)
+					echo "package:${!#} uid:10123"
+					;;
+				*)
+					usage "pm $*"
+					;;
+			esac
+			;;
+		monkey)
+			sleep 3
+			echo ":Monkey: seed=1 count=${!#}"
+			echo "// CRASH: com.example.app (pid 4242)"
+			echo "Events injected: ${!#}"
+			echo "// Monkey finished"
+			;;
+		stat)
+			# Method traces and other device files report a fixed size.
+			echo "524288"
+			;;
+		sh)
+			# Run shell workloads (e.g. calibration) on the host; the arguments arrive quoted as on a device.
+			eval "/bin/sh $*"
+			;;
+		ls)
+			if [[ "${1:-}" =~ ^/proc/([0-9]+)/task$ ]]; then
+				printf '4242\n4243\n4244\n4250\n'
+				return
+			fi
 			return 0
 			;;
 		*)
@@ -116,7 +332,7 @@ case "$cmd" in
 	devices)
 		if [[ "${1:-}" == "-l" ]]; then
 			echo "List of devices attached"
-			echo "${DEVICE_ID}\tdevice usb:1-1 product:mock model:Pixel_Mock device:pixelmock"
+			printf '%s\tdevice usb:1-1 product:mock model:Pixel_Mock device:pixelmock\n' "${DEVICE_ID}"
 			exit 0
 		fi
 		usage "devices $*"
@@ -127,6 +343,48 @@ case "$cmd" in
 	getprop)
 		echo ""
 		;;
+	logcat)
+		case " $* " in
+			*" StrictMode:D "*)
+				echo "01-01 12:00:00.050  4242  4242 D StrictMode: StrictMode policy violation; ~duration=45 ms: android.os.strictmode.DiskReadViolation"
+				echo "01-01 12:00:00.050  4242  4242 D StrictMode: 	at android.os.StrictMode\$AndroidBlockGuardPolicy.onReadFromDisk(StrictMode.java:1659)"
+				echo "01-01 12:00:00.060  4242  4242 D StrictMode: StrictMode policy violation; ~duration=180 ms: android.os.strictmode.NetworkViolation"
+				echo "01-01 12:00:00.080  4242  4242 I DesignBench: render-complete component=Card"
+				echo "01-01 12:00:00.090  4242  4242 D StrictMode: StrictMode policy violation; ~duration=12 ms: android.os.strictmode.DiskReadViolation"
+				echo "01-01 12:00:00.120  4242  4260 D StrictMode: StrictMode policy violation: android.os.strictmode.LeakedClosableViolation: A resource was acquired at attached stack trace but never released."
+				;;
+			*" DesignBench:I "*)
+				echo "01-01 12:00:00.020  4242  4242 I DesignBench: components names=[\"Card\",\"PrimaryButton\"]"
+				;;
+			*" ActivityTaskManager:I "*)
+				echo "I/ActivityTaskManager( 1234): Fully drawn com.example/.Main: +1s20ms"
+				;;
+			*)
+				echo "01-01 12:00:00.100  4242  4242 I mock    : Benchmark component rendered"
+				;;
+		esac
+		;;
+	version)
+		echo "Android Debug Bridge version 1.0.41"
+		echo "Version 34.0.5-10900879"
+		echo "Installed as /opt/android-sdk/platform-tools/adb"
+		;;
+	emu)
+		echo "OK: killing emulator, bye bye"
+		;;
+	install)
+		apk="${*: -1}"
+		if [[ ! -f "$apk" ]]; then
+			echo "adb: failed to stat $apk: No such file or directory" >&2
+			exit 1
+		fi
+		echo "Performing Streamed Install"
+		echo "Success"
+		;;
+	pull)
+		: >"${2:-/dev/null}"
+		echo "mock-adb: pulled ${1:-}"
+		;;
 	*)
 		echo "mock-adb: noop for $cmd $*" >&2
 		;;